@@ -0,0 +1,45 @@
+// Package config loads the optional fsdiff TOML configuration file.
+package config
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/notify"
+)
+
+// Config is the top-level fsdiff configuration
+type Config struct {
+	// Notification sinks, one per [[notify]] table
+	Notify []notify.SinkConfig `toml:"notify"`
+
+	// External change analyzers, one per [[analyzer]] table
+	Analyzers []AnalyzerConfig `toml:"analyzer"`
+}
+
+// AnalyzerConfig describes a subprocess analyzer, see diff.ExecAnalyzer
+type AnalyzerConfig struct {
+	Name    string   `toml:"name"`
+	Command []string `toml:"command"` // Program and arguments
+}
+
+// Load reads and decodes the configuration file at path
+func Load(path string) (*Config, error) {
+	var cfg Config
+	md, err := toml.DecodeFile(path, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode config %s: %v", path, err)
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("unknown keys in config %s: %v", path, undecoded)
+	}
+
+	for i, a := range cfg.Analyzers {
+		if a.Name == "" || len(a.Command) == 0 {
+			return nil, fmt.Errorf("analyzer %d in %s needs a name and a command", i+1, path)
+		}
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,125 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+// Change is a single change handed to analyzers
+type Change struct {
+	Path    string               `json:"path"`
+	Type    ChangeType           `json:"type"`
+	Old     *snapshot.FileRecord `json:"old,omitempty"`
+	New     *snapshot.FileRecord `json:"new,omitempty"`
+	Changes []string             `json:"changes,omitempty"` // What changed, for modified files
+}
+
+// Finding is something an analyzer wants to report about a change
+type Finding struct {
+	Analyzer string     `json:"analyzer"`
+	Path     string     `json:"path"`
+	Type     ChangeType `json:"type"`
+	Severity int        `json:"severity"` // 1-10 scale, same as CriticalChange
+	Message  string     `json:"message"`
+}
+
+// Analyzer inspects changes and attaches findings. Analyzers that hold
+// resources may also implement io.Closer; Close is called once all changes
+// have been analyzed.
+type Analyzer interface {
+	Name() string
+	Analyze(change *Change) ([]Finding, error)
+}
+
+var (
+	analyzersMu sync.Mutex
+	analyzers   []Analyzer
+)
+
+// RegisterAnalyzer adds an analyzer that runs on every diff. It is meant to
+// be called from init functions of org-specific detector packages.
+func RegisterAnalyzer(a Analyzer) {
+	analyzersMu.Lock()
+	defer analyzersMu.Unlock()
+	analyzers = append(analyzers, a)
+}
+
+// RegisteredAnalyzers returns all analyzers added with RegisterAnalyzer
+func RegisteredAnalyzers() []Analyzer {
+	analyzersMu.Lock()
+	defer analyzersMu.Unlock()
+	return append([]Analyzer(nil), analyzers...)
+}
+
+// RunAnalyzers feeds every change in the result to each analyzer and stores
+// their findings on the result, highest severity first. An analyzer that
+// fails is reported in the returned error but doesn't stop the others.
+func (r *Result) RunAnalyzers(list []Analyzer) error {
+	var errs []error
+
+	for _, a := range list {
+		err := r.forEachChange(func(change *Change) error {
+			findings, err := a.Analyze(change)
+			if err != nil {
+				return err
+			}
+			for _, f := range findings {
+				f.Analyzer = a.Name()
+				if f.Path == "" {
+					f.Path = change.Path
+				}
+				if f.Type == "" {
+					f.Type = change.Type
+				}
+				r.Findings = append(r.Findings, f)
+			}
+			return nil
+		})
+
+		if closer, ok := a.(io.Closer); ok {
+			if cerr := closer.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("analyzer %s: %v", a.Name(), err))
+		}
+	}
+
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		return r.Findings[i].Severity > r.Findings[j].Severity
+	})
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d analyzers failed: %v", len(errs), errs)
+	}
+	return nil
+}
+
+// forEachChange calls fn for every change in path order
+func (r *Result) forEachChange(fn func(*Change) error) error {
+	byType := r.GetChangesByType()
+
+	for _, path := range byType[ChangeAdded] {
+		if err := fn(&Change{Path: path, Type: ChangeAdded, New: r.Added[path]}); err != nil {
+			return err
+		}
+	}
+	for _, path := range byType[ChangeModified] {
+		detail := r.Modified[path]
+		if err := fn(&Change{Path: path, Type: ChangeModified, Old: detail.OldRecord, New: detail.NewRecord, Changes: detail.Changes}); err != nil {
+			return err
+		}
+	}
+	for _, path := range byType[ChangeDeleted] {
+		if err := fn(&Change{Path: path, Type: ChangeDeleted, Old: r.Deleted[path]}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
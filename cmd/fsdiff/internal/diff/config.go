@@ -9,6 +9,7 @@ import (
 // Config holds diff configuration
 type Config struct {
 	IgnorePatterns []string
+	Workers        int // Workers used to shard large comparisons; <= 1 disables
 	Verbose        bool
 	ShowHashes     bool
 	OnlyChanges    bool
@@ -28,6 +29,8 @@ type Result struct {
 	Added     map[string]*snapshot.FileRecord `json:"added"`
 	Modified  map[string]*ChangeDetail        `json:"modified"`
 	Deleted   map[string]*snapshot.FileRecord `json:"deleted"`
+	Findings  []Finding                       `json:"findings,omitempty"` // Set by RunAnalyzers
+	State     []StateChange                   `json:"state_changes,omitempty"`
 	Summary   Summary                         `json:"summary"`
 }
 
@@ -40,14 +43,16 @@ type ChangeDetail struct {
 
 // Summary contains summary statistics
 type Summary struct {
-	AddedCount     int           `json:"added_count"`
-	ModifiedCount  int           `json:"modified_count"`
-	DeletedCount   int           `json:"deleted_count"`
-	TotalChanges   int           `json:"total_changes"`
-	AddedSize      int64         `json:"added_size"`
-	DeletedSize    int64         `json:"deleted_size"`
-	SizeDiff       int64         `json:"size_diff"`
-	ComparisonTime time.Duration `json:"comparison_time"`
+	AddedCount       int           `json:"added_count"`
+	ModifiedCount    int           `json:"modified_count"`
+	DeletedCount     int           `json:"deleted_count"`
+	TotalChanges     int           `json:"total_changes"`
+	AddedSize        int64         `json:"added_size"`
+	DeletedSize      int64         `json:"deleted_size"`
+	SizeDiff         int64         `json:"size_diff"`          // AddedSize - DeletedSize
+	ModifiedSizeDiff int64         `json:"modified_size_diff"` // Net size change of modified files
+	StateChanges     int           `json:"state_changes"`      // Not included in TotalChanges
+	ComparisonTime   time.Duration `json:"comparison_time"`
 }
 
 // PathIgnorer handles ignore pattern matching for diffs
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/locale"
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
 	systemv2 "pkg.jsn.cam/jsn/cmd/fsdiff/internal/system/v2"
 )
@@ -18,13 +19,16 @@ func New(config *Config) *Differ {
 	}
 
 	return &Differ{
-		config: config,
-		ignorer: &PathIgnorer{
-			patterns: config.IgnorePatterns,
-		},
+		config:  config,
+		ignorer: NewPathIgnorer(config.IgnorePatterns),
 	}
 }
 
+// NewPathIgnorer creates an ignorer using the diff pattern rules
+func NewPathIgnorer(patterns []string) *PathIgnorer {
+	return &PathIgnorer{patterns: patterns}
+}
+
 // Compare compares two snapshots and returns the differences
 func (d *Differ) Compare(baseline, current *snapshot.Snapshot) *Result {
 	startTime := time.Now()
@@ -32,9 +36,9 @@ func (d *Differ) Compare(baseline, current *snapshot.Snapshot) *Result {
 	if d.config.Verbose {
 		fmt.Printf("🔍 Comparing snapshots...\n")
 		fmt.Printf("   Baseline: %d files (%s)\n",
-			baseline.Stats.FileCount, baseline.SystemInfo.Timestamp.Format("2006-01-02 15:04:05"))
+			baseline.Stats.FileCount, locale.Time(baseline.SystemInfo.Timestamp))
 		fmt.Printf("   Current:  %d files (%s)\n",
-			current.Stats.FileCount, current.SystemInfo.Timestamp.Format("2006-01-02 15:04:05"))
+			current.Stats.FileCount, locale.Time(current.SystemInfo.Timestamp))
 	}
 
 	result := &Result{
@@ -52,6 +56,7 @@ func (d *Differ) Compare(baseline, current *snapshot.Snapshot) *Result {
 	} else {
 		d.compareBruteForce(baseline, current, result)
 	}
+	result.State = CompareState(baseline.SystemInfo.State, current.SystemInfo.State)
 
 	// Calculate summary
 	result.Summary = d.calculateSummary(result, time.Since(startTime))
@@ -92,6 +97,12 @@ func (d *Differ) compareMerkleTrees(baseline, current *snapshot.Snapshot, result
 
 // compareBruteForce performs traditional file-by-file comparison
 func (d *Differ) compareBruteForce(baseline, current *snapshot.Snapshot, result *Result) {
+	workers := d.config.Workers
+	if workers > 1 && len(baseline.Files)+len(current.Files) >= parallelThreshold {
+		d.compareParallel(baseline, current, result, workers)
+		return
+	}
+
 	if d.config.Verbose {
 		fmt.Printf("📊 Using brute force comparison...\n")
 	}
@@ -109,30 +120,7 @@ func (d *Differ) compareBruteForce(baseline, current *snapshot.Snapshot, result
 	total := len(allPaths)
 
 	for path := range allPaths {
-		if d.ignorer.ShouldIgnore(path) {
-			continue
-		}
-
-		baselineRecord, inBaseline := baseline.Files[path]
-		currentRecord, inCurrent := current.Files[path]
-
-		if !inBaseline && inCurrent {
-			// File was added
-			result.Added[path] = currentRecord
-		} else if inBaseline && !inCurrent {
-			// File was deleted
-			result.Deleted[path] = baselineRecord
-		} else if inBaseline {
-			// File exists in both - check if modified
-			if !d.filesEqual(baselineRecord, currentRecord) {
-				changes := d.detectChanges(baselineRecord, currentRecord)
-				result.Modified[path] = &ChangeDetail{
-					OldRecord: baselineRecord,
-					NewRecord: currentRecord,
-					Changes:   changes,
-				}
-			}
-		}
+		d.comparePath(path, baseline, current, result.Added, result.Modified, result.Deleted)
 
 		processed++
 		if d.config.Verbose && processed%10000 == 0 {
@@ -142,6 +130,35 @@ func (d *Differ) compareBruteForce(baseline, current *snapshot.Snapshot, result
 	}
 }
 
+// comparePath classifies a single path and records it in the matching map
+func (d *Differ) comparePath(path string, baseline, current *snapshot.Snapshot,
+	added map[string]*snapshot.FileRecord, modified map[string]*ChangeDetail, deleted map[string]*snapshot.FileRecord) {
+	if d.ignorer.ShouldIgnore(path) {
+		return
+	}
+
+	baselineRecord, inBaseline := baseline.Files[path]
+	currentRecord, inCurrent := current.Files[path]
+
+	if !inBaseline && inCurrent {
+		// File was added
+		added[path] = currentRecord
+	} else if inBaseline && !inCurrent {
+		// File was deleted
+		deleted[path] = baselineRecord
+	} else if inBaseline {
+		// File exists in both - check if modified
+		if !d.filesEqual(baselineRecord, currentRecord) {
+			changes := d.detectChanges(baselineRecord, currentRecord)
+			modified[path] = &ChangeDetail{
+				OldRecord: baselineRecord,
+				NewRecord: currentRecord,
+				Changes:   changes,
+			}
+		}
+	}
+}
+
 // filesEqual checks if two file records are equal
 func (d *Differ) filesEqual(a, b *snapshot.FileRecord) bool {
 	if a.IsDir && b.IsDir {
@@ -228,8 +245,8 @@ func (d *Differ) detectChanges(old,
 
 	if !old.ModTime.Equal(new.ModTime) {
 		changes = append(changes, fmt.Sprintf("mtime (%s → %s)",
-			old.ModTime.Format("2006-01-02 15:04:05"),
-			new.ModTime.Format("2006-01-02 15:04:05")))
+			locale.Time(old.ModTime),
+			locale.Time(new.ModTime)))
 	}
 
 	// Check v2 FileInfo changes
@@ -338,6 +355,7 @@ func (d *Differ) calculateSummary(result *Result, duration time.Duration) Summar
 		AddedCount:     len(result.Added),
 		ModifiedCount:  len(result.Modified),
 		DeletedCount:   len(result.Deleted),
+		StateChanges:   len(result.State),
 		ComparisonTime: duration,
 	}
 
@@ -352,6 +370,10 @@ func (d *Differ) calculateSummary(result *Result, duration time.Duration) Summar
 		summary.DeletedSize += record.Size
 	}
 
+	for _, change := range result.Modified {
+		summary.ModifiedSizeDiff += change.NewRecord.Size - change.OldRecord.Size
+	}
+
 	summary.SizeDiff = summary.AddedSize - summary.DeletedSize
 
 	return summary
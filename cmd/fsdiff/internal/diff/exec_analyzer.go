@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// ExecAnalyzer runs an external command that speaks a line-delimited JSON
+// protocol, so detectors can be written in any language:
+//
+//   - fsdiff writes one Change object per line to the command's stdin
+//   - the command answers each line with one JSON array of findings on
+//     stdout (an empty array when it has nothing to say)
+//   - stdin is closed once all changes have been sent
+//
+// The Analyzer, Path and Type fields of findings may be omitted; they
+// default to the analyzer name and the change being answered.
+type ExecAnalyzer struct {
+	name    string
+	command []string
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Scanner
+	enc    *json.Encoder
+}
+
+// NewExecAnalyzer creates an analyzer backed by command
+func NewExecAnalyzer(name string, command []string) *ExecAnalyzer {
+	return &ExecAnalyzer{name: name, command: command}
+}
+
+func (e *ExecAnalyzer) Name() string {
+	return e.name
+}
+
+// start launches the subprocess on first use
+func (e *ExecAnalyzer) start() error {
+	if len(e.command) == 0 {
+		return fmt.Errorf("no command configured")
+	}
+
+	// cmd is only kept once it has started, so Close has nothing to
+	// clean up after a failed start
+	cmd := exec.Command(e.command[0], e.command[1:]...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %v", e.command[0], err)
+	}
+
+	e.cmd = cmd
+	e.stdin = stdin
+	e.enc = json.NewEncoder(stdin)
+	e.stdout = bufio.NewScanner(stdout)
+	e.stdout.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return nil
+}
+
+func (e *ExecAnalyzer) Analyze(change *Change) ([]Finding, error) {
+	if e.cmd == nil {
+		if err := e.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.enc.Encode(change); err != nil {
+		return nil, fmt.Errorf("failed to send change: %v", err)
+	}
+
+	if !e.stdout.Scan() {
+		if err := e.stdout.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("analyzer exited before answering %s", change.Path)
+	}
+
+	var findings []Finding
+	if err := json.Unmarshal(e.stdout.Bytes(), &findings); err != nil {
+		return nil, fmt.Errorf("invalid response for %s: %v", change.Path, err)
+	}
+	return findings, nil
+}
+
+// Close ends the subprocess and waits for it to exit
+func (e *ExecAnalyzer) Close() error {
+	if e.cmd == nil {
+		return nil
+	}
+	e.stdin.Close()
+	return e.cmd.Wait()
+}
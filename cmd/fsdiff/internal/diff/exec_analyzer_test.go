@@ -0,0 +1,80 @@
+//go:build unix
+
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+func analyzerResult() *Result {
+	return &Result{
+		Added: map[string]*snapshot.FileRecord{
+			"/etc/cron.d/backdoor": {Path: "/etc/cron.d/backdoor", Hash: "a"},
+		},
+		Modified: map[string]*ChangeDetail{
+			"/etc/passwd": {
+				OldRecord: &snapshot.FileRecord{Path: "/etc/passwd", Hash: "b"},
+				NewRecord: &snapshot.FileRecord{Path: "/etc/passwd", Hash: "c"},
+				Changes:   []string{"content"},
+			},
+		},
+		Deleted: map[string]*snapshot.FileRecord{},
+	}
+}
+
+func TestExecAnalyzer(t *testing.T) {
+	// Flags every change, and only the first with a path of its own
+	script := `n=0; while read -r line; do
+		n=$((n+1))
+		if [ $n = 1 ]; then echo '[{"path":"/elsewhere","severity":3,"message":"first"}]'
+		else echo '[{"severity":8,"message":"later"}]'; fi
+	done`
+	r := analyzerResult()
+	if err := r.RunAnalyzers([]Analyzer{NewExecAnalyzer("script", []string{"sh", "-c", script})}); err != nil {
+		t.Fatalf("RunAnalyzers: %v", err)
+	}
+
+	want := []Finding{
+		{Analyzer: "script", Path: "/etc/passwd", Type: ChangeModified, Severity: 8, Message: "later"},
+		{Analyzer: "script", Path: "/elsewhere", Type: ChangeAdded, Severity: 3, Message: "first"},
+	}
+	if len(r.Findings) != len(want) {
+		t.Fatalf("Findings = %+v, want %+v", r.Findings, want)
+	}
+	for i := range want {
+		if r.Findings[i] != want[i] {
+			t.Errorf("Findings[%d] = %+v, want %+v", i, r.Findings[i], want[i])
+		}
+	}
+}
+
+func TestExecAnalyzerMissingBinary(t *testing.T) {
+	a := NewExecAnalyzer("missing", []string{"/nonexistent/fsdiff-analyzer"})
+	if _, err := a.Analyze(&Change{Path: "/etc/passwd", Type: ChangeAdded}); err == nil {
+		t.Error("Analyze: expected error for a missing binary")
+	}
+	if err := a.Close(); err != nil {
+		t.Errorf("Close after a failed start: %v", err)
+	}
+
+	r := analyzerResult()
+	err := r.RunAnalyzers([]Analyzer{NewExecAnalyzer("missing", []string{"/nonexistent/fsdiff-analyzer"})})
+	if err == nil || !strings.Contains(err.Error(), "analyzer missing") {
+		t.Errorf("RunAnalyzers = %v, want an error naming the analyzer", err)
+	}
+}
+
+func TestExecAnalyzerMalformedOutput(t *testing.T) {
+	// cat answers with the change object itself rather than an array
+	r := analyzerResult()
+	err := r.RunAnalyzers([]Analyzer{NewExecAnalyzer("cat", []string{"cat"})})
+	if err == nil || !strings.Contains(err.Error(), "invalid response") {
+		t.Errorf("RunAnalyzers = %v, want an invalid response error", err)
+	}
+	if len(r.Findings) != 0 {
+		t.Errorf("Findings = %+v, want none", r.Findings)
+	}
+}
@@ -0,0 +1,26 @@
+package diff
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/system"
+)
+
+// CheckComparable returns an error if the snapshots were taken on different
+// hosts or of different scan roots. Such comparisons are valid (a golden
+// image against a host, say) but are far more often a mix-up that would
+// report every file as added and deleted. Snapshots missing the metadata
+// pass.
+func CheckComparable(baseline, current system.SystemInfo) error {
+	if baseline.Hostname != "" && current.Hostname != "" && baseline.Hostname != current.Hostname {
+		return fmt.Errorf("baseline is from host %q but current is from %q", baseline.Hostname, current.Hostname)
+	}
+
+	if baseline.ScanRoot != "" && current.ScanRoot != "" &&
+		filepath.Clean(baseline.ScanRoot) != filepath.Clean(current.ScanRoot) {
+		return fmt.Errorf("baseline scanned %s but current scanned %s", baseline.ScanRoot, current.ScanRoot)
+	}
+
+	return nil
+}
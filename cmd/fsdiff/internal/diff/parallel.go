@@ -0,0 +1,100 @@
+package diff
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cespare/xxhash/v2"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+// parallelThreshold is the number of unique paths below which the
+// single-threaded comparison is faster than sharding. A var so tests can
+// shard small fixtures.
+var parallelThreshold = 50000
+
+// shardResult holds one worker's share of the comparison
+type shardResult struct {
+	added    map[string]*snapshot.FileRecord
+	modified map[string]*ChangeDetail
+	deleted  map[string]*snapshot.FileRecord
+}
+
+// shardFor assigns a path to a worker by hashing its parent directory, so
+// siblings are compared by the same worker and stay cache-friendly.
+func shardFor(path string, shards int) int {
+	return int(xxhash.Sum64String(filepath.Dir(path)) % uint64(shards))
+}
+
+// compareParallel shards the path space across workers and merges their
+// results. Each worker owns disjoint output maps so no locking is needed
+// until the final merge.
+func (d *Differ) compareParallel(baseline, current *snapshot.Snapshot, result *Result, workers int) {
+	if d.config.Verbose {
+		fmt.Printf("⚡ Using parallel comparison with %d workers...\n", workers)
+	}
+
+	// Partition paths. Paths present in both snapshots are only queued once,
+	// from the baseline side.
+	shards := make([][]string, workers)
+	for path := range baseline.Files {
+		i := shardFor(path, workers)
+		shards[i] = append(shards[i], path)
+	}
+	for path := range current.Files {
+		if _, ok := baseline.Files[path]; ok {
+			continue
+		}
+		i := shardFor(path, workers)
+		shards[i] = append(shards[i], path)
+	}
+
+	total := 0
+	for _, shard := range shards {
+		total += len(shard)
+	}
+
+	var processed int64
+	partials := make([]shardResult, workers)
+
+	var wg sync.WaitGroup
+	for i := range shards {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			part := shardResult{
+				added:    make(map[string]*snapshot.FileRecord),
+				modified: make(map[string]*ChangeDetail),
+				deleted:  make(map[string]*snapshot.FileRecord),
+			}
+
+			for _, path := range shards[i] {
+				d.comparePath(path, baseline, current, part.added, part.modified, part.deleted)
+
+				if n := atomic.AddInt64(&processed, 1); d.config.Verbose && n%100000 == 0 {
+					fmt.Printf("📊 Processed %d/%d files (%.1f%%)\n",
+						n, total, float64(n)/float64(total)*100)
+				}
+			}
+
+			partials[i] = part
+		}(i)
+	}
+	wg.Wait()
+
+	// Merge shard results
+	for _, part := range partials {
+		for path, record := range part.added {
+			result.Added[path] = record
+		}
+		for path, change := range part.modified {
+			result.Modified[path] = change
+		}
+		for path, record := range part.deleted {
+			result.Deleted[path] = record
+		}
+	}
+}
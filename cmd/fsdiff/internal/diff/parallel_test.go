@@ -0,0 +1,55 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+func TestCompareParallelMatchesSequential(t *testing.T) {
+	defer func(n int) { parallelThreshold = n }(parallelThreshold)
+	parallelThreshold = 1
+
+	baseline := &snapshot.Snapshot{Files: map[string]*snapshot.FileRecord{}}
+	current := &snapshot.Snapshot{Files: map[string]*snapshot.FileRecord{}}
+	for dir := range 20 {
+		for file := range 10 {
+			path := fmt.Sprintf("/srv/d%02d/f%d", dir, file)
+			hash := fmt.Sprintf("%d-%d", dir, file)
+			baseline.Files[path] = &snapshot.FileRecord{Path: path, Hash: hash, Size: 1}
+
+			switch {
+			case file == 0:
+				// Moved to the next directory, which shards elsewhere
+				moved := fmt.Sprintf("/srv/d%02d/moved%d", (dir+1)%20, dir)
+				current.Files[moved] = &snapshot.FileRecord{Path: moved, Hash: hash, Size: 1}
+			case file == 1:
+				// Renamed within its directory
+				renamed := fmt.Sprintf("/srv/d%02d/renamed", dir)
+				current.Files[renamed] = &snapshot.FileRecord{Path: renamed, Hash: hash, Size: 1}
+			case file == 2:
+				current.Files[path] = &snapshot.FileRecord{Path: path, Hash: hash + "x", Size: 2}
+			default:
+				current.Files[path] = &snapshot.FileRecord{Path: path, Hash: hash, Size: 1}
+			}
+		}
+	}
+
+	want := New(&Config{Workers: 1}).Compare(baseline, current)
+	got := New(&Config{Workers: 7}).Compare(baseline, current)
+
+	if !reflect.DeepEqual(got.Added, want.Added) {
+		t.Errorf("Added = %v, want %v", got.Added, want.Added)
+	}
+	if !reflect.DeepEqual(got.Modified, want.Modified) {
+		t.Errorf("Modified = %v, want %v", got.Modified, want.Modified)
+	}
+	if !reflect.DeepEqual(got.Deleted, want.Deleted) {
+		t.Errorf("Deleted = %v, want %v", got.Deleted, want.Deleted)
+	}
+	if want.Summary.AddedCount != 40 || want.Summary.ModifiedCount != 20 || want.Summary.DeletedCount != 40 {
+		t.Errorf("unexpected counts: %+v", want.Summary)
+	}
+}
@@ -0,0 +1,97 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/system"
+)
+
+// StateChange is a difference in system state between two snapshots
+type StateChange struct {
+	Kind   string     `json:"kind"` // listener, module, user or group
+	Type   ChangeType `json:"type"`
+	Item   string     `json:"item"`
+	Detail string     `json:"detail,omitempty"`
+}
+
+// CompareState lists system state changes. Nothing is reported unless both
+// snapshots captured state.
+func CompareState(baseline, current *system.State) []StateChange {
+	if baseline == nil || current == nil {
+		return nil
+	}
+
+	var changes []StateChange
+	// A listener is its protocol, address and port; which process holds it
+	// is only shown
+	changes = append(changes, compareItems("listener", baseline.Listeners, current.Listeners,
+		func(l system.Listener) string { return l.String() },
+		func(l system.Listener) string { return "" },
+		func(l system.Listener) string { return l.Process })...)
+	changes = append(changes, compareItems("module", baseline.Modules, current.Modules,
+		func(m system.KernelModule) string { return m.Name },
+		func(m system.KernelModule) string { return fmt.Sprintf("size %d", m.Size) }, nil)...)
+	changes = append(changes, compareItems("user", baseline.Users, current.Users,
+		func(u system.User) string { return u.Name },
+		func(u system.User) string {
+			return fmt.Sprintf("uid %d, gid %d, home %s, shell %s", u.UID, u.GID, u.Home, u.Shell)
+		}, nil)...)
+	changes = append(changes, compareItems("group", baseline.Groups, current.Groups,
+		func(g system.Group) string { return g.Name },
+		func(g system.Group) string {
+			return fmt.Sprintf("gid %d, members [%s]", g.GID, strings.Join(g.Members, ","))
+		}, nil)...)
+
+	return changes
+}
+
+// compareItems matches items by key and reports those whose description
+// differs as modified. note, if set, adds to an item's detail without
+// counting as a change.
+func compareItems[T any](kind string, baseline, current []T, key, describe, note func(T) string) []StateChange {
+	detail := func(item T) string {
+		desc := describe(item)
+		if note == nil {
+			return desc
+		}
+		if n := note(item); n != "" {
+			if desc == "" {
+				return n
+			}
+			return desc + ", " + n
+		}
+		return desc
+	}
+
+	old := make(map[string]T, len(baseline))
+	for _, item := range baseline {
+		old[key(item)] = item
+	}
+
+	var changes []StateChange
+	seen := make(map[string]bool, len(current))
+	for _, item := range current {
+		k := key(item)
+		seen[k] = true
+
+		oldItem, ok := old[k]
+		switch {
+		case !ok:
+			changes = append(changes, StateChange{Kind: kind, Type: ChangeAdded, Item: k, Detail: detail(item)})
+		case describe(oldItem) != describe(item):
+			changes = append(changes, StateChange{Kind: kind, Type: ChangeModified, Item: k,
+				Detail: fmt.Sprintf("%s → %s", detail(oldItem), detail(item))})
+		}
+	}
+
+	for _, item := range baseline {
+		if k := key(item); !seen[k] {
+			changes = append(changes, StateChange{Kind: kind, Type: ChangeDeleted, Item: k, Detail: detail(item)})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Item < changes[j].Item })
+	return changes
+}
@@ -0,0 +1,29 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/system"
+)
+
+func TestCompareStateListeners(t *testing.T) {
+	baseline := &system.State{Listeners: []system.Listener{
+		{Proto: "tcp", Address: "0.0.0.0", Port: 22, Process: "sshd"},
+		{Proto: "tcp", Address: "127.0.0.1", Port: 5432, Process: "postgres"},
+	}}
+	current := &system.State{Listeners: []system.Listener{
+		// Restarted under another name, which isn't a change
+		{Proto: "tcp", Address: "0.0.0.0", Port: 22, Process: "sshd-session"},
+		{Proto: "tcp", Address: "0.0.0.0", Port: 4444, Process: "nc"},
+	}}
+
+	got := CompareState(baseline, current)
+	want := []StateChange{
+		{Kind: "listener", Type: ChangeAdded, Item: "tcp 0.0.0.0:4444", Detail: "nc"},
+		{Kind: "listener", Type: ChangeDeleted, Item: "tcp 127.0.0.1:5432", Detail: "postgres"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareState = %+v, want %+v", got, want)
+	}
+}
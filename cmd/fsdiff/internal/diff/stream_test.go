@@ -0,0 +1,53 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+func TestStreamMatchesCompare(t *testing.T) {
+	// Enough paths to span several words of the seen bitset
+	baseline := &snapshot.Snapshot{Files: map[string]*snapshot.FileRecord{}}
+	current := &snapshot.Snapshot{Files: map[string]*snapshot.FileRecord{}}
+	for i := range 200 {
+		path := fmt.Sprintf("/srv/%03d", i)
+		baseline.Files[path] = &snapshot.FileRecord{Path: path, Hash: "a", Size: 1}
+		switch i % 4 {
+		case 0: // deleted
+		case 1:
+			current.Files[path] = &snapshot.FileRecord{Path: path, Hash: "b", Size: 2}
+		default:
+			current.Files[path] = &snapshot.FileRecord{Path: path, Hash: "a", Size: 1}
+		}
+	}
+	for _, path := range []string{"/srv/new", "/var/app.log"} {
+		current.Files[path] = &snapshot.FileRecord{Path: path, Hash: "c", Size: 3}
+	}
+	baseline.Files["/var/old.log"] = &snapshot.FileRecord{Path: "/var/old.log", Hash: "d"}
+
+	d := New(&Config{IgnorePatterns: []string{"*.log"}})
+	want := d.Compare(baseline, current)
+
+	s := d.NewStream(baseline)
+	for _, record := range current.Files {
+		s.Add(record)
+	}
+	// The live scan discards its records, so Finish gets none
+	got := s.Finish(&snapshot.Snapshot{})
+
+	if !reflect.DeepEqual(got.Added, want.Added) {
+		t.Errorf("Added = %v, want %v", got.Added, want.Added)
+	}
+	if !reflect.DeepEqual(got.Modified, want.Modified) {
+		t.Errorf("Modified = %v, want %v", got.Modified, want.Modified)
+	}
+	if !reflect.DeepEqual(got.Deleted, want.Deleted) {
+		t.Errorf("Deleted = %v, want %v", got.Deleted, want.Deleted)
+	}
+	if got.Summary.AddedCount != 1 || got.Summary.ModifiedCount != 50 || got.Summary.DeletedCount != 50 {
+		t.Errorf("unexpected counts: %+v", got.Summary)
+	}
+}
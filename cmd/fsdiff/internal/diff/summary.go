@@ -0,0 +1,52 @@
+package diff
+
+import (
+	"time"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+// Summarize counts changes between two snapshots without building the
+// Added/Modified/Deleted maps. It is meant for cheap scheduled drift checks
+// where only the totals matter.
+func (d *Differ) Summarize(baseline, current *snapshot.Snapshot) Summary {
+	startTime := time.Now()
+	var summary Summary
+
+	for path, record := range current.Files {
+		if d.ignorer.ShouldIgnore(path) {
+			continue
+		}
+
+		baselineRecord, inBaseline := baseline.Files[path]
+		if !inBaseline {
+			summary.AddedCount++
+			summary.AddedSize += record.Size
+			continue
+		}
+
+		if !d.filesEqual(baselineRecord, record) {
+			summary.ModifiedCount++
+			summary.ModifiedSizeDiff += record.Size - baselineRecord.Size
+		}
+	}
+
+	for path, record := range baseline.Files {
+		if _, inCurrent := current.Files[path]; inCurrent {
+			continue
+		}
+		if d.ignorer.ShouldIgnore(path) {
+			continue
+		}
+
+		summary.DeletedCount++
+		summary.DeletedSize += record.Size
+	}
+
+	summary.TotalChanges = summary.AddedCount + summary.ModifiedCount + summary.DeletedCount
+	summary.SizeDiff = summary.AddedSize - summary.DeletedSize
+	summary.StateChanges = len(CompareState(baseline.SystemInfo.State, current.SystemInfo.State))
+	summary.ComparisonTime = time.Since(startTime)
+
+	return summary
+}
@@ -0,0 +1,38 @@
+package diff
+
+import (
+	"testing"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+func TestSummarizeMatchesCompare(t *testing.T) {
+	baseline := &snapshot.Snapshot{Files: map[string]*snapshot.FileRecord{
+		"/etc/passwd":  {Path: "/etc/passwd", Hash: "a", Size: 100},
+		"/etc/shadow":  {Path: "/etc/shadow", Hash: "b", Size: 50},
+		"/etc/hosts":   {Path: "/etc/hosts", Hash: "c", Size: 10},
+		"/var/app.log": {Path: "/var/app.log", Hash: "d", Size: 5},
+	}}
+	current := &snapshot.Snapshot{Files: map[string]*snapshot.FileRecord{
+		"/etc/passwd":  {Path: "/etc/passwd", Hash: "a2", Size: 120},
+		"/etc/hosts":   {Path: "/etc/hosts", Hash: "c", Size: 10},
+		"/etc/new":     {Path: "/etc/new", Hash: "e", Size: 7},
+		"/var/app.log": {Path: "/var/app.log", Hash: "d2", Size: 6},
+	}}
+
+	d := New(&Config{IgnorePatterns: []string{"*.log"}})
+	got := d.Summarize(baseline, current)
+	want := d.Compare(baseline, current).Summary
+
+	got.ComparisonTime, want.ComparisonTime = 0, 0
+	if got != want {
+		t.Errorf("Summarize = %+v, Compare summary = %+v", got, want)
+	}
+
+	if got.AddedCount != 1 || got.ModifiedCount != 1 || got.DeletedCount != 1 {
+		t.Errorf("unexpected counts: %+v", got)
+	}
+	if got.SizeDiff != 7-50 || got.ModifiedSizeDiff != 20 {
+		t.Errorf("unexpected size deltas: %+v", got)
+	}
+}
@@ -0,0 +1,507 @@
+// Package image reads container images from local OCI layouts and docker save
+// archives so the files on disk can be verified against them.
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const (
+	mediaTypeOCIIndex   = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerList = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Entry is a single path in the flattened image filesystem
+type Entry struct {
+	Path     string
+	Hash     string // xxhash64 of regular file contents, same encoding as snapshots
+	Size     int64
+	Mode     fs.FileMode
+	Uid      int
+	Gid      int
+	Linkname string // Symlink target
+}
+
+// Image is the flattened filesystem of one image
+type Image struct {
+	ManifestDigest string // Empty for docker save archives, which have no manifest blob
+	ConfigDigest   string
+	DiffIDs        []string // Uncompressed layer digests, bottom first
+	Files          map[string]*Entry
+
+	layers []layer
+}
+
+// layer locates a layer blob and the digests it must match
+type layer struct {
+	name   string // Path inside the image source
+	digest string // Digest of the blob as stored, empty if not known
+	diffID string // Digest of the uncompressed tar
+}
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Platform  *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform,omitempty"`
+}
+
+type index struct {
+	Manifests []descriptor `json:"manifests"`
+}
+
+type manifest struct {
+	Config descriptor   `json:"config"`
+	Layers []descriptor `json:"layers"`
+}
+
+type imageConfig struct {
+	RootFS struct {
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+}
+
+// dockerManifest is an entry of manifest.json in docker save output
+type dockerManifest struct {
+	Config string   `json:"Config"`
+	Layers []string `json:"Layers"`
+}
+
+// blobStore reads files from an image source
+type blobStore interface {
+	open(name string) (io.ReadCloser, error)
+}
+
+// dirStore reads from an unpacked OCI layout or docker save directory
+type dirStore string
+
+func (d dirStore) open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// tarStore reads out of an OCI or docker save archive. Each open scans the
+// archive from the start, which is cheap next to hashing the layers.
+type tarStore string
+
+func (t tarStore) open(name string) (io.ReadCloser, error) {
+	f, err := os.Open(string(t))
+	if err != nil {
+		return nil, err
+	}
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if path.Clean(hdr.Name) == name {
+			return struct {
+				io.Reader
+				io.Closer
+			}{tr, f}, nil
+		}
+	}
+
+	f.Close()
+	return nil, fmt.Errorf("%s not found in %s: %w", name, t, fs.ErrNotExist)
+}
+
+// Load reads the image at src, which may be an OCI layout or docker save
+// output, either unpacked or as a tar archive. digest selects the image by
+// manifest or config digest and may be empty when src holds a single image
+// for this platform. Every blob is checked against its digest, so a
+// tampered image source is reported as an error rather than trusted.
+func Load(src, digest string) (*Image, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var store blobStore = tarStore(src)
+	if info.IsDir() {
+		store = dirStore(src)
+	}
+
+	if digest != "" && !strings.Contains(digest, ":") {
+		digest = "sha256:" + digest
+	}
+
+	img, err := loadOCI(store, digest)
+	if errors.Is(err, fs.ErrNotExist) {
+		img, err = loadDocker(store, digest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	img.Files = make(map[string]*Entry)
+	for _, l := range img.layers {
+		if err := img.applyLayer(store, l); err != nil {
+			return nil, fmt.Errorf("failed to apply layer %s: %v", l.diffID, err)
+		}
+	}
+
+	return img, nil
+}
+
+// loadOCI resolves digest through the OCI layout's index.json
+func loadOCI(store blobStore, digest string) (*Image, error) {
+	data, err := readFile(store, "index.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var idx index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse index.json: %v", err)
+	}
+
+	candidates, err := flattenIndex(store, idx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*Image
+	for _, desc := range candidates {
+		if digest == "" && desc.Platform != nil &&
+			(desc.Platform.OS != runtime.GOOS || desc.Platform.Architecture != runtime.GOARCH) {
+			continue
+		}
+
+		data, err := readBlob(store, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		var m manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest %s: %v", desc.Digest, err)
+		}
+
+		if digest != "" && desc.Digest != digest && m.Config.Digest != digest {
+			continue
+		}
+
+		cfg, err := readConfig(store, blobName(m.Config.Digest), m.Config.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if len(cfg.RootFS.DiffIDs) != len(m.Layers) {
+			return nil, fmt.Errorf("manifest %s has %d layers but config lists %d", desc.Digest, len(m.Layers), len(cfg.RootFS.DiffIDs))
+		}
+
+		img := &Image{ManifestDigest: desc.Digest, ConfigDigest: m.Config.Digest, DiffIDs: cfg.RootFS.DiffIDs}
+		for i, l := range m.Layers {
+			img.layers = append(img.layers, layer{name: blobName(l.Digest), digest: l.Digest, diffID: cfg.RootFS.DiffIDs[i]})
+		}
+		matches = append(matches, img)
+	}
+
+	return pickImage(matches, digest)
+}
+
+// flattenIndex collects the image manifests of idx, descending into nested
+// indexes such as multi-platform images
+func flattenIndex(store blobStore, idx index) ([]descriptor, error) {
+	var out []descriptor
+	for _, desc := range idx.Manifests {
+		if desc.MediaType != mediaTypeOCIIndex && desc.MediaType != mediaTypeDockerList {
+			out = append(out, desc)
+			continue
+		}
+
+		data, err := readBlob(store, desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		var nested index
+		if err := json.Unmarshal(data, &nested); err != nil {
+			return nil, fmt.Errorf("failed to parse index %s: %v", desc.Digest, err)
+		}
+		manifests, err := flattenIndex(store, nested)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, manifests...)
+	}
+	return out, nil
+}
+
+// loadDocker resolves digest through a docker save manifest.json
+func loadDocker(store blobStore, digest string) (*Image, error) {
+	data, err := readFile(store, "manifest.json")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("not an OCI layout or docker save archive (no index.json or manifest.json)")
+		}
+		return nil, err
+	}
+
+	var manifests []dockerManifest
+	if err := json.Unmarshal(data, &manifests); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest.json: %v", err)
+	}
+
+	var matches []*Image
+	for _, m := range manifests {
+		cfgData, err := readFile(store, m.Config)
+		if err != nil {
+			return nil, err
+		}
+		configDigest := sha256Digest(cfgData)
+		if digest != "" && configDigest != digest {
+			continue
+		}
+
+		var cfg imageConfig
+		if err := json.Unmarshal(cfgData, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %v", m.Config, err)
+		}
+		if len(cfg.RootFS.DiffIDs) != len(m.Layers) {
+			return nil, fmt.Errorf("image %s has %d layers but config lists %d", configDigest, len(m.Layers), len(cfg.RootFS.DiffIDs))
+		}
+
+		img := &Image{ConfigDigest: configDigest, DiffIDs: cfg.RootFS.DiffIDs}
+		for i, name := range m.Layers {
+			img.layers = append(img.layers, layer{name: path.Clean(name), diffID: cfg.RootFS.DiffIDs[i]})
+		}
+		matches = append(matches, img)
+	}
+
+	return pickImage(matches, digest)
+}
+
+// pickImage insists on exactly one matching image
+func pickImage(matches []*Image, digest string) (*Image, error) {
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) == 0 && digest != "":
+		return nil, fmt.Errorf("no image with manifest or config digest %s", digest)
+	case len(matches) == 0:
+		return nil, fmt.Errorf("no image for %s/%s", runtime.GOOS, runtime.GOARCH)
+	default:
+		return nil, fmt.Errorf("%d images found, select one with a digest", len(matches))
+	}
+}
+
+// applyLayer unpacks a layer's entries over the files of lower layers
+func (img *Image) applyLayer(store blobStore, l layer) error {
+	rc, err := store.open(l.name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	blobHash := sha256.New()
+	br := bufio.NewReader(io.TeeReader(rc, blobHash))
+
+	var r io.Reader = br
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	case bytes.HasPrefix(magic, zstdMagic):
+		return fmt.Errorf("zstd compressed layers are not supported")
+	}
+
+	diffHash := sha256.New()
+	upper, whiteouts, opaque, err := readLayer(io.TeeReader(r, diffHash))
+	if err != nil {
+		return err
+	}
+
+	// Drain trailing padding so the digests cover the whole blob
+	if _, err := io.Copy(io.Discard, io.TeeReader(r, diffHash)); err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		return err
+	}
+
+	if got := hashDigest(diffHash); got != l.diffID {
+		return fmt.Errorf("uncompressed digest %s does not match %s", got, l.diffID)
+	}
+	if l.digest != "" {
+		if got := hashDigest(blobHash); got != l.digest {
+			return fmt.Errorf("blob digest %s does not match %s", got, l.digest)
+		}
+	}
+
+	// Whiteouts only hide lower layers, so apply them before merging
+	for _, dir := range opaque {
+		img.removeChildren(dir)
+	}
+	for _, p := range whiteouts {
+		delete(img.Files, p)
+		img.removeChildren(p)
+	}
+
+	for p, entry := range upper {
+		if lower, ok := img.Files[p]; ok && lower.Mode.IsDir() && !entry.Mode.IsDir() {
+			img.removeChildren(p)
+		}
+		img.Files[p] = entry
+	}
+
+	return nil
+}
+
+// readLayer reads one layer tar into its entries and whiteout markers
+func readLayer(r io.Reader) (entries map[string]*Entry, whiteouts, opaque []string, err error) {
+	entries = make(map[string]*Entry)
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, whiteouts, opaque, nil
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		name := path.Clean("/" + hdr.Name)
+		if name == "/" {
+			continue
+		}
+
+		dir, base := path.Split(name)
+		switch {
+		case base == whiteoutOpaque:
+			opaque = append(opaque, path.Clean(dir))
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			whiteouts = append(whiteouts, path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+			continue
+		}
+
+		entry := &Entry{
+			Path:     name,
+			Size:     hdr.Size,
+			Mode:     hdr.FileInfo().Mode(),
+			Uid:      hdr.Uid,
+			Gid:      hdr.Gid,
+			Linkname: hdr.Linkname,
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			h := xxhash.New()
+			if _, err := io.Copy(h, tr); err != nil {
+				return nil, nil, nil, err
+			}
+			entry.Hash = fmt.Sprintf("%x", h.Sum(nil))
+		case tar.TypeLink:
+			target, ok := entries[path.Clean("/"+hdr.Linkname)]
+			if !ok {
+				return nil, nil, nil, fmt.Errorf("hard link %s points to missing %s", name, hdr.Linkname)
+			}
+			link := *target
+			link.Path = name
+			entry = &link
+		}
+
+		entries[name] = entry
+	}
+}
+
+// removeChildren deletes every path below dir
+func (img *Image) removeChildren(dir string) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	for p := range img.Files {
+		if strings.HasPrefix(p, prefix) {
+			delete(img.Files, p)
+		}
+	}
+}
+
+// readConfig reads and digest-checks an image config
+func readConfig(store blobStore, name, digest string) (*imageConfig, error) {
+	data, err := readFile(store, name)
+	if err != nil {
+		return nil, err
+	}
+	if got := sha256Digest(data); got != digest {
+		return nil, fmt.Errorf("config digest %s does not match %s", got, digest)
+	}
+
+	var cfg imageConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", digest, err)
+	}
+	return &cfg, nil
+}
+
+// readBlob reads a content-addressed blob and checks its digest
+func readBlob(store blobStore, digest string) ([]byte, error) {
+	data, err := readFile(store, blobName(digest))
+	if err != nil {
+		return nil, err
+	}
+	if got := sha256Digest(data); got != digest {
+		return nil, fmt.Errorf("blob digest %s does not match %s", got, digest)
+	}
+	return data, nil
+}
+
+func readFile(store blobStore, name string) ([]byte, error) {
+	rc, err := store.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// blobName maps "sha256:abc" to its path in an OCI layout
+func blobName(digest string) string {
+	alg, encoded, _ := strings.Cut(digest, ":")
+	return path.Join("blobs", alg, encoded)
+}
+
+func sha256Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func hashDigest(h hash.Hash) string {
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,267 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// tarFile is a layer entry; a name ending in / is a directory
+type tarFile struct {
+	name, body, link string
+}
+
+func layerTar(t *testing.T, files ...tarFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(f.body))}
+		switch {
+		case strings.HasSuffix(f.name, "/"):
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		case f.link != "":
+			hdr.Typeflag, hdr.Linkname = tar.TypeLink, f.link
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			tw.Write([]byte(f.body))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func contentHash(s string) string {
+	return fmt.Sprintf("%016x", xxhash.Sum64String(s))
+}
+
+// testLayers are a base layer and an upper layer that whites out /etc/b,
+// makes /opt/dir opaque and replaces /etc/a. The upper layer is gzipped.
+func testLayers(t *testing.T) (blobs [][]byte, diffIDs []string) {
+	lower := layerTar(t,
+		tarFile{name: "etc/"},
+		tarFile{name: "etc/a", body: "a"},
+		tarFile{name: "etc/b", body: "b"},
+		tarFile{name: "etc/a-link", link: "etc/a"},
+		tarFile{name: "opt/"},
+		tarFile{name: "opt/dir/"},
+		tarFile{name: "opt/dir/x", body: "x"},
+	)
+	upper := layerTar(t,
+		tarFile{name: "etc/.wh.b"},
+		tarFile{name: "etc/a", body: "a2"},
+		tarFile{name: "opt/dir/.wh..wh..opq"},
+		tarFile{name: "opt/dir/y", body: "y"},
+	)
+	return [][]byte{lower, gzipped(t, upper)}, []string{sha256Digest(lower), sha256Digest(upper)}
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func marshal(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// writeOCI writes an OCI layout of one image to dir and returns its
+// manifest digest
+func writeOCI(t *testing.T, dir string, blobs [][]byte, diffIDs []string) string {
+	t.Helper()
+	writeBlob := func(data []byte) string {
+		digest := sha256Digest(data)
+		writeFile(t, filepath.Join(dir, filepath.FromSlash(blobName(digest))), data)
+		return digest
+	}
+
+	var cfg imageConfig
+	cfg.RootFS.DiffIDs = diffIDs
+	m := manifest{Config: descriptor{Digest: writeBlob(marshal(t, cfg))}}
+	for _, blob := range blobs {
+		m.Layers = append(m.Layers, descriptor{Digest: writeBlob(blob)})
+	}
+	manifestDigest := writeBlob(marshal(t, m))
+	writeFile(t, filepath.Join(dir, "index.json"), marshal(t, index{Manifests: []descriptor{{Digest: manifestDigest}}}))
+	return manifestDigest
+}
+
+func checkFiles(t *testing.T, img *Image) {
+	t.Helper()
+	want := map[string]string{
+		"/etc":        "",
+		"/etc/a":      contentHash("a2"),
+		"/etc/a-link": contentHash("a"),
+		"/opt":        "",
+		"/opt/dir":    "",
+		"/opt/dir/y":  contentHash("y"),
+	}
+	if len(img.Files) != len(want) {
+		t.Errorf("got %d files, want %d: %v", len(img.Files), len(want), img.Files)
+	}
+	for p, hash := range want {
+		entry, ok := img.Files[p]
+		if !ok {
+			t.Errorf("%s missing", p)
+			continue
+		}
+		if entry.Hash != hash {
+			t.Errorf("%s hash = %q, want %q", p, entry.Hash, hash)
+		}
+	}
+}
+
+func TestLoadOCI(t *testing.T) {
+	dir := t.TempDir()
+	blobs, diffIDs := testLayers(t)
+	manifestDigest := writeOCI(t, dir, blobs, diffIDs)
+
+	img, err := Load(dir, "")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if img.ManifestDigest != manifestDigest {
+		t.Errorf("ManifestDigest = %s, want %s", img.ManifestDigest, manifestDigest)
+	}
+	checkFiles(t, img)
+
+	// Selecting by digest, without the algorithm prefix
+	if _, err := Load(dir, strings.TrimPrefix(manifestDigest, "sha256:")); err != nil {
+		t.Errorf("Load by digest: %v", err)
+	}
+	if _, err := Load(dir, "sha256:0000"); err == nil {
+		t.Error("Load with an unknown digest: expected error")
+	}
+}
+
+func TestLoadBadDigest(t *testing.T) {
+	tests := []struct {
+		name string
+		// modify breaks the layout written by writeOCI
+		modify func(t *testing.T, dir string, blobs [][]byte, diffIDs []string)
+		want   string
+	}{
+		{
+			name: "tampered layer",
+			modify: func(t *testing.T, dir string, blobs [][]byte, diffIDs []string) {
+				evil := layerTar(t, tarFile{name: "etc/a", body: "evil"})
+				writeFile(t, filepath.Join(dir, filepath.FromSlash(blobName(sha256Digest(blobs[0])))), evil)
+			},
+			want: "does not match",
+		},
+		{
+			name: "tampered compressed layer",
+			modify: func(t *testing.T, dir string, blobs [][]byte, diffIDs []string) {
+				evil := gzipped(t, layerTar(t, tarFile{name: "etc/a", body: "evil"}))
+				writeFile(t, filepath.Join(dir, filepath.FromSlash(blobName(sha256Digest(blobs[1])))), evil)
+			},
+			want: "does not match",
+		},
+		{
+			name: "tampered config",
+			modify: func(t *testing.T, dir string, blobs [][]byte, diffIDs []string) {
+				data, _ := os.ReadFile(filepath.Join(dir, "index.json"))
+				var idx index
+				json.Unmarshal(data, &idx)
+				data, _ = os.ReadFile(filepath.Join(dir, filepath.FromSlash(blobName(idx.Manifests[0].Digest))))
+				var m manifest
+				json.Unmarshal(data, &m)
+				writeFile(t, filepath.Join(dir, filepath.FromSlash(blobName(m.Config.Digest))), []byte(`{"rootfs":{"diff_ids":[]}}`))
+			},
+			want: "config digest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			blobs, diffIDs := testLayers(t)
+			writeOCI(t, dir, blobs, diffIDs)
+			tt.modify(t, dir, blobs, diffIDs)
+
+			_, err := Load(dir, "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBadDiffID(t *testing.T) {
+	// The config is intact but lists the wrong uncompressed digest
+	dir := t.TempDir()
+	blobs, diffIDs := testLayers(t)
+	diffIDs[1] = diffIDs[0]
+	writeOCI(t, dir, blobs, diffIDs)
+
+	_, err := Load(dir, "")
+	if err == nil || !strings.Contains(err.Error(), "uncompressed digest") {
+		t.Errorf("Load = %v, want an uncompressed digest error", err)
+	}
+}
+
+func TestLoadDockerArchive(t *testing.T) {
+	blobs, diffIDs := testLayers(t)
+	var cfg imageConfig
+	cfg.RootFS.DiffIDs = diffIDs
+	cfgData := marshal(t, cfg)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	add := func(name string, data []byte) {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))})
+		tw.Write(data)
+	}
+	add("config.json", cfgData)
+	add("lower/layer.tar", blobs[0])
+	add("upper/layer.tar", blobs[1])
+	add("manifest.json", marshal(t, []dockerManifest{{Config: "config.json", Layers: []string{"lower/layer.tar", "upper/layer.tar"}}}))
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "app.tar")
+	writeFile(t, archive, buf.Bytes())
+
+	img, err := Load(archive, "")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if img.ConfigDigest != sha256Digest(cfgData) || img.ManifestDigest != "" {
+		t.Errorf("digests = %s, %q; want %s, none", img.ConfigDigest, img.ManifestDigest, sha256Digest(cfgData))
+	}
+	checkFiles(t, img)
+}
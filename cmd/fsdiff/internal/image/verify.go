@@ -0,0 +1,127 @@
+package image
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+// specialBits are the mode bits compared besides the permission bits
+const specialBits = fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+// Mismatch is an image path whose on-disk copy differs from the image
+type Mismatch struct {
+	Path    string   `json:"path"`
+	Reasons []string `json:"reasons"`
+}
+
+// Report is the outcome of verifying a snapshot against an image
+type Report struct {
+	ConfigDigest   string     `json:"config_digest"`
+	ManifestDigest string     `json:"manifest_digest,omitempty"`
+	Root           string     `json:"root"`
+	Checked        int        `json:"checked"`
+	Ignored        int        `json:"ignored"`
+	Tampered       []Mismatch `json:"tampered"`
+	Missing        []string   `json:"missing"`
+}
+
+// Clean reports whether every image path was found unchanged
+func (r *Report) Clean() bool {
+	return len(r.Tampered) == 0 && len(r.Missing) == 0
+}
+
+// Verify checks every file of img against the snapshot, with the image's
+// root filesystem mounted at root. Files on disk that are not part of the
+// image are not reported; the image only vouches for its own contents.
+// Symlink targets are not recorded in snapshots and so are not compared.
+func Verify(img *Image, snap *snapshot.Snapshot, root string, ignore func(string) bool) *Report {
+	report := &Report{
+		ConfigDigest:   img.ConfigDigest,
+		ManifestDigest: img.ManifestDigest,
+		Root:           root,
+	}
+
+	paths := make([]string, 0, len(img.Files))
+	for p := range img.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var missingDir string
+	for _, p := range paths {
+		entry := img.Files[p]
+		diskPath := filepath.Join(root, filepath.FromSlash(p))
+
+		// Children of a missing directory are implied by it
+		if missingDir != "" && strings.HasPrefix(p, missingDir+"/") {
+			continue
+		}
+		if ignore != nil && ignore(diskPath) {
+			report.Ignored++
+			continue
+		}
+		report.Checked++
+
+		record, ok := snap.Files[diskPath]
+		if !ok {
+			report.Missing = append(report.Missing, diskPath)
+			if entry.Mode.IsDir() {
+				missingDir = p
+			}
+			continue
+		}
+
+		if reasons := compareEntry(entry, record); len(reasons) > 0 {
+			report.Tampered = append(report.Tampered, Mismatch{Path: diskPath, Reasons: reasons})
+		}
+	}
+
+	return report
+}
+
+// compareEntry lists how a snapshot record differs from the image entry
+func compareEntry(entry *Entry, record *snapshot.FileRecord) []string {
+	if entry.Mode.Type() != record.Mode.Type() {
+		return []string{fmt.Sprintf("type %s, expected %s", typeName(record.Mode), typeName(entry.Mode))}
+	}
+
+	var reasons []string
+	if entry.Mode.IsRegular() {
+		switch {
+		case record.Hash == "ERROR":
+			reasons = append(reasons, "content unreadable")
+		case record.Hash != entry.Hash:
+			reasons = append(reasons, fmt.Sprintf("content (size %d, expected %d)", record.Size, entry.Size))
+		}
+	}
+
+	want := entry.Mode & (fs.ModePerm | specialBits)
+	got := record.Mode & (fs.ModePerm | specialBits)
+	if want != got {
+		reasons = append(reasons, fmt.Sprintf("mode %s, expected %s", got, want))
+	}
+
+	if info := record.FileInfo; info != nil && (int(info.OwnerID) != entry.Uid || int(info.GroupID) != entry.Gid) {
+		reasons = append(reasons, fmt.Sprintf("owner %d:%d, expected %d:%d", info.OwnerID, info.GroupID, entry.Uid, entry.Gid))
+	}
+
+	return reasons
+}
+
+func typeName(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "directory"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	case mode.IsRegular():
+		return "file"
+	default:
+		return "special file"
+	}
+}
@@ -0,0 +1,45 @@
+package image
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+func TestVerify(t *testing.T) {
+	img := &Image{ConfigDigest: "sha256:c", Files: map[string]*Entry{
+		"/etc":           {Path: "/etc", Mode: fs.ModeDir | 0755},
+		"/etc/passwd":    {Path: "/etc/passwd", Mode: 0644, Hash: contentHash("root")},
+		"/etc/shadow":    {Path: "/etc/shadow", Mode: 0640, Hash: contentHash("x")},
+		"/etc/hosts":     {Path: "/etc/hosts", Mode: 0644, Hash: contentHash("h")},
+		"/opt":           {Path: "/opt", Mode: fs.ModeDir | 0755},
+		"/opt/app":       {Path: "/opt/app", Mode: 0755, Hash: contentHash("app")},
+		"/var/log/x.log": {Path: "/var/log/x.log", Mode: 0644},
+	}}
+	snap := &snapshot.Snapshot{Files: map[string]*snapshot.FileRecord{
+		"/mnt/etc":        {Path: "/mnt/etc", Mode: fs.ModeDir | 0755},
+		"/mnt/etc/passwd": {Path: "/mnt/etc/passwd", Mode: 0644, Hash: contentHash("root")},
+		"/mnt/etc/shadow": {Path: "/mnt/etc/shadow", Mode: 0666, Hash: contentHash("x")},
+		"/mnt/etc/hosts":  {Path: "/mnt/etc/hosts", Mode: 0644, Hash: contentHash("evil"), Size: 4},
+		"/mnt/extra":      {Path: "/mnt/extra", Mode: 0644},
+	}}
+
+	report := Verify(img, snap, "/mnt", func(p string) bool { return p == "/mnt/var/log/x.log" })
+
+	want := []Mismatch{
+		{Path: "/mnt/etc/hosts", Reasons: []string{"content (size 4, expected 0)"}},
+		{Path: "/mnt/etc/shadow", Reasons: []string{"mode -rw-rw-rw-, expected -rw-r-----"}},
+	}
+	if !reflect.DeepEqual(report.Tampered, want) {
+		t.Errorf("Tampered = %+v, want %+v", report.Tampered, want)
+	}
+	// /opt/app is implied by its missing directory
+	if !reflect.DeepEqual(report.Missing, []string{"/mnt/opt"}) {
+		t.Errorf("Missing = %v, want [/mnt/opt]", report.Missing)
+	}
+	if report.Checked != 5 || report.Ignored != 1 || report.Clean() {
+		t.Errorf("Checked = %d, Ignored = %d, Clean = %v; want 5, 1, false", report.Checked, report.Ignored, report.Clean())
+	}
+}
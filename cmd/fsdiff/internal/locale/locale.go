@@ -0,0 +1,162 @@
+// Package locale formats dates and numbers for console output and reports.
+// The active locale and timezone are process-wide, set once from flags.
+package locale
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale describes how dates and numbers are written
+type Locale struct {
+	Name       string
+	TimeLayout string // Go time layout, without the zone
+	Group      string // Thousands separator, empty for none
+	Decimal    string
+}
+
+// ISO is the default: sortable dates and ungrouped numbers
+var ISO = &Locale{Name: "iso", TimeLayout: "2006-01-02 15:04:05", Decimal: "."}
+
+var locales = map[string]*Locale{
+	"iso":   ISO,
+	"en-US": {Name: "en-US", TimeLayout: "01/02/2006 3:04:05 PM", Group: ",", Decimal: "."},
+	"en-GB": {Name: "en-GB", TimeLayout: "02/01/2006 15:04:05", Group: ",", Decimal: "."},
+	"de-DE": {Name: "de-DE", TimeLayout: "02.01.2006 15:04:05", Group: ".", Decimal: ","},
+	"fr-FR": {Name: "fr-FR", TimeLayout: "02/01/2006 15:04:05", Group: " ", Decimal: ","},
+	"es-ES": {Name: "es-ES", TimeLayout: "02/01/2006 15:04:05", Group: ".", Decimal: ","},
+	"nl-NL": {Name: "nl-NL", TimeLayout: "02-01-2006 15:04:05", Group: ".", Decimal: ","},
+	"ja-JP": {Name: "ja-JP", TimeLayout: "2006/01/02 15:04:05", Group: ",", Decimal: "."},
+}
+
+var (
+	current  = ISO
+	location = time.Local
+)
+
+// Names lists the supported locales
+func Names() []string {
+	names := make([]string, 0, len(locales))
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup finds a locale by BCP 47 or POSIX name, e.g. "de-DE", "de_DE.UTF-8"
+// or just "de". "auto" reads LC_ALL, LC_TIME and LANG, falling back to ISO.
+func Lookup(name string) (*Locale, error) {
+	if name == "" {
+		return ISO, nil
+	}
+	if name == "auto" {
+		for _, env := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+			if v := os.Getenv(env); v != "" && v != "C" && v != "POSIX" {
+				if l, err := Lookup(v); err == nil {
+					return l, nil
+				}
+			}
+		}
+		return ISO, nil
+	}
+
+	// de_DE.UTF-8@euro -> de-DE
+	name, _, _ = strings.Cut(name, ".")
+	name, _, _ = strings.Cut(name, "@")
+	name = strings.ReplaceAll(name, "_", "-")
+
+	for key, l := range locales {
+		if strings.EqualFold(key, name) {
+			return l, nil
+		}
+	}
+	// Language only, e.g. "de"
+	for _, key := range Names() {
+		lang, _, _ := strings.Cut(key, "-")
+		if strings.EqualFold(lang, name) {
+			return locales[key], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown locale %q (supported: %s, auto)", name, strings.Join(Names(), ", "))
+}
+
+// LoadLocation resolves a -timezone value: an IANA name, "UTC" or "Local"
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q: %v", name, err)
+	}
+	return loc, nil
+}
+
+// Set makes l and loc the process-wide formatting settings
+func Set(l *Locale, loc *time.Location) {
+	current = l
+	location = loc
+}
+
+// Time formats t in the configured timezone, with the zone so readers in
+// other regions know which it is
+func Time(t time.Time) string {
+	return t.In(location).Format(current.TimeLayout + " MST")
+}
+
+// Int formats n with the locale's grouping
+func Int[T ~int | ~int64](n T) string {
+	s := strconv.FormatInt(int64(n), 10)
+	if current.Group == "" {
+		return s
+	}
+
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteString(current.Group)
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String()
+}
+
+// Float formats f with prec decimals and the locale's separators
+func Float(f float64, prec int) string {
+	s := strconv.FormatFloat(f, 'f', prec, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	n, _ := strconv.ParseInt(whole, 10, 64)
+
+	out := Int(n)
+	if n == 0 && strings.HasPrefix(whole, "-") {
+		out = "-" + out
+	}
+	if frac != "" {
+		out += current.Decimal + frac
+	}
+	return out
+}
+
+// Bytes formats a size in binary units
+func Bytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return Int(bytes) + " B"
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%s %cB", Float(float64(bytes)/float64(div), 1), "KMGTPE"[exp])
+}
@@ -0,0 +1,49 @@
+package locale
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatting(t *testing.T) {
+	defer Set(ISO, time.Local)
+
+	ts := time.Date(2025, 3, 4, 17, 5, 6, 0, time.UTC)
+	tokyo, err := LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	tests := []struct {
+		locale, time, int, bytes string
+	}{
+		{"iso", "2025-03-05 02:05:06 JST", "1234567", "1.5 MB"},
+		{"en_US.UTF-8", "03/05/2025 2:05:06 AM JST", "1,234,567", "1.5 MB"},
+		{"de", "05.03.2025 02:05:06 JST", "1.234.567", "1,5 MB"},
+	}
+
+	for _, tt := range tests {
+		l, err := Lookup(tt.locale)
+		if err != nil {
+			t.Fatalf("Lookup(%q): %v", tt.locale, err)
+		}
+		Set(l, tokyo)
+
+		if got := Time(ts); got != tt.time {
+			t.Errorf("%s: Time = %q, want %q", tt.locale, got, tt.time)
+		}
+		if got := Int(1234567); got != tt.int {
+			t.Errorf("%s: Int = %q, want %q", tt.locale, got, tt.int)
+		}
+		if got := Bytes(1572864); got != tt.bytes {
+			t.Errorf("%s: Bytes = %q, want %q", tt.locale, got, tt.bytes)
+		}
+	}
+
+	if got := Int(-1234); got != "-1.234" {
+		t.Errorf("Int(-1234) = %q, want -1.234", got)
+	}
+	if _, err := Lookup("xx-YY"); err == nil {
+		t.Error("Lookup accepted an unknown locale")
+	}
+}
@@ -121,3 +121,19 @@ func calculateMerkleRootParallel(files map[string]*snapshot.FileRecord) uint64 {
 
 	return finalHasher.Sum64()
 }
+
+// RollingRoot XORs the record hashes together, matching the root written by
+// streaming scans that never hold every record at once
+func RollingRoot(files map[string]*snapshot.FileRecord) uint64 {
+	var root uint64
+	for _, record := range files {
+		root ^= HashRecord(record)
+	}
+	return root
+}
+
+// Verify reports whether the snapshot's recorded merkle root matches its
+// files under either root calculation
+func Verify(snap *snapshot.Snapshot) bool {
+	return snap.MerkleRoot == RollingRoot(snap.Files) || snap.MerkleRoot == CalculateMerkleRoot(snap.Files)
+}
@@ -0,0 +1,224 @@
+// Package notify delivers diff results to chat notification sinks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/diff"
+)
+
+// SinkConfig configures a single notification sink. It is decoded from a
+// [[notify]] table in the fsdiff config file.
+type SinkConfig struct {
+	Name string `toml:"name"`
+	Type string `toml:"type"` // slack, discord or matrix
+
+	// Webhook URL for slack and discord sinks
+	URL string `toml:"url"`
+
+	// Matrix settings
+	Homeserver string `toml:"homeserver"`
+	Room       string `toml:"room"`
+	Token      string `toml:"token"`
+
+	// Routing: critical changes at or above MinSeverity are sent. A zero
+	// MinSeverity sends no critical changes. Summary sends the overall
+	// change counts.
+	MinSeverity int  `toml:"min_severity"`
+	Summary     bool `toml:"summary"`
+}
+
+// Message is a rendered notification
+type Message struct {
+	Title string
+	Lines []string
+}
+
+// Text renders the message as plain text
+func (m *Message) Text() string {
+	return m.Title + "\n" + strings.Join(m.Lines, "\n")
+}
+
+// Sink sends messages to a notification backend
+type Sink interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Notifier routes diff results to the configured sinks
+type Notifier struct {
+	client *http.Client
+	sinks  []SinkConfig
+}
+
+// maxCriticalLines caps how many critical changes are listed per message
+const maxCriticalLines = 25
+
+// New creates a notifier for the given sink configurations
+func New(sinks []SinkConfig) (*Notifier, error) {
+	n := &Notifier{
+		client: &http.Client{Timeout: 30 * time.Second},
+		sinks:  sinks,
+	}
+
+	for i, cfg := range sinks {
+		if _, err := n.sink(cfg); err != nil {
+			return nil, fmt.Errorf("notify sink %d (%s): %v", i, cfg.Name, err)
+		}
+	}
+
+	return n, nil
+}
+
+// sink builds the Sink implementation for a configuration
+func (n *Notifier) sink(cfg SinkConfig) (Sink, error) {
+	switch cfg.Type {
+	case "slack":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("slack sink requires url")
+		}
+		return &slackSink{client: n.client, url: cfg.URL}, nil
+	case "discord":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("discord sink requires url")
+		}
+		return &discordSink{client: n.client, url: cfg.URL}, nil
+	case "matrix":
+		if cfg.Homeserver == "" || cfg.Room == "" || cfg.Token == "" {
+			return nil, fmt.Errorf("matrix sink requires homeserver, room and token")
+		}
+		return &matrixSink{client: n.client, homeserver: cfg.Homeserver, room: cfg.Room, token: cfg.Token}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+	}
+}
+
+// Routed is the message rendered for one sink
+type Routed struct {
+	Sink    SinkConfig
+	Message *Message
+}
+
+// Notify sends each sink the parts of result it is routed to. Errors from
+// individual sinks are collected so one broken sink doesn't silence others.
+func (n *Notifier) Notify(ctx context.Context, result *diff.Result) error {
+	return n.Send(ctx, n.Route(result))
+}
+
+// Route renders the message for each sink that something in result is
+// routed to. Nothing in the messages points into result.
+func (n *Notifier) Route(result *diff.Result) []Routed {
+	critical := result.GetCriticalChanges()
+	host := result.Current.SystemInfo.Hostname
+
+	var routed []Routed
+	for _, cfg := range n.sinks {
+		if msg := buildMessage(cfg, host, result, critical); msg != nil {
+			routed = append(routed, Routed{Sink: cfg, Message: msg})
+		}
+	}
+	return routed
+}
+
+// Send delivers messages rendered by Route
+func (n *Notifier) Send(ctx context.Context, routed []Routed) error {
+	var errs []string
+	for _, r := range routed {
+		sink, err := n.sink(r.Sink)
+		if err == nil {
+			err = sink.Send(ctx, r.Message)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", sinkName(r.Sink), err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("notification failures: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func sinkName(cfg SinkConfig) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return cfg.Type
+}
+
+// buildMessage renders the message for one sink, or nil if nothing is
+// routed to it
+func buildMessage(cfg SinkConfig, host string, result *diff.Result, critical []diff.CriticalChange) *Message {
+	var routed []diff.CriticalChange
+	if cfg.MinSeverity > 0 {
+		for _, change := range critical {
+			if change.Severity >= cfg.MinSeverity {
+				routed = append(routed, change)
+			}
+		}
+	}
+
+	if len(routed) == 0 && !cfg.Summary {
+		return nil
+	}
+
+	msg := &Message{}
+	if len(routed) > 0 {
+		msg.Title = fmt.Sprintf("🚨 fsdiff: %d critical changes on %s", len(routed), host)
+	} else {
+		msg.Title = fmt.Sprintf("📊 fsdiff: %d changes on %s", result.Summary.TotalChanges, host)
+	}
+
+	if cfg.Summary {
+		s := result.Summary
+		msg.Lines = append(msg.Lines, fmt.Sprintf("Added: %d, Modified: %d, Deleted: %d (total %d)",
+			s.AddedCount, s.ModifiedCount, s.DeletedCount, s.TotalChanges))
+	}
+
+	for i, change := range routed {
+		if i >= maxCriticalLines {
+			msg.Lines = append(msg.Lines, fmt.Sprintf("... and %d more", len(routed)-maxCriticalLines))
+			break
+		}
+		msg.Lines = append(msg.Lines, fmt.Sprintf("[%d] %s %s - %s",
+			change.Severity, strings.ToUpper(string(change.Type)), change.Path, change.Reason))
+	}
+
+	return msg
+}
+
+// postJSON sends a JSON payload and treats any non-2xx response as an error
+func postJSON(ctx context.Context, client *http.Client, method, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	return nil
+}
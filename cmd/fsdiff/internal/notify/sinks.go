@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// slackSink posts to a Slack incoming webhook
+type slackSink struct {
+	client *http.Client
+	url    string
+}
+
+func (s *slackSink) Send(ctx context.Context, msg *Message) error {
+	text := "*" + msg.Title + "*\n```\n" + strings.Join(msg.Lines, "\n") + "\n```"
+	return postJSON(ctx, s.client, http.MethodPost, s.url, nil, map[string]string{"text": text})
+}
+
+// discordSink posts to a Discord webhook
+type discordSink struct {
+	client *http.Client
+	url    string
+}
+
+// discordMaxContent is Discord's message length limit, in characters
+const discordMaxContent = 2000
+
+func (s *discordSink) Send(ctx context.Context, msg *Message) error {
+	content := "**" + msg.Title + "**\n```\n" + strings.Join(msg.Lines, "\n") + "\n```"
+	if utf8.RuneCountInString(content) > discordMaxContent {
+		content = truncateRunes(content, discordMaxContent-8) + "\n...```"
+	}
+	return postJSON(ctx, s.client, http.MethodPost, s.url, nil, map[string]string{"content": content})
+}
+
+// truncateRunes returns the first n characters of s, never splitting one
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// matrixSink sends an m.text event to a Matrix room via the client-server API
+type matrixSink struct {
+	client     *http.Client
+	homeserver string
+	room       string
+	token      string
+}
+
+func (s *matrixSink) Send(ctx context.Context, msg *Message) error {
+	txnID := fmt.Sprintf("fsdiff-%d", time.Now().UnixNano())
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimSuffix(s.homeserver, "/"), url.PathEscape(s.room), txnID)
+
+	return postJSON(ctx, s.client, http.MethodPut, endpoint,
+		map[string]string{"Authorization": "Bearer " + s.token},
+		map[string]string{"msgtype": "m.text", "body": msg.Text()})
+}
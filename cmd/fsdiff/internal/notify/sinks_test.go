@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDiscordTruncatesOnRunes(t *testing.T) {
+	var content string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("bad payload: %v", err)
+		}
+		content = body["content"]
+	}))
+	defer srv.Close()
+
+	s := &discordSink{client: srv.Client(), url: srv.URL}
+	msg := &Message{Title: "changes", Lines: []string{strings.Repeat("é", 3000)}}
+	if err := s.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if !utf8.ValidString(content) {
+		t.Errorf("content isn't valid UTF-8")
+	}
+	if n := utf8.RuneCountInString(content); n > discordMaxContent {
+		t.Errorf("content is %d characters, want at most %d", n, discordMaxContent)
+	}
+	if !strings.HasSuffix(content, "\n...```") {
+		t.Errorf("truncated content doesn't close the code block: %q", content[len(content)-20:])
+	}
+}
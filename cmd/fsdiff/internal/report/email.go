@@ -0,0 +1,165 @@
+package report
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/diff"
+)
+
+// EmailConfig holds SMTP settings for report delivery
+type EmailConfig struct {
+	To       []string
+	From     string
+	SMTPHost string // host:port
+	Username string
+	Password string
+}
+
+// emailCriticalLimit caps how many critical changes are inlined in the body
+const emailCriticalLimit = 20
+
+var emailSummaryTemplate = template.Must(template.New("summary").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #1f2937;">
+<h2>fsdiff report for {{.Host}}</h2>
+<p>Baseline {{.Baseline}} compared against {{.Current}}.</p>
+<table cellpadding="6" style="border-collapse: collapse;">
+<tr><td>Added</td><td><b>{{.Summary.AddedCount}}</b></td></tr>
+<tr><td>Modified</td><td><b>{{.Summary.ModifiedCount}}</b></td></tr>
+<tr><td>Deleted</td><td><b>{{.Summary.DeletedCount}}</b></td></tr>
+<tr><td>Total</td><td><b>{{.Summary.TotalChanges}}</b></td></tr>
+</table>
+{{if .Critical}}
+<h3 style="color: #b91c1c;">Critical changes ({{.CriticalCount}})</h3>
+<ul>
+{{range .Critical}}<li><code>{{.Type}}</code> {{.Path}} &mdash; {{.Reason}} (severity {{.Severity}})</li>
+{{end}}</ul>
+{{if gt .CriticalCount (len .Critical)}}<p>... and {{.More}} more in the attached report.</p>{{end}}
+{{else}}
+<p>No critical changes detected.</p>
+{{end}}
+<p style="color: #6b7280;">The full interactive report is attached.</p>
+</body>
+</html>
+`))
+
+// SendEmail delivers an inline HTML summary with the full report attached
+func SendEmail(result *diff.Result, cfg *EmailConfig) error {
+	if len(cfg.To) == 0 {
+		return fmt.Errorf("no email recipients configured")
+	}
+	if cfg.SMTPHost == "" {
+		return fmt.Errorf("no SMTP host configured")
+	}
+
+	msg, err := buildEmail(result, cfg)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		host, _, err := net.SplitHostPort(cfg.SMTPHost)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP host %q: %v", cfg.SMTPHost, err)
+		}
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+
+	// smtp.SendMail upgrades to STARTTLS when the server offers it
+	if err := smtp.SendMail(cfg.SMTPHost, auth, cfg.From, cfg.To, msg); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+
+	return nil
+}
+
+// buildEmail assembles the MIME message
+func buildEmail(result *diff.Result, cfg *EmailConfig) ([]byte, error) {
+	critical := result.GetCriticalChanges()
+	host := result.Current.SystemInfo.Hostname
+
+	inline := critical
+	if len(inline) > emailCriticalLimit {
+		inline = inline[:emailCriticalLimit]
+	}
+
+	var summary bytes.Buffer
+	if err := emailSummaryTemplate.Execute(&summary, map[string]interface{}{
+		"Host":          host,
+		"Baseline":      formatTime(result.Baseline.SystemInfo.Timestamp),
+		"Current":       formatTime(result.Current.SystemInfo.Timestamp),
+		"Summary":       result.Summary,
+		"Critical":      inline,
+		"CriticalCount": len(critical),
+		"More":          len(critical) - len(inline),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to render email summary: %v", err)
+	}
+
+	var attachment bytes.Buffer
+	if err := RenderHTML(result, &attachment); err != nil {
+		return nil, err
+	}
+
+	subject := fmt.Sprintf("fsdiff: %d changes on %s", result.Summary.TotalChanges, host)
+	if len(critical) > 0 {
+		subject += fmt.Sprintf(" (%d critical)", len(critical))
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	fmt.Fprintf(&body, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&body, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&body, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64Lines(part, summary.Bytes())
+
+	filename := fmt.Sprintf("fsdiff-%s-%s.html", host, result.Generated.Format("20060102-150405"))
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=UTF-8; name=\"" + filename + "\""},
+		"Content-Disposition":       {"attachment; filename=\"" + filename + "\""},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64Lines(part, attachment.Bytes())
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return body.Bytes(), nil
+}
+
+// writeBase64Lines writes base64 wrapped at 76 characters per RFC 2045
+func writeBase64Lines(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		w.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	w.Write([]byte(encoded + "\r\n"))
+}
@@ -3,12 +3,15 @@ package report
 import (
 	"context"
 	"fmt"
+	"html"
+	"io"
 	"os"
 	"sort"
 	"strings"
 	"time"
 
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/diff"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/locale"
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
 )
 
@@ -16,6 +19,18 @@ import (
 
 // GenerateHTML creates a detailed HTML report of the differences using templ
 func GenerateHTML(result *diff.Result, filename string) error {
+	// Create output file
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %v", err)
+	}
+	defer file.Close()
+
+	return RenderHTML(result, file)
+}
+
+// RenderHTML writes the full HTML report to w
+func RenderHTML(result *diff.Result, w io.Writer) error {
 	// Build file trees
 	addedTree := buildFileTree(result.Added, nil)
 	modifiedTree := buildModifiedTree(result.Modified)
@@ -32,18 +47,12 @@ func GenerateHTML(result *diff.Result, filename string) error {
 		AddedTreeHTML:     renderTreeToHTML(addedTree, "added", "text-green-400"),
 		ModifiedTreeHTML:  renderModifiedTreeToHTML(modifiedTree, "modified", "text-yellow-400"),
 		DeletedTreeHTML:   renderTreeToHTML(deletedTree, "deleted", "text-red-400"),
+		StateChangesHTML:  renderStateChangesToHTML(result.State),
 	}
 
-	// Create output file
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create report file: %v", err)
-	}
-	defer file.Close()
-
 	// Render template
 	ctx := context.Background()
-	if err := reportTemplate(data).Render(ctx, file); err != nil {
+	if err := reportTemplate(data).Render(ctx, w); err != nil {
 		return fmt.Errorf("failed to render template: %v", err)
 	}
 
@@ -58,6 +67,7 @@ type HTMLReportData struct {
 	AddedTreeHTML     string
 	ModifiedTreeHTML  string
 	DeletedTreeHTML   string
+	StateChangesHTML  string // Empty unless system state changed
 	CriticalChanges   []diff.CriticalChange
 	TopLargestAdded   []FileSize
 	TopLargestDeleted []FileSize
@@ -111,20 +121,15 @@ func getTopLargestDeletedFiles(files map[string]*snapshot.FileRecord, limit int)
 
 // Helper functions for template
 func formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return locale.Bytes(bytes)
 }
 
 func formatTime(t time.Time) string {
-	return t.Format("2006-01-02 15:04:05")
+	return locale.Time(t)
+}
+
+func formatCount(n int) string {
+	return locale.Int(n)
 }
 
 func getChangeIcon(changeType diff.ChangeType) string {
@@ -328,6 +333,53 @@ func renderTreeToHTML(tree map[string]*TreeNode, prefix, colorClass string) stri
 	return html.String()
 }
 
+// renderStateChangesToHTML generates the system state changes section
+func renderStateChangesToHTML(changes []diff.StateChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`
+		<div class="bg-gray-800/50 backdrop-blur-sm rounded-2xl shadow-xl border border-gray-700/50 p-6 mb-8 animate-fade-in">
+			<h2 class="text-2xl font-bold text-gray-100 mb-4 flex items-center">
+				<span class="text-3xl mr-3">⚙️</span>
+				System State Changes
+				<span class="ml-2 bg-purple-500 text-white text-xs px-2 py-1 rounded-full">%d</span>
+			</h2>
+			<div class="overflow-x-auto">
+				<table class="w-full">
+					<thead>
+						<tr class="border-b border-gray-600">
+							<th class="text-left py-3 px-4 font-semibold text-gray-300">Kind</th>
+							<th class="text-left py-3 px-4 font-semibold text-gray-300">Change</th>
+							<th class="text-left py-3 px-4 font-semibold text-gray-300">Item</th>
+							<th class="text-left py-3 px-4 font-semibold text-gray-300">Detail</th>
+						</tr>
+					</thead>
+					<tbody>`, len(changes)))
+
+	for _, change := range changes {
+		b.WriteString(fmt.Sprintf(`
+						<tr class="border-b border-gray-700 hover:bg-gray-700/30 transition-colors">
+							<td class="py-3 px-4 text-sm text-gray-400">%s</td>
+							<td class="py-3 px-4 text-sm">%s %s</td>
+							<td class="py-3 px-4"><code class="bg-gray-900 px-2 py-1 rounded text-xs font-mono text-gray-200">%s</code></td>
+							<td class="py-3 px-4 text-sm text-gray-400">%s</td>
+						</tr>`,
+			change.Kind, getChangeIcon(change.Type), change.Type,
+			html.EscapeString(change.Item), html.EscapeString(change.Detail)))
+	}
+
+	b.WriteString(`
+					</tbody>
+				</table>
+			</div>
+		</div>`)
+
+	return b.String()
+}
+
 // renderModifiedTreeToHTML generates HTML for the modified file tree
 func renderModifiedTreeToHTML(tree map[string]*TreeNode, prefix, colorClass string) string {
 	var html strings.Builder
@@ -0,0 +1,79 @@
+package scanner
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+// profileKeep is how many of the most expensive directories are stored in
+// the snapshot; the long tail of cheap directories isn't useful for tuning.
+const profileKeep = 500
+
+// profiler accumulates per-directory scan timings. A nil profiler, for
+// scans without Config.Profile, records nothing, so file workers don't
+// contend for its lock.
+type profiler struct {
+	mu   sync.Mutex
+	dirs map[string]*snapshot.DirTiming
+}
+
+func newProfiler() *profiler {
+	return &profiler{dirs: make(map[string]*snapshot.DirTiming)}
+}
+
+func (p *profiler) entry(dir string) *snapshot.DirTiming {
+	t, ok := p.dirs[dir]
+	if !ok {
+		t = &snapshot.DirTiming{Path: dir}
+		p.dirs[dir] = t
+	}
+	return t
+}
+
+// recordReadDir records the time spent listing a directory
+func (p *profiler) recordReadDir(dir string, d time.Duration) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.entry(dir).ReadDirTime += d
+	p.mu.Unlock()
+}
+
+// recordFile records the time spent stat-ing and hashing a file
+func (p *profiler) recordFile(path string, d time.Duration, hashed int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	t := p.entry(filepath.Dir(path))
+	t.HashTime += d
+	t.HashedBytes += hashed
+	t.FileCount++
+	p.mu.Unlock()
+}
+
+// top returns the most expensive directories by total time
+func (p *profiler) top() []snapshot.DirTiming {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	timings := make([]snapshot.DirTiming, 0, len(p.dirs))
+	for _, t := range p.dirs {
+		timings = append(timings, *t)
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		return timings[i].Total() > timings[j].Total()
+	})
+	if len(timings) > profileKeep {
+		timings = timings[:profileKeep]
+	}
+	return timings
+}
@@ -2,8 +2,8 @@ package scanner
 
 import (
 	"compress/gzip"
-	"encoding/gob"
 	"fmt"
+	"log/slog"
 	"os"
 	"runtime"
 	"runtime/pprof"
@@ -11,11 +11,15 @@ import (
 	"sync/atomic"
 	"time"
 
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/locale"
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/merkle"
 
 	"golang.org/x/sys/unix"
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/system"
+	systemv2 "pkg.jsn.cam/jsn/cmd/fsdiff/internal/system/v2"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/pkg/fsdiff"
+	jslog "pkg.jsn.cam/jsn/internal/slog"
 )
 
 type Config struct {
@@ -23,14 +27,33 @@ type Config struct {
 	Workers        int
 	BufferSize     int
 	Verbose        bool
+
+	// CaptureState records listening sockets, kernel modules, users and
+	// groups in the snapshot's system info
+	CaptureState bool
+
+	// Profile records how long each directory took to list and hash, for
+	// fsdiff profile. It costs a lock per file, so it's off by default.
+	Profile bool
+
+	// OnRecord, if set, is called from the collector goroutine for every
+	// record as it is scanned. Used to diff a live filesystem while scanning.
+	OnRecord func(*snapshot.FileRecord)
+
+	// DiscardRecords leaves the snapshot's Files empty, so a scan whose
+	// records are consumed by OnRecord doesn't also hold them all in memory.
+	// The snapshot then has no merkle root either.
+	DiscardRecords bool
 }
 
 type Scanner struct {
-	config  *Config
-	stats   *ScanStats
-	ignorer *PathIgnorer
-	hasher  *Hasher
-	walker  *Walker
+	config   *Config
+	stats    *ScanStats
+	ignorer  *PathIgnorer
+	hasher   *Hasher
+	walker   *Walker
+	profiler *profiler
+	log      *slog.Logger
 }
 
 type ScanStats struct {
@@ -65,12 +88,23 @@ func New(config *Config) *Scanner {
 		unix.Setrlimit(unix.RLIMIT_NOFILE, &rLimit)
 	}
 
+	// Per-path errors are only counted in the output; -slog-level
+	// scanner=DEBUG says what they were
+	log := jslog.Logger("scanner")
+
+	var prof *profiler
+	if config.Profile {
+		prof = newProfiler()
+	}
+
 	return &Scanner{
-		config:  config,
-		stats:   &ScanStats{},
-		ignorer: newPathIgnorer(config.IgnorePatterns),
-		hasher:  newHasher(config.Workers, config.BufferSize),
-		walker:  newWalker(config.Workers * 2),
+		config:   config,
+		stats:    &ScanStats{},
+		ignorer:  newPathIgnorer(config.IgnorePatterns),
+		hasher:   newHasher(config.Workers, config.BufferSize),
+		walker:   newWalker(config.Workers*2, prof, log),
+		log:      log,
+		profiler: prof,
 	}
 }
 
@@ -98,10 +132,16 @@ func (s *Scanner) ScanFilesystem(rootPath string) (*snapshot.Snapshot, error) {
 		defer collectorWg.Done()
 		for result := range results {
 			if result.Error != nil {
+				s.log.Debug("can't scan path", "err", result.Error)
 				atomic.AddInt64(&s.stats.Errors, 1)
 				continue
 			}
-			files[result.Record.Path] = result.Record
+			if !s.config.DiscardRecords {
+				files[result.Record.Path] = result.Record
+			}
+			if s.config.OnRecord != nil {
+				s.config.OnRecord(result.Record)
+			}
 
 			if result.Record.IsDir {
 				atomic.AddInt64(&s.stats.DirsProcessed, 1)
@@ -122,9 +162,10 @@ func (s *Scanner) ScanFilesystem(rootPath string) (*snapshot.Snapshot, error) {
 	// Build snapshot
 	duration := time.Since(s.stats.StartTime)
 	snap := &snapshot.Snapshot{
-		SystemInfo: system.GetSystemInfo(rootPath),
+		SystemInfo: s.systemInfo(rootPath),
 		Files:      files,
 		MerkleRoot: merkle.CalculateMerkleRoot(files),
+		DirTimings: s.profiler.top(),
 		Stats: snapshot.ScanStats{
 			FileCount:    int(atomic.LoadInt64(&s.stats.FilesProcessed)),
 			DirCount:     int(atomic.LoadInt64(&s.stats.DirsProcessed)),
@@ -141,6 +182,50 @@ func (s *Scanner) ScanFilesystem(rootPath string) (*snapshot.Snapshot, error) {
 	return snap, err
 }
 
+// systemInfo gathers system metadata, with system state if configured
+func (s *Scanner) systemInfo(rootPath string) system.SystemInfo {
+	info := system.GetSystemInfo(rootPath)
+	if s.config.CaptureState {
+		info.State = system.CaptureState()
+	}
+	return info
+}
+
+// Record builds the record for a single path exactly as a scan would
+func (s *Scanner) Record(path string) (*snapshot.FileRecord, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &snapshot.FileRecord{
+		Path:     path,
+		Mode:     info.Mode(),
+		ModTime:  info.ModTime(),
+		IsDir:    info.IsDir(),
+		FileInfo: systemv2.GetFileInfo(path, info),
+	}
+	if !info.IsDir() {
+		record.Size = info.Size()
+	}
+
+	if info.Mode().IsRegular() {
+		hash, err := s.hasher.HashFile(path, info.Size())
+		if err != nil {
+			record.Hash = "ERROR"
+		} else {
+			record.Hash = hash
+		}
+	}
+
+	return record, nil
+}
+
+// Ignored reports whether path matches the scanner's ignore patterns
+func (s *Scanner) Ignored(path string) bool {
+	return s.ignorer.ShouldIgnore(path)
+}
+
 // ScanToFile performs a streaming scan that writes directly to a snapshot file
 // This keeps memory usage low by never holding all files in memory at once
 func (s *Scanner) ScanToFile(rootPath, outputFile string) error {
@@ -165,8 +250,17 @@ func (s *Scanner) ScanToFile(rootPath, outputFile string) error {
 	}
 	defer gzWriter.Close()
 
-	// Create snapshot encoder
-	encoder := gob.NewEncoder(gzWriter)
+	// Create snapshot writer
+	writer, err := snapshot.NewWriter(gzWriter)
+	if err != nil {
+		return fmt.Errorf("failed to write header: %v", err)
+	}
+
+	// Write header with system info; stats are written at the end
+	systemInfo := s.systemInfo(rootPath)
+	if err := writer.WriteHeader(fsdiff.SnapshotVersion, systemInfo); err != nil {
+		return fmt.Errorf("failed to write header: %v", err)
+	}
 
 	// Start progress monitor
 	ctx := make(chan struct{})
@@ -174,27 +268,11 @@ func (s *Scanner) ScanToFile(rootPath, outputFile string) error {
 		go s.progressMonitor(ctx)
 	}
 
-	// Create header with system info
-	systemInfo := system.GetSystemInfo(rootPath)
-	header := &snapshot.Snapshot{
-		Version:    "streaming", // Special version for streaming snapshots
-		SystemInfo: systemInfo,
-		Files:      nil,                  // Will be populated incrementally
-		Stats:      snapshot.ScanStats{}, // Will be updated at the end
-	}
-
-	// Write header (we'll update stats later)
-	if err := encoder.Encode(header); err != nil {
-		return fmt.Errorf("failed to write header: %v", err)
-	}
-
-	// Start result collector with memory-limited batch and rolling merkle calculation
+	// Start result collector with rolling merkle calculation
 	results := make(chan *FileResult, s.config.Workers*2)
-	const batchSize = 10000 // Process files in batches of 10k
-	batch := make([]*snapshot.FileRecord, 0, batchSize)
 	// Use rolling XOR for merkle root calculation to avoid accumulating all hashes
 	var rollingMerkleRoot uint64 = 0
-	batchCount := 0
+	written := 0
 
 	var collectorWg sync.WaitGroup
 	collectorWg.Add(1)
@@ -203,14 +281,20 @@ func (s *Scanner) ScanToFile(rootPath, outputFile string) error {
 
 		for result := range results {
 			if result.Error != nil {
+				s.log.Debug("can't scan path", "err", result.Error)
 				atomic.AddInt64(&s.stats.Errors, 1)
 				continue
 			}
 
-			// Add to current batch
-			batch = append(batch, result.Record)
+			// Records are streamed straight to disk
+			if err := writer.WriteRecord(result.Record); err != nil {
+				s.log.Debug("can't write record", "path", result.Record.Path, "err", err)
+				atomic.AddInt64(&s.stats.Errors, 1)
+				continue
+			}
 			// Rolling XOR for merkle calculation - no memory accumulation
 			rollingMerkleRoot ^= merkle.HashRecord(result.Record)
+			written++
 
 			// Update stats
 			if result.Record.IsDir {
@@ -219,28 +303,6 @@ func (s *Scanner) ScanToFile(rootPath, outputFile string) error {
 				atomic.AddInt64(&s.stats.FilesProcessed, 1)
 				atomic.AddInt64(&s.stats.BytesProcessed, result.Record.Size)
 			}
-
-			// Write batch when full
-			if len(batch) >= batchSize {
-				if err := encoder.Encode(batch); err != nil {
-					atomic.AddInt64(&s.stats.Errors, 1)
-				}
-				batch = batch[:0] // Reset batch, reuse underlying array
-				batchCount++
-
-				// Force flush to disk every 10 batches to prevent gzip buffer buildup
-				if batchCount%10 == 0 {
-					gzWriter.Flush()
-				}
-				runtime.GC() // Force GC after each batch
-			}
-		}
-
-		// Write final batch
-		if len(batch) > 0 {
-			if err := encoder.Encode(batch); err != nil {
-				atomic.AddInt64(&s.stats.Errors, 1)
-			}
 		}
 	}()
 
@@ -261,12 +323,21 @@ func (s *Scanner) ScanToFile(rootPath, outputFile string) error {
 		ScanDuration: duration,
 	}
 
-	if err := encoder.Encode(finalStats); err != nil {
+	merkleData := snapshot.SimpleMerkleData{
+		RootHash:  rollingMerkleRoot,
+		LeafCount: finalStats.FileCount,
+		Depth:     snapshot.CalculateSimpleDepth(written),
+	}
+	if err := writer.WriteTrailer(finalStats, rollingMerkleRoot, merkleData); err != nil {
 		return fmt.Errorf("failed to write final stats: %v", err)
 	}
 
-	if err := encoder.Encode(rollingMerkleRoot); err != nil {
-		return fmt.Errorf("failed to write merkle root: %v", err)
+	if err := writer.WriteDirTimings(s.profiler.top()); err != nil {
+		return fmt.Errorf("failed to write scan profile: %v", err)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush snapshot: %v", err)
 	}
 
 	// Ensure all data is written
@@ -361,14 +432,5 @@ func (s *Scanner) printSummary(snap *snapshot.Snapshot) {
 }
 
 func formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return locale.Bytes(bytes)
 }
@@ -1,10 +1,12 @@
 package scanner
 
 import (
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
 	systemv2 "pkg.jsn.cam/jsn/cmd/fsdiff/internal/system/v2"
@@ -14,6 +16,8 @@ type Walker struct {
 	dirQueue chan string
 	fileJobs chan FileJob
 	results  chan<- *FileResult
+	profiler *profiler
+	log      *slog.Logger
 	workers  int
 }
 
@@ -27,10 +31,12 @@ type FileResult struct {
 	Error  error
 }
 
-func newWalker(queueSize int) *Walker {
+func newWalker(queueSize int, prof *profiler, log *slog.Logger) *Walker {
 	return &Walker{
 		dirQueue: make(chan string, 1000),
 		fileJobs: make(chan FileJob, queueSize),
+		profiler: prof,
+		log:      log,
 		workers:  0,
 	}
 }
@@ -90,8 +96,11 @@ func (w *Walker) dirWorker(wg *sync.WaitGroup, ignorer *PathIgnorer, activeDirs
 	defer wg.Done()
 
 	for path := range w.dirQueue {
+		start := time.Now()
 		entries, err := os.ReadDir(path)
+		w.profiler.recordReadDir(path, time.Since(start))
 		if err != nil {
+			w.log.Debug("can't read directory", "path", path, "err", err)
 			if atomic.AddInt64(activeDirs, -1) == 0 {
 				dirMutex.Lock()
 				if !*dirClosed {
@@ -112,6 +121,7 @@ func (w *Walker) dirWorker(wg *sync.WaitGroup, ignorer *PathIgnorer, activeDirs
 
 			info, err := entry.Info()
 			if err != nil {
+				w.log.Debug("can't stat", "path", fullPath, "err", err)
 				continue
 			}
 
@@ -158,8 +168,11 @@ func (w *Walker) dirWorker(wg *sync.WaitGroup, ignorer *PathIgnorer, activeDirs
 }
 
 func (w *Walker) processDir(path string, ignorer *PathIgnorer) {
+	start := time.Now()
 	entries, err := os.ReadDir(path)
+	w.profiler.recordReadDir(path, time.Since(start))
 	if err != nil {
+		w.log.Debug("can't read directory", "path", path, "err", err)
 		return
 	}
 
@@ -171,6 +184,7 @@ func (w *Walker) processDir(path string, ignorer *PathIgnorer) {
 
 		info, err := entry.Info()
 		if err != nil {
+			w.log.Debug("can't stat", "path", fullPath, "err", err)
 			continue
 		}
 
@@ -197,6 +211,9 @@ func (w *Walker) fileWorker(wg *sync.WaitGroup, hasher *Hasher, results chan<- *
 	defer wg.Done()
 
 	for job := range w.fileJobs {
+		start := time.Now()
+		var hashed int64
+
 		record := &snapshot.FileRecord{
 			Path:     job.Path,
 			Size:     job.Info.Size(),
@@ -210,11 +227,14 @@ func (w *Walker) fileWorker(wg *sync.WaitGroup, hasher *Hasher, results chan<- *
 		if job.Info.Mode().IsRegular() {
 			hash, err := hasher.HashFile(job.Path, job.Info.Size())
 			if err != nil {
+				w.log.Debug("can't hash file", "path", job.Path, "err", err)
 				record.Hash = "ERROR"
 			} else {
 				record.Hash = hash
+				hashed = job.Info.Size()
 			}
 		}
+		w.profiler.recordFile(job.Path, time.Since(start), hashed)
 
 		results <- &FileResult{Record: record}
 	}
@@ -0,0 +1,769 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/system"
+	systemv2 "pkg.jsn.cam/jsn/cmd/fsdiff/internal/system/v2"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/pkg/fsdiff"
+)
+
+// Magic prefixes the decompressed contents of a portable snapshot file.
+// Files without it are treated as legacy gob snapshots.
+const Magic = "FSDIFFPB"
+
+// Field numbers of the top-level Snapshot message, see snapshot.proto.
+const (
+	fieldFormatVersion = 1
+	fieldVersion       = 2
+	fieldSystemInfo    = 3
+	fieldFiles         = 4
+	fieldStats         = 5
+	fieldMerkleRoot    = 6
+	fieldMerkleData    = 7
+	fieldDirTimings    = 8
+)
+
+// maxFieldSize bounds the length-delimited fields Decode reads. Legitimate
+// fields are a single record or the header and trailer, far below it, so a
+// larger length means a corrupt file rather than a buffer worth allocating.
+const maxFieldSize = 64 << 20
+
+// Writer streams a snapshot in the portable format. Because protobuf allows
+// the fields of a message to appear in any order, the header can be written
+// before the records are known and the stats after they have all been seen.
+type Writer struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewWriter writes the magic and format version to w and returns a Writer
+// for the rest of the snapshot.
+func NewWriter(w io.Writer) (*Writer, error) {
+	sw := &Writer{w: bufio.NewWriterSize(w, 256*1024)}
+	if _, err := sw.w.WriteString(Magic); err != nil {
+		return nil, err
+	}
+	sw.buf = appendVarintField(sw.buf[:0], fieldFormatVersion, fsdiff.SnapshotFormatVersion)
+	if _, err := sw.w.Write(sw.buf); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// WriteHeader writes the snapshot version and system information.
+func (sw *Writer) WriteHeader(version string, info system.SystemInfo) error {
+	sw.buf = appendStringField(sw.buf[:0], fieldVersion, version)
+	sw.buf = appendMessageField(sw.buf, fieldSystemInfo, encodeSystemInfo(nil, &info))
+	_, err := sw.w.Write(sw.buf)
+	return err
+}
+
+// WriteRecord appends a single file record.
+func (sw *Writer) WriteRecord(record *FileRecord) error {
+	sw.buf = appendMessageField(sw.buf[:0], fieldFiles, encodeFileRecord(nil, record))
+	_, err := sw.w.Write(sw.buf)
+	return err
+}
+
+// WriteTrailer writes the scan statistics and merkle information.
+func (sw *Writer) WriteTrailer(stats ScanStats, merkleRoot uint64, merkleData SimpleMerkleData) error {
+	sw.buf = appendMessageField(sw.buf[:0], fieldStats, encodeScanStats(nil, &stats))
+	sw.buf = appendFixed64Field(sw.buf, fieldMerkleRoot, merkleRoot)
+	sw.buf = appendMessageField(sw.buf, fieldMerkleData, encodeMerkleData(nil, &merkleData))
+	_, err := sw.w.Write(sw.buf)
+	return err
+}
+
+// WriteDirTimings writes the per-directory scan profile.
+func (sw *Writer) WriteDirTimings(timings []DirTiming) error {
+	sw.buf = sw.buf[:0]
+	for i := range timings {
+		sw.buf = appendMessageField(sw.buf, fieldDirTimings, encodeDirTiming(nil, &timings[i]))
+	}
+	_, err := sw.w.Write(sw.buf)
+	return err
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (sw *Writer) Flush() error {
+	return sw.w.Flush()
+}
+
+// Encode writes a complete snapshot in the portable format.
+func Encode(w io.Writer, snap *Snapshot) error {
+	sw, err := NewWriter(w)
+	if err != nil {
+		return err
+	}
+	if err := sw.WriteHeader(snap.Version, snap.SystemInfo); err != nil {
+		return err
+	}
+	for _, record := range snap.Files {
+		if err := sw.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+	if err := sw.WriteTrailer(snap.Stats, snap.MerkleRoot, snap.MerkleData); err != nil {
+		return err
+	}
+	if err := sw.WriteDirTimings(snap.DirTimings); err != nil {
+		return err
+	}
+	return sw.Flush()
+}
+
+// Decode reads a portable snapshot, including the leading magic, from r.
+// When headerOnly is set the file records are skipped without being
+// decoded, which keeps header inspection cheap on large snapshots.
+func Decode(r io.Reader, headerOnly bool) (*Snapshot, error) {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReaderSize(r, 256*1024)
+	}
+
+	magic := make([]byte, len(Magic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot magic: %v", err)
+	}
+	if string(magic) != Magic {
+		return nil, fmt.Errorf("not a portable snapshot (bad magic %q)", magic)
+	}
+
+	snap := &Snapshot{}
+	if !headerOnly {
+		snap.Files = make(map[string]*FileRecord)
+	}
+
+	var payload []byte
+	for {
+		tag, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read field tag: %v", err)
+		}
+		field, wt := int(tag>>3), int(tag&7)
+
+		switch wt {
+		case wireVarint:
+			v, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, errTruncated
+			}
+			if field == fieldFormatVersion && v > fsdiff.SnapshotFormatVersion {
+				return nil, fmt.Errorf("snapshot format version %d is newer than supported version %d",
+					v, fsdiff.SnapshotFormatVersion)
+			}
+		case wireFixed64:
+			var v [8]byte
+			if _, err := io.ReadFull(br, v[:]); err != nil {
+				return nil, errTruncated
+			}
+			if field == fieldMerkleRoot {
+				snap.MerkleRoot = binary.LittleEndian.Uint64(v[:])
+			}
+		case wireFixed32:
+			if _, err := br.Discard(4); err != nil {
+				return nil, errTruncated
+			}
+		case wireBytes:
+			l, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, errTruncated
+			}
+			if l > maxFieldSize {
+				return nil, fmt.Errorf("field %d is %d bytes, more than the %d allowed", field, l, maxFieldSize)
+			}
+			if field == fieldFiles && headerOnly {
+				if _, err := br.Discard(int(l)); err != nil {
+					return nil, errTruncated
+				}
+				continue
+			}
+			if uint64(cap(payload)) < l {
+				payload = make([]byte, l)
+			}
+			payload = payload[:l]
+			if _, err := io.ReadFull(br, payload); err != nil {
+				return nil, errTruncated
+			}
+			if err := decodeTopLevel(snap, field, payload); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d for field %d", wt, field)
+		}
+	}
+
+	return snap, nil
+}
+
+func decodeTopLevel(snap *Snapshot, field int, payload []byte) error {
+	var err error
+	switch field {
+	case fieldVersion:
+		snap.Version = string(payload)
+	case fieldSystemInfo:
+		err = decodeSystemInfo(payload, &snap.SystemInfo)
+	case fieldFiles:
+		record := &FileRecord{}
+		if err = decodeFileRecord(payload, record); err == nil {
+			snap.Files[record.Path] = record
+		}
+	case fieldStats:
+		err = decodeScanStats(payload, &snap.Stats)
+	case fieldMerkleData:
+		err = decodeMerkleData(payload, &snap.MerkleData)
+	case fieldDirTimings:
+		var t DirTiming
+		if err = decodeDirTiming(payload, &t); err == nil {
+			snap.DirTimings = append(snap.DirTimings, t)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode field %d: %v", field, err)
+	}
+	return nil
+}
+
+func timeToNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func nanosToTime(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+func encodeSystemInfo(b []byte, s *system.SystemInfo) []byte {
+	b = appendInt64Field(b, 1, timeToNanos(s.Timestamp))
+	b = appendStringField(b, 2, s.Hostname)
+	b = appendStringField(b, 3, s.OS)
+	b = appendStringField(b, 4, s.Arch)
+	b = appendStringField(b, 5, s.Distro)
+	b = appendStringField(b, 6, s.KernelVer)
+	b = appendStringField(b, 7, s.ScanRoot)
+	b = appendStringField(b, 8, s.GoVersion)
+	b = appendInt64Field(b, 9, int64(s.ScanDuration))
+	b = appendInt64Field(b, 10, int64(s.CPUCount))
+	if s.State != nil {
+		b = appendMessageField(b, 11, encodeState(nil, s.State))
+	}
+	return b
+}
+
+func decodeSystemInfo(b []byte, s *system.SystemInfo) error {
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wt, err := r.next()
+		if err != nil {
+			return err
+		}
+		if wt == wireVarint {
+			var v uint64
+			if v, err = r.varint(); err != nil {
+				return err
+			}
+			switch field {
+			case 1:
+				s.Timestamp = nanosToTime(int64(v))
+			case 9:
+				s.ScanDuration = time.Duration(v)
+			case 10:
+				s.CPUCount = int(int32(v))
+			}
+			continue
+		}
+		if wt != wireBytes {
+			if err := r.skip(wt); err != nil {
+				return err
+			}
+			continue
+		}
+		if field == 11 {
+			msg, err := r.bytes()
+			if err != nil {
+				return err
+			}
+			s.State = &system.State{}
+			if err := decodeState(msg, s.State); err != nil {
+				return err
+			}
+			continue
+		}
+		v, err := r.string()
+		if err != nil {
+			return err
+		}
+		switch field {
+		case 2:
+			s.Hostname = v
+		case 3:
+			s.OS = v
+		case 4:
+			s.Arch = v
+		case 5:
+			s.Distro = v
+		case 6:
+			s.KernelVer = v
+		case 7:
+			s.ScanRoot = v
+		case 8:
+			s.GoVersion = v
+		}
+	}
+	return nil
+}
+
+func encodeFileRecord(b []byte, rec *FileRecord) []byte {
+	b = appendStringField(b, 1, rec.Path)
+	b = appendStringField(b, 2, rec.Hash)
+	b = appendInt64Field(b, 3, rec.Size)
+	b = appendVarintField(b, 4, uint64(rec.Mode))
+	b = appendBoolField(b, 5, rec.IsDir)
+	b = appendInt64Field(b, 6, timeToNanos(rec.ModTime))
+	if rec.FileInfo != nil {
+		b = appendMessageField(b, 7, encodeFileInfo(nil, rec.FileInfo))
+	}
+	return b
+}
+
+func decodeFileRecord(b []byte, rec *FileRecord) error {
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wt, err := r.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case field == 1 && wt == wireBytes:
+			rec.Path, err = r.string()
+		case field == 2 && wt == wireBytes:
+			rec.Hash, err = r.string()
+		case field == 3 && wt == wireVarint:
+			var v uint64
+			v, err = r.varint()
+			rec.Size = int64(v)
+		case field == 4 && wt == wireVarint:
+			var v uint64
+			v, err = r.varint()
+			rec.Mode = fs.FileMode(v)
+		case field == 5 && wt == wireVarint:
+			var v uint64
+			v, err = r.varint()
+			rec.IsDir = v != 0
+		case field == 6 && wt == wireVarint:
+			var v uint64
+			v, err = r.varint()
+			rec.ModTime = nanosToTime(int64(v))
+		case field == 7 && wt == wireBytes:
+			var msg []byte
+			if msg, err = r.bytes(); err == nil {
+				rec.FileInfo = &systemv2.FileInfo{}
+				err = decodeFileInfo(msg, rec.FileInfo)
+			}
+		default:
+			err = r.skip(wt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeFileInfo(b []byte, fi *systemv2.FileInfo) []byte {
+	b = appendVarintField(b, 1, uint64(fi.OwnerID))
+	b = appendVarintField(b, 2, uint64(fi.GroupID))
+	b = appendVarintField(b, 3, uint64(fi.Permissions))
+	b = appendFixed64Field(b, 4, fi.Hash)
+	if fi.Metadata != nil {
+		b = appendMessageField(b, 5, encodeFileMetadata(nil, fi.Metadata))
+	}
+	return b
+}
+
+func decodeFileInfo(b []byte, fi *systemv2.FileInfo) error {
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wt, err := r.next()
+		if err != nil {
+			return err
+		}
+		var v uint64
+		switch {
+		case field == 1 && wt == wireVarint:
+			v, err = r.varint()
+			fi.OwnerID = uint32(v)
+		case field == 2 && wt == wireVarint:
+			v, err = r.varint()
+			fi.GroupID = uint32(v)
+		case field == 3 && wt == wireVarint:
+			v, err = r.varint()
+			fi.Permissions = uint16(v)
+		case field == 4 && wt == wireFixed64:
+			fi.Hash, err = r.fixed64()
+		case field == 5 && wt == wireBytes:
+			var msg []byte
+			if msg, err = r.bytes(); err == nil {
+				fi.Metadata = &systemv2.FileMetadata{}
+				err = decodeFileMetadata(msg, fi.Metadata)
+			}
+		default:
+			err = r.skip(wt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeFileMetadata(b []byte, m *systemv2.FileMetadata) []byte {
+	b = appendStringMapField(b, 1, m.SELinux)
+	b = appendStringMapField(b, 2, m.Xattrs)
+	b = appendStringField(b, 3, m.Capabilities)
+	for _, acl := range m.ACLs {
+		b = appendBytesField(b, 4, []byte(acl))
+	}
+	b = appendBoolField(b, 5, m.Immutable)
+	b = appendBoolField(b, 6, m.AppendOnly)
+	return b
+}
+
+func decodeFileMetadata(b []byte, m *systemv2.FileMetadata) error {
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wt, err := r.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case (field == 1 || field == 2) && wt == wireBytes:
+			var entry []byte
+			if entry, err = r.bytes(); err != nil {
+				return err
+			}
+			k, v, err := readStringMapEntry(entry)
+			if err != nil {
+				return err
+			}
+			if field == 1 {
+				if m.SELinux == nil {
+					m.SELinux = make(map[string]string)
+				}
+				m.SELinux[k] = v
+			} else {
+				if m.Xattrs == nil {
+					m.Xattrs = make(map[string]string)
+				}
+				m.Xattrs[k] = v
+			}
+		case field == 3 && wt == wireBytes:
+			m.Capabilities, err = r.string()
+		case field == 4 && wt == wireBytes:
+			var acl string
+			if acl, err = r.string(); err == nil {
+				m.ACLs = append(m.ACLs, acl)
+			}
+		case field == 5 && wt == wireVarint:
+			var v uint64
+			v, err = r.varint()
+			m.Immutable = v != 0
+		case field == 6 && wt == wireVarint:
+			var v uint64
+			v, err = r.varint()
+			m.AppendOnly = v != 0
+		default:
+			err = r.skip(wt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeScanStats(b []byte, s *ScanStats) []byte {
+	b = appendInt64Field(b, 1, int64(s.FileCount))
+	b = appendInt64Field(b, 2, int64(s.DirCount))
+	b = appendInt64Field(b, 3, s.TotalSize)
+	b = appendInt64Field(b, 4, int64(s.ErrorCount))
+	b = appendInt64Field(b, 5, int64(s.ScanDuration))
+	return b
+}
+
+func decodeScanStats(b []byte, s *ScanStats) error {
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wt, err := r.next()
+		if err != nil {
+			return err
+		}
+		if wt != wireVarint {
+			if err := r.skip(wt); err != nil {
+				return err
+			}
+			continue
+		}
+		v, err := r.varint()
+		if err != nil {
+			return err
+		}
+		switch field {
+		case 1:
+			s.FileCount = int(v)
+		case 2:
+			s.DirCount = int(v)
+		case 3:
+			s.TotalSize = int64(v)
+		case 4:
+			s.ErrorCount = int(v)
+		case 5:
+			s.ScanDuration = time.Duration(v)
+		}
+	}
+	return nil
+}
+
+func encodeMerkleData(b []byte, m *SimpleMerkleData) []byte {
+	b = appendFixed64Field(b, 1, m.RootHash)
+	b = appendInt64Field(b, 2, int64(m.LeafCount))
+	b = appendInt64Field(b, 3, int64(m.Depth))
+	return b
+}
+
+func decodeMerkleData(b []byte, m *SimpleMerkleData) error {
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wt, err := r.next()
+		if err != nil {
+			return err
+		}
+		var v uint64
+		switch {
+		case field == 1 && wt == wireFixed64:
+			m.RootHash, err = r.fixed64()
+		case field == 2 && wt == wireVarint:
+			v, err = r.varint()
+			m.LeafCount = int(v)
+		case field == 3 && wt == wireVarint:
+			v, err = r.varint()
+			m.Depth = int(v)
+		default:
+			err = r.skip(wt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeDirTiming(b []byte, t *DirTiming) []byte {
+	b = appendStringField(b, 1, t.Path)
+	b = appendInt64Field(b, 2, int64(t.ReadDirTime))
+	b = appendInt64Field(b, 3, int64(t.HashTime))
+	b = appendInt64Field(b, 4, t.HashedBytes)
+	b = appendInt64Field(b, 5, int64(t.FileCount))
+	return b
+}
+
+func decodeDirTiming(b []byte, t *DirTiming) error {
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wt, err := r.next()
+		if err != nil {
+			return err
+		}
+		if field == 1 && wt == wireBytes {
+			if t.Path, err = r.string(); err != nil {
+				return err
+			}
+			continue
+		}
+		if wt != wireVarint {
+			if err := r.skip(wt); err != nil {
+				return err
+			}
+			continue
+		}
+		v, err := r.varint()
+		if err != nil {
+			return err
+		}
+		switch field {
+		case 2:
+			t.ReadDirTime = time.Duration(v)
+		case 3:
+			t.HashTime = time.Duration(v)
+		case 4:
+			t.HashedBytes = int64(v)
+		case 5:
+			t.FileCount = int(v)
+		}
+	}
+	return nil
+}
+
+func encodeState(b []byte, s *system.State) []byte {
+	for _, l := range s.Listeners {
+		var msg []byte
+		msg = appendStringField(msg, 1, l.Proto)
+		msg = appendStringField(msg, 2, l.Address)
+		msg = appendVarintField(msg, 3, uint64(l.Port))
+		msg = appendStringField(msg, 4, l.Process)
+		b = appendMessageField(b, 1, msg)
+	}
+	for _, m := range s.Modules {
+		var msg []byte
+		msg = appendStringField(msg, 1, m.Name)
+		msg = appendInt64Field(msg, 2, m.Size)
+		b = appendMessageField(b, 2, msg)
+	}
+	for _, u := range s.Users {
+		var msg []byte
+		msg = appendStringField(msg, 1, u.Name)
+		msg = appendVarintField(msg, 2, uint64(u.UID))
+		msg = appendVarintField(msg, 3, uint64(u.GID))
+		msg = appendStringField(msg, 4, u.Home)
+		msg = appendStringField(msg, 5, u.Shell)
+		b = appendMessageField(b, 3, msg)
+	}
+	for _, g := range s.Groups {
+		var msg []byte
+		msg = appendStringField(msg, 1, g.Name)
+		msg = appendVarintField(msg, 2, uint64(g.GID))
+		for _, member := range g.Members {
+			msg = appendBytesField(msg, 3, []byte(member))
+		}
+		b = appendMessageField(b, 4, msg)
+	}
+	return b
+}
+
+func decodeState(b []byte, s *system.State) error {
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wt, err := r.next()
+		if err != nil {
+			return err
+		}
+		if wt != wireBytes {
+			if err := r.skip(wt); err != nil {
+				return err
+			}
+			continue
+		}
+		msg, err := r.bytes()
+		if err != nil {
+			return err
+		}
+
+		switch field {
+		case 1:
+			var l system.Listener
+			err = decodeFields(msg, func(field int, v uint64, str string) {
+				switch field {
+				case 1:
+					l.Proto = str
+				case 2:
+					l.Address = str
+				case 3:
+					l.Port = int(v)
+				case 4:
+					l.Process = str
+				}
+			})
+			s.Listeners = append(s.Listeners, l)
+		case 2:
+			var m system.KernelModule
+			err = decodeFields(msg, func(field int, v uint64, str string) {
+				switch field {
+				case 1:
+					m.Name = str
+				case 2:
+					m.Size = int64(v)
+				}
+			})
+			s.Modules = append(s.Modules, m)
+		case 3:
+			var u system.User
+			err = decodeFields(msg, func(field int, v uint64, str string) {
+				switch field {
+				case 1:
+					u.Name = str
+				case 2:
+					u.UID = int(v)
+				case 3:
+					u.GID = int(v)
+				case 4:
+					u.Home = str
+				case 5:
+					u.Shell = str
+				}
+			})
+			s.Users = append(s.Users, u)
+		case 4:
+			var g system.Group
+			err = decodeFields(msg, func(field int, v uint64, str string) {
+				switch field {
+				case 1:
+					g.Name = str
+				case 2:
+					g.GID = int(v)
+				case 3:
+					g.Members = append(g.Members, str)
+				}
+			})
+			s.Groups = append(s.Groups, g)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeFields walks a flat message of varint and string fields, calling fn
+// with whichever of v or str applies. Other wire types are skipped.
+func decodeFields(b []byte, fn func(field int, v uint64, str string)) error {
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wt, err := r.next()
+		if err != nil {
+			return err
+		}
+		switch wt {
+		case wireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return err
+			}
+			fn(field, v, "")
+		case wireBytes:
+			str, err := r.string()
+			if err != nil {
+				return err
+			}
+			fn(field, 0, str)
+		default:
+			if err := r.skip(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/system"
+	systemv2 "pkg.jsn.cam/jsn/cmd/fsdiff/internal/system/v2"
+)
+
+func testSnapshot() *Snapshot {
+	ts := time.Unix(1700000000, 12345)
+	return &Snapshot{
+		Version: "2.0.0",
+		SystemInfo: system.SystemInfo{
+			Timestamp: ts,
+			Hostname:  "host1",
+			OS:        "linux",
+			Arch:      "amd64",
+			Distro:    "Debian",
+			KernelVer: "6.1.0",
+			ScanRoot:  "/",
+			GoVersion: "go1.24",
+			CPUCount:  8,
+			State: &system.State{
+				Listeners: []system.Listener{{Proto: "tcp", Address: "0.0.0.0", Port: 22, Process: "sshd"}},
+				Modules:   []system.KernelModule{{Name: "ext4", Size: 1000000}},
+				Users:     []system.User{{Name: "root", UID: 0, GID: 0, Home: "/root", Shell: "/bin/bash"}},
+				Groups:    []system.Group{{Name: "wheel", GID: 10, Members: []string{"root", "admin"}}},
+			},
+		},
+		Files: map[string]*FileRecord{
+			"/etc/passwd": {
+				Path:    "/etc/passwd",
+				Hash:    "ef46db3751d8e999",
+				Size:    1234,
+				Mode:    0644,
+				ModTime: ts,
+				FileInfo: &systemv2.FileInfo{
+					OwnerID:     0,
+					GroupID:     0,
+					Permissions: 0644,
+					Metadata: &systemv2.FileMetadata{
+						SELinux:   map[string]string{"label": "system_u:object_r:etc_t:s0"},
+						Xattrs:    map[string]string{"user.a": "1", "user.b": "2"},
+						ACLs:      []string{"access:u::rw-"},
+						Immutable: true,
+					},
+				},
+			},
+			"/etc": {
+				Path:     "/etc",
+				Mode:     0755 | 1<<31,
+				IsDir:    true,
+				FileInfo: &systemv2.FileInfo{OwnerID: 1000, GroupID: 1000, Permissions: 0o4755},
+			},
+		},
+		Stats: ScanStats{
+			FileCount:    1,
+			DirCount:     1,
+			TotalSize:    1234,
+			ScanDuration: 3 * time.Second,
+		},
+		MerkleRoot: 0xdeadbeefcafef00d,
+		MerkleData: SimpleMerkleData{RootHash: 0xdeadbeefcafef00d, LeafCount: 1, Depth: 2},
+		DirTimings: []DirTiming{
+			{Path: "/etc", ReadDirTime: time.Millisecond, HashTime: 2 * time.Second, HashedBytes: 1234, FileCount: 1},
+		},
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	want := testSnapshot()
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, want); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	got, err := Decode(&buf, false)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if !got.SystemInfo.Timestamp.Equal(want.SystemInfo.Timestamp) {
+		t.Errorf("timestamp = %v, want %v", got.SystemInfo.Timestamp, want.SystemInfo.Timestamp)
+	}
+	got.SystemInfo.Timestamp = want.SystemInfo.Timestamp
+	for path, record := range got.Files {
+		if !record.ModTime.Equal(want.Files[path].ModTime) {
+			t.Errorf("%s: mod time = %v, want %v", path, record.ModTime, want.Files[path].ModTime)
+		}
+		record.ModTime = want.Files[path].ModTime
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestDecodeHeaderOnly(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, testSnapshot()); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	got, err := Decode(&buf, true)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got.Files != nil {
+		t.Errorf("header-only decode returned %d files", len(got.Files))
+	}
+	if got.SystemInfo.Hostname != "host1" || got.Stats.TotalSize != 1234 {
+		t.Errorf("header fields not decoded: %+v", got)
+	}
+}
+
+func TestDecodeSkipsUnknownFields(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, testSnapshot()); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	// Simulate a newer writer appending fields this reader doesn't know about.
+	var extra []byte
+	extra = appendStringField(extra, 99, "from the future")
+	extra = appendVarintField(extra, 100, 42)
+	extra = appendFixed64Field(extra, 101, 7)
+	buf.Write(extra)
+
+	got, err := Decode(&buf, false)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(got.Files) != 2 {
+		t.Errorf("got %d files, want 2", len(got.Files))
+	}
+}
+
+func TestDecodeRejectsNewerFormat(t *testing.T) {
+	var b []byte
+	b = append(b, Magic...)
+	b = appendVarintField(b, fieldFormatVersion, 99)
+
+	if _, err := Decode(bytes.NewReader(b), false); err == nil {
+		t.Fatal("expected error for newer format version")
+	}
+}
+
+func TestDecodeRejectsOversizedField(t *testing.T) {
+	var b []byte
+	b = append(b, Magic...)
+	b = appendTag(b, fieldFiles, wireBytes)
+	b = binary.AppendUvarint(b, 1<<62)
+
+	for _, headerOnly := range []bool{false, true} {
+		if _, err := Decode(bytes.NewReader(b), headerOnly); err == nil {
+			t.Errorf("Decode(headerOnly=%v): expected error for oversized field", headerOnly)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package snapshot
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Rebase is a path prefix rewrite, e.g. /mnt/image -> / to compare a
+// mounted image against a host scanned at its root
+type Rebase struct {
+	From string
+	To   string
+}
+
+// ParseRebase parses an "old:new" rebase rule
+func ParseRebase(rule string) (Rebase, error) {
+	from, to, ok := strings.Cut(rule, ":")
+	if !ok || !filepath.IsAbs(from) || !filepath.IsAbs(to) {
+		return Rebase{}, fmt.Errorf("invalid rebase %q, expected /old/root:/new/root", rule)
+	}
+	return Rebase{From: filepath.Clean(from), To: filepath.Clean(to)}, nil
+}
+
+// Path rewrites p if it is at or below From
+func (r Rebase) Path(p string) (string, bool) {
+	if p == r.From {
+		return r.To, true
+	}
+
+	prefix := r.From
+	if prefix != "/" {
+		prefix += "/"
+	}
+	if !strings.HasPrefix(p, prefix) {
+		return p, false
+	}
+	return filepath.Join(r.To, p[len(prefix):]), true
+}
+
+// RebasePath rewrites p with the first rule it is at or below and returns
+// that rule's index, or p and -1 if none match. Stopping at the first match
+// keeps overlapping rules from moving a path twice.
+func RebasePath(p string, rules []Rebase) (string, int) {
+	for i, r := range rules {
+		if newPath, ok := r.Path(p); ok {
+			return newPath, i
+		}
+	}
+	return p, -1
+}
+
+// Rebase rewrites every path in the snapshot with RebasePath and returns how
+// many records each rule moved. Records are copied, never modified in place,
+// so records shared with a stream are left alone. If two paths end up the
+// same, one would hide the other from a diff, so Rebase returns an error
+// and leaves the snapshot unchanged.
+func (s *Snapshot) Rebase(rules []Rebase) ([]int, error) {
+	moved := make([]int, len(rules))
+	files := make(map[string]*FileRecord, len(s.Files))
+	var collisions []string
+	for p, record := range s.Files {
+		if newPath, i := RebasePath(p, rules); i >= 0 {
+			rebased := *record
+			rebased.Path = newPath
+			record = &rebased
+			p = newPath
+			moved[i]++
+		}
+		if _, ok := files[p]; ok {
+			collisions = append(collisions, p)
+		}
+		files[p] = record
+	}
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		return nil, fmt.Errorf("rebasing maps %d paths onto paths already in the snapshot, e.g. %s", len(collisions), collisions[0])
+	}
+	s.Files = files
+
+	for i := range s.DirTimings {
+		s.DirTimings[i].Path, _ = RebasePath(s.DirTimings[i].Path, rules)
+	}
+	s.SystemInfo.ScanRoot, _ = RebasePath(filepath.Clean(s.SystemInfo.ScanRoot), rules)
+
+	// The cached tree is keyed by the old paths
+	s.Tree = nil
+	return moved, nil
+}
@@ -0,0 +1,105 @@
+package snapshot
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRebasePath(t *testing.T) {
+	tests := []struct {
+		rule, path, want string
+		moved            bool
+	}{
+		{"/mnt/image:/", "/mnt/image", "/", true},
+		{"/mnt/image:/", "/mnt/image/etc/passwd", "/etc/passwd", true},
+		{"/mnt/image:/", "/mnt/image2/etc", "/mnt/image2/etc", false},
+		{"/:/mnt/image", "/etc/passwd", "/mnt/image/etc/passwd", true},
+		{"/srv/a:/srv/b", "/srv/a/x", "/srv/b/x", true},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRebase(tt.rule)
+		if err != nil {
+			t.Fatalf("ParseRebase(%q): %v", tt.rule, err)
+		}
+		got, moved := r.Path(tt.path)
+		if got != tt.want || moved != tt.moved {
+			t.Errorf("%s on %s = %s, %v; want %s, %v", tt.rule, tt.path, got, moved, tt.want, tt.moved)
+		}
+	}
+}
+
+func TestRebaseSnapshot(t *testing.T) {
+	snap := testSnapshot()
+	original := snap.Files["/etc/passwd"]
+
+	r, _ := ParseRebase("/:/mnt/image")
+	if moved, err := snap.Rebase([]Rebase{r}); err != nil || moved[0] != 2 {
+		t.Errorf("Rebase = %v, %v; want [2], nil", moved, err)
+	}
+
+	record, ok := snap.Files["/mnt/image/etc/passwd"]
+	if !ok || record.Path != "/mnt/image/etc/passwd" {
+		t.Fatalf("rebased record missing or wrong: %+v", record)
+	}
+	if original.Path != "/etc/passwd" {
+		t.Errorf("original record was modified in place")
+	}
+	if snap.SystemInfo.ScanRoot != "/mnt/image" {
+		t.Errorf("scan root = %s, want /mnt/image", snap.SystemInfo.ScanRoot)
+	}
+}
+
+func TestRebaseFirstMatch(t *testing.T) {
+	var rules []Rebase
+	for _, rule := range []string{"/etc:/srv/etc", "/srv:/data", "/:/mnt/image"} {
+		r, err := ParseRebase(rule)
+		if err != nil {
+			t.Fatalf("ParseRebase(%q): %v", rule, err)
+		}
+		rules = append(rules, r)
+	}
+
+	// Chained, /etc/passwd would go on to /data/etc/passwd and then to
+	// /mnt/image/data/etc/passwd
+	if got, i := RebasePath("/etc/passwd", rules); got != "/srv/etc/passwd" || i != 0 {
+		t.Errorf("RebasePath(/etc/passwd) = %s, %d; want /srv/etc/passwd, 0", got, i)
+	}
+
+	snap := testSnapshot()
+	moved, err := snap.Rebase(rules)
+	if err != nil {
+		t.Fatalf("Rebase: %v", err)
+	}
+	if _, ok := snap.Files["/srv/etc/passwd"]; !ok {
+		t.Errorf("/etc/passwd not rebased to /srv/etc/passwd: %v", snap.Files)
+	}
+	if snap.SystemInfo.ScanRoot != "/mnt/image" {
+		t.Errorf("scan root = %s, want /mnt/image", snap.SystemInfo.ScanRoot)
+	}
+	if want := []int{2, 0, 0}; !slices.Equal(moved, want) {
+		t.Errorf("moved = %v, want %v", moved, want)
+	}
+}
+
+func TestRebaseCollision(t *testing.T) {
+	snap := testSnapshot()
+	snap.Files["/mnt/image/etc/passwd"] = &FileRecord{Path: "/mnt/image/etc/passwd"}
+
+	// /mnt/image/etc/passwd lands on the existing /etc/passwd
+	r, _ := ParseRebase("/mnt/image:/")
+	if _, err := snap.Rebase([]Rebase{r}); err == nil || !strings.Contains(err.Error(), "/etc/passwd") {
+		t.Errorf("Rebase = %v, want a collision on /etc/passwd", err)
+	}
+	if len(snap.Files) != 3 || snap.Files["/mnt/image/etc/passwd"] == nil {
+		t.Errorf("snapshot changed by a failed rebase: %v", snap.Files)
+	}
+
+	// Two rules onto the same root
+	a, _ := ParseRebase("/etc:/srv")
+	b, _ := ParseRebase("/mnt/image/etc:/srv")
+	if _, err := snap.Rebase([]Rebase{a, b}); err == nil || !strings.Contains(err.Error(), "/srv/passwd") {
+		t.Errorf("Rebase = %v, want a collision on /srv/passwd", err)
+	}
+}
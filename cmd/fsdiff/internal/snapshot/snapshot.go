@@ -1,13 +1,16 @@
 package snapshot
 
 import (
+	"bufio"
 	"compress/gzip"
 	"encoding/gob"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"time"
 
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/locale"
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/system"
 	systemv2 "pkg.jsn.cam/jsn/cmd/fsdiff/internal/system/v2"
 	"pkg.jsn.cam/jsn/cmd/fsdiff/pkg/fsdiff"
@@ -33,6 +36,20 @@ type ScanStats struct {
 	ScanDuration time.Duration `json:"scan_duration"`
 }
 
+// DirTiming records how long a scan spent in a single directory
+type DirTiming struct {
+	Path        string        `json:"path"`
+	ReadDirTime time.Duration `json:"read_dir_time"`
+	HashTime    time.Duration `json:"hash_time"` // Time spent stat-ing and hashing direct children
+	HashedBytes int64         `json:"hashed_bytes"`
+	FileCount   int           `json:"file_count"`
+}
+
+// Total returns the combined listing and hashing time
+func (t DirTiming) Total() time.Duration {
+	return t.ReadDirTime + t.HashTime
+}
+
 // SimpleMerkleData contains just the essential merkle information for serialization
 type SimpleMerkleData struct {
 	RootHash  uint64 `json:"root_hash"`
@@ -49,6 +66,7 @@ type Snapshot struct {
 	Stats      ScanStats              `json:"stats"`
 	MerkleData SimpleMerkleData       `json:"merkle_data"` // Store essential merkle info
 	MerkleRoot uint64                 `json:"merkle_root"`
+	DirTimings []DirTiming            `json:"dir_timings,omitempty"` // Slowest directories of the scan
 }
 
 // SnapshotHeader contains metadata for quick snapshot inspection
@@ -58,6 +76,7 @@ type SnapshotHeader struct {
 	SystemInfo system.SystemInfo `json:"system_info"`
 	Stats      ScanStats         `json:"stats"`
 	MerkleRoot uint64            `json:"merkle_root"`
+	DirTimings []DirTiming       `json:"dir_timings,omitempty"`
 }
 
 // Save saves a snapshot to disk with compression
@@ -71,7 +90,7 @@ func Save(snapshot *Snapshot, filename string) error {
 		snapshot.MerkleData = SimpleMerkleData{
 			RootHash:  snapshot.MerkleRoot,
 			LeafCount: snapshot.Stats.FileCount,
-			Depth:     calculateSimpleDepth(len(snapshot.Files)),
+			Depth:     CalculateSimpleDepth(len(snapshot.Files)),
 		}
 	}
 
@@ -99,9 +118,8 @@ func Save(snapshot *Snapshot, filename string) error {
 		fsdiff.Version, snapshot.SystemInfo.String())
 	gzWriter.ModTime = time.Now()
 
-	// Encode the snapshot
-	encoder := gob.NewEncoder(gzWriter)
-	if err := encoder.Encode(snapshot); err != nil {
+	// Encode the snapshot in the portable format
+	if err := Encode(gzWriter, snapshot); err != nil {
 		// Restore tree reference
 		snapshot.Tree = originalTree
 		return fmt.Errorf("failed to encode snapshot: %v", err)
@@ -148,10 +166,8 @@ func Load(filename string) (*Snapshot, error) {
 	}
 	defer gzReader.Close()
 
-	// Decode the snapshot
-	decoder := gob.NewDecoder(gzReader)
-	var snapshot Snapshot
-	if err := decoder.Decode(&snapshot); err != nil {
+	snapshot, err := decode(gzReader, false)
+	if err != nil {
 		return nil, fmt.Errorf("failed to decode snapshot: %v", err)
 	}
 
@@ -166,11 +182,11 @@ func Load(filename string) (*Snapshot, error) {
 
 	fmt.Printf("📖 Loaded snapshot: %s (%s) - %d files, %d dirs\n",
 		snapshot.SystemInfo.Hostname,
-		snapshot.SystemInfo.Timestamp.Format("2006-01-02 15:04:05"),
+		locale.Time(snapshot.SystemInfo.Timestamp),
 		snapshot.Stats.FileCount,
 		snapshot.Stats.DirCount)
 
-	return &snapshot, nil
+	return snapshot, nil
 }
 
 // SimpleMerkleTree is a minimal tree representation for compatibility
@@ -199,8 +215,8 @@ type SimpleTreeComparison struct {
 	Same      bool
 }
 
-// calculateSimpleDepth estimates tree depth based on file count
-func calculateSimpleDepth(fileCount int) int {
+// CalculateSimpleDepth estimates tree depth based on file count
+func CalculateSimpleDepth(fileCount int) int {
 	if fileCount <= 1 {
 		return 1
 	}
@@ -227,10 +243,9 @@ func LoadHeader(filename string) (*SnapshotHeader, error) {
 	}
 	defer gzReader.Close()
 
-	// Try to read just enough to get the header
-	decoder := gob.NewDecoder(gzReader)
-	var snapshot Snapshot
-	if err := decoder.Decode(&snapshot); err != nil {
+	// Portable snapshots skip the file records entirely
+	snapshot, err := decode(gzReader, true)
+	if err != nil {
 		return nil, fmt.Errorf("failed to decode snapshot header: %v", err)
 	}
 
@@ -239,12 +254,29 @@ func LoadHeader(filename string) (*SnapshotHeader, error) {
 		SystemInfo: snapshot.SystemInfo,
 		Stats:      snapshot.Stats,
 		MerkleRoot: snapshot.MerkleRoot,
+		DirTimings: snapshot.DirTimings,
 		Created:    snapshot.SystemInfo.Timestamp,
 	}
 
 	return header, nil
 }
 
+// decode reads either a portable snapshot or, for files written by older
+// fsdiff versions, a legacy gob-encoded one.
+func decode(r io.Reader, headerOnly bool) (*Snapshot, error) {
+	br := bufio.NewReaderSize(r, 256*1024)
+	magic, err := br.Peek(len(Magic))
+	if err == nil && string(magic) == Magic {
+		return Decode(br, headerOnly)
+	}
+
+	var snapshot Snapshot
+	if err := gob.NewDecoder(br).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
 // Validate performs basic validation on a snapshot
 func (s *Snapshot) Validate() error {
 	if s.Version == "" {
@@ -301,7 +333,7 @@ func (s *Snapshot) GetFileRecord(path string) (*FileRecord, bool) {
 func (s *Snapshot) Summary() string {
 	return fmt.Sprintf("Snapshot: %s@%s (%d files, %d dirs, %s, scan took %v)",
 		s.SystemInfo.Hostname,
-		s.SystemInfo.Timestamp.Format("2006-01-02 15:04:05"),
+		locale.Time(s.SystemInfo.Timestamp),
 		s.Stats.FileCount,
 		s.Stats.DirCount,
 		formatBytes(s.Stats.TotalSize),
@@ -309,14 +341,5 @@ func (s *Snapshot) Summary() string {
 }
 
 func formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return locale.Bytes(bytes)
 }
@@ -0,0 +1,186 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Minimal protobuf wire format helpers for the schema in snapshot.proto.
+// Hand-rolled so the snapshot package has no code generation step and no
+// runtime dependency beyond the standard library.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+func appendTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendInt64Field(b []byte, field int, v int64) []byte {
+	return appendVarintField(b, field, uint64(v))
+}
+
+func appendBoolField(b []byte, field int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendVarintField(b, field, 1)
+}
+
+func appendFixed64Field(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, field, wireFixed64)
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendStringField(b []byte, field int, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendMessageField encodes a nested message. Empty messages are still
+// written so that presence survives a round trip.
+func appendMessageField(b []byte, field int, msg []byte) []byte {
+	return appendBytesField(b, field, msg)
+}
+
+// appendStringMapField encodes a map<string, string> as repeated entries.
+func appendStringMapField(b []byte, field int, m map[string]string) []byte {
+	for k, v := range m {
+		var entry []byte
+		entry = appendStringField(entry, 1, k)
+		entry = appendStringField(entry, 2, v)
+		b = appendMessageField(b, field, entry)
+	}
+	return b
+}
+
+// wireReader iterates over the fields of a single encoded message.
+type wireReader struct {
+	buf []byte
+}
+
+// next returns the next field number and wire type.
+func (r *wireReader) next() (field int, wireType int, err error) {
+	tag, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, 0, errTruncated
+	}
+	r.buf = r.buf[n:]
+	return int(tag >> 3), int(tag & 7), nil
+}
+
+func (r *wireReader) done() bool {
+	return len(r.buf) == 0
+}
+
+func (r *wireReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+func (r *wireReader) fixed64() (uint64, error) {
+	if len(r.buf) < 8 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v, nil
+}
+
+func (r *wireReader) bytes() ([]byte, error) {
+	l, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.buf)) < l {
+		return nil, errTruncated
+	}
+	v := r.buf[:l]
+	r.buf = r.buf[l:]
+	return v, nil
+}
+
+func (r *wireReader) string() (string, error) {
+	b, err := r.bytes()
+	return string(b), err
+}
+
+// skip discards a field value of the given wire type, allowing readers to
+// ignore fields added by newer writers.
+func (r *wireReader) skip(wireType int) error {
+	switch wireType {
+	case wireVarint:
+		_, err := r.varint()
+		return err
+	case wireFixed64:
+		_, err := r.fixed64()
+		return err
+	case wireBytes:
+		_, err := r.bytes()
+		return err
+	case wireFixed32:
+		if len(r.buf) < 4 {
+			return errTruncated
+		}
+		r.buf = r.buf[4:]
+		return nil
+	default:
+		return fmt.Errorf("unsupported wire type %d", wireType)
+	}
+}
+
+// readStringMapEntry decodes a single map<string, string> entry.
+func readStringMapEntry(b []byte) (string, string, error) {
+	var key, value string
+	r := &wireReader{buf: b}
+	for !r.done() {
+		field, wt, err := r.next()
+		if err != nil {
+			return "", "", err
+		}
+		switch {
+		case field == 1 && wt == wireBytes:
+			key, err = r.string()
+		case field == 2 && wt == wireBytes:
+			value, err = r.string()
+		default:
+			err = r.skip(wt)
+		}
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return key, value, nil
+}
@@ -19,6 +19,7 @@ type SystemInfo struct {
 	GoVersion    string        `json:"go_version"`
 	ScanDuration time.Duration `json:"scan_duration"`
 	CPUCount     int           `json:"cpu_count"`
+	State        *State        `json:"state,omitempty"` // Set when system state capture is enabled
 }
 
 func (s *SystemInfo) String() string {
@@ -0,0 +1,192 @@
+package system
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"pkg.jsn.cam/jsn/internal/procnet"
+)
+
+// State is system state beyond files, optionally captured with a snapshot
+type State struct {
+	Listeners []Listener     `json:"listeners,omitempty"`
+	Modules   []KernelModule `json:"modules,omitempty"`
+	Users     []User         `json:"users,omitempty"`
+	Groups    []Group        `json:"groups,omitempty"`
+}
+
+// Listener is a listening TCP socket or bound UDP socket
+type Listener struct {
+	Proto   string `json:"proto"` // tcp, tcp6, udp or udp6
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+	Process string `json:"process,omitempty"` // Command name of the owner, if visible
+}
+
+// KernelModule is a loaded kernel module
+type KernelModule struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// User is an entry of /etc/passwd
+type User struct {
+	Name  string `json:"name"`
+	UID   int    `json:"uid"`
+	GID   int    `json:"gid"`
+	Home  string `json:"home"`
+	Shell string `json:"shell"`
+}
+
+// Group is an entry of /etc/group
+type Group struct {
+	Name    string   `json:"name"`
+	GID     int      `json:"gid"`
+	Members []string `json:"members,omitempty"`
+}
+
+// String identifies the socket, e.g. "tcp 0.0.0.0:22"
+func (l Listener) String() string {
+	return l.Proto + " " + net.JoinHostPort(l.Address, strconv.Itoa(l.Port))
+}
+
+// CaptureState reads the current listening sockets, kernel modules, users
+// and groups. Sources that can't be read are left empty.
+func CaptureState() *State {
+	state := &State{}
+	state.Listeners = readListeners()
+	state.Modules = readModules()
+	state.Users = readUsers("/etc/passwd")
+	state.Groups = readGroups("/etc/group")
+	return state
+}
+
+// readListeners returns the listening TCP and bound UDP sockets in /proc/net,
+// in table order, then by port and address
+func readListeners() []Listener {
+	sockets, err := procnet.Read()
+	if err != nil {
+		return nil
+	}
+	owners, _ := procnet.Owners()
+	comms := make(map[int]string)
+
+	seen := make(map[string]bool)
+	var listeners []Listener
+	for _, s := range sockets {
+		if s.State != "listen" {
+			continue
+		}
+		l := Listener{Proto: s.Proto, Address: s.Local.Addr().String(), Port: int(s.Local.Port())}
+		if pids := owners[s.Inode]; len(pids) > 0 {
+			l.Process = comm(pids[0], comms)
+		}
+		// SO_REUSEPORT sockets show up once per worker
+		if key := l.String(); !seen[key] {
+			seen[key] = true
+			listeners = append(listeners, l)
+		}
+	}
+
+	tables := make(map[string]int, len(procnet.Tables))
+	for i, proto := range procnet.Tables {
+		tables[proto] = i
+	}
+	sort.SliceStable(listeners, func(i, j int) bool {
+		a, b := listeners[i], listeners[j]
+		if a.Proto != b.Proto {
+			return tables[a.Proto] < tables[b.Proto]
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		return a.Address < b.Address
+	})
+	return listeners
+}
+
+// comm returns the command name of pid, caching it in comms
+func comm(pid int, comms map[int]string) string {
+	name, ok := comms[pid]
+	if !ok {
+		data, _ := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+		name = strings.TrimSpace(string(data))
+		comms[pid] = name
+	}
+	return name
+}
+
+// readModules parses /proc/modules
+func readModules() []KernelModule {
+	f, err := os.Open("/proc/modules")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var modules []KernelModule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[1], 10, 64)
+		modules = append(modules, KernelModule{Name: fields[0], Size: size})
+	}
+
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Name < modules[j].Name })
+	return modules
+}
+
+// readUsers parses a passwd file
+func readUsers(path string) []User {
+	var users []User
+	readColonFile(path, 7, func(fields []string) {
+		uid, _ := strconv.Atoi(fields[2])
+		gid, _ := strconv.Atoi(fields[3])
+		users = append(users, User{Name: fields[0], UID: uid, GID: gid, Home: fields[5], Shell: fields[6]})
+	})
+	return users
+}
+
+// readGroups parses a group file
+func readGroups(path string) []Group {
+	var groups []Group
+	readColonFile(path, 4, func(fields []string) {
+		gid, _ := strconv.Atoi(fields[2])
+		g := Group{Name: fields[0], GID: gid}
+		if fields[3] != "" {
+			g.Members = strings.Split(fields[3], ",")
+		}
+		groups = append(groups, g)
+	})
+	return groups
+}
+
+// readColonFile calls fn for each line of a colon-separated database with
+// at least n fields, skipping comments and NIS entries
+func readColonFile(path string, n int, fn func([]string)) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || line[0] == '#' || line[0] == '+' || line[0] == '-' {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) >= n {
+			fn(fields)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultChunkSize is the upload chunk size; each chunk is one request
+const DefaultChunkSize = 8 << 20
+
+// maxAttempts bounds retries of a failing request before giving up
+const maxAttempts = 5
+
+// Client pushes and pulls snapshots
+type Client struct {
+	HTTP      *http.Client
+	Token     string // Sent as a bearer token if set
+	ChunkSize int64
+
+	// Progress, if set, is called after every chunk
+	Progress func(done, total int64)
+}
+
+// ObjectURL appends the snapshot's file name to a URL ending in "/"
+func ObjectURL(url, file string) string {
+	if strings.HasSuffix(url, "/") {
+		return url + path.Base(file)
+	}
+	return url
+}
+
+// Push uploads file to url, resuming a previous partial upload
+func (c *Client) Push(ctx context.Context, file, url string) error {
+	sum, err := Verify(file)
+	if err != nil {
+		return err
+	}
+
+	// Skip the upload entirely if the server already has this snapshot
+	if resp, err := c.do(ctx, http.MethodHead, url, nil, nil); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK && resp.Header.Get(headerSHA256) == sum.SHA256 {
+			return nil
+		}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset, err := c.remoteOffset(ctx, url+partSuffix)
+	if err != nil {
+		return err
+	}
+	if offset > sum.Size {
+		offset = 0 // A different, larger snapshot was being uploaded; start over
+	}
+
+	chunkSize := c.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	buf := make([]byte, chunkSize)
+
+	failures := 0
+	for offset < sum.Size {
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		resp, err := c.do(ctx, http.MethodPatch, url+partSuffix, bytes.NewReader(buf[:n]),
+			http.Header{headerOffset: {strconv.FormatInt(offset, 10)}})
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		switch {
+		case err == nil && resp.StatusCode == http.StatusNoContent:
+			offset += int64(n)
+			failures = 0
+			if c.Progress != nil {
+				c.Progress(offset, sum.Size)
+			}
+		case err == nil && resp.StatusCode == http.StatusConflict:
+			// The server has a different amount than we thought; follow it
+			if offset, err = strconv.ParseInt(resp.Header.Get(headerOffset), 10, 64); err != nil || offset > sum.Size {
+				offset = 0
+			}
+		default:
+			if err == nil {
+				err = &statusError{"upload rejected", resp.Status, resp.StatusCode}
+			}
+			if failures++; failures >= maxAttempts || !retryable(err) || ctx.Err() != nil {
+				return err
+			}
+			if offset, err = c.backoff(ctx, failures, url+partSuffix); err != nil {
+				return err
+			}
+		}
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, url, nil, http.Header{
+		headerSHA256:     {sum.SHA256},
+		headerMerkleRoot: {formatRoot(sum.MerkleRoot)},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("server rejected snapshot: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// Pull downloads url to file, resuming from file.part if present. The
+// download is only moved into place once it verifies.
+func (c *Client) Pull(ctx context.Context, url, file string) error {
+	part := file + partSuffix
+	out, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var wantSHA, wantRoot string
+	failures := 0
+	for {
+		offset, err := out.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+
+		done, sha, root, err := c.pullRange(ctx, url, out, offset)
+		if sha != "" {
+			wantSHA, wantRoot = sha, root
+		}
+		if done {
+			break
+		}
+		if failures++; failures >= maxAttempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if _, err := c.backoff(ctx, failures, ""); err != nil {
+			return err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	sum, err := Verify(part)
+	if err != nil {
+		return err
+	}
+	if wantSHA != "" && sum.SHA256 != wantSHA {
+		os.Remove(part)
+		return fmt.Errorf("download corrupt: sha256 %s, server has %s", sum.SHA256, wantSHA)
+	}
+	if wantRoot != "" && formatRoot(sum.MerkleRoot) != wantRoot {
+		os.Remove(part)
+		return fmt.Errorf("download corrupt: merkle root %016x, server has %s", sum.MerkleRoot, wantRoot)
+	}
+
+	return os.Rename(part, file)
+}
+
+// pullRange fetches url from offset onwards into out. It returns done once
+// the whole file has been received.
+func (c *Client) pullRange(ctx context.Context, url string, out *os.File, offset int64) (done bool, sha, root string, err error) {
+	var header http.Header
+	if offset > 0 {
+		header = http.Header{"Range": {fmt.Sprintf("bytes=%d-", offset)}}
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, url, nil, header)
+	if err != nil {
+		return false, "", "", err
+	}
+	defer resp.Body.Close()
+
+	sha, root = resp.Header.Get(headerSHA256), resp.Header.Get(headerMerkleRoot)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Range not honoured; start from scratch
+		if err := out.Truncate(0); err != nil {
+			return false, sha, root, err
+		}
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			return false, sha, root, err
+		}
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Already have everything
+		return true, sha, root, nil
+	default:
+		return false, sha, root, &statusError{"download failed", resp.Status, resp.StatusCode}
+	}
+
+	total := offset + resp.ContentLength
+	written, err := io.Copy(out, &progressReader{r: resp.Body, done: offset, total: total, fn: c.Progress})
+	if err != nil {
+		return false, sha, root, err
+	}
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return false, sha, root, io.ErrUnexpectedEOF
+	}
+	return true, sha, root, nil
+}
+
+// remoteOffset asks how much of a partial upload the server has
+func (c *Client) remoteOffset(ctx context.Context, url string) (int64, error) {
+	resp, err := c.do(ctx, http.MethodHead, url, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return strconv.ParseInt(resp.Header.Get(headerOffset), 10, 64)
+	case http.StatusNotFound:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("failed to query upload offset: %s", resp.Status)
+	}
+}
+
+// backoff waits before retry attempt n and, for uploads, re-reads the
+// server's offset since a failed chunk may have been partly applied
+func (c *Client) backoff(ctx context.Context, n int, partURL string) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-time.After(time.Duration(n*n) * time.Second):
+	}
+
+	if partURL == "" {
+		return 0, nil
+	}
+	return c.remoteOffset(ctx, partURL)
+}
+
+func (c *Client) do(ctx context.Context, method, url string, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// statusError is an unexpected HTTP status
+type statusError struct {
+	op     string
+	status string
+	code   int
+}
+
+func (e *statusError) Error() string {
+	return e.op + ": " + e.status
+}
+
+// retryable reports whether err may go away by trying again
+func retryable(err error) bool {
+	var se *statusError
+	return !errors.As(err, &se) || se.code >= 500
+}
+
+// progressReader reports download progress
+type progressReader struct {
+	r           io.Reader
+	done, total int64
+	fn          func(done, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.done += int64(n)
+	if p.fn != nil && n > 0 {
+		p.fn(p.done, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,241 @@
+package transfer
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxChunkSize bounds a single PATCH body
+const maxChunkSize = 64 << 20
+
+// Server stores pushed snapshots under a directory. URL paths map directly
+// onto it, so https://server/snapshots/host1/base.snap is stored as
+// <Dir>/snapshots/host1/base.snap.
+type Server struct {
+	Dir   string
+	Token string // Required as a bearer token if set
+
+	mu sync.Mutex // Serializes appends and completion
+}
+
+// CheckAddr makes sure a store listening on addr, an address for
+// httpserver.Listen, is only reachable from this machine unless it has a
+// token, since without one anyone who reaches it can read and overwrite
+// snapshots.
+func CheckAddr(addr, token string) error {
+	network, rest, ok := strings.Cut(addr, ":")
+	switch {
+	case ok && network == "unix":
+		return nil
+	case ok && (network == "tcp" || network == "tcp4" || network == "tcp6"):
+		addr = rest
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("failed to parse listen address %q: %v", addr, err)
+	}
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	if token == "" {
+		return fmt.Errorf("listen address %s is reachable from other machines, so it needs -store-token", addr)
+	}
+	return nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Token != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.Token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	name := path.Clean("/" + r.URL.Path)
+	file := filepath.Join(s.Dir, filepath.FromSlash(name))
+	isPart := strings.HasSuffix(name, partSuffix)
+
+	switch {
+	case strings.HasSuffix(name, metaSuffix):
+		http.NotFound(w, r)
+	case r.Method == http.MethodHead && isPart:
+		s.partOffset(w, file)
+	case r.Method == http.MethodPatch && isPart:
+		s.appendPart(w, r, file)
+	case r.Method == http.MethodPost && !isPart:
+		s.complete(w, r, file)
+	case (r.Method == http.MethodGet || r.Method == http.MethodHead) && !isPart:
+		s.serve(w, r, file)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serve sends a snapshot with its checksums, or lists a directory
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, file string) {
+	info, err := os.Stat(file)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		names := []string{}
+		for _, e := range entries {
+			if e.Type().IsRegular() && !strings.HasSuffix(e.Name(), partSuffix) && !strings.HasSuffix(e.Name(), metaSuffix) {
+				names = append(names, e.Name())
+			}
+		}
+		sort.Strings(names)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(names)
+		return
+	}
+
+	if data, err := os.ReadFile(file + metaSuffix); err == nil {
+		var sum Checksum
+		if json.Unmarshal(data, &sum) == nil {
+			w.Header().Set(headerSHA256, sum.SHA256)
+			w.Header().Set(headerMerkleRoot, formatRoot(sum.MerkleRoot))
+		}
+	}
+
+	// ServeContent handles Range requests, which is all resuming needs
+	f, err := os.Open(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+// partOffset reports how much of an upload has been received
+func (s *Server) partOffset(w http.ResponseWriter, file string) {
+	info, err := os.Stat(file)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set(headerOffset, strconv.FormatInt(info.Size(), 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// appendPart adds a chunk to an upload if it starts where the file ends
+func (s *Server) appendPart(w http.ResponseWriter, r *http.Request, file string) {
+	offset, err := strconv.ParseInt(r.Header.Get(headerOffset), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "missing or invalid "+headerOffset, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// A fresh upload of a different snapshot restarts at zero
+	if offset == 0 && size > 0 {
+		if err := f.Truncate(0); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		size, _ = f.Seek(0, io.SeekStart)
+	}
+	if offset != size {
+		w.Header().Set(headerOffset, strconv.FormatInt(size, 10))
+		http.Error(w, fmt.Sprintf("upload is at offset %d", size), http.StatusConflict)
+		return
+	}
+
+	n, err := io.Copy(f, http.MaxBytesReader(w, r.Body, maxChunkSize))
+	if err != nil {
+		// Keep whatever arrived; the client resumes from the new size
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set(headerOffset, strconv.FormatInt(size+n, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// complete verifies an upload against the client's checksums and moves it
+// into place
+func (s *Server) complete(w http.ResponseWriter, r *http.Request, file string) {
+	wantSHA := r.Header.Get(headerSHA256)
+	wantRoot, err := parseRoot(r.Header.Get(headerMerkleRoot))
+	if wantSHA == "" || err != nil {
+		http.Error(w, "missing "+headerSHA256+" or "+headerMerkleRoot, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	part := file + partSuffix
+	if _, err := os.Stat(part); err != nil {
+		http.Error(w, "no upload in progress", http.StatusNotFound)
+		return
+	}
+
+	sum, err := Verify(part)
+	switch {
+	case err != nil:
+		os.Remove(part)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case sum.SHA256 != wantSHA:
+		os.Remove(part)
+		http.Error(w, fmt.Sprintf("sha256 mismatch: received %s", sum.SHA256), http.StatusUnprocessableEntity)
+		return
+	case sum.MerkleRoot != wantRoot:
+		os.Remove(part)
+		http.Error(w, fmt.Sprintf("merkle root mismatch: received %016x", sum.MerkleRoot), http.StatusUnprocessableEntity)
+		return
+	}
+
+	meta, _ := json.Marshal(sum)
+	if err := os.WriteFile(file+metaSuffix, meta, 0644); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := os.Rename(part, file); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
@@ -0,0 +1,70 @@
+// Package transfer moves snapshots to and from an fsdiff store over HTTP.
+//
+// Downloads are plain GETs resumed with Range requests. Uploads append
+// chunks to "<name>.part" with PATCH requests carrying the expected
+// Upload-Offset, so an interrupted push continues where the server's copy
+// ends, and are completed with a POST that the server only accepts once
+// the assembled file matches the client's SHA-256 and merkle root.
+package transfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/merkle"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+const (
+	headerOffset     = "Upload-Offset"
+	headerSHA256     = "X-Snapshot-Sha256"
+	headerMerkleRoot = "X-Merkle-Root"
+
+	partSuffix = ".part"
+	metaSuffix = ".meta"
+)
+
+// Checksum identifies a snapshot file's bytes and contents
+type Checksum struct {
+	SHA256     string `json:"sha256"`
+	MerkleRoot uint64 `json:"merkle_root"`
+	Size       int64  `json:"size"`
+}
+
+// Verify hashes the snapshot file at path and checks that its files match
+// its recorded merkle root
+func Verify(path string) (*Checksum, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := snapshot.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if !merkle.Verify(snap) {
+		return nil, fmt.Errorf("%s: files do not match merkle root %016x", path, snap.MerkleRoot)
+	}
+
+	return &Checksum{SHA256: hex.EncodeToString(h.Sum(nil)), MerkleRoot: snap.MerkleRoot, Size: size}, nil
+}
+
+func formatRoot(root uint64) string {
+	return fmt.Sprintf("%016x", root)
+}
+
+func parseRoot(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
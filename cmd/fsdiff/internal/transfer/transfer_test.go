@@ -0,0 +1,127 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/merkle"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+func writeSnapshot(t *testing.T, file string) {
+	t.Helper()
+	snap := &snapshot.Snapshot{Version: "2.0.0", Files: map[string]*snapshot.FileRecord{}}
+	for i := 0; i < 200; i++ {
+		p := filepath.Join("/data", string(rune('a'+i%26)), "file"+string(rune('0'+i%10)))
+		snap.Files[p] = &snapshot.FileRecord{Path: p, Hash: "0123456789abcdef", Size: int64(i)}
+	}
+	snap.MerkleRoot = merkle.RollingRoot(snap.Files)
+	if err := snapshot.Save(snap, file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPushPullResume(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "base.snap")
+	writeSnapshot(t, src)
+	data, _ := os.ReadFile(src)
+
+	store := filepath.Join(dir, "store")
+	srv := httptest.NewServer(&Server{Dir: store, Token: "secret"})
+	defer srv.Close()
+
+	// An earlier push was interrupted partway through
+	if err := os.MkdirAll(filepath.Join(store, "host1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(store, "host1", "base.snap.part"), data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var offsets []int64
+	c := &Client{Token: "secret", ChunkSize: 64, Progress: func(done, total int64) { offsets = append(offsets, done) }}
+	url := ObjectURL(srv.URL+"/host1/", src)
+	if err := c.Push(context.Background(), src, url); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if len(offsets) == 0 || offsets[0] <= int64(len(data)/2) {
+		t.Errorf("push did not resume from the partial upload: first offset %v", offsets)
+	}
+
+	stored, err := os.ReadFile(filepath.Join(store, "host1", "base.snap"))
+	if err != nil || !bytes.Equal(stored, data) {
+		t.Fatalf("stored snapshot differs from source (err %v)", err)
+	}
+
+	// Resume a download that already has the first bytes
+	dst := filepath.Join(dir, "pulled.snap")
+	if err := os.WriteFile(dst+partSuffix, data[:10], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Client{Token: "secret"}).Pull(context.Background(), url, dst); err != nil {
+		t.Fatalf("Pull: %v", err)
+	}
+	pulled, _ := os.ReadFile(dst)
+	if !bytes.Equal(pulled, data) {
+		t.Error("pulled snapshot differs from source")
+	}
+
+	if err := (&Client{}).Pull(context.Background(), url, dst); err == nil {
+		t.Error("pull without token succeeded")
+	}
+}
+
+func TestCompleteRejectsCorruptUpload(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "base.snap")
+	writeSnapshot(t, src)
+
+	store := filepath.Join(dir, "store")
+	srv := httptest.NewServer(&Server{Dir: store})
+	defer srv.Close()
+
+	// A stale partial upload of different bytes gets appended to
+	if err := os.MkdirAll(store, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(store, "base.snap.part"), []byte("not a snapshot"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (&Client{}).Push(context.Background(), src, srv.URL+"/base.snap"); err == nil {
+		t.Fatal("push onto a corrupt partial upload succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(store, "base.snap")); err == nil {
+		t.Error("corrupt upload was stored")
+	}
+
+	// The server discarded the bad part, so trying again works
+	if err := (&Client{}).Push(context.Background(), src, srv.URL+"/base.snap"); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+}
+
+func TestCheckAddr(t *testing.T) {
+	for _, tc := range []struct {
+		addr, token string
+		ok          bool
+	}{
+		{"localhost:7080", "", true},
+		{"127.0.0.1:7080", "", true},
+		{"tcp6:[::1]:7080", "", true},
+		{"unix:/run/fsdiff.sock", "", true},
+		{":7080", "", false},
+		{"tcp4:0.0.0.0:7080", "", false},
+		{"0.0.0.0:7080", "secret", true},
+		{"7080", "", false},
+	} {
+		if err := CheckAddr(tc.addr, tc.token); (err == nil) != tc.ok {
+			t.Errorf("CheckAddr(%q, %q) = %v", tc.addr, tc.token, err)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package watch
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchMask covers every event that can change a snapshot record
+const watchMask = unix.FAN_CREATE | unix.FAN_DELETE | unix.FAN_MODIFY | unix.FAN_ATTRIB |
+	unix.FAN_MOVED_FROM | unix.FAN_MOVED_TO | unix.FAN_DELETE_SELF | unix.FAN_MOVE_SELF |
+	unix.FAN_ONDIR
+
+// Offsets into struct fanotify_event_metadata and the info records that
+// follow it, see fanotify(7)
+const (
+	metaEventLen   = 0
+	metaLen        = 6
+	metaMask       = 8
+	metaSize       = 24
+	infoType       = 0
+	infoLen        = 2
+	infoFsid       = 4
+	infoHandleSize = 12
+	infoHandleType = 16
+	infoHandle     = 20
+)
+
+// mount is a filesystem under the watched root
+type mount struct {
+	path string
+	fd   int // Any fd on the filesystem, for open_by_handle_at
+}
+
+// Run marks every filesystem under the root, takes the initial scan and
+// then applies events until ctx is cancelled. It needs Linux 5.9 or newer
+// and CAP_SYS_ADMIN. Filesystems that cannot report file handles, such as
+// procfs, are skipped with a warning.
+func (w *Watcher) Run(ctx context.Context) error {
+	fd, err := unix.FanotifyInit(unix.FAN_CLASS_NOTIF|unix.FAN_REPORT_DFID_NAME|unix.FAN_CLOEXEC|unix.FAN_NONBLOCK,
+		unix.O_RDONLY|unix.O_LARGEFILE)
+	if err != nil {
+		return fmt.Errorf("fanotify unavailable (needs Linux 5.9+ and CAP_SYS_ADMIN): %v", err)
+	}
+	defer unix.Close(fd)
+
+	mounts, err := w.markMounts(fd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, m := range mounts {
+			unix.Close(m.fd)
+		}
+	}()
+
+	// Events during the scan queue up in the kernel and are replayed after
+	// it; re-reading a path is idempotent so nothing is lost
+	if err := w.rescan(); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 256*1024)
+	pending := make(map[string]struct{})
+	lastFlush := time.Now()
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		pfd := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		if _, err := unix.Poll(pfd, int(flushInterval/time.Millisecond)); err != nil && !errors.Is(err, unix.EINTR) {
+			return fmt.Errorf("failed to poll fanotify: %v", err)
+		}
+
+		overflow := false
+		for {
+			n, err := unix.Read(fd, buf)
+			if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) || n <= 0 {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read fanotify events: %v", err)
+			}
+			if w.parseEvents(buf[:n], mounts, pending) {
+				overflow = true
+			}
+		}
+
+		if overflow {
+			fmt.Printf("⚠️  Warning: fanotify queue overflowed, rescanning %s\n", w.root)
+			clear(pending)
+			if err := w.rescan(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if len(pending) > 0 && time.Since(lastFlush) >= flushInterval {
+			w.apply(pending)
+			clear(pending)
+			lastFlush = time.Now()
+		}
+	}
+}
+
+// markMounts adds a filesystem mark for each mount at or below the root
+func (w *Watcher) markMounts(fd int) (map[[2]int32]mount, error) {
+	points, err := mountPoints(w.root)
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := make(map[[2]int32]mount)
+	for _, p := range points {
+		if w.scanner.Ignored(p) {
+			continue
+		}
+
+		if err := unix.FanotifyMark(fd, unix.FAN_MARK_ADD|unix.FAN_MARK_FILESYSTEM, watchMask, unix.AT_FDCWD, p); err != nil {
+			if p == w.root {
+				return nil, fmt.Errorf("failed to watch %s: %v", p, err)
+			}
+			fmt.Printf("⚠️  Warning: not watching %s: %v\n", p, err)
+			continue
+		}
+
+		var st unix.Statfs_t
+		if err := unix.Statfs(p, &st); err != nil {
+			return nil, err
+		}
+		if _, ok := mounts[st.Fsid.Val]; ok {
+			continue
+		}
+		mfd, err := unix.Open(p, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return nil, err
+		}
+		mounts[st.Fsid.Val] = mount{path: p, fd: mfd}
+	}
+
+	return mounts, nil
+}
+
+// mountPoints lists the root and every mount point below it
+func mountPoints(root string) ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	points := []string{root}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		p := unescapeMountPath(fields[4])
+		if p != root && (root == "/" || strings.HasPrefix(p, root+"/")) {
+			points = append(points, p)
+		}
+	}
+	return points, sc.Err()
+}
+
+// unescapeMountPath decodes the octal escapes used in mountinfo
+func unescapeMountPath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] == '\\' && i+3 < len(p) {
+			if c, err := strconv.ParseUint(p[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String()
+}
+
+// parseEvents resolves each event to a path and adds it to pending. It
+// returns true if the kernel dropped events.
+func (w *Watcher) parseEvents(buf []byte, mounts map[[2]int32]mount, pending map[string]struct{}) bool {
+	overflow := false
+
+	for len(buf) >= metaSize {
+		eventLen := int(binary.NativeEndian.Uint32(buf[metaEventLen:]))
+		if eventLen < metaSize || eventLen > len(buf) {
+			break
+		}
+		event := buf[:eventLen]
+		buf = buf[eventLen:]
+
+		w.mu.Lock()
+		w.status.Events++
+		w.status.LastEvent = time.Now()
+		w.mu.Unlock()
+
+		if binary.NativeEndian.Uint64(event[metaMask:])&unix.FAN_Q_OVERFLOW != 0 {
+			overflow = true
+			continue
+		}
+
+		info := event[binary.NativeEndian.Uint16(event[metaLen:]):]
+		for len(info) >= infoHandle {
+			recordLen := int(binary.NativeEndian.Uint16(info[infoLen:]))
+			if recordLen < infoHandle || recordLen > len(info) {
+				break
+			}
+			if path, ok := w.resolve(info[:recordLen], mounts); ok {
+				pending[path] = struct{}{}
+			}
+			info = info[recordLen:]
+		}
+	}
+
+	return overflow
+}
+
+// resolve turns a DFID_NAME info record into a path inside the root
+func (w *Watcher) resolve(record []byte, mounts map[[2]int32]mount) (string, bool) {
+	if record[infoType] != unix.FAN_EVENT_INFO_TYPE_DFID_NAME {
+		return "", false
+	}
+
+	fsid := [2]int32{
+		int32(binary.NativeEndian.Uint32(record[infoFsid:])),
+		int32(binary.NativeEndian.Uint32(record[infoFsid+4:])),
+	}
+	m, ok := mounts[fsid]
+	if !ok {
+		return "", false
+	}
+
+	size := int(binary.NativeEndian.Uint32(record[infoHandleSize:]))
+	if infoHandle+size > len(record) {
+		return "", false
+	}
+	handle := unix.NewFileHandle(int32(binary.NativeEndian.Uint32(record[infoHandleType:])), record[infoHandle:infoHandle+size])
+
+	// The directory may already be gone; its own deletion event covers it
+	dirFd, err := unix.OpenByHandleAt(m.fd, handle, unix.O_PATH|unix.O_CLOEXEC)
+	if err != nil {
+		return "", false
+	}
+	dir, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(dirFd))
+	unix.Close(dirFd)
+	if err != nil {
+		return "", false
+	}
+
+	name := record[infoHandle+size:]
+	if i := strings.IndexByte(string(name), 0); i >= 0 {
+		name = name[:i]
+	}
+
+	path := dir
+	if len(name) > 0 && string(name) != "." {
+		path = filepath.Join(dir, string(name))
+	}
+
+	if !w.inRoot(path) || w.scanner.Ignored(path) {
+		return "", false
+	}
+	return path, true
+}
@@ -0,0 +1,65 @@
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/diff"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/report"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+// Handler serves diffs of the live snapshot against baseline:
+//
+//	GET /summary  change counters as JSON, like -summary-only
+//	GET /changes  changed paths by type as JSON
+//	GET /report   the full HTML report
+//	GET /status   watcher counters as JSON
+func (w *Watcher) Handler(baseline *snapshot.Snapshot, d *diff.Differ) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /summary", func(rw http.ResponseWriter, r *http.Request) {
+		var summary diff.Summary
+		w.View(func(current *snapshot.Snapshot) {
+			summary = d.Summarize(baseline, current)
+		})
+		writeJSON(rw, summary)
+	})
+
+	mux.HandleFunc("GET /changes", func(rw http.ResponseWriter, r *http.Request) {
+		var changes map[diff.ChangeType][]string
+		w.View(func(current *snapshot.Snapshot) {
+			changes = d.Compare(baseline, current).GetChangesByType()
+		})
+		writeJSON(rw, changes)
+	})
+
+	mux.HandleFunc("GET /report", func(rw http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		var err error
+		// The result points into the live snapshot, so render under the lock
+		w.View(func(current *snapshot.Snapshot) {
+			err = report.RenderHTML(d.Compare(baseline, current), &buf)
+		})
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		rw.Write(buf.Bytes())
+	})
+
+	mux.HandleFunc("GET /status", func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, w.Status())
+	})
+
+	return mux
+}
+
+func writeJSON(rw http.ResponseWriter, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(rw)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
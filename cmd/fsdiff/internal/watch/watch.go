@@ -0,0 +1,211 @@
+// Package watch keeps an in-memory snapshot current from filesystem events
+// so diffs against a baseline can be served without rescanning.
+package watch
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/scanner"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+)
+
+// flushInterval is how long changed paths are collected before they are
+// re-read, so a file being written is hashed once rather than per write.
+const flushInterval = 250 * time.Millisecond
+
+// Status describes the watcher for the status endpoint
+type Status struct {
+	Root      string    `json:"root"`
+	Files     int       `json:"files"`
+	Events    int64     `json:"events"`
+	Updates   int64     `json:"updates"`
+	Rescans   int64     `json:"rescans"`
+	Started   time.Time `json:"started"`
+	LastEvent time.Time `json:"last_event,omitempty"`
+}
+
+// Watcher holds the live snapshot of a directory tree
+type Watcher struct {
+	root    string
+	config  *scanner.Config
+	scanner *scanner.Scanner
+
+	mu      sync.RWMutex
+	current *snapshot.Snapshot
+	status  Status
+}
+
+// New creates a watcher for root. The initial scan happens in Run.
+func New(root string, config *scanner.Config) *Watcher {
+	root = filepath.Clean(root)
+	return &Watcher{
+		root:    root,
+		config:  config,
+		scanner: scanner.New(config),
+		status:  Status{Root: root, Started: time.Now()},
+	}
+}
+
+// View calls fn with the current snapshot. The snapshot must not be kept
+// or modified after fn returns; events are held back while fn runs.
+func (w *Watcher) View(fn func(current *snapshot.Snapshot)) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	fn(w.current)
+}
+
+// Status returns counters about the watcher
+func (w *Watcher) Status() Status {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	status := w.status
+	if w.current != nil {
+		status.Files = len(w.current.Files)
+	}
+	return status
+}
+
+// rescan replaces the snapshot with a full scan, used at startup and when
+// events were lost
+func (w *Watcher) rescan() error {
+	current, err := scanner.New(w.config).ScanFilesystem(w.root)
+	if err != nil {
+		return fmt.Errorf("failed to scan %s: %v", w.root, err)
+	}
+
+	w.mu.Lock()
+	if w.current != nil {
+		w.status.Rescans++
+	}
+	w.current = current
+	w.mu.Unlock()
+	return nil
+}
+
+// inRoot reports whether path is inside the watched tree
+func (w *Watcher) inRoot(path string) bool {
+	if w.root == "/" {
+		return strings.HasPrefix(path, "/")
+	}
+	return path == w.root || strings.HasPrefix(path, w.root+"/")
+}
+
+// apply re-reads every changed path and folds the result into the snapshot
+func (w *Watcher) apply(paths map[string]struct{}) {
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	// Read outside the lock; hashing can be slow
+	records := make(map[string]*snapshot.FileRecord)
+	var gone []string
+	for _, p := range sorted {
+		if p == w.root {
+			continue
+		}
+		record, err := w.scanner.Record(p)
+		if err != nil {
+			gone = append(gone, p)
+			continue
+		}
+		records[p] = record
+
+		// A directory moved in from elsewhere arrives as a single event
+		if record.IsDir && !w.known(p) {
+			w.readTree(p, records)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, record := range records {
+		w.put(record)
+	}
+	if len(gone) > 0 {
+		w.remove(gone)
+	}
+
+	w.status.Updates += int64(len(paths))
+	w.current.SystemInfo.Timestamp = time.Now()
+}
+
+// known reports whether path is in the snapshot
+func (w *Watcher) known(path string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	_, ok := w.current.Files[path]
+	return ok
+}
+
+// readTree reads every record below dir into records
+func (w *Watcher) readTree(dir string, records map[string]*snapshot.FileRecord) {
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == dir {
+			return nil
+		}
+		if w.scanner.Ignored(p) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if record, err := w.scanner.Record(p); err == nil {
+			records[p] = record
+		}
+		return nil
+	})
+}
+
+// put stores a record, keeping the snapshot stats in step
+func (w *Watcher) put(record *snapshot.FileRecord) {
+	stats := &w.current.Stats
+	if old, ok := w.current.Files[record.Path]; ok {
+		w.unaccount(old)
+	}
+
+	w.current.Files[record.Path] = record
+	if record.IsDir {
+		stats.DirCount++
+	} else {
+		stats.FileCount++
+		stats.TotalSize += record.Size
+	}
+}
+
+// remove deletes paths and everything below them in one pass over the
+// snapshot, so deleting a large tree doesn't rescan it per directory
+func (w *Watcher) remove(paths []string) {
+	removed := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		removed[p] = struct{}{}
+	}
+
+	for p, record := range w.current.Files {
+		for dir := p; dir != w.root && dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+			if _, ok := removed[dir]; ok {
+				w.unaccount(record)
+				delete(w.current.Files, p)
+				break
+			}
+		}
+	}
+}
+
+func (w *Watcher) unaccount(record *snapshot.FileRecord) {
+	stats := &w.current.Stats
+	if record.IsDir {
+		stats.DirCount--
+	} else {
+		stats.FileCount--
+		stats.TotalSize -= record.Size
+	}
+}
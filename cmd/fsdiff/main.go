@@ -1,24 +1,46 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"path"
+	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"pkg.jsn.cam/jsn/internal"
+	"pkg.jsn.cam/jsn/internal/health"
+	"pkg.jsn.cam/jsn/internal/httpserver"
+	"pkg.jsn.cam/jsn/internal/metrics"
+	"pkg.jsn.cam/jsn/internal/run"
 
 	"pkg.jsn.cam/jsn/cmd/fsdiff/pkg/fsdiff"
 
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/config"
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/diff"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/image"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/locale"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/notify"
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/report"
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/scanner"
 	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/snapshot"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/system"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/transfer"
+	"pkg.jsn.cam/jsn/cmd/fsdiff/internal/watch"
 
 	_ "net/http/pprof"
+	_ "time/tzdata" // -timezone works on hosts without a zoneinfo database
 )
 
 var (
@@ -26,8 +48,36 @@ var (
 	verbose = flag.Bool("v", true, "Verbose output")
 	debug   = flag.Bool("d", false, "Enable pprof profiling on port 6060")
 	ignore  = flag.String("ignore", "", "Comma-separated list of paths/patterns to ignore (e.g., '.cache,node_modules,*.log')")
+	cfgFile = flag.String("config", "", "TOML config file (notification sinks)")
+	state   = flag.Bool("system-state", false, "Record listening sockets, kernel modules, users and groups with the scan")
+	profile = flag.Bool("profile-scan", false, "Record how long each directory took to scan, for the profile command")
+
+	allowCrossHost = flag.Bool("allow-cross-host", false, "Allow comparing snapshots from different hosts or scan roots")
+	rebase         = flag.String("rebase", "", "Comma-separated old:new path prefix rewrites applied before comparing (e.g. /mnt/image:/)")
+
+	timezone   = flag.String("timezone", "Local", "Timezone for report timestamps: Local, UTC or an IANA name like Europe/Berlin")
+	localeName = flag.String("locale", "iso", "Date and number format for reports (iso, en-US, de-DE, ..., or auto for $LANG)")
+	storeToken = flag.String("store-token", "", "Bearer token for push/pull, and required by store if set")
+
+	exposeMetrics = flag.Bool("metrics", false, "Serve Prometheus metrics at /metrics in watch and store")
+
+	digest = flag.String("digest", "", "Image manifest or config digest to verify against (image command)")
+
+	summaryOnly = flag.Bool("summary-only", false, "Only print change counters and size deltas as JSON; exits 2 if anything changed")
+
+	emailTo      = flag.String("email-to", "", "Comma-separated list of addresses to email the report to")
+	emailFrom    = flag.String("email-from", "fsdiff@localhost", "Sender address for emailed reports")
+	smtpHost     = flag.String("smtp-host", "localhost:25", "SMTP server (host:port) used for emailed reports")
+	smtpUser     = flag.String("smtp-user", "", "SMTP username, if the server requires authentication")
+	smtpPassword = flag.String("smtp-password", "", "SMTP password, if the server requires authentication")
 )
 
+// cfg is the loaded -config file, empty if none was given
+var cfg = &config.Config{}
+
+// summaryOut receives the JSON summary in -summary-only mode
+var summaryOut io.Writer
+
 func main() {
 	internal.HandleStartup()
 
@@ -42,6 +92,25 @@ func main() {
 		}()
 	}
 
+	// Keep stdout clean for the JSON summary; progress output goes to stderr
+	if *summaryOnly {
+		summaryOut = os.Stdout
+		os.Stdout = os.Stderr
+	}
+
+	if err := setupLocale(); err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *cfgFile != "" {
+		var err error
+		if cfg, err = config.Load(*cfgFile); err != nil {
+			fmt.Printf("❌ Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	command := flag.Args()[0]
 
 	switch command {
@@ -51,6 +120,18 @@ func main() {
 		handleDiff()
 	case "live":
 		handleLive()
+	case "profile":
+		handleProfile()
+	case "image":
+		handleImage()
+	case "watch":
+		handleWatch()
+	case "push":
+		handlePush()
+	case "pull":
+		handlePull()
+	case "store":
+		handleStore()
 	case "version":
 		fmt.Printf("fsdiff version %s\n", fsdiff.Version)
 	default:
@@ -69,6 +150,12 @@ func printUsage() {
 	fmt.Println("  snapshot <root_path> <output_file>    Create filesystem snapshot")
 	fmt.Println("  diff <baseline> <current> [report]    Compare two snapshots")
 	fmt.Println("  live <baseline> <root_path> [report]  Compare baseline to live filesystem")
+	fmt.Println("  profile <snapshot> [limit]            Show the slowest directories of a scan")
+	fmt.Println("  image <image> <snapshot|root> [mount] Verify files on disk against a local OCI layout or docker save archive")
+	fmt.Println("  watch <baseline> <root_path> [addr]   Track changes with fanotify and serve diffs over HTTP")
+	fmt.Println("  push <snapshot> <url>                 Upload a snapshot to a store, resuming if interrupted")
+	fmt.Println("  pull <url> [output_file]              Download a snapshot from a store, resuming if interrupted")
+	fmt.Println("  store <dir> [addr]                    Run a snapshot store for push and pull")
 	fmt.Println("  version                               Show version information")
 	fmt.Println("")
 	fmt.Println("OPTIONS:")
@@ -76,12 +163,28 @@ func printUsage() {
 	fmt.Println("  -v              Verbose output")
 	fmt.Println("  -d              Enable pprof profiling on port 6060")
 	fmt.Println("  -ignore string  Comma-separated ignore patterns (e.g., '.cache,*.tmp')")
+	fmt.Println("  -email-to list  Email the report to these addresses (see -smtp-host, -smtp-user)")
+	fmt.Println("  -config file    TOML config file with [[notify]] sinks")
+	fmt.Println("  -summary-only   Print only JSON counters and size deltas (exit 2 on drift)")
+	fmt.Println("  -digest string  Image manifest or config digest for the image command")
+	fmt.Println("  -system-state   Record sockets, kernel modules, users and groups with scans")
+	fmt.Println("  -profile-scan   Record per-directory scan timings for the profile command")
+	fmt.Println("  -allow-cross-host  Allow comparing snapshots from different hosts or scan roots")
+	fmt.Println("  -rebase old:new    Rewrite path prefixes before comparing (e.g. /mnt/image:/)")
+	fmt.Println("  -store-token string  Bearer token for push, pull and store")
+	fmt.Println("  -metrics             Serve Prometheus metrics at /metrics in watch and store")
+	fmt.Println("  -timezone zone       Report timestamps in this zone (default: Local)")
+	fmt.Println("  -locale name         Date and number format: iso, en-US, de-DE, ... or auto")
 	fmt.Println("")
 	fmt.Println("EXAMPLES:")
 	fmt.Println("  fsdiff snapshot / baseline.snap")
 	fmt.Println("  fsdiff diff baseline.snap current.snap changes.html")
 	fmt.Println("  fsdiff -ignore '.cache,node_modules' live baseline.snap /")
 	fmt.Println("  fsdiff -workers 8 -v snapshot /home/user user-snapshot.snap")
+	fmt.Println("  fsdiff -digest sha256:4f2a... image app.oci.tar /")
+	fmt.Println("  fsdiff -allow-cross-host -rebase /mnt/golden:/ diff golden.snap host.snap")
+	fmt.Println("  fsdiff -timezone UTC -locale en-GB diff baseline.snap current.snap report.html")
+	fmt.Println("  fsdiff push baseline.snap https://snapshots.example.com/host1/")
 }
 
 func handleSnapshot() {
@@ -95,13 +198,15 @@ func handleSnapshot() {
 	outputFile := args[1]
 
 	// Parse ignore patterns
-	ignorePatterns := parseIgnorePatterns(*ignore)
+	ignorePatterns := parseList(*ignore)
 
 	// Create scanner with configuration
 	config := &scanner.Config{
 		Workers:        *workers,
 		Verbose:        *verbose,
 		IgnorePatterns: ignorePatterns,
+		CaptureState:   *state,
+		Profile:        *profile,
 	}
 
 	fmt.Printf("🔍 Scanning filesystem: %s\n", rootPath)
@@ -122,6 +227,340 @@ func handleSnapshot() {
 	fmt.Printf("✅ Snapshot created successfully!\n")
 }
 
+func handleProfile() {
+	args := flag.Args()[1:]
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println("Usage: fsdiff profile <snapshot> [limit]")
+		os.Exit(1)
+	}
+
+	limit := 20
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			fmt.Printf("❌ Invalid limit: %s\n", args[1])
+			os.Exit(1)
+		}
+		limit = n
+	}
+
+	header, err := snapshot.LoadHeader(args[0])
+	if err != nil {
+		fmt.Printf("❌ Error loading snapshot: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(header.DirTimings) == 0 {
+		fmt.Println("⚠️  Snapshot has no scan profile (scan with -profile-scan to record one)")
+		os.Exit(1)
+	}
+
+	fmt.Printf("⏱️  Scan profile: %s (%d files, %d dirs, scan took %v)\n\n",
+		header.SystemInfo.Hostname, header.Stats.FileCount, header.Stats.DirCount,
+		header.Stats.ScanDuration.Truncate(time.Millisecond))
+
+	// Timings are stored slowest first
+	slowest := header.DirTimings
+	if len(slowest) > limit {
+		slowest = slowest[:limit]
+	}
+
+	fmt.Println("🐢 SLOWEST DIRECTORIES:")
+	fmt.Printf("   %10s %10s %10s %8s  %s\n", "TOTAL", "READDIR", "HASH", "FILES", "PATH")
+	for _, t := range slowest {
+		fmt.Printf("   %10v %10v %10v %8d  %s\n",
+			t.Total().Truncate(time.Microsecond), t.ReadDirTime.Truncate(time.Microsecond),
+			t.HashTime.Truncate(time.Microsecond), t.FileCount, t.Path)
+	}
+	fmt.Println()
+
+	largest := make([]snapshot.DirTiming, len(header.DirTimings))
+	copy(largest, header.DirTimings)
+	sort.Slice(largest, func(i, j int) bool {
+		return largest[i].HashedBytes > largest[j].HashedBytes
+	})
+	if len(largest) > limit {
+		largest = largest[:limit]
+	}
+
+	fmt.Println("🔥 LARGEST HASHING CONTRIBUTORS:")
+	fmt.Printf("   %10s %10s %12s  %s\n", "BYTES", "HASH", "THROUGHPUT", "PATH")
+	for _, t := range largest {
+		if t.HashedBytes == 0 {
+			break
+		}
+		throughput := "-"
+		if t.HashTime > 0 {
+			throughput = formatBytes(int64(float64(t.HashedBytes)/t.HashTime.Seconds())) + "/s"
+		}
+		fmt.Printf("   %10s %10v %12s  %s\n",
+			formatBytes(t.HashedBytes), t.HashTime.Truncate(time.Microsecond), throughput, t.Path)
+	}
+	fmt.Println()
+
+	fmt.Println("💡 Exclude hot directories you don't need with -ignore, e.g.:")
+	fmt.Printf("   fsdiff -ignore '%s' snapshot %s <output_file>\n", slowest[0].Path, header.SystemInfo.ScanRoot)
+}
+
+func handleImage() {
+	args := flag.Args()[1:]
+	if len(args) < 2 || len(args) > 3 {
+		fmt.Println("Usage: fsdiff [-digest sha256:...] image <oci_layout|docker_archive> <snapshot|root_path> [mount_point]")
+		os.Exit(1)
+	}
+
+	imagePath := args[0]
+	target := args[1]
+
+	fmt.Printf("📦 Loading image: %s\n", imagePath)
+	img, err := image.Load(imagePath, *digest)
+	if err != nil {
+		fmt.Printf("❌ Error loading image: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("   Config %s, %d layers, %d paths\n", img.ConfigDigest, len(img.DiffIDs), len(img.Files))
+
+	// The target is either a snapshot file or a directory to scan now
+	var current *snapshot.Snapshot
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		fmt.Printf("🔍 Scanning filesystem: %s\n", target)
+		s := scanner.New(&scanner.Config{Workers: *workers, Verbose: *verbose})
+		current, err = s.ScanFilesystem(target)
+		if err != nil {
+			fmt.Printf("❌ Error scanning filesystem: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		fmt.Printf("📖 Loading snapshot: %s\n", target)
+		current, err = snapshot.Load(target)
+		if err != nil {
+			fmt.Printf("❌ Error loading snapshot: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// The image root defaults to the scanned directory
+	mount := current.SystemInfo.ScanRoot
+	if len(args) == 3 {
+		mount = args[2]
+	}
+
+	ignorer := diff.NewPathIgnorer(parseList(*ignore))
+	result := image.Verify(img, current, mount, ignorer.ShouldIgnore)
+
+	if *summaryOnly {
+		enc := json.NewEncoder(summaryOut)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			fmt.Printf("❌ Error writing summary: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 60))
+	fmt.Println("📦 IMAGE VERIFICATION")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Image mounted at: %s\n", mount)
+	fmt.Printf("   Checked:  %d paths\n", result.Checked)
+	fmt.Printf("   Ignored:  %d paths\n", result.Ignored)
+	fmt.Printf("   Tampered: %d paths\n", len(result.Tampered))
+	fmt.Printf("   Missing:  %d paths\n\n", len(result.Missing))
+
+	if result.Clean() {
+		fmt.Println("✅ All image files match!")
+		return
+	}
+
+	if len(result.Tampered) > 0 {
+		fmt.Printf("🚨 TAMPERED:\n")
+		for _, m := range result.Tampered {
+			fmt.Printf("   ~ %s: %s\n", m.Path, strings.Join(m.Reasons, ", "))
+		}
+		fmt.Println()
+	}
+	if len(result.Missing) > 0 {
+		fmt.Printf("❓ MISSING:\n")
+		for _, p := range result.Missing {
+			fmt.Printf("   - %s\n", p)
+		}
+		fmt.Println()
+	}
+
+	os.Exit(2)
+}
+
+func handleWatch() {
+	args := flag.Args()[1:]
+	if len(args) < 2 || len(args) > 3 {
+		fmt.Println("Usage: fsdiff watch <baseline> <root_path> [listen_addr]")
+		os.Exit(1)
+	}
+
+	baselineFile := args[0]
+	rootPath := args[1]
+	addr := "localhost:7070"
+	if len(args) == 3 {
+		addr = args[2]
+	}
+
+	fmt.Printf("📖 Loading baseline: %s\n", baselineFile)
+	baseline, err := snapshot.Load(baselineFile)
+	if err != nil {
+		fmt.Printf("❌ Error loading baseline: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Live paths can't be rewritten, so rules only re-root the baseline
+	rebaseSnapshot(baseline, parseRebases())
+	currentInfo := system.GetSystemInfo(rootPath)
+	checkComparable(baseline.SystemInfo, currentInfo)
+
+	ignorePatterns := parseList(*ignore)
+	w := watch.New(rootPath, &scanner.Config{
+		Workers:        *workers,
+		Verbose:        *verbose,
+		IgnorePatterns: ignorePatterns,
+	})
+	d := diff.New(&diff.Config{
+		IgnorePatterns: ignorePatterns,
+		Workers:        *workers,
+	})
+
+	health.Register(health.Ready, "scan", func(context.Context) error {
+		var err error
+		w.View(func(current *snapshot.Snapshot) {
+			if current == nil {
+				err = errors.New("initial scan hasn't finished")
+			}
+		})
+		return err
+	})
+
+	ctx := run.Context()
+	srv := &httpserver.Server{Addr: addr, Handler: daemonHandler(w.Handler(baseline, d), metrics.FirstSegment)}
+	served := make(chan struct{})
+	go func() {
+		fmt.Printf("🌐 Serving diffs on http://%s/summary (also /changes, /report, /status)\n", addr)
+		if err := srv.ListenAndServe(ctx); err != nil {
+			fmt.Printf("❌ Error serving diffs: %v\n", err)
+			os.Exit(1)
+		}
+		close(served)
+	}()
+
+	go watchNotifications(ctx, w, baseline, d)
+
+	fmt.Printf("👀 Watching %s\n", rootPath)
+	if err := w.Run(ctx); err != nil {
+		fmt.Printf("❌ Error watching filesystem: %v\n", err)
+		os.Exit(1)
+	}
+
+	<-served
+	run.Shutdown()
+}
+
+func handlePush() {
+	args := flag.Args()[1:]
+	if len(args) != 2 {
+		fmt.Println("Usage: fsdiff push <snapshot> <url>")
+		os.Exit(1)
+	}
+
+	file := args[0]
+	url := transfer.ObjectURL(args[1], file)
+
+	c := &transfer.Client{Token: *storeToken, Progress: transferProgress("Uploading")}
+	fmt.Printf("📤 Pushing %s to %s\n", file, url)
+	if err := c.Push(context.Background(), file, url); err != nil {
+		fmt.Printf("\n❌ Error pushing snapshot: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\n✅ Snapshot pushed and verified!\n")
+}
+
+func handlePull() {
+	args := flag.Args()[1:]
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println("Usage: fsdiff pull <url> [output_file]")
+		os.Exit(1)
+	}
+
+	url := args[0]
+	file := path.Base(url)
+	if len(args) == 2 {
+		file = args[1]
+	}
+
+	c := &transfer.Client{Token: *storeToken, Progress: transferProgress("Downloading")}
+	fmt.Printf("📥 Pulling %s to %s\n", url, file)
+	if err := c.Pull(context.Background(), url, file); err != nil {
+		fmt.Printf("\n❌ Error pulling snapshot: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\n✅ Snapshot pulled and verified!\n")
+}
+
+func handleStore() {
+	args := flag.Args()[1:]
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println("Usage: fsdiff store <dir> [listen_addr]")
+		os.Exit(1)
+	}
+
+	addr := "localhost:7080"
+	if len(args) == 2 {
+		addr = args[1]
+	}
+
+	if err := transfer.CheckAddr(addr, *storeToken); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("🗄️  Storing snapshots in %s, listening on %s\n", args[0], addr)
+	health.Register(health.Ready, "store-dir", health.WritableDir(args[0]))
+
+	// Snapshot names would make too many path labels
+	srv := &httpserver.Server{Addr: addr, Handler: daemonHandler(&transfer.Server{Dir: args[0], Token: *storeToken}, nil)}
+	if err := srv.ListenAndServe(run.Context()); err != nil {
+		fmt.Printf("❌ Error serving store: %v\n", err)
+		os.Exit(1)
+	}
+	run.Shutdown()
+}
+
+// daemonHandler wraps h with the health check endpoints shared by watch and
+// store, and with /metrics when -metrics is set. path names each request's
+// route in the metrics, as for metrics.Options.
+func daemonHandler(h http.Handler, path func(*http.Request) string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /.jsn.health/live", health.Handler(health.Live))
+	mux.Handle("GET /.jsn.health/ready", health.Handler(health.Ready))
+	if *exposeMetrics {
+		mux.Handle("GET /metrics", promhttp.Handler())
+		h = metrics.New(metrics.Options{Path: path}).Wrap(h)
+	}
+	mux.Handle("/", h)
+	return mux
+}
+
+// transferProgress prints a progress line for push and pull
+func transferProgress(verb string) func(done, total int64) {
+	var last time.Time
+	return func(done, total int64) {
+		if !*verbose || (time.Since(last) < 200*time.Millisecond && done < total) {
+			return
+		}
+		last = time.Now()
+		if total > 0 {
+			fmt.Printf("\r   %s %s / %s (%d%%)", verb, formatBytes(done), formatBytes(total), done*100/total)
+		} else {
+			fmt.Printf("\r   %s %s", verb, formatBytes(done))
+		}
+	}
+}
+
 func handleDiff() {
 	args := flag.Args()[1:]
 	if len(args) < 2 || len(args) > 3 {
@@ -137,7 +576,7 @@ func handleDiff() {
 	}
 
 	// Parse ignore patterns for diff
-	ignorePatterns := parseIgnorePatterns(*ignore)
+	ignorePatterns := parseList(*ignore)
 
 	fmt.Printf("📖 Loading baseline: %s\n", baselineFile)
 	baseline, err := snapshot.Load(baselineFile)
@@ -153,14 +592,25 @@ func handleDiff() {
 		os.Exit(1)
 	}
 
+	rebases := parseRebases()
+	rebaseSnapshot(baseline, rebases)
+	rebaseSnapshot(current, rebases)
+	checkComparable(baseline.SystemInfo, current.SystemInfo)
+
 	fmt.Printf("🔍 Comparing snapshots...\n")
 	config := &diff.Config{
 		IgnorePatterns: ignorePatterns,
+		Workers:        *workers,
 		Verbose:        *verbose,
 	}
 
 	d := diff.New(config)
+	if *summaryOnly {
+		printSummaryOnly(d.Summarize(baseline, current))
+	}
+
 	result := d.Compare(baseline, current)
+	runAnalyzers(result)
 
 	// Print summary
 	printDiffSummary(result)
@@ -174,6 +624,9 @@ func handleDiff() {
 		}
 		fmt.Printf("✅ Report saved successfully!\n")
 	}
+
+	sendEmailReport(result)
+	sendNotifications(result)
 }
 
 func handleLive() {
@@ -191,7 +644,7 @@ func handleLive() {
 	}
 
 	// Parse ignore patterns
-	ignorePatterns := parseIgnorePatterns(*ignore)
+	ignorePatterns := parseList(*ignore)
 
 	fmt.Printf("📖 Loading baseline: %s\n", baselineFile)
 	baseline, err := snapshot.Load(baselineFile)
@@ -200,11 +653,42 @@ func handleLive() {
 		os.Exit(1)
 	}
 
+	diffConfig := &diff.Config{
+		IgnorePatterns: ignorePatterns,
+		Workers:        *workers,
+		Verbose:        *verbose,
+	}
+
+	d := diff.New(diffConfig)
+
+	rebases := parseRebases()
+	rebaseSnapshot(baseline, rebases)
+	currentInfo := system.GetSystemInfo(rootPath)
+	currentInfo.ScanRoot, _ = snapshot.RebasePath(filepath.Clean(rootPath), rebases)
+	checkComparable(baseline.SystemInfo, currentInfo)
+
 	fmt.Printf("🔍 Scanning current filesystem: %s\n", rootPath)
 	scanConfig := &scanner.Config{
 		Workers:        *workers,
 		Verbose:        *verbose,
 		IgnorePatterns: ignorePatterns,
+		CaptureState:   *state,
+		Profile:        *profile,
+	}
+
+	// Compare records against the baseline as they are scanned
+	var stream *diff.Stream
+	if !*summaryOnly {
+		stream = d.NewStream(baseline)
+		scanConfig.OnRecord = func(record *snapshot.FileRecord) {
+			if len(rebases) > 0 {
+				rebased := *record
+				rebased.Path, _ = snapshot.RebasePath(record.Path, rebases)
+				record = &rebased
+			}
+			stream.Add(record)
+		}
+		scanConfig.DiscardRecords = true
 	}
 
 	s := scanner.New(scanConfig)
@@ -213,15 +697,15 @@ func handleLive() {
 		fmt.Printf("❌ Error scanning filesystem: %v\n", err)
 		os.Exit(1)
 	}
+	rebaseSnapshot(current, rebases)
 
-	fmt.Printf("🔍 Comparing with baseline...\n")
-	diffConfig := &diff.Config{
-		IgnorePatterns: ignorePatterns,
-		Verbose:        *verbose,
+	if *summaryOnly {
+		printSummaryOnly(d.Summarize(baseline, current))
 	}
 
-	d := diff.New(diffConfig)
-	result := d.Compare(baseline, current)
+	fmt.Printf("🔍 Comparing with baseline...\n")
+	result := stream.Finish(current)
+	runAnalyzers(result)
 
 	// Print summary
 	printDiffSummary(result)
@@ -235,18 +719,230 @@ func handleLive() {
 		}
 		fmt.Printf("✅ Report saved successfully!\n")
 	}
+
+	sendEmailReport(result)
+	sendNotifications(result)
+}
+
+// setupLocale applies -timezone and -locale to console and report output
+func setupLocale() error {
+	loc, err := locale.LoadLocation(*timezone)
+	if err != nil {
+		return err
+	}
+	l, err := locale.Lookup(*localeName)
+	if err != nil {
+		return err
+	}
+	locale.Set(l, loc)
+	return nil
+}
+
+// parseRebases parses the -rebase rules
+func parseRebases() []snapshot.Rebase {
+	var rebases []snapshot.Rebase
+	for _, rule := range parseList(*rebase) {
+		r, err := snapshot.ParseRebase(rule)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(1)
+		}
+		rebases = append(rebases, r)
+	}
+	return rebases
+}
+
+// rebaseSnapshot applies the rebase rules to a snapshot
+func rebaseSnapshot(snap *snapshot.Snapshot, rebases []snapshot.Rebase) {
+	moved, err := snap.Rebase(rebases)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+	for i, r := range rebases {
+		if moved[i] > 0 {
+			fmt.Printf("🔀 Rebased %d paths from %s to %s\n", moved[i], r.From, r.To)
+		}
+	}
 }
 
-func parseIgnorePatterns(ignore string) []string {
-	if ignore == "" {
+// checkComparable refuses cross-host or cross-root comparisons unless
+// -allow-cross-host is set
+func checkComparable(baseline, current system.SystemInfo) {
+	err := diff.CheckComparable(baseline, current)
+	if err == nil {
+		return
+	}
+
+	if *allowCrossHost {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+		return
+	}
+
+	fmt.Printf("❌ Refusing to compare: %v\n", err)
+	fmt.Println("   Use -allow-cross-host if this is intended, and -rebase old:new to line up different scan roots")
+	os.Exit(1)
+}
+
+// printSummaryOnly writes the summary as JSON and exits, with status 2 if
+// anything changed so scheduled checks can alert on drift
+func printSummaryOnly(summary diff.Summary) {
+	enc := json.NewEncoder(summaryOut)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(summary); err != nil {
+		fmt.Printf("❌ Error writing summary: %v\n", err)
+		os.Exit(1)
+	}
+
+	if summary.TotalChanges > 0 || summary.StateChanges > 0 {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+// sendEmailReport emails the report if -email-to is set
+func sendEmailReport(result *diff.Result) {
+	if *emailTo == "" {
+		return
+	}
+
+	cfg := &report.EmailConfig{
+		To:       parseList(*emailTo),
+		From:     *emailFrom,
+		SMTPHost: *smtpHost,
+		Username: *smtpUser,
+		Password: *smtpPassword,
+	}
+
+	fmt.Printf("📧 Emailing report to: %s\n", strings.Join(cfg.To, ", "))
+	if err := report.SendEmail(result, cfg); err != nil {
+		fmt.Printf("❌ Error emailing report: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Report emailed successfully!\n")
+}
+
+// runAnalyzers runs registered analyzers and those from the config file
+func runAnalyzers(result *diff.Result) {
+	analyzers := diff.RegisteredAnalyzers()
+	for _, a := range cfg.Analyzers {
+		analyzers = append(analyzers, diff.NewExecAnalyzer(a.Name, a.Command))
+	}
+	if len(analyzers) == 0 || result.Summary.TotalChanges == 0 {
+		return
+	}
+
+	fmt.Printf("🧪 Running %d analyzers...\n", len(analyzers))
+	if err := result.RunAnalyzers(analyzers); err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+}
+
+// sendNotifications routes the result to the sinks in the config file
+func sendNotifications(result *diff.Result) {
+	if len(cfg.Notify) == 0 {
+		return
+	}
+
+	n, err := notify.New(cfg.Notify)
+	if err != nil {
+		fmt.Printf("❌ Error configuring notifications: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("🔔 Sending notifications to %d sinks\n", len(cfg.Notify))
+	if err := n.Notify(context.Background(), result); err != nil {
+		fmt.Printf("❌ Error sending notifications: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// watchNotifyInterval is how often watch mode checks whether the diff
+// changed, so a burst of writes becomes one notification
+const watchNotifyInterval = 30 * time.Second
+
+// watchNotifications sends the watcher's diff to the sinks in the config
+// file whenever what they would be told changes, until ctx is cancelled.
+// Failures are logged rather than fatal so one bad send doesn't stop the
+// watcher.
+func watchNotifications(ctx context.Context, w *watch.Watcher, baseline *snapshot.Snapshot, d *diff.Differ) {
+	if len(cfg.Notify) == 0 {
+		return
+	}
+
+	n, err := notify.New(cfg.Notify)
+	if err != nil {
+		fmt.Printf("❌ Error configuring notifications: %v\n", err)
+		os.Exit(1)
+	}
+
+	ticker := time.NewTicker(watchNotifyInterval)
+	defer ticker.Stop()
+
+	var last watch.Status
+	checked := false
+	sent := make(map[string]string) // Last message text by sink
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		status := w.Status()
+		if checked && status.Updates == last.Updates && status.Rescans == last.Rescans {
+			continue
+		}
+
+		// Messages are rendered under the lock and sent after it's released
+		var routed []notify.Routed
+		scanned := false
+		w.View(func(current *snapshot.Snapshot) {
+			if current != nil {
+				scanned = true
+				routed = n.Route(d.Compare(baseline, current))
+			}
+		})
+		if !scanned {
+			continue
+		}
+		last, checked = status, true
+
+		var changed []notify.Routed
+		for _, r := range routed {
+			text := r.Message.Text()
+			if sent[sinkKey(r.Sink)] != text {
+				sent[sinkKey(r.Sink)] = text
+				changed = append(changed, r)
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		fmt.Printf("🔔 Sending notifications to %d sinks\n", len(changed))
+		if err := n.Send(ctx, changed); err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+	}
+}
+
+// sinkKey identifies a sink in the config file
+func sinkKey(sink notify.SinkConfig) string {
+	return sink.Name + "\x00" + sink.Type + "\x00" + sink.URL + "\x00" + sink.Room
+}
+
+// parseList splits a comma-separated flag value, dropping empty entries
+func parseList(list string) []string {
+	if list == "" {
 		return nil
 	}
-	patterns := strings.Split(ignore, ",")
-	result := make([]string, 0, len(patterns))
-	for _, pattern := range patterns {
-		pattern = strings.TrimSpace(pattern)
-		if pattern != "" {
-			result = append(result, pattern)
+	items := strings.Split(list, ",")
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
 		}
 	}
 	return result
@@ -260,12 +956,12 @@ func printDiffSummary(result *diff.Result) {
 	fmt.Printf("Baseline: %s (%s) - %s\n",
 		result.Baseline.SystemInfo.Hostname,
 		result.Baseline.SystemInfo.Distro,
-		result.Baseline.SystemInfo.Timestamp.Format("2006-01-02 15:04:05"))
+		locale.Time(result.Baseline.SystemInfo.Timestamp))
 
 	fmt.Printf("Current:  %s (%s) - %s\n\n",
 		result.Current.SystemInfo.Hostname,
 		result.Current.SystemInfo.Distro,
-		result.Current.SystemInfo.Timestamp.Format("2006-01-02 15:04:05"))
+		locale.Time(result.Current.SystemInfo.Timestamp))
 
 	summary := result.Summary
 	fmt.Printf("📈 CHANGES:\n")
@@ -274,8 +970,18 @@ func printDiffSummary(result *diff.Result) {
 	fmt.Printf("   Deleted:  %d files/directories\n", summary.DeletedCount)
 	fmt.Printf("   Total:    %d changes\n\n", summary.TotalChanges)
 
+	if len(result.State) > 0 {
+		fmt.Printf("⚙️  SYSTEM STATE CHANGES:\n")
+		for _, change := range result.State {
+			fmt.Printf("   %s %s %s: %s\n", getChangeIcon(string(change.Type)), change.Kind, change.Item, change.Detail)
+		}
+		fmt.Println()
+	}
+
 	if summary.TotalChanges == 0 {
-		fmt.Println("✅ No changes detected!")
+		if len(result.State) == 0 {
+			fmt.Println("✅ No changes detected!")
+		}
 		return
 	}
 
@@ -289,6 +995,14 @@ func printDiffSummary(result *diff.Result) {
 		fmt.Println()
 	}
 
+	if len(result.Findings) > 0 {
+		fmt.Printf("🧪 ANALYZER FINDINGS:\n")
+		for _, f := range result.Findings {
+			fmt.Printf("   [%s] %s %s: %s (severity %d)\n", f.Analyzer, f.Type, f.Path, f.Message, f.Severity)
+		}
+		fmt.Println()
+	}
+
 	// Show sample of changes
 	showSampleChanges("Added", result.Added, 5)
 	showSampleChanges("Modified", result.Modified, 5)
@@ -377,13 +1091,17 @@ func showSampleChanges(changeType string, changes interface{}, limit int) {
 	fmt.Println()
 }
 
+func formatBytes(bytes int64) string {
+	return locale.Bytes(bytes)
+}
+
 func getChangeIcon(changeType string) string {
 	switch changeType {
-	case "Added":
+	case "Added", string(diff.ChangeAdded):
 		return "+"
-	case "Modified":
+	case "Modified", string(diff.ChangeModified):
 		return "~"
-	case "Deleted":
+	case "Deleted", string(diff.ChangeDeleted):
 		return "-"
 	default:
 		return "?"
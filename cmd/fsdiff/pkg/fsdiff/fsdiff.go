@@ -1,4 +1,8 @@
 package fsdiff
 
 const Version = "0.5.0"
-const SnapshotVersion = "1.0.0" // Version of the snapshot format
+const SnapshotVersion = "2.0.0" // Version of the snapshot format
+
+// SnapshotFormatVersion is the wire format version written to portable
+// snapshots. Version 1 was the legacy gob encoding.
+const SnapshotFormatVersion = 2
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// goGetCounts counts go-get=1 requests per repo, since startup or since the
+// -stats-file was created. It lives outside the mux so counts survive config
+// reloads.
+var goGetCounts sync.Map // repo path -> *atomic.Int64
+
+// countGoGets wraps a repo handler to count go tool requests for it
+func countGoGets(repo string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("go-get") == "1" {
+			n, _ := goGetCounts.LoadOrStore(repo, new(atomic.Int64))
+			n.(*atomic.Int64).Add(1)
+			countGoGetClient(r.UserAgent())
+			countGoGetDay(time.Now())
+			// The index shows download counts
+			countsVersion.Add(1)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// goGets returns the go-get=1 request count for repo
+func goGets(repo string) int64 {
+	if n, ok := goGetCounts.Load(repo); ok {
+		return n.(*atomic.Int64).Load()
+	}
+	return 0
+}
+
+// apiRepo is a repo as returned by /api/repos
+type apiRepo struct {
+	Name          string   `json:"name"`
+	ImportPath    string   `json:"import_path"`
+	Provider      string   `json:"provider"`
+	Description   string   `json:"description,omitempty"`
+	SourceURL     string   `json:"source_url"`
+	GodocURL      string   `json:"godoc_url"`
+	Deprecated    string   `json:"deprecated,omitempty"`
+	Replacement   string   `json:"replacement,omitempty"`
+	Retracted     []string `json:"retracted,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	Archived      bool     `json:"archived,omitempty"`
+	GoGetRequests int64    `json:"go_get_requests"`
+	// Metadata is only present with -metadata-refresh
+	Metadata *RepoMeta `json:"metadata,omitempty"`
+}
+
+// reposAPI serves the repo list as JSON, sorted by name. go_get_requests
+// counts as in /api/stats. ?tag= lists only the repos with that tag.
+func reposAPI(repos []Repo, domain string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tag := strings.ToLower(r.URL.Query().Get("tag"))
+		out := make([]apiRepo, 0, len(repos))
+		for _, repo := range repos {
+			if tag != "" && !slices.Contains(repo.Tags, tag) {
+				continue
+			}
+			item := apiRepo{
+				Name:          repo.Repo,
+				ImportPath:    domain + "/" + repo.Repo,
+				Provider:      repo.Kind,
+				Description:   repo.Summary(),
+				SourceURL:     repo.URL(),
+				GodocURL:      repo.GodocURL(),
+				Replacement:   repo.Replacement,
+				Retracted:     repo.Retracted,
+				Tags:          repo.Tags,
+				Archived:      repo.IsArchived(),
+				GoGetRequests: goGets(repo.statsKey()),
+				Metadata:      repo.Metadata(),
+			}
+			if repo.IsDeprecated() {
+				item.Deprecated = repo.DeprecationNotice()
+			}
+			out = append(out, item)
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(map[string]any{"repos": out})
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+
+	"github.com/a-h/templ"
+)
+
+// Badge colors, as on shields.io
+const (
+	badgeGray   = "#555"
+	badgeGreen  = "#4c1"
+	badgeBlue   = "#007ec6"
+	badgeMuted  = "#9f9f9f"
+	badgeRed    = "#e05d44"
+	badgeOrange = "#fe7d37"
+)
+
+// badge renders a flat two-part SVG badge. Text widths are estimated, as
+// there's no font to measure with; Verdana at 11px averages about 7px.
+func badge(label, message, color string) string {
+	lw, mw := 10+7*len(label), 10+7*len(message)
+	label, message = html.EscapeString(label), html.EscapeString(message)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">`+
+		`<title>%[4]s: %[5]s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="%[7]s"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[8]d" y="14">%[4]s</text><text x="%[9]d" y="14">%[5]s</text></g></svg>`,
+		lw+mw, lw, mw, label, message, color, badgeGray, lw/2, lw+mw/2)
+}
+
+// shortCount formats n like 1.2k for badges
+func shortCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return strconv.FormatFloat(float64(n)/1_000_000, 'f', 1, 64) + "M"
+	case n >= 1_000:
+		return strconv.FormatFloat(float64(n)/1_000, 'f', 1, 64) + "k"
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// badgeHandler serves /.jsn.badge/{kind}/{repo...} for the repos in byName:
+// "version" (needs -metadata-refresh) and "downloads"
+func badgeHandler(byName map[string]Repo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo, ok := byName[r.PathValue("repo")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch r.PathValue("kind") {
+		case "version":
+			if v := repo.LatestVersion(); v != "" {
+				serveBadge(w, badge("version", v, badgeBlue))
+			} else {
+				serveBadge(w, badge("version", "unknown", badgeMuted))
+			}
+		case "downloads":
+			serveBadge(w, badge("downloads", shortCount(goGets(repo.statsKey())), badgeGreen))
+		case "status":
+			switch {
+			case repo.IsArchived():
+				serveBadge(w, badge("status", "archived", badgeRed))
+			case repo.IsDeprecated():
+				serveBadge(w, badge("status", "deprecated", badgeOrange))
+			default:
+				serveBadge(w, badge("status", "active", badgeGreen))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// sourceBadge is the "source link" badge on the package pages
+var sourceBadge = badge("source", "link", badgeGreen)
+
+func serveBadge(w http.ResponseWriter, svg string) {
+	w.Header().Set("Content-Type", "image/svg+xml")
+	// Short enough for counts to move, long enough for GitHub's image proxy
+	// to keep a copy
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write([]byte(svg))
+}
+
+// archivedNotice shows the status badge and ArchivedNotice for archived
+// repos, and nothing otherwise
+func archivedNotice(repo Repo) templ.Component {
+	if !repo.IsArchived() {
+		return templ.NopComponent
+	}
+	return templ.Raw(fmt.Sprintf(`<p class="archived"><img alt="Archived" src="/.jsn.badge/status/%s"> %s</p>`,
+		html.EscapeString(repo.Repo), html.EscapeString(repo.ArchivedNotice())))
+}
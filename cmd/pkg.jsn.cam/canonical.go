@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/a-h/templ"
+	"pkg.jsn.cam/jsn/jass"
+)
+
+// canonicalHost returns the vanity domain a request for an unknown host
+// belongs to: the domain itself for its www. variant, else -domain.
+// advise is true if the request should be told it used the wrong host.
+func canonicalHost(host string, muxes map[string]*http.ServeMux, goGet bool) (canonical string, advise bool) {
+	if d, ok := strings.CutPrefix(host, "www."); ok && muxes[d] != nil {
+		return d, true
+	}
+	// Other hosts, like localhost or the hosting provider's name, only
+	// matter to the go command: its import paths must match
+	return *domain, goGet
+}
+
+// wrongHost answers a request that used host instead of the canonical
+// vanity domain and records it, so links and imports using the wrong path
+// can be tracked down. The go command is served the canonical go-import
+// tags, so its error names the right import path; browsers get a page
+// pointing there.
+func wrongHost(w http.ResponseWriter, r *http.Request, host, canonical string, mux *http.ServeMux, lg *slog.Logger) {
+	goGet := r.FormValue("go-get") == "1"
+	kind := "browser"
+	if goGet {
+		kind = "go-get"
+	}
+	nonCanonicalRequests.WithLabelValues(canonical, kind).Inc()
+	lg.WarnContext(r.Context(), "request for non-canonical import path",
+		"host", host,
+		"path", r.URL.Path,
+		"canonical", canonical,
+		"kind", kind,
+		"referer", r.Referer(),
+		"user_agent", r.UserAgent(),
+	)
+
+	if goGet {
+		r = r.Clone(r.Context())
+		r.Host = canonical
+		mux.ServeHTTP(w, r)
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	wrong, right := host+path, canonical+path
+	url := "https://" + right
+	w.Header().Set("Link", "<"+url+`>; rel="canonical"`)
+	body := templ.Raw(fmt.Sprintf(`<section>
+<p><code>%s</code> isn't the canonical import path. Use <a href="%s"><code>%s</code></a> instead:</p>
+<pre><code>go get %s</code></pre>
+<p>If a link or import statement brought you here, please update it.</p>
+</section>`, html.EscapeString(wrong), html.EscapeString(url), html.EscapeString(right), html.EscapeString(right)))
+	head := templ.Raw(`<link rel="canonical" href="` + html.EscapeString(url) + `">`)
+	templ.Handler(jass.Base("Wrong import path", head, nil, body, nil)).ServeHTTP(w, r)
+}
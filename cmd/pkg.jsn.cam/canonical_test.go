@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWrongHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	site, err := NewSite(path, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name, url string
+		status    int
+		want      string
+	}{
+		{"canonical", "https://pkg.jsn.cam/jsn?go-get=1", http.StatusOK, `content="pkg.jsn.cam/jsn git`},
+		{"www go get", "https://www.pkg.jsn.cam/jsn?go-get=1", http.StatusOK, `content="pkg.jsn.cam/jsn git`},
+		{"www browser", "https://www.pkg.jsn.cam/jsn/sub", http.StatusOK, `Use <a href="https://pkg.jsn.cam/jsn/sub">`},
+		{"other host go get", "https://jsn.fly.dev/jsn?go-get=1", http.StatusOK, `content="pkg.jsn.cam/jsn git`},
+		{"other host browser", "http://localhost:2143/", http.StatusOK, `href="#jsn"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			site.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			body, _ := io.ReadAll(rec.Result().Body)
+			if rec.Code != tt.status || !strings.Contains(string(body), tt.want) {
+				t.Errorf("got %d, want %d with %s in\n%s", rec.Code, tt.status, tt.want, body)
+			}
+		})
+	}
+}
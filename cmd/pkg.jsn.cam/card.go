@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a-h/templ"
+)
+
+// Social card size, as recommended for OpenGraph and Twitter
+const (
+	cardWidth  = 1200
+	cardHeight = 630
+	cardMargin = 80
+)
+
+// Card palette indexes
+const (
+	cardBackground = iota
+	cardText
+	cardMuted
+	cardAccent
+)
+
+var cardPalette = color.Palette{
+	cardBackground: color.RGBA{0x18, 0x18, 0x1b, 0xff},
+	cardText:       color.RGBA{0xfa, 0xfa, 0xfa, 0xff},
+	cardMuted:      color.RGBA{0xa1, 0xa1, 0xaa, 0xff},
+	cardAccent:     color.RGBA{0x00, 0xad, 0xd8, 0xff}, // Go blue
+}
+
+// renderCard draws the OpenGraph image for repo: its name, description and
+// go get line in the bitmap font
+func renderCard(repo Repo) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, cardWidth, cardHeight), cardPalette)
+	fillRect(img, image.Rect(0, 0, cardWidth, 16), cardAccent)
+
+	drawText(img, cardMargin, 72, 4, cardMuted, repo.VanityDomain)
+
+	// The name as large as fits on one line
+	width := cardWidth - 2*cardMargin
+	scale := min(14, max(4, width/(6*utf8.RuneCountInString(repo.Repo))))
+	drawText(img, cardMargin, 130, scale, cardText, truncate(repo.Repo, width/(6*scale)))
+
+	y := 130 + 7*scale + 40
+	for _, line := range wrap(repo.Summary(), width/(6*4), 3) {
+		drawText(img, cardMargin, y, 4, cardMuted, line)
+		y += 7*4 + 16
+	}
+
+	stats := downloads(repo)
+	if m := repo.Metadata(); m != nil {
+		stats += fmt.Sprintf(" - %d stars", m.Stars)
+	}
+	if v := repo.LatestVersion(); v != "" {
+		stats = v + " - " + stats
+	}
+	drawText(img, cardMargin, cardHeight-cardMargin-7*4-48, 4, cardAccent, truncate("go get "+repo.ImportPath(), width/(6*4)))
+	drawText(img, cardMargin, cardHeight-cardMargin-7*3, 3, cardMuted, stats)
+	return img
+}
+
+// cardComponent renders the card as a PNG, for cachedPage
+func cardComponent(repo Repo) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		return png.Encode(w, renderCard(repo))
+	})
+}
+
+// drawText draws s with its top left corner at x, y, each font pixel scale
+// pixels wide
+func drawText(img *image.Paletted, x, y, scale int, c uint8, s string) {
+	for _, r := range s {
+		g := glyph(r)
+		for row, bits := range g {
+			for col := range 5 {
+				if bits&(1<<(4-col)) != 0 {
+					px, py := x+col*scale, y+row*scale
+					fillRect(img, image.Rect(px, py, px+scale, py+scale), c)
+				}
+			}
+		}
+		x += 6 * scale
+	}
+}
+
+func fillRect(img *image.Paletted, r image.Rectangle, c uint8) {
+	r = r.Intersect(img.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetColorIndex(x, y, c)
+		}
+	}
+}
+
+// truncate shortens s to n characters, ending it with "..." if it's cut
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:max(0, n-3)]) + "..."
+}
+
+// wrap breaks s into at most lines lines of up to width characters,
+// truncating the last one if there's more
+func wrap(s string, width, lines int) []string {
+	var out []string
+	var line string
+	for _, word := range strings.Fields(s) {
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			out = append(out, line)
+			line = word
+		}
+	}
+	if line != "" {
+		out = append(out, line)
+	}
+
+	if len(out) > lines {
+		out = out[:lines]
+		out[lines-1] = truncate(out[lines-1]+" ...", width)
+	}
+	for i := range out {
+		out[i] = truncate(out[i], width)
+	}
+	return out
+}
+
+// openGraph returns the link preview tags for repo's page
+func openGraph(repo Repo) templ.Component {
+	var b bytes.Buffer
+	page := "https://" + repo.ImportPath()
+	tags := [][2]string{
+		{"og:type", "website"},
+		{"og:title", repo.ImportPath()},
+		{"og:description", repo.Summary()},
+		{"og:url", page},
+		{"og:image", "https://" + repo.VanityDomain + "/.jsn.card/" + repo.Repo},
+		{"og:image:width", fmt.Sprint(cardWidth)},
+		{"og:image:height", fmt.Sprint(cardHeight)},
+		{"twitter:card", "summary_large_image"},
+	}
+	for _, t := range tags {
+		if t[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&b, `<meta property="%s" content="%s">`, t[0], templ.EscapeString(t[1]))
+	}
+	if len(repo.Tags) > 0 {
+		fmt.Fprintf(&b, `<meta name="keywords" content="%s">`, templ.EscapeString(strings.Join(repo.Tags, ", ")))
+	}
+	return templ.Raw(b.String())
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	got := wrap("a highly scalable and stateless counting API", 12, 3)
+	want := []string{"a highly", "scalable and", "stateless..."}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := wrap("short", 12, 3); !slices.Equal(got, []string{"short"}) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRenderCard(t *testing.T) {
+	img := renderCard(Repo{VanityDomain: "pkg.jsn.cam", Repo: "abacus", Description: "A counting API"})
+	if b := img.Bounds(); b.Dx() != cardWidth || b.Dy() != cardHeight {
+		t.Errorf("got %v", b)
+	}
+}
+
+func TestBadge(t *testing.T) {
+	svg := badge("version", "<v1>", badgeBlue)
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "&lt;v1&gt;") {
+		t.Errorf("got %s", svg)
+	}
+	if got := shortCount(1234); got != "1.2k" {
+		t.Errorf("shortCount(1234) = %q", got)
+	}
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"cmp"
 	"fmt"
 	"log/slog"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -14,6 +17,10 @@ type Config struct {
 	Repo map[string]RepoProvider `toml:"repo"`
 	// This will be filled manually after parsing
 	Repos map[string]RepoConfig
+	// Links maps paths to short link redirects
+	Links map[string]Link `toml:"links"`
+	// Pages customizes the pages that aren't about a repo
+	Pages Pages `toml:"pages"`
 }
 
 // RepoProvider defines a source of repositories (e.g., GitHub, GitLab)
@@ -21,12 +28,50 @@ type RepoProvider struct {
 	Username string `toml:"username"`
 	URL      string `toml:"url"`
 	Default  bool   `toml:"default"`
+	// Branch that go-source links point at, "master" if unset
+	Branch string `toml:"branch"`
+	// Wildcard serves any unconfigured /name as a repo of this provider
+	Wildcard bool `toml:"wildcard"`
+	// Redirect is where browsers are sent, see Repo.Redirect
+	Redirect string `toml:"redirect"`
+	// Domain is the vanity domain this provider's repos are served on,
+	// -domain if unset
+	Domain string `toml:"domain"`
+	// Scheme is how the go tool clones, see Repo.Scheme
+	Scheme string `toml:"scheme"`
+	// ArchivedGone answers go get for archived repos with 410 Gone
+	ArchivedGone bool `toml:"archived_gone"`
 }
 
 // RepoConfig defines a specific repository configuration
 type RepoConfig struct {
 	Type        string `toml:"type"`
 	Description string `toml:"desc"`
+	// Branch overrides the provider's branch for this repo
+	Branch string `toml:"branch"`
+	// Source is the repository name on the forge when it differs from the
+	// first element of the path
+	Source string `toml:"repo"`
+	// Subdir is the module's directory within the repository
+	Subdir string `toml:"subdir"`
+	// Redirect overrides the provider's redirect for this repo
+	Redirect string `toml:"redirect"`
+	// Domain overrides the provider's vanity domain for this repo
+	Domain string `toml:"domain"`
+	// Scheme overrides the provider's clone scheme for this repo
+	Scheme string `toml:"scheme"`
+	// Deprecated marks the repo deprecated with this message
+	Deprecated string `toml:"deprecated"`
+	// Replacement is the import path users should move to
+	Replacement string `toml:"replacement"`
+	// Retracted lists retracted versions, e.g. "v1.0.1" or "[v1.1.0, v1.1.3]"
+	Retracted []string `toml:"retracted"`
+	// Tags are topics the repo is listed under, e.g. "cli" or "http"
+	Tags []string `toml:"tags"`
+	// Archived marks the repo archived even if the provider doesn't say so
+	Archived bool `toml:"archived"`
+	// ArchivedGone overrides the provider's archived_gone for this repo
+	ArchivedGone *bool `toml:"archived_gone"`
 }
 
 // LoadConfig loads and parses the TOML configuration file
@@ -35,11 +80,14 @@ func LoadConfig(path string, lg *slog.Logger) (*Config, error) {
 	config := &Config{
 		Repo:  make(map[string]RepoProvider),
 		Repos: make(map[string]RepoConfig),
+		Links: make(map[string]Link),
 	}
 
 	// Decode the TOML file with metadata to handle undecoded keys
 	var tmp struct {
-		Repo map[string]RepoProvider `toml:"repo"`
+		Repo  map[string]RepoProvider `toml:"repo"`
+		Links map[string]Link         `toml:"links"`
+		Pages Pages                   `toml:"pages"`
 	}
 
 	_, err := DecodeFile(path, &tmp)
@@ -50,6 +98,27 @@ func LoadConfig(path string, lg *slog.Logger) (*Config, error) {
 	// Copy the decoded repo section
 	config.Repo = tmp.Repo
 
+	for key, link := range tmp.Links {
+		path, ok := linkPath(key)
+		if !ok || !validLinkURL(link.URL) {
+			lg.Error("invalid link, skipping", "path", key, "url", link.URL)
+			continue
+		}
+		link.Domain = cmp.Or(strings.ToLower(link.Domain), *domain)
+		config.Links[path] = link
+	}
+	config.Pages = tmp.Pages
+	for name := range config.Pages.WellKnown {
+		if !validWellKnown(name) {
+			lg.Error("invalid .well-known name, skipping", "name", name)
+			delete(config.Pages.WellKnown, name)
+		}
+	}
+	for name, provider := range config.Repo {
+		provider.Domain = strings.ToLower(provider.Domain)
+		config.Repo[name] = provider
+	}
+
 	// Process all top-level tables that aren't "repo"
 	rawData := map[string]interface{}{}
 	if _, err := DecodeFile(path, &rawData); err != nil {
@@ -58,8 +127,8 @@ func LoadConfig(path string, lg *slog.Logger) (*Config, error) {
 
 	// Iterate through the raw data and extract repo configs
 	for key, value := range rawData {
-		// Skip the "repo" table which we already processed
-		if key == "repo" {
+		// Skip the tables which we already processed
+		if key == "repo" || key == "links" || key == "pages" {
 			continue
 		}
 
@@ -76,6 +145,77 @@ func LoadConfig(path string, lg *slog.Logger) (*Config, error) {
 				repoConfig.Description = desc
 			}
 
+			if branch, ok := tableData["branch"].(string); ok {
+				repoConfig.Branch = branch
+			}
+
+			if source, ok := tableData["repo"].(string); ok {
+				repoConfig.Source = source
+			}
+
+			if subdir, ok := tableData["subdir"].(string); ok {
+				repoConfig.Subdir = strings.Trim(subdir, "/")
+			}
+
+			if domain, ok := tableData["domain"].(string); ok {
+				repoConfig.Domain = strings.ToLower(domain)
+			}
+
+			if redirect, ok := tableData["redirect"].(string); ok {
+				repoConfig.Redirect = redirect
+			}
+
+			if scheme, ok := tableData["scheme"].(string); ok {
+				repoConfig.Scheme = scheme
+			}
+
+			if deprecated, ok := tableData["deprecated"].(string); ok {
+				repoConfig.Deprecated = deprecated
+			}
+
+			if replacement, ok := tableData["replacement"].(string); ok {
+				repoConfig.Replacement = replacement
+			}
+
+			if retracted, ok := tableData["retracted"].([]interface{}); ok {
+				for _, v := range retracted {
+					if version, ok := v.(string); ok {
+						repoConfig.Retracted = append(repoConfig.Retracted, version)
+					}
+				}
+			}
+
+			if tags, ok := tableData["tags"].([]interface{}); ok {
+				var names []string
+				for _, v := range tags {
+					if tag, ok := v.(string); ok {
+						names = append(names, tag)
+					}
+				}
+				var invalid []string
+				repoConfig.Tags, invalid = normalizeTags(names)
+				for _, tag := range invalid {
+					lg.Warn("ignoring invalid tag", "repo", key, "tag", tag)
+				}
+			}
+
+			if archived, ok := tableData["archived"].(bool); ok {
+				repoConfig.Archived = archived
+			}
+
+			if gone, ok := tableData["archived_gone"].(bool); ok {
+				repoConfig.ArchivedGone = &gone
+			}
+
+			// Keys may be paths like "jsn/cmd/fsdiff" for nested modules.
+			// They become ServeMux patterns, which panic on names like
+			// "x/{y}", so each segment has to be a valid repo name.
+			key = strings.Trim(key, "/")
+			if !validRepoPath(key) {
+				lg.Error("invalid repo path, skipping", "repo", key)
+				continue
+			}
+
 			// Add to the repos map
 			config.Repos[key] = repoConfig
 		}
@@ -122,18 +262,89 @@ func BuildRepos(config *Config, lg *slog.Logger) []Repo {
 			continue
 		}
 
+		redirect := cmp.Or(repoConfig.Redirect, provider.Redirect)
+		if !validRedirect(redirect) {
+			lg.Error("invalid redirect, using the package page", "redirect", redirect, "slug", slug)
+			redirect = ""
+		}
+
+		scheme := cmp.Or(repoConfig.Scheme, provider.Scheme)
+		if !validScheme(scheme) {
+			lg.Error("invalid clone scheme, using https", "scheme", scheme, "slug", slug)
+			scheme = ""
+		}
+
 		repos = append(repos, Repo{
-			Kind:        repoType,
-			Domain:      getRepoDomain(provider.URL),
-			User:        provider.Username,
-			Repo:        slug,
-			Description: repoConfig.Description,
+			VanityDomain: cmp.Or(repoConfig.Domain, provider.Domain, *domain),
+			Kind:         repoType,
+			Domain:       getRepoDomain(provider.URL),
+			User:         provider.Username,
+			Repo:         slug,
+			Description:  repoConfig.Description,
+			Branch:       cmp.Or(repoConfig.Branch, provider.Branch),
+			Source:       repoConfig.Source,
+			Subdir:       repoConfig.Subdir,
+			Redirect:     redirect,
+			Scheme:       scheme,
+			Deprecated:   repoConfig.Deprecated,
+			Replacement:  repoConfig.Replacement,
+			Retracted:    repoConfig.Retracted,
+			Tags:         repoConfig.Tags,
+			Archived:     repoConfig.Archived,
+			ArchivedGone: provider.ArchivedGone,
 		})
+		if repoConfig.ArchivedGone != nil {
+			repos[len(repos)-1].ArchivedGone = *repoConfig.ArchivedGone
+		}
 	}
 
 	return repos
 }
 
+// WildcardProviders returns the providers with wildcard = true as Repos
+// without a name, by vanity domain. Only one provider per domain may be
+// the wildcard.
+func WildcardProviders(config *Config, lg *slog.Logger) map[string]Repo {
+	names := make(map[string][]string)
+	for name, provider := range config.Repo {
+		if provider.Wildcard {
+			d := cmp.Or(provider.Domain, *domain)
+			names[d] = append(names[d], name)
+		}
+	}
+
+	wildcards := make(map[string]Repo)
+	for d, providers := range names {
+		if len(providers) > 1 {
+			sort.Strings(providers)
+			lg.Error("more than one wildcard repo provider, ignoring wildcard", "domain", d, "providers", providers)
+			continue
+		}
+
+		provider := config.Repo[providers[0]]
+		redirect := provider.Redirect
+		if !validRedirect(redirect) {
+			lg.Error("invalid redirect, using pkg.go.dev", "redirect", redirect, "provider", providers[0])
+			redirect = ""
+		}
+		scheme := provider.Scheme
+		if !validScheme(scheme) {
+			lg.Error("invalid clone scheme, using https", "scheme", scheme, "provider", providers[0])
+			scheme = ""
+		}
+		wildcards[d] = Repo{
+			VanityDomain: d,
+			Kind:         providers[0],
+			Domain:       getRepoDomain(provider.URL),
+			User:         provider.Username,
+			Branch:       provider.Branch,
+			Redirect:     redirect,
+			Scheme:       scheme,
+		}
+	}
+	return wildcards
+}
+
 // getRepoDomain extracts the domain from a URL
 func getRepoDomain(url string) string {
 	return strings.TrimPrefix(url, "https://")
@@ -183,6 +394,13 @@ func stringify(v interface{}) string {
 	return fmt.Sprintf("%v", v)
 }
 func DecodeFile(path string, v any) (toml.MetaData, error) {
+	if path == envConfig {
+		data, err := envTOML(os.Environ())
+		if err != nil {
+			return toml.MetaData{}, err
+		}
+		return toml.Decode(string(data), v)
+	}
 	fp, err := Open(path)
 	if err != nil {
 		return toml.MetaData{}, err
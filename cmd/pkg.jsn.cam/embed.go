@@ -10,6 +10,12 @@ import (
 //go:embed config.toml
 var defaults embed.FS
 
+// onDisk reports whether the config at path is a file that can change, not
+// the embedded defaults or the environment
+func onDisk(path string) bool {
+	return !strings.HasPrefix(path, "(data)/") && path != envConfig
+}
+
 func Open(path string) (fs.File, error) {
 	if strings.HasPrefix(path, "(data)/") {
 		fname := strings.TrimPrefix(path, "(data)/")
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// envConfig as -config reads the config from environment variables instead
+// of a file, for containers where mounting one is awkward
+const envConfig = "(env)"
+
+// envPrefix starts every config environment variable
+const envPrefix = "PKGJSN_"
+
+// envSection describes one kind of table in the config. Each variable is
+// PKGJSN_<SECTION>_<ID>_<KEY>, like flagenv: upper case with dashes and dots
+// as underscores. The table's name is ID lower-cased with underscores as
+// dashes, or the <ID>_NAME variable for names that can't be written that
+// way, like "jsn/cmd/fsdiff".
+type envSection struct {
+	// table is where the section's tables go, "" for the top level
+	table string
+	// keys maps each config key to its kind: "string", "bool" or "list"
+	// (comma-separated)
+	keys map[string]string
+}
+
+var envSections = map[string]envSection{
+	"PROVIDER": {table: "repo", keys: map[string]string{
+		"username": "string", "url": "string", "default": "bool", "branch": "string",
+		"wildcard": "bool", "redirect": "string", "domain": "string", "scheme": "string",
+		"archived_gone": "bool",
+	}},
+	"REPO": {keys: map[string]string{
+		"type": "string", "desc": "string", "branch": "string", "repo": "string",
+		"subdir": "string", "redirect": "string", "domain": "string", "scheme": "string",
+		"deprecated": "string", "replacement": "string", "retracted": "list", "tags": "list",
+		"archived": "bool", "archived_gone": "bool",
+	}},
+	"LINK": {table: "links", keys: map[string]string{
+		"url": "string", "domain": "string",
+	}},
+	"WELL_KNOWN": {keys: map[string]string{
+		"content": "string",
+	}},
+}
+
+// envPages are the [pages] settings, PKGJSN_PAGES_<KEY>
+var envPages = []string{"not_found", "robots"}
+
+// envTOML builds a TOML config from environ, a list of KEY=value strings as
+// returned by os.Environ
+func envTOML(environ []string) ([]byte, error) {
+	config := map[string]any{}
+	pages := map[string]any{}
+	wellKnown := map[string]any{}
+
+	// Tables by section and ID, and the names set with _NAME
+	tables := map[string]map[string]map[string]any{}
+	names := map[string]map[string]string{}
+
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		rest, ok := strings.CutPrefix(key, envPrefix)
+		if !ok {
+			continue
+		}
+
+		// Repos that need no settings can just be listed
+		if rest == "REPOS" {
+			for _, name := range splitList(value) {
+				config[name] = map[string]any{}
+			}
+			continue
+		}
+
+		if setting, ok := strings.CutPrefix(rest, "PAGES_"); ok {
+			name := strings.ToLower(setting)
+			if !slices.Contains(envPages, name) {
+				return nil, fmt.Errorf("unknown setting %s", key)
+			}
+			pages[name] = value
+			continue
+		}
+
+		section, id, setting, err := splitEnvKey(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		if tables[section] == nil {
+			tables[section] = map[string]map[string]any{}
+			names[section] = map[string]string{}
+		}
+		if tables[section][id] == nil {
+			tables[section][id] = map[string]any{}
+		}
+		if setting == "name" {
+			names[section][id] = value
+			continue
+		}
+
+		var v any = value
+		switch envSections[section].keys[setting] {
+		case "bool":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			v = b
+		case "list":
+			v = splitList(value)
+		}
+		tables[section][id][setting] = v
+	}
+
+	for section, byID := range tables {
+		for id, table := range byID {
+			name := names[section][id]
+			if name == "" {
+				name = strings.ReplaceAll(strings.ToLower(id), "_", "-")
+			}
+
+			switch s := envSections[section]; {
+			case section == "WELL_KNOWN":
+				if names[section][id] == "" {
+					return nil, fmt.Errorf("%sWELL_KNOWN_%s_NAME is required, e.g. security.txt", envPrefix, id)
+				}
+				wellKnown[name] = table["content"]
+			case s.table == "":
+				if prev, ok := config[name].(map[string]any); ok && len(prev) > 0 {
+					return nil, fmt.Errorf("repo %q is defined twice", name)
+				}
+				config[name] = table
+			default:
+				parent, _ := config[s.table].(map[string]any)
+				if parent == nil {
+					parent = map[string]any{}
+					config[s.table] = parent
+				}
+				parent[name] = table
+			}
+		}
+	}
+
+	if len(wellKnown) > 0 {
+		pages["well_known"] = wellKnown
+	}
+	if len(pages) > 0 {
+		config["pages"] = pages
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(config); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// splitEnvKey splits SECTION_ID_KEY, with the prefix removed, into its
+// parts. The key is matched from the end since IDs may contain underscores.
+func splitEnvKey(rest string) (section, id, key string, err error) {
+	for name, s := range envSections {
+		after, ok := strings.CutPrefix(rest, name+"_")
+		if !ok {
+			continue
+		}
+
+		keys := append(slices.Collect(maps.Keys(s.keys)), "name")
+		// Longest first, so a key is never mistaken for the end of another
+		sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+		for _, k := range keys {
+			id, ok := strings.CutSuffix(after, "_"+strings.ToUpper(k))
+			if ok && id != "" {
+				return name, id, k, nil
+			}
+		}
+		return "", "", "", fmt.Errorf("unknown %s setting", strings.ToLower(name))
+	}
+	return "", "", "", fmt.Errorf("unknown section, want one of PROVIDER, REPO, LINK, WELL_KNOWN or PAGES")
+}
+
+// splitList splits a comma-separated list, keeping retraction ranges like
+// "[v1.1.0, v1.1.3]" whole
+func splitList(s string) []string {
+	var list []string
+	depth, start := 0, 0
+	for i := 0; i <= len(s); i++ {
+		switch {
+		case i < len(s) && s[i] == '[':
+			depth++
+		case i < len(s) && s[i] == ']':
+			depth--
+		case i == len(s) || s[i] == ',' && depth == 0:
+			if item := strings.TrimSpace(s[start:i]); item != "" {
+				list = append(list, item)
+			}
+			start = i + 1
+		}
+	}
+	return list
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+func TestEnvConfig(t *testing.T) {
+	for k, v := range map[string]string{
+		"PKGJSN_PROVIDER_GITHUB_USERNAME":    "JasonLovesDoggo",
+		"PKGJSN_PROVIDER_GITHUB_URL":         "github.com",
+		"PKGJSN_PROVIDER_GITHUB_DEFAULT":     "true",
+		"PKGJSN_REPOS":                       "jsn, abacus",
+		"PKGJSN_REPO_CADDY_DEFENDER_DESC":    "Block AI scrapers",
+		"PKGJSN_REPO_CADDY_DEFENDER_TAGS":    "caddy,http",
+		"PKGJSN_REPO_FSDIFF_NAME":            "jsn/cmd/fsdiff",
+		"PKGJSN_REPO_FSDIFF_REPO":            "jsn",
+		"PKGJSN_LINK_BLOG_URL":               "https://jasoncameron.dev",
+		"PKGJSN_PAGES_ROBOTS":                "User-agent: *",
+		"PKGJSN_WELL_KNOWN_SECURITY_NAME":    "security.txt",
+		"PKGJSN_WELL_KNOWN_SECURITY_CONTENT": "Contact: mailto:me@example.com",
+		"PKGJSN_REPO_ABACUS_RETRACTED":       "v1.0.0, [v1.1.0, v1.1.3]",
+	} {
+		t.Setenv(k, v)
+	}
+
+	config, err := LoadConfig(envConfig, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := config.Repo["github"]; p.Username != "JasonLovesDoggo" || !p.Default {
+		t.Errorf("provider = %+v", p)
+	}
+	var names []string
+	for name := range config.Repos {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if want := []string{"abacus", "caddy-defender", "jsn", "jsn/cmd/fsdiff"}; !slices.Equal(names, want) {
+		t.Errorf("repos = %q, want %q", names, want)
+	}
+	if r := config.Repos["caddy-defender"]; r.Description != "Block AI scrapers" || !slices.Equal(r.Tags, []string{"caddy", "http"}) {
+		t.Errorf("caddy-defender = %+v", r)
+	}
+	if r := config.Repos["jsn/cmd/fsdiff"]; r.Source != "jsn" {
+		t.Errorf("fsdiff = %+v", r)
+	}
+	if r := config.Repos["abacus"]; !slices.Equal(r.Retracted, []string{"v1.0.0", "[v1.1.0, v1.1.3]"}) {
+		t.Errorf("abacus retracted = %q", r.Retracted)
+	}
+	if l := config.Links["/blog"]; l.URL != "https://jasoncameron.dev" {
+		t.Errorf("links = %+v", config.Links)
+	}
+	if config.Pages.Robots != "User-agent: *" || config.Pages.WellKnown["security.txt"] == "" {
+		t.Errorf("pages = %+v", config.Pages)
+	}
+	if repos := BuildRepos(config, slog.Default()); len(repos) != 4 {
+		t.Errorf("got %d repos, want 4", len(repos))
+	}
+}
+
+func TestEnvConfigErrors(t *testing.T) {
+	for _, env := range []string{
+		"PKGJSN_REPO_JSN_COLOR=blue",
+		"PKGJSN_THING_JSN_DESC=x",
+		"PKGJSN_PROVIDER_GITHUB_DEFAULT=yes please",
+		"PKGJSN_PAGES_FOOTER=x",
+		"PKGJSN_WELL_KNOWN_SECURITY_CONTENT=x",
+	} {
+		if _, err := envTOML([]string{env}); err == nil {
+			t.Errorf("%s: no error", env)
+		}
+	}
+}
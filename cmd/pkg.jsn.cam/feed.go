@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxFeedEntries caps how many changes the Atom feed keeps
+const maxFeedEntries = 50
+
+// change is a package being added or its description changing
+type change struct {
+	Repo    Repo
+	Added   bool
+	Updated time.Time
+}
+
+// changes records package changes across config reloads, newest first.
+// The config has no history, so everything counts as added when the
+// server starts; entry IDs are stable so feed readers don't repeat them.
+var changes struct {
+	sync.Mutex
+	log []change
+}
+
+// recordChanges compares the repos before and after a reload and logs
+// added packages and changed descriptions
+func recordChanges(before, after []Repo, now time.Time) {
+	old := make(map[string]Repo, len(before))
+	for _, repo := range before {
+		old[repo.ImportPath()] = repo
+	}
+
+	var added []change
+	for _, repo := range after {
+		prev, ok := old[repo.ImportPath()]
+		switch {
+		case !ok:
+			added = append(added, change{Repo: repo, Added: true, Updated: now})
+		case prev.Description != repo.Description:
+			added = append(added, change{Repo: repo, Updated: now})
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].Repo.ImportPath() < added[j].Repo.ImportPath() })
+
+	changes.Lock()
+	defer changes.Unlock()
+	changes.log = append(added, changes.log...)
+	if len(changes.log) > maxFeedEntries {
+		changes.log = changes.log[:maxFeedEntries]
+	}
+}
+
+// recentChanges returns the changes to repos on domain, newest first
+func recentChanges(domain string) []change {
+	changes.Lock()
+	defer changes.Unlock()
+
+	var log []change
+	for _, c := range changes.log {
+		if c.Repo.VanityDomain == domain {
+			log = append(log, c)
+		}
+	}
+	return log
+}
+
+// lastChanged returns when repo was last added or changed
+func lastChanged(repo Repo) time.Time {
+	for _, c := range recentChanges(repo.VanityDomain) {
+		if c.Repo.Repo == repo.Repo {
+			return c.Updated
+		}
+	}
+	return time.Time{}
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary,omitempty"`
+}
+
+// atomHandler serves an Atom feed of added packages and changed
+// descriptions
+func atomHandler(domain string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "https://" + domain
+		feed := atomFeed{
+			ID:    base + "/feed.atom",
+			Title: domain + " Go packages",
+			Links: []atomLink{
+				{Href: base + "/feed.atom", Rel: "self"},
+				{Href: base + "/"},
+			},
+			Author: atomAuthor{Name: domain},
+		}
+
+		log := recentChanges(domain)
+		if len(log) > 0 {
+			feed.Updated = log[0].Updated.UTC().Format(time.RFC3339)
+		} else {
+			feed.Updated = time.Now().UTC().Format(time.RFC3339)
+		}
+
+		for _, c := range log {
+			entry := atomEntry{
+				Updated: c.Updated.UTC().Format(time.RFC3339),
+				Link:    atomLink{Href: base + "/" + c.Repo.Repo},
+				Summary: c.Repo.Description,
+			}
+			if c.Added {
+				entry.ID = base + "/" + c.Repo.Repo
+				entry.Title = "New package: " + c.Repo.ImportPath()
+			} else {
+				entry.ID = base + "/" + c.Repo.Repo + "#" + entry.Updated
+				entry.Title = "Updated description: " + c.Repo.ImportPath()
+			}
+			feed.Entries = append(feed.Entries, entry)
+		}
+
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		w.Write([]byte(xml.Header))
+		xml.NewEncoder(w).Encode(feed)
+	})
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapHandler serves a sitemap of the index and each package's page
+func sitemapHandler(repos []Repo, domain string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "https://" + domain
+		set := sitemapURLSet{URLs: []sitemapURL{{Loc: base + "/"}}}
+
+		for _, repo := range repos {
+			u := sitemapURL{Loc: base + "/" + repo.Repo}
+			if t := lastChanged(repo); !t.IsZero() {
+				u.LastMod = t.UTC().Format("2006-01-02")
+			}
+			set.URLs = append(set.URLs, u)
+		}
+		sort.Slice(set.URLs[1:], func(i, j int) bool { return set.URLs[i+1].Loc < set.URLs[j+1].Loc })
+
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Write([]byte(xml.Header))
+		xml.NewEncoder(w).Encode(set)
+	})
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a-h/templ"
+)
+
+// linkStatus is the result of the last check of a repo's source URL
+type linkStatus struct {
+	OK      bool
+	Status  int // 0 if the request failed
+	Err     string
+	Checked time.Time
+}
+
+// repoLinks holds the latest linkStatus by source URL. Like repoMeta it's
+// outside the mux so results survive config reloads.
+var repoLinks sync.Map // source URL -> *linkStatus
+
+// LinkBroken reports whether the last check found the repo's source gone
+func (r Repo) LinkBroken() bool {
+	if s, ok := repoLinks.Load(r.URL()); ok {
+		return !s.(*linkStatus).OK
+	}
+	return false
+}
+
+// LinkChecker periodically checks that every repo's source URL resolves
+type LinkChecker struct {
+	HTTP  *http.Client
+	Every time.Duration
+
+	lg   *slog.Logger
+	kick chan struct{}
+}
+
+// NewLinkChecker creates a LinkChecker checking every interval
+func NewLinkChecker(every time.Duration, lg *slog.Logger) *LinkChecker {
+	return &LinkChecker{
+		HTTP:  &http.Client{Timeout: 15 * time.Second},
+		Every: every,
+		lg:    lg,
+		kick:  make(chan struct{}, 1),
+	}
+}
+
+// Kick asks for a check soon, e.g. after the config was reloaded
+func (c *LinkChecker) Kick() {
+	select {
+	case c.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Run checks repos() now, every c.Every, and on Kick until ctx is done
+func (c *LinkChecker) Run(ctx context.Context, repos func() []Repo) {
+	ticker := time.NewTicker(c.Every)
+	defer ticker.Stop()
+
+	for {
+		c.Check(ctx, repos())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.kick:
+		}
+	}
+}
+
+// Check checks each repo's source URL. A 4xx or a failed request marks it
+// broken; rate limiting and server errors say nothing about the repo, so
+// the previous result is kept.
+func (c *LinkChecker) Check(ctx context.Context, repos []Repo) {
+	repoLinkOK.Reset()
+	seen := make(map[string]bool)
+	for _, repo := range repos {
+		url := repo.URL()
+		if !seen[url] {
+			seen[url] = true
+			c.check(ctx, url)
+		}
+
+		ok := 1.0
+		if repo.LinkBroken() {
+			ok = 0
+		}
+		repoLinkOK.WithLabelValues(repo.Repo).Set(ok)
+	}
+	c.lg.Debug("checked repo links", "urls", len(seen))
+}
+
+func (c *LinkChecker) check(ctx context.Context, url string) {
+	status, err := c.head(ctx, url)
+	result := &linkStatus{Status: status, Checked: time.Now()}
+	switch {
+	case err != nil:
+		result.Err = err.Error()
+	case status == http.StatusTooManyRequests || status >= http.StatusInternalServerError:
+		linkChecks.WithLabelValues("unknown").Inc()
+		c.lg.Debug("can't tell if repo link works", "url", url, "status", status)
+		return
+	default:
+		result.OK = status < http.StatusBadRequest
+	}
+
+	if result.OK {
+		linkChecks.WithLabelValues("ok").Inc()
+	} else {
+		linkChecks.WithLabelValues("broken").Inc()
+		c.lg.Warn("repo link is broken", "url", url, "status", status, "err", result.Err)
+	}
+
+	prev, loaded := repoLinks.Swap(url, result)
+	if !loaded || prev.(*linkStatus).OK != result.OK {
+		// The index shows broken links
+		pageVersion.Add(1)
+	}
+}
+
+// head requests url, retrying with GET for servers that don't allow HEAD
+func (c *LinkChecker) head(ctx context.Context, url string) (int, error) {
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("User-Agent", "pkg.jsn.cam link checker")
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			return resp.StatusCode, nil
+		}
+	}
+	return http.StatusMethodNotAllowed, nil
+}
+
+// brokenLinksBanner warns about repos whose source can't be reached, or
+// renders nothing if they all can
+func brokenLinksBanner(repos []Repo) templ.Component {
+	var broken []string
+	for _, repo := range repos {
+		if repo.LinkBroken() {
+			broken = append(broken, fmt.Sprintf(`<a href="%s">%s</a>`,
+				html.EscapeString(repo.URL()), html.EscapeString(repo.Repo)))
+		}
+	}
+	if len(broken) == 0 {
+		return templ.NopComponent
+	}
+	return templ.Raw(`<p class="warning"><strong>Warning:</strong> the source of ` +
+		strings.Join(broken, ", ") + ` can't be reached, so go get may fail.</p>`)
+}
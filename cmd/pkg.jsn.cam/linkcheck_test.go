@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLinkChecker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/gone":
+			http.NotFound(w, r)
+		case "/nohead":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/flaky":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewLinkChecker(0, slog.Default())
+	ctx := context.Background()
+	for _, test := range []struct {
+		path   string
+		broken bool
+	}{
+		{"/ok", false},
+		{"/gone", true},
+		{"/nohead", false},
+	} {
+		url := srv.URL + test.path
+		c.check(ctx, url)
+		s, ok := repoLinks.Load(url)
+		if !ok {
+			t.Errorf("%s: no result", test.path)
+			continue
+		}
+		if got := !s.(*linkStatus).OK; got != test.broken {
+			t.Errorf("%s: broken = %v, want %v", test.path, got, test.broken)
+		}
+	}
+
+	// Server errors keep the previous result
+	flaky := srv.URL + "/flaky"
+	repoLinks.Store(flaky, &linkStatus{OK: true})
+	c.check(ctx, flaky)
+	if s, _ := repoLinks.Load(flaky); !s.(*linkStatus).OK {
+		t.Error("/flaky: a 503 marked the link broken")
+	}
+}
+
+func TestBrokenLinksBanner(t *testing.T) {
+	repo := Repo{Repo: "linkcheck-test", Domain: "example.com", User: "someone"}
+	render := func() string {
+		var buf bytes.Buffer
+		if err := brokenLinksBanner([]Repo{repo}).Render(context.Background(), &buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	if got := render(); got != "" {
+		t.Errorf("unchecked repo: got banner %q", got)
+	}
+	repoLinks.Store(repo.URL(), &linkStatus{OK: false, Status: http.StatusNotFound})
+	defer repoLinks.Delete(repo.URL())
+	if got := render(); !strings.Contains(got, "linkcheck-test") {
+		t.Errorf("broken repo: got banner %q", got)
+	}
+}
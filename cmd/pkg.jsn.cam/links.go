@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Link is a short link redirect, configured as [links."/name"]
+type Link struct {
+	URL string `toml:"url"`
+	// Domain is the vanity domain the link is served on, -domain if unset
+	Domain string `toml:"domain"`
+}
+
+// linkPath normalizes a [links] key to the path it's served at, reporting
+// false for keys that can't be used as one
+func linkPath(key string) (string, bool) {
+	path := "/" + strings.Trim(key, "/")
+	if path == "/" || strings.ContainsAny(path, "{}?# \t\n") {
+		return "", false
+	}
+	return path, true
+}
+
+// validLinkURL reports whether u is an absolute http(s) URL or a path on
+// this domain
+func validLinkURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	switch parsed.Scheme {
+	case "http", "https":
+		return parsed.Host != ""
+	case "":
+		return strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//")
+	}
+	return false
+}
+
+// registerLinks adds the short links to mux. Links can't shadow repos or
+// the site's own pages, only the not found or wildcard fallback.
+func registerLinks(mux *http.ServeMux, links map[string]Link, lg *slog.Logger) {
+	paths := make([]string, 0, len(links))
+	for path := range links {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		link := links[path]
+
+		_, pattern := mux.Handler(&http.Request{Method: http.MethodGet, URL: &url.URL{Path: path}})
+		if pattern != "/" {
+			lg.Error("link conflicts with another route, skipping", "path", path, "route", pattern)
+			continue
+		}
+
+		mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+			linkClicks.WithLabelValues(path).Inc()
+			http.Redirect(w, r, link.URL, http.StatusFound)
+		})
+		lg.Debug("registered link", "path", path, "url", link.URL)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"pkg.jsn.cam/jsn/internal/httpserver"
+)
+
+// listenFDsStart is the first file descriptor systemd passes
+const listenFDsStart = 3
+
+// Listen opens a listener for each comma-separated address in addrs:
+//
+//   - "host:port" or ":port" listens on TCP, both IPv4 and IPv6 for a
+//     wildcard host
+//   - "tcp4:host:port" or "tcp6:host:port" listens on one address family
+//     only, so "tcp6:[::]:2143" is IPv6-only
+//   - "unix:<path>" listens on a unix socket
+//
+// If any address fails the listeners opened so far are closed.
+func Listen(addrs string) ([]net.Listener, error) {
+	var lns []net.Listener
+	for addr := range strings.SplitSeq(addrs, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+
+		ln, err := listenAddr(addr)
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return nil, fmt.Errorf("failed to listen on %s: %v", addr, err)
+		}
+		lns = append(lns, ln)
+	}
+	if len(lns) == 0 {
+		return nil, errors.New("no listen addresses")
+	}
+	return lns, nil
+}
+
+func listenAddr(addr string) (net.Listener, error) {
+	ln, err := httpserver.Listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	// Let the reverse proxy's group connect
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if err := os.Chmod(path, 0o660); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to set socket permissions: %v", err)
+		}
+	}
+	return ln, nil
+}
+
+// ActivationListeners returns the sockets passed with the
+// LISTEN_PID/LISTEN_FDS protocol (sd_listen_fds(3)), or nil if there are
+// none
+func ActivationListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+
+	// Children shouldn't think the sockets are theirs
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	lns := make([]net.Listener, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "systemd-socket-"+strconv.Itoa(fd))
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to use socket %d from systemd: %v", fd, err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// Serve serves handler on every listener until ctx is done or any of them
+// fails, then shuts down gracefully
+func Serve(ctx context.Context, lns []net.Listener, handler http.Handler, lg *slog.Logger) error {
+	for _, ln := range lns {
+		lg.Info("listening", "addr", ln.Addr().String())
+	}
+	srv := &httpserver.Server{Handler: handler, Listeners: lns, Logger: lg}
+	return srv.ListenAndServe(ctx)
+}
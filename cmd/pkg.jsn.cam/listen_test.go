@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestListen(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "pkg.sock")
+	lns, err := Listen("127.0.0.1:0, tcp4:127.0.0.1:0, unix:" + sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, ln := range lns {
+			ln.Close()
+		}
+	}()
+
+	want := []string{"tcp", "tcp", "unix"}
+	if len(lns) != len(want) {
+		t.Fatalf("got %d listeners, want %d", len(lns), len(want))
+	}
+	for i, ln := range lns {
+		if got := ln.Addr().Network(); got != want[i] {
+			t.Errorf("listener %d: got %s, want %s", i, got, want[i])
+		}
+	}
+
+	if _, err := Listen("tcp4:[::1]:0"); err == nil {
+		t.Error("IPv6 address on tcp4: want error")
+	}
+	if _, err := Listen(" , "); err == nil {
+		t.Error("no addresses: want error")
+	}
+}
@@ -2,38 +2,107 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/a-h/templ"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
 	"pkg.jsn.cam/jsn/internal"
+	"pkg.jsn.cam/jsn/internal/health"
+	"pkg.jsn.cam/jsn/internal/httpserver"
+	"pkg.jsn.cam/jsn/internal/middleware"
+	"pkg.jsn.cam/jsn/internal/run"
+	jslog "pkg.jsn.cam/jsn/internal/slog"
 	"pkg.jsn.cam/jsn/jass"
 )
 
 //go:generate go tool templ generate
 
 var (
-	domain      = flag.String("domain", "pkg.jsn.cam", "domain this is run on")
-	port        = flag.String("port", "2143", "HTTP port to listen on")
-	metricsPort = flag.String("metrics-port", "9091", "Prometheus metrics HTTP port")
-	tomlConfig  = flag.String("config", "./config.toml", "TOML config file")
+	domain        = flag.String("domain", "pkg.jsn.cam", "domain this is run on, and the default for repos that don't set one")
+	port          = flag.String("port", "2143", "HTTP port to listen on")
+	listen        = flag.String("listen", "", "comma-separated addresses to listen on instead of -port: host:port, tcp4:host:port or tcp6:host:port for one address family, or unix:<path>; systemd activation sockets take precedence")
+	metricsPort   = flag.String("metrics-port", "9091", "Prometheus metrics HTTP port")
+	metricsListen = flag.String("metrics-listen", "", "comma-separated addresses for the metrics server instead of -metrics-port, as for -listen")
+	tomlConfig    = flag.String("config", "./config.toml", "TOML config file, or (env) to read the config from PKGJSN_* environment variables")
+	statsFile     = flag.String("stats-file", "", "file to keep go-get download counts in across restarts (empty keeps them in memory)")
+
+	webhookSecret = flag.String("webhook-secret", "", "GitHub webhook secret; enables POST /.jsn.webhook, which pulls the config's git checkout on push and reloads it")
+	configGitDir  = flag.String("config-git-dir", "", "git checkout -webhook-secret pulls (default the -config file's directory)")
+
+	metadataRefresh = flag.Duration("metadata-refresh", 0, "fetch descriptions, stars and archived status from provider APIs this often (0 disables)")
+	githubToken     = flag.String("github-token", "", "GitHub API token for -metadata-refresh, to avoid the anonymous rate limit")
+	linkCheck       = flag.Duration("link-check", 0, "check that each repo's source URL still resolves this often, warning on the index if not (0 disables)")
+
+	rateLimit       = flag.Float64("rate-limit", 0, "requests per second allowed per client IP (0 disables)")
+	rateBurst       = flag.Int("rate-burst", 20, "requests a client may make at once before -rate-limit applies")
+	rateAllow       = flag.String("rate-allow", "", "comma-separated IPs and CIDR prefixes exempt from -rate-limit")
+	clientIPHeader  = flag.String("client-ip-header", "", "header to read the client IP from behind a proxy, e.g. Fly-Client-IP; for lists like X-Forwarded-For, the last entry, which the proxy added")
+	requestIDHeader = flag.String("request-id-header", "", "header to take request IDs from behind a proxy, e.g. Fly-Request-Id; generated if unset")
+
+	modCache    = flag.String("mod-cache", "", "serve a caching GOPROXY for the vanity domains' modules at /mod/, keeping downloads in this directory (empty disables)")
+	modUpstream = flag.String("mod-upstream", "https://proxy.golang.org", "GOPROXY that -mod-cache fetches from")
+
+	sumDB         = flag.Bool("sumdb", false, "proxy the checksum database at /sumdb/ (and /mod/sumdb/ with -mod-cache)")
+	sumDBKey      = flag.String("sumdb-key", SumGolangOrgKey, "verifier key of the checksum database -sumdb proxies; responses not signed by it are rejected")
+	sumDBUpstream = flag.String("sumdb-upstream", "", "checksum database URL for -sumdb (default https://<name from -sumdb-key>)")
+
+	hstsMaxAge     = flag.Duration("hsts-max-age", 365*24*time.Hour, "Strict-Transport-Security max-age (0 disables)")
+	csp            = flag.String("csp", DefaultCSP, "Content-Security-Policy header (empty disables)")
+	referrerPolicy = flag.String("referrer-policy", "strict-origin-when-cross-origin", "Referrer-Policy header (empty disables)")
+
+	useACME       = flag.Bool("acme", false, "serve HTTPS on -tls-port with certificates from an ACME CA; -port then answers HTTP-01 challenges and redirects to HTTPS")
+	tlsPort       = flag.String("tls-port", "443", "HTTPS port to listen on with -acme")
+	acmeEmail     = flag.String("acme-email", "", "contact email for the ACME account")
+	acmeCache     = flag.String("acme-cache", "./acme-cache", "directory to keep ACME certificates and the account key in")
+	acmeDirectory = flag.String("acme-directory", acme.LetsEncryptURL, "ACME directory URL")
 )
 
 func main() {
 	internal.HandleStartup()
 
+	// Log lines for a request carry its ID
+	slog.SetDefault(slog.New(middleware.RequestIDHandler{Handler: slog.Default().Handler()}))
+
 	lg := slog.Default().With("domain", *domain, "configPath", *tomlConfig)
+	httpLog := logger("http")
 
 	// Resolve path relative to executable
 
 	configPath := *tomlConfig
 	lg.Debug("loading config", "path", configPath)
 
+	// The module proxy is mounted by NewMux, so it has to exist first
+	if *modCache != "" {
+		var err error
+		modProxy, err = NewModProxy(*modUpstream, *modCache, logger("modproxy"))
+		if err != nil {
+			lg.Error("can't set up module proxy", "err", err)
+			os.Exit(1)
+		}
+	}
+	if *sumDB {
+		var err error
+		sumDBProxy, err = NewSumDBProxy(*sumDBKey, *sumDBUpstream, logger("sumdb"))
+		if err != nil {
+			lg.Error("can't set up checksum database proxy", "err", err)
+			os.Exit(1)
+		}
+	}
+
 	// Load config and repositories from TOML file
-	config, err := LoadConfig(configPath, lg)
+	site, err := NewSite(configPath, httpLog)
 	if err != nil {
 		lg.Error("can't decode config at either path",
 			"path", configPath,
@@ -41,54 +110,293 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Build the list of repositories from the config
-	repos := BuildRepos(config, lg)
+	// Stop serving when asked to, then save the stats
+	ctx := run.Context()
 
-	// Debug logging for repos
-	lg.Debug("loaded repos", "count", len(repos))
-	for i, repo := range repos {
-		lg.Debug("loaded repo", "index", i, "repo", repo)
+	if *statsFile != "" {
+		if err := LoadStats(*statsFile); err != nil {
+			lg.Error("can't load stats", "path", *statsFile, "err", err)
+			os.Exit(1)
+		}
+
+		// Save the counts one last time once the server has stopped
+		statsCtx, stopStats := context.WithCancel(context.Background())
+		persisted := make(chan struct{})
+		go func() {
+			PersistStats(statsCtx, *statsFile, time.Minute, logger("stats"))
+			close(persisted)
+		}()
+		run.OnShutdown("stats", func(ctx context.Context) error {
+			stopStats()
+			select {
+			case <-persisted:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
 	}
 
-	mux := http.NewServeMux()
+	// Pick up config changes without dropping in-flight requests
+	go site.Watch(ctx)
+
+	if *metadataRefresh > 0 {
+		site.enricher = NewEnricher(*metadataRefresh, *githubToken, logger("metadata"))
+		go site.enricher.Run(ctx, site.Repos)
+	}
+	if *linkCheck > 0 {
+		site.linkChecker = NewLinkChecker(*linkCheck, logger("linkcheck"))
+		go site.linkChecker.Run(ctx, site.Repos)
+	}
+
+	registerHealthChecks(site)
+
+	// The health checks and webhook are the same on every vanity domain
+	root := http.NewServeMux()
+	root.Handle("GET /.jsn.health/live", health.Handler(health.Live))
+	root.Handle("GET /.jsn.health/ready", health.Handler(health.Ready))
+	if *webhookSecret != "" {
+		if !onDisk(configPath) {
+			lg.Error("-webhook-secret needs a config file on disk, not the embedded one or (env)")
+			os.Exit(1)
+		}
+		dir := cmp.Or(*configGitDir, filepath.Dir(configPath))
+		root.Handle("POST /.jsn.webhook", NewGitWebhook(*webhookSecret, dir, site, logger("webhook")))
+	}
+	root.Handle("/", site)
+	var app http.Handler = root
 
 	// Start metrics server on separate port
-	RegisterMetricsHandler(*metricsPort, lg)
+	RegisterMetricsHandler(ctx, cmp.Or(*metricsListen, ":"+*metricsPort), lg)
+
+	// Wrap the site with the metrics middleware, outside the rate limiter so
+	// rejected requests are counted too
+	inner := app
+	if *rateLimit > 0 {
+		allow, err := ParseAllowlist(*rateAllow)
+		if err != nil {
+			lg.Error("can't parse -rate-allow", "err", err)
+			os.Exit(1)
+		}
+		inner = (&RateLimiter{
+			Rate:   *rateLimit,
+			Burst:  max(*rateBurst, 1),
+			Allow:  allow,
+			Header: *clientIPHeader,
+		}).Middleware(app)
+		lg.Info("rate limiting", "rate", *rateLimit, "burst", *rateBurst, "allow", len(allow))
+	}
+	inner = SecurityHeaders{
+		HSTS:           *hstsMaxAge,
+		CSP:            *csp,
+		ReferrerPolicy: *referrerPolicy,
+	}.Middleware(inner)
+	handler := middleware.RequestIDMiddleware(*requestIDHeader, httpLog, MetricsMiddleware(middleware.RecoverMiddleware(httpLog, inner)))
+
+	if *useACME {
+		err = serveACME(ctx, handler, site.Domains, httpLog)
+	} else {
+		var lns []net.Listener
+		lns, err = ActivationListeners()
+		if err == nil && lns == nil {
+			lns, err = Listen(cmp.Or(*listen, ":"+*port))
+		}
+		if err == nil {
+			err = Serve(ctx, lns, handler, httpLog)
+		}
+	}
+	if err != nil {
+		lg.Error("can't start server", "err", err)
+	}
+	if serr := run.Shutdown(); err == nil {
+		err = serr
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// registerHealthChecks registers the checks that pkg.jsn.cam can serve:
+// a config with domains, and the storage and upstreams of the proxies and
+// stats when they're used
+func registerHealthChecks(site *Site) {
+	health.Register(health.Ready, "config", func(context.Context) error {
+		if len(site.Domains()) == 0 {
+			return errors.New("no domains configured")
+		}
+		return nil
+	})
+	if modProxy != nil {
+		health.Register(health.Ready, "mod-cache", health.WritableDir(modProxy.Dir))
+		health.Register(health.Ready, "mod-upstream", health.Reachable(modProxy.HTTP, modProxy.Upstream+"/"))
+	}
+	if sumDBProxy != nil {
+		health.Register(health.Ready, "sumdb-upstream", health.Reachable(sumDBProxy.HTTP, sumDBProxy.Upstream+"/latest"))
+	}
+	if *statsFile != "" {
+		health.Register(health.Ready, "stats-file", health.WritableDir(filepath.Dir(*statsFile)))
+	}
+}
+
+// logger returns the logger for a component of the server, see
+// jslog.Logger, whose lines for a request carry its ID.
+func logger(component string) *slog.Logger {
+	h := middleware.RequestIDHandler{Handler: jslog.Logger(component).Handler()}
+	return slog.New(h).With("domain", *domain, "configPath", *tomlConfig)
+}
+
+// serveACME serves handler over HTTPS with automatic certificates for the
+// hosts domains returns, and answers HTTP-01 challenges and redirects on
+// -port, until ctx is done. domains is asked on every new host, so domains
+// added by config reloads get certificates too.
+func serveACME(ctx context.Context, handler http.Handler, domains func() []string, lg *slog.Logger) error {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: domainPolicy(domains),
+		Email:      *acmeEmail,
+		Cache:      autocert.DirCache(*acmeCache),
+		Client:     &acme.Client{DirectoryURL: *acmeDirectory},
+	}
+
+	go func() {
+		lg.Info("listening", "port", *port, "acme", "http-01")
+		srv := &httpserver.Server{Addr: ":" + *port, Handler: m.HTTPHandler(nil), Logger: lg}
+		if err := srv.ListenAndServe(ctx); err != nil {
+			lg.Error("can't start ACME HTTP server", "err", err)
+		}
+	}()
+
+	srv := &httpserver.Server{
+		Addr:      ":" + *tlsPort,
+		Handler:   handler,
+		TLSConfig: m.TLSConfig(),
+		Logger:    lg,
+	}
+
+	lg.Info("listening", "port", *tlsPort, "tls", true)
+	return srv.ListenAndServe(ctx)
+}
+
+// domainPolicy allows certificates for the hosts domains returns at the time
+// they're asked for
+func domainPolicy(domains func() []string) autocert.HostPolicy {
+	return func(ctx context.Context, host string) error {
+		if !slices.Contains(domains(), host) {
+			return fmt.Errorf("acme: %q isn't a configured domain", host)
+		}
+		return nil
+	}
+}
+
+// NewMux builds the handlers for the vanity domain host, serving repos,
+// links and the configured pages. If hasWildcard is set, unconfigured names
+// are served from the wildcard provider instead of 404ing.
+func NewMux(host string, repos []Repo, links map[string]Link, pages Pages, wildcard Repo, hasWildcard bool, lg *slog.Logger) *http.ServeMux {
+	mux := http.NewServeMux()
 
 	// Register handlers for each repository
 	for _, repo := range repos {
-		repo.RegisterHandlers(mux, *domain, lg)
+		repo.RegisterHandlers(mux, host, lg)
 	}
 
 	jass.Mount(mux)
 
-	mux.Handle("/{$}", templ.Handler(
-		jass.Base(
-			fmt.Sprintf("%s Go packages", *domain),
-			nil,
+	index := newCachedPage(func(context.Context) templ.Component {
+		return jass.Base(
+			fmt.Sprintf("%s Go packages", host),
+			templ.Raw(`<link rel="alternate" type="application/atom+xml" href="/feed.atom">`),
 			nil,
-			Index(repos),
+			templ.Join(brokenLinksBanner(repos), Index(repos)),
 			footer(),
-		),
-	))
+		)
+	})
+	index.showsCounts = true
+	// Render it now rather than on the first request after a reload
+	if _, _, err := index.render(context.Background()); err != nil {
+		lg.Error("can't render index", "err", err)
+	}
+	mux.Handle("/{$}", index)
+
+	notFound := pages.notFoundHandler()
+	if hasWildcard {
+		notFound = WildcardHandler(wildcard, host, notFound)
+	}
+	mux.Handle("/", notFound)
 
-	mux.Handle("/", templ.Handler(
-		jass.Simple("Not found", NotFound()),
-		templ.WithStatus(http.StatusNotFound)),
-	)
+	pages.register(mux)
 
 	mux.Handle("/.jsn.botinfo", templ.Handler(
 		jass.Simple("jsn repo bots", BotInfo()),
 	))
 
-	lg.Info("listening", "port", *port)
+	mux.HandleFunc("/.jsn.stats", func(w http.ResponseWriter, r *http.Request) {
+		templ.Handler(
+			jass.Simple(fmt.Sprintf("%s download stats", host), StatsPage(snapshotStats())),
+		).ServeHTTP(w, r)
+	})
 
-	// Wrap the mux with the metrics middleware
-	handler := MetricsMiddleware(mux)
+	mux.Handle("GET /.jsn.tag/{tag...}", tagHandler(repos, notFound))
+	mux.Handle("GET /api/repos", reposAPI(repos, host))
+	mux.Handle("GET /api/stats", statsAPI())
+	mux.Handle("GET /sitemap.xml", sitemapHandler(repos, host))
+	mux.Handle("GET /feed.atom", atomHandler(host))
 
-	err = http.ListenAndServe(":"+*port, handler)
-	if err != nil {
-		lg.Error("can't start server", "err", err)
-		os.Exit(1)
+	if modProxy != nil {
+		mux.Handle("GET /mod/", modProxy.Handler("/mod/", host))
+	}
+	if sumDBProxy != nil {
+		// The go command looks for it under GOPROXY, so mount it next to
+		// the module proxy too
+		mux.Handle("GET /sumdb/", sumDBProxy.Handler("/sumdb/"))
+		if modProxy != nil {
+			mux.Handle("GET /mod/sumdb/", sumDBProxy.Handler("/mod/sumdb/"))
+		}
 	}
+
+	// Badges and link preview cards, so READMEs and previews don't need a
+	// third party
+	byName := make(map[string]Repo, len(repos))
+	cards := make(map[string]*cachedPage, len(repos))
+	for _, repo := range repos {
+		byName[repo.Repo] = repo
+		cards[repo.Repo] = &cachedPage{
+			component:   func(context.Context) templ.Component { return cardComponent(repo) },
+			contentType: "image/png",
+			showsCounts: true,
+		}
+	}
+	mux.Handle("GET /.jsn.badge/source", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveBadge(w, sourceBadge)
+	}))
+	mux.Handle("GET /.jsn.badge/{kind}/{repo...}", badgeHandler(byName))
+	mux.HandleFunc("GET /.jsn.card/{repo...}", func(w http.ResponseWriter, r *http.Request) {
+		card, ok := cards[r.PathValue("repo")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		card.ServeHTTP(w, r)
+	})
+
+	// go.mod deprecation and retraction notices for tooling
+	noticed := make(map[string]Repo)
+	for _, repo := range repos {
+		if repo.IsDeprecated() || len(repo.Retracted) > 0 {
+			noticed[repo.Repo] = repo
+		}
+	}
+	mux.HandleFunc("/.jsn.deprecated/{repo...}", func(w http.ResponseWriter, r *http.Request) {
+		repo, ok := noticed[r.PathValue("repo")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, repo.GoModNotice(host))
+	})
+
+	// Short links go last so they can't take over any of the above
+	registerLinks(mux, links, lg)
+
+	return mux
 }
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDomainPolicy(t *testing.T) {
+	domains := []string{"pkg.jsn.cam"}
+	policy := domainPolicy(func() []string { return domains })
+
+	if err := policy(context.Background(), "pkg.jsn.cam"); err != nil {
+		t.Errorf("configured domain: %v", err)
+	}
+	if err := policy(context.Background(), "new.jsn.cam"); err == nil {
+		t.Error("unconfigured domain allowed")
+	}
+
+	// Domains from a config reload get certificates without a restart
+	domains = append(domains, "new.jsn.cam")
+	if err := policy(context.Background(), "new.jsn.cam"); err != nil {
+		t.Errorf("domain added by a reload: %v", err)
+	}
+}
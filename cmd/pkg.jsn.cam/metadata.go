@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RepoMeta is what the provider's API says about a repository
+type RepoMeta struct {
+	Description string    `json:"description,omitempty"`
+	Stars       int       `json:"stars"`
+	LastCommit  time.Time `json:"last_commit,omitzero"`
+	Archived    bool      `json:"archived"`
+	Fetched     time.Time `json:"fetched"`
+}
+
+// String summarizes the metadata for the index page
+func (m *RepoMeta) String() string {
+	s := fmt.Sprintf("★ %d", m.Stars)
+	if !m.LastCommit.IsZero() {
+		s += " · last commit " + m.LastCommit.Format("2006-01-02")
+	}
+	return s
+}
+
+// repoMeta caches fetched metadata by source URL. Entries are replaced,
+// never modified, so readers can use them without locking.
+var repoMeta sync.Map // source URL -> *RepoMeta
+
+// Metadata returns the fetched metadata for the repo, or nil
+func (r Repo) Metadata() *RepoMeta {
+	if m, ok := repoMeta.Load(r.URL()); ok {
+		return m.(*RepoMeta)
+	}
+	return nil
+}
+
+// moduleVersions caches each module's latest version by import path. Unlike
+// repoMeta it's per module, as nested modules are versioned on their own.
+var moduleVersions sync.Map // import path -> string
+
+// LatestVersion returns the module's latest version as reported by the
+// module proxy, or "" if it hasn't been fetched
+func (r Repo) LatestVersion() string {
+	if v, ok := moduleVersions.Load(r.ImportPath()); ok {
+		return v.(string)
+	}
+	return ""
+}
+
+// Summary returns the provider's description if one was fetched, falling
+// back to the one in the config
+func (r Repo) Summary() string {
+	if m := r.Metadata(); m != nil && m.Description != "" {
+		return m.Description
+	}
+	return r.Description
+}
+
+// Enricher periodically fetches repo metadata from the provider APIs
+type Enricher struct {
+	HTTP        *http.Client
+	GitHubToken string
+	Every       time.Duration
+
+	lg   *slog.Logger
+	kick chan struct{}
+}
+
+// NewEnricher creates an Enricher refreshing every interval
+func NewEnricher(every time.Duration, githubToken string, lg *slog.Logger) *Enricher {
+	return &Enricher{
+		HTTP:        &http.Client{Timeout: 15 * time.Second},
+		GitHubToken: githubToken,
+		Every:       every,
+		lg:          lg,
+		kick:        make(chan struct{}, 1),
+	}
+}
+
+// Kick asks for a refresh soon, e.g. after the config was reloaded
+func (e *Enricher) Kick() {
+	select {
+	case e.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Run refreshes the metadata of repos() now, every e.Every, and on Kick
+// until ctx is done
+func (e *Enricher) Run(ctx context.Context, repos func() []Repo) {
+	ticker := time.NewTicker(e.Every)
+	defer ticker.Stop()
+
+	for {
+		e.Refresh(ctx, repos())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-e.kick:
+		}
+	}
+}
+
+// Refresh fetches metadata for every repo, keeping the previous metadata
+// for repos whose provider couldn't be reached
+func (e *Enricher) Refresh(ctx context.Context, repos []Repo) {
+	seen := make(map[string]bool)
+	for _, repo := range repos {
+		if v, err := e.latestVersion(ctx, repo); err != nil {
+			e.lg.Debug("can't fetch latest version", "repo", repo, "err", err)
+		} else if old := repo.LatestVersion(); v != old {
+			moduleVersions.Store(repo.ImportPath(), v)
+			pageVersion.Add(1)
+		}
+
+		// Nested modules share their repository's metadata
+		key := repo.URL()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		meta, err := e.fetch(ctx, repo)
+		if err != nil {
+			e.lg.Error("can't fetch repo metadata", "repo", repo, "err", err)
+			continue
+		}
+		repoMeta.Store(key, meta)
+		pageVersion.Add(1)
+	}
+	e.lg.Debug("refreshed repo metadata", "repos", len(seen))
+}
+
+func (e *Enricher) fetch(ctx context.Context, repo Repo) (*RepoMeta, error) {
+	meta := &RepoMeta{Fetched: time.Now()}
+
+	switch repo.Kind {
+	case "github":
+		api := "https://api.github.com"
+		if repo.Domain != "github.com" {
+			api = "https://" + repo.Domain + "/api/v3" // GitHub Enterprise
+		}
+
+		var resp struct {
+			Description string    `json:"description"`
+			Stars       int       `json:"stargazers_count"`
+			PushedAt    time.Time `json:"pushed_at"`
+			Archived    bool      `json:"archived"`
+		}
+		if err := e.get(ctx, api+"/repos/"+repo.User+"/"+repo.source(), e.GitHubToken, &resp); err != nil {
+			return nil, err
+		}
+		meta.Description, meta.Stars, meta.LastCommit, meta.Archived = resp.Description, resp.Stars, resp.PushedAt, resp.Archived
+
+	case "gitea":
+		var resp struct {
+			Description string    `json:"description"`
+			Stars       int       `json:"stars_count"`
+			UpdatedAt   time.Time `json:"updated_at"`
+			Archived    bool      `json:"archived"`
+		}
+		if err := e.get(ctx, "https://"+repo.Domain+"/api/v1/repos/"+repo.User+"/"+repo.source(), "", &resp); err != nil {
+			return nil, err
+		}
+		meta.Description, meta.Stars, meta.LastCommit, meta.Archived = resp.Description, resp.Stars, resp.UpdatedAt, resp.Archived
+
+	case "gitlab":
+		var resp struct {
+			Description    string    `json:"description"`
+			Stars          int       `json:"star_count"`
+			LastActivityAt time.Time `json:"last_activity_at"`
+			Archived       bool      `json:"archived"`
+		}
+		project := url.PathEscape(repo.User + "/" + repo.source())
+		if err := e.get(ctx, "https://"+repo.Domain+"/api/v4/projects/"+project, "", &resp); err != nil {
+			return nil, err
+		}
+		meta.Description, meta.Stars, meta.LastCommit, meta.Archived = resp.Description, resp.Stars, resp.LastActivityAt, resp.Archived
+
+	default:
+		return nil, fmt.Errorf("no metadata API for provider %q", repo.Kind)
+	}
+
+	meta.Description = strings.TrimSpace(meta.Description)
+	return meta, nil
+}
+
+// latestVersion asks the module proxy (-mod-upstream) for the module's
+// latest version
+func (e *Enricher) latestVersion(ctx context.Context, repo Repo) (string, error) {
+	var resp struct {
+		Version string `json:"Version"`
+	}
+	u := strings.TrimSuffix(*modUpstream, "/") + "/" + escapeModulePath(repo.ImportPath()) + "/@latest"
+	if err := e.get(ctx, u, "", &resp); err != nil {
+		return "", err
+	}
+	return resp.Version, nil
+}
+
+// escapeModulePath case-encodes a module path for the GOPROXY protocol:
+// capitals become "!" and the lower case letter
+func escapeModulePath(path string) string {
+	var b strings.Builder
+	for _, c := range path {
+		if 'A' <= c && c <= 'Z' {
+			b.WriteByte('!')
+			c += 'a' - 'A'
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (e *Enricher) get(ctx context.Context, url, token string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "pkg.jsn.cam")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := e.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -2,29 +2,22 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"pkg.jsn.cam/jsn/internal/metrics"
 )
 
 var (
-	// totalRequests tracks the total number of requests to the server
-	totalRequests = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "requests_total",
-		Help: "The total number of requests to the pkg.jsn.cam server",
-	})
-
-	// requestDuration tracks the duration of requests
-	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "request_duration_seconds",
-		Help:    "The duration of requests to the pkg.jsn.cam server",
-		Buckets: prometheus.DefBuckets,
-	}, []string{"path"})
+	// requestMetrics are the requests, their durations and response sizes,
+	// by method, code and first path segment, which is the repo or link for
+	// most requests
+	requestMetrics = metrics.New(metrics.Options{Path: metrics.FirstSegment})
 
 	// repoRequests tracks the number of requests per repository
 	repoRequests = promauto.NewCounterVec(prometheus.CounterOpts{
@@ -32,25 +25,73 @@ var (
 		Help: "The total number of requests per repository",
 	}, []string{"repo"})
 
-	// httpResponseCodes tracks the HTTP response codes
-	httpResponseCodes = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "http_response_codes_total",
-		Help: "The total number of HTTP response codes",
-	}, []string{"code"})
-
 	// goGetRequests tracks the number of go-get=1 requests (actual Go tool downloads)
 	goGetRequests = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "goget_requests_total",
 		Help: "The total number of go-get=1 requests (actual Go tool downloads)",
 	})
+
+	// goGetClientRequests tracks go-get=1 requests to repos by client and Go release
+	goGetClientRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "goget_client_requests_total",
+		Help: "The total number of go-get=1 requests to repos by client (proxy.golang.org, go, ...) and Go release",
+	}, []string{"client", "go_version"})
+
+	// modProxyRequests tracks module proxy requests by how they were served
+	modProxyRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mod_proxy_requests_total",
+		Help: "The total number of module proxy requests by result (hit, miss, stale, not_found, error)",
+	}, []string{"result"})
+
+	// linkClicks tracks the number of times each short link was followed
+	linkClicks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "link_clicks_total",
+		Help: "The total number of short link redirects per link",
+	}, []string{"link"})
+
+	// rateLimitedRequests tracks the number of requests rejected with 429
+	rateLimitedRequests = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rate_limited_requests_total",
+		Help: "The total number of requests rejected by the rate limiter",
+	})
+
+	// sumDBRejected tracks checksum database responses that failed verification
+	sumDBRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "sumdb_rejected_total",
+		Help: "The total number of checksum database responses not signed by the pinned key",
+	})
+
+	// nonCanonicalRequests tracks requests that used the wrong host for a
+	// vanity domain
+	nonCanonicalRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "non_canonical_requests_total",
+		Help: "The total number of requests using a non-canonical host, by canonical domain and kind (go-get, browser)",
+	}, []string{"canonical", "kind"})
+
+	// repoLinkOK tracks whether each repo's source URL resolved when last
+	// checked
+	repoLinkOK = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "repo_link_ok",
+		Help: "Whether the repo's source URL resolved when last checked (1) or not (0)",
+	}, []string{"repo"})
+
+	// linkChecks tracks repo link checks by result
+	linkChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "link_checks_total",
+		Help: "The total number of repo link checks by result (ok, broken, unknown)",
+	}, []string{"result"})
+
+	// webhookRequests tracks config webhook deliveries by outcome
+	webhookRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_requests_total",
+		Help: "The total number of config webhook requests by result (reloaded, unchanged, ignored, bad_signature, error)",
+	}, []string{"result"})
 )
 
 // MetricsMiddleware wraps an http.Handler and records metrics for each request
 func MetricsMiddleware(next http.Handler) http.Handler {
+	next = requestMetrics.Wrap(next)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Track total requests
-		totalRequests.Inc()
-
 		// Track if this is a go-get=1 request (actual Go tool download)
 		if r.FormValue("go-get") == "1" {
 			goGetRequests.Inc()
@@ -71,15 +112,8 @@ func MetricsMiddleware(next http.Handler) http.Handler {
 
 		// Create a response writer wrapper to capture the status code
 		wrw := newResponseWriterWrapper(w)
-
-		// Track request duration
-		start := time.Now()
 		next.ServeHTTP(wrw, r)
-		duration := time.Since(start).Seconds()
-
-		// Record metrics
-		requestDuration.WithLabelValues(path).Observe(duration)
-		httpResponseCodes.WithLabelValues(wrw.statusCode()).Inc()
+		countRequest(wrw.code)
 	})
 }
 
@@ -100,21 +134,19 @@ func (rww *responseWriterWrapper) WriteHeader(code int) {
 	rww.ResponseWriter.WriteHeader(code)
 }
 
-// statusCode returns the captured status code as a string
-func (rww *responseWriterWrapper) statusCode() string {
-	return http.StatusText(rww.code)
-}
-
-// RegisterMetricsHandler starts a separate HTTP server for metrics
-func RegisterMetricsHandler(port string, lg *slog.Logger) {
+// RegisterMetricsHandler starts a separate HTTP server for metrics, until
+// ctx is done
+func RegisterMetricsHandler(ctx context.Context, addrs string, lg *slog.Logger) {
 	// Create a new mux for metrics
 	metricsMux := http.NewServeMux()
 	metricsMux.Handle("/metrics", promhttp.Handler())
 
 	// Start the metrics server in a goroutine
 	go func() {
-		lg.Info("starting metrics server", "port", port, "path", "/metrics")
-		err := http.ListenAndServe(":"+port, metricsMux)
+		lns, err := Listen(addrs)
+		if err == nil {
+			err = Serve(ctx, lns, metricsMux, lg.With("server", "metrics"))
+		}
 		if err != nil {
 			lg.Error("metrics server failed", "err", err)
 		}
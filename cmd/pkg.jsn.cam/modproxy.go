@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"pkg.jsn.cam/jsn/internal/middleware"
+)
+
+// ModProxy serves the GOPROXY protocol for modules on a vanity domain by
+// fetching from an upstream proxy and keeping everything it fetched on
+// disk. Versions are immutable so they're served from the cache forever;
+// version lists and @latest are refetched but fall back to the cached copy
+// when the upstream can't be reached, so builds keep working while the
+// forge or the upstream is down.
+type ModProxy struct {
+	// Upstream is the GOPROXY URL to fetch from
+	Upstream string
+	// Dir is the cache directory
+	Dir  string
+	HTTP *http.Client
+	lg   *slog.Logger
+}
+
+// modProxy is set by -mod-cache
+var modProxy *ModProxy
+
+// NewModProxy creates a ModProxy caching in dir
+func NewModProxy(upstream, dir string, lg *slog.Logger) (*ModProxy, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create module cache: %v", err)
+	}
+	return &ModProxy{
+		Upstream: strings.TrimSuffix(upstream, "/"),
+		Dir:      dir,
+		HTTP:     &http.Client{Timeout: 2 * time.Minute},
+		lg:       lg,
+	}, nil
+}
+
+var (
+	// Module paths and versions arrive case-encoded, so they're lower case
+	// with ! marking capitals
+	modPathRe    = regexp.MustCompile(`^[a-z0-9.\-_~!+]+(/[a-z0-9.\-_~!+]+)*$`)
+	modVersionRe = regexp.MustCompile(`^[a-z0-9.\-_~!+]+\.(info|mod|zip)$`)
+)
+
+// Handler serves requests under prefix for modules within the vanity
+// domain host, e.g. /mod/pkg.jsn.cam/jsn/@v/list
+func (p *ModProxy) Handler(prefix, host string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, prefix)
+
+		var module, file string
+		var immutable bool
+		if m, ok := strings.CutSuffix(rest, "/@latest"); ok {
+			module, file = m, "@latest"
+		} else if m, f, ok := strings.Cut(rest, "/@v/"); ok {
+			module, file = m, f
+			immutable = f != "list"
+			if immutable && !modVersionRe.MatchString(f) {
+				http.NotFound(w, r)
+				return
+			}
+		} else {
+			http.NotFound(w, r)
+			return
+		}
+
+		if !modPathRe.MatchString(module) || strings.Contains(module, "..") || !strings.HasPrefix(module+"/", host+"/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		urlPath := module + "/@v/" + file
+		if file == "@latest" {
+			urlPath = module + "/@latest"
+		}
+		cached := filepath.Join(p.Dir, filepath.FromSlash(urlPath))
+
+		if immutable {
+			if _, err := os.Stat(cached); err == nil {
+				modProxyRequests.WithLabelValues("hit").Inc()
+				p.serve(w, r, cached)
+				return
+			}
+		}
+
+		status, err := p.fetch(r, urlPath, cached)
+		switch {
+		case err == nil:
+			modProxyRequests.WithLabelValues("miss").Inc()
+			p.serve(w, r, cached)
+		case status == http.StatusNotFound || status == http.StatusGone:
+			// Let the go command move on to the next proxy
+			modProxyRequests.WithLabelValues("not_found").Inc()
+			http.Error(w, "not found", http.StatusNotFound)
+		case !immutable && fileExists(cached):
+			modProxyRequests.WithLabelValues("stale").Inc()
+			p.lg.WarnContext(r.Context(), "serving cached module data, upstream failed", "path", urlPath, "err", err)
+			p.serve(w, r, cached)
+		default:
+			modProxyRequests.WithLabelValues("error").Inc()
+			p.lg.ErrorContext(r.Context(), "can't fetch module data", "path", urlPath, "err", err)
+			middleware.Error(w, r, "upstream unavailable", http.StatusBadGateway)
+		}
+	})
+}
+
+// fetch downloads urlPath from the upstream into the file dst, returning
+// the upstream status code along with any error
+func (p *ModProxy) fetch(r *http.Request, urlPath, dst string) (int, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, p.Upstream+"/"+urlPath, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "pkg.jsn.cam")
+
+	resp, err := p.HTTP.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("GET %s: %s", req.URL, resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return resp.StatusCode, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".download-*")
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return resp.StatusCode, fmt.Errorf("failed to download %s: %v", req.URL, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, os.Rename(tmp.Name(), dst)
+}
+
+func (p *ModProxy) serve(w http.ResponseWriter, r *http.Request, path string) {
+	switch {
+	case strings.HasSuffix(path, ".info"), strings.HasSuffix(path, "@latest"):
+		w.Header().Set("Content-Type", "application/json")
+	case strings.HasSuffix(path, ".zip"):
+		w.Header().Set("Content-Type", "application/zip")
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	http.ServeFile(w, r, path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
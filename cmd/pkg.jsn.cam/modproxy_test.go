@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestModProxy(t *testing.T) {
+	var up atomic.Bool
+	up.Store(true)
+	var fetches atomic.Int32
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if !up.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/pkg.jsn.cam/jsn/@v/list":
+			io.WriteString(w, "v1.0.0\n")
+		case "/pkg.jsn.cam/jsn/@v/v1.0.0.mod":
+			io.WriteString(w, "module pkg.jsn.cam/jsn\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	p, err := NewModProxy(upstream.URL, t.TempDir(), slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := p.Handler("/mod/", "pkg.jsn.cam")
+
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	if code, body := get("/mod/pkg.jsn.cam/jsn/@v/v1.0.0.mod"); code != http.StatusOK || body != "module pkg.jsn.cam/jsn\n" {
+		t.Errorf("mod: got %d %q", code, body)
+	}
+	if code, _ := get("/mod/pkg.jsn.cam/jsn/@v/list"); code != http.StatusOK {
+		t.Errorf("list: got %d", code)
+	}
+	if code, _ := get("/mod/pkg.jsn.cam/jsn/@v/v2.0.0.info"); code != http.StatusNotFound {
+		t.Errorf("missing version: got %d, want 404", code)
+	}
+
+	// With the upstream down, versions come from the cache and lists are stale
+	up.Store(false)
+	before := fetches.Load()
+	if code, _ := get("/mod/pkg.jsn.cam/jsn/@v/v1.0.0.mod"); code != http.StatusOK {
+		t.Errorf("cached mod: got %d", code)
+	}
+	if fetches.Load() != before {
+		t.Error("cached version was refetched")
+	}
+	if code, body := get("/mod/pkg.jsn.cam/jsn/@v/list"); code != http.StatusOK || body != "v1.0.0\n" {
+		t.Errorf("stale list: got %d %q", code, body)
+	}
+	if code, _ := get("/mod/pkg.jsn.cam/other/@latest"); code != http.StatusBadGateway {
+		t.Errorf("uncached latest with upstream down: got %d, want 502", code)
+	}
+
+	// Only the vanity domain's modules are proxied
+	for _, path := range []string{
+		"/mod/github.com/x/y/@v/list",
+		"/mod/pkg.jsn.cam.evil.com/x/@v/list",
+		"/mod/pkg.jsn.cam/../etc/@v/list",
+		"/mod/pkg.jsn.cam/jsn/@v/v1.0.0.exe",
+	} {
+		if code, _ := get(path); code != http.StatusNotFound {
+			t.Errorf("%s: got %d, want 404", path, code)
+		}
+	}
+}
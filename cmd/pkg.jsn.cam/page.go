@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/a-h/templ"
+	"pkg.jsn.cam/jsn/internal/middleware"
+)
+
+// pageVersion is bumped whenever something the pages show changes between
+// config reloads: READMEs and provider metadata
+var pageVersion atomic.Uint64
+
+// countsVersion is bumped whenever download counts change. Only the pages
+// that show them, like the index, are rendered again for it, as go get
+// requests are most of the traffic.
+var countsVersion atomic.Uint64
+
+// cachedPage serves a page from memory, rendering it again only when
+// pageVersion, or countsVersion for pages with showsCounts, has moved since. Each mux builds its own pages, so a config
+// reload starts them over. Pages carry a strong ETag hashed from the body
+// and matching If-None-Match requests get a 304.
+type cachedPage struct {
+	component func(ctx context.Context) templ.Component
+	// contentType is text/html unless set
+	contentType string
+	// showsCounts is set for pages showing download counts
+	showsCounts bool
+
+	mu       sync.Mutex
+	rendered bool
+	version  uint64
+	body     []byte
+	etag     string
+}
+
+func newCachedPage(component func(ctx context.Context) templ.Component) *cachedPage {
+	return &cachedPage{component: component}
+}
+
+// render returns the page and its ETag, rendering it if it's out of date
+func (p *cachedPage) render(ctx context.Context) ([]byte, string, error) {
+	// Load the version first so a change during rendering isn't missed. Both
+	// only go up, so their sum changes whenever either does.
+	v := pageVersion.Load()
+	if p.showsCounts {
+		v += countsVersion.Load()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rendered && p.version == v {
+		return p.body, p.etag, nil
+	}
+
+	var buf bytes.Buffer
+	if err := p.component(ctx).Render(ctx, &buf); err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	p.body, p.etag = buf.Bytes(), `"`+hex.EncodeToString(sum[:16])+`"`
+	p.rendered, p.version = true, v
+	return p.body, p.etag, nil
+}
+
+// ServeHTTP implements http.Handler
+func (p *cachedPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, etag, err := p.render(r.Context())
+	if err != nil {
+		middleware.Error(w, r, "can't render page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", cmp.Or(p.contentType, "text/html; charset=utf-8"))
+	w.Header().Set("ETag", etag)
+	// ServeContent handles If-None-Match, HEAD and Range
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
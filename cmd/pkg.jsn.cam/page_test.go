@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a-h/templ"
+)
+
+func TestCachedPage(t *testing.T) {
+	text, renders := "hello", 0
+	page := newCachedPage(func(context.Context) templ.Component {
+		renders++
+		return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+			_, err := io.WriteString(w, text)
+			return err
+		})
+	})
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		page.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || first.Body.String() != "hello" || etag == "" {
+		t.Fatalf("got %d %q with ETag %q", first.Code, first.Body, etag)
+	}
+
+	if rec := get(etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("matching If-None-Match: got %d %q, want empty 304", rec.Code, rec.Body)
+	}
+
+	// Served from memory until something on the page changes
+	text = "changed"
+	if rec := get(""); rec.Body.String() != "hello" || renders != 1 {
+		t.Errorf("got %q after %d renders, want the cached page", rec.Body, renders)
+	}
+
+	pageVersion.Add(1)
+	if rec := get(etag); rec.Code != http.StatusOK || rec.Body.String() != "changed" || rec.Header().Get("ETag") == etag {
+		t.Errorf("after a change: got %d %q with ETag %q", rec.Code, rec.Body, rec.Header().Get("ETag"))
+	}
+}
+
+func TestCachedPageGoGet(t *testing.T) {
+	var detailRenders, indexRenders int
+	component := func(n *int) func(context.Context) templ.Component {
+		return func(context.Context) templ.Component {
+			*n++
+			return templ.Raw("page")
+		}
+	}
+	detail := newCachedPage(component(&detailRenders))
+	index := newCachedPage(component(&indexRenders))
+	index.showsCounts = true
+	goGet := countGoGets("test/gogetpage", http.NotFoundHandler())
+
+	for range 3 {
+		goGet.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/gogetpage?go-get=1", nil))
+		for _, page := range []*cachedPage{detail, index} {
+			page.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+	}
+	// Only the index shows the download counts that go get changes
+	if detailRenders != 1 || indexRenders != 3 {
+		t.Errorf("detail page rendered %d times, index %d; want 1 and 3", detailRenders, indexRenders)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/a-h/templ"
+	"pkg.jsn.cam/jsn/internal/markdown"
+	"pkg.jsn.cam/jsn/jass"
+)
+
+// Pages is the [pages] table of the config
+type Pages struct {
+	// NotFound is Markdown shown on 404 pages instead of the default text
+	NotFound string `toml:"not_found"`
+	// Robots is served as /robots.txt if set
+	Robots string `toml:"robots"`
+	// WellKnown maps names under /.well-known/, e.g. "security.txt", to
+	// their contents
+	WellKnown map[string]string `toml:"well_known"`
+}
+
+// validWellKnown reports whether name can be served under /.well-known/
+func validWellKnown(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\?#")
+}
+
+// notFoundHandler serves the 404 page, with the configured text if any
+func (p Pages) notFoundHandler() http.Handler {
+	body := NotFound()
+	if p.NotFound != "" {
+		body = templ.Raw("<section>" + markdown.Render(p.NotFound, "", "", "") + "</section>")
+	}
+	return templ.Handler(
+		jass.Simple("Not found", body),
+		templ.WithStatus(http.StatusNotFound),
+	)
+}
+
+// register adds /robots.txt and the /.well-known/ files to mux
+func (p Pages) register(mux *http.ServeMux) {
+	if p.Robots != "" {
+		mux.Handle("GET /robots.txt", textFile("text/plain; charset=utf-8", p.Robots))
+	}
+	for name, content := range p.WellKnown {
+		ctype := mime.TypeByExtension(path.Ext(name))
+		if ctype == "" {
+			ctype = "text/plain; charset=utf-8"
+		}
+		mux.Handle("GET /.well-known/"+name, textFile(ctype, content))
+	}
+}
+
+func textFile(ctype, content string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ctype)
+		w.Write([]byte(content))
+	})
+}
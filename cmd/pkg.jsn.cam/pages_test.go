@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPages(t *testing.T) {
+	pages := Pages{
+		NotFound:  "Nothing *here*.",
+		Robots:    "User-agent: *\nDisallow: /mod/\n",
+		WellKnown: map[string]string{"security.txt": "Contact: mailto:a@example.com\n", "assetlinks.json": "[]"},
+	}
+	mux := http.NewServeMux()
+	pages.register(mux)
+	mux.Handle("/", pages.notFoundHandler())
+
+	for _, tt := range []struct {
+		path, ctype, body string
+		code              int
+	}{
+		{"/robots.txt", "text/plain; charset=utf-8", "Disallow: /mod/", http.StatusOK},
+		{"/.well-known/security.txt", "text/plain; charset=utf-8", "Contact:", http.StatusOK},
+		{"/.well-known/assetlinks.json", "application/json", "[]", http.StatusOK},
+		{"/missing", "text/html; charset=utf-8", "Nothing <em>here</em>.", http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.code || rec.Header().Get("Content-Type") != tt.ctype || !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("%s: got %d %s %q", tt.path, rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+		}
+	}
+
+	for _, name := range []string{"", "..", "a/b"} {
+		if validWellKnown(name) {
+			t.Errorf("validWellKnown(%q) = true", name)
+		}
+	}
+}
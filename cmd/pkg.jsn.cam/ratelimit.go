@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a per-client token bucket rate limiter. IPv6 clients are
+// limited per /64, since they usually get a whole one to rotate through.
+type RateLimiter struct {
+	// Rate is how many requests per second each client may make
+	Rate float64
+	// Burst is how many requests a client may make at once
+	Burst int
+	// Allow lists clients that are never limited
+	Allow []netip.Prefix
+	// Header, if set, is the header the client IP is taken from, for
+	// running behind a proxy such as Fly's (Fly-Client-IP). For a list like
+	// X-Forwarded-For, the last entry is used: it's the one the proxy in
+	// front added, the ones before it come from the client.
+	Header string
+
+	mu        sync.Mutex
+	buckets   map[netip.Prefix]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// ParseAllowlist parses a comma-separated list of IPs and CIDR prefixes
+func ParseAllowlist(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if strings.Contains(item, "/") {
+			p, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse allowlist entry %q: %v", item, err)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse allowlist entry %q: %v", item, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// Middleware rejects requests from clients over their rate with 429 Too
+// Many Requests
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr, ok := l.clientIP(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if wait, ok := l.allow(addr, time.Now()); !ok {
+			rateLimitedRequests.Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the address requests from r are counted against
+func (l *RateLimiter) clientIP(r *http.Request) (netip.Addr, bool) {
+	if vs := r.Header.Values(l.Header); l.Header != "" && len(vs) > 0 {
+		// Only the last entry of a list is from the proxy
+		v := vs[len(vs)-1]
+		v = v[strings.LastIndex(v, ",")+1:]
+		if addr, err := netip.ParseAddr(strings.TrimSpace(v)); err == nil {
+			return addr.Unmap(), true
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// allow takes a token for addr, returning how long until one is available
+// if there are none left
+func (l *RateLimiter) allow(addr netip.Addr, now time.Time) (time.Duration, bool) {
+	for _, p := range l.Allow {
+		if p.Contains(addr) {
+			return 0, true
+		}
+	}
+
+	key := netip.PrefixFrom(addr, addr.BitLen())
+	if addr.Is6() {
+		key, _ = addr.Prefix(64)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = make(map[netip.Prefix]*bucket)
+	}
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.Rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// sweep forgets clients whose buckets have refilled, at most once a minute
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	full := time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, key)
+		}
+	}
+}
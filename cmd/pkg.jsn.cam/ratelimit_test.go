@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := &RateLimiter{Rate: 1, Burst: 2}
+	now := time.Now()
+	a := netip.MustParseAddr("192.0.2.1")
+
+	for i := range 2 {
+		if _, ok := l.allow(a, now); !ok {
+			t.Fatalf("request %d within burst was limited", i)
+		}
+	}
+	wait, ok := l.allow(a, now)
+	if ok {
+		t.Fatal("request over burst was allowed")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("wait = %v, want (0, 1s]", wait)
+	}
+
+	if _, ok := l.allow(netip.MustParseAddr("192.0.2.2"), now); !ok {
+		t.Error("other client was limited")
+	}
+	if _, ok := l.allow(a, now.Add(time.Second)); !ok {
+		t.Error("request after refill was limited")
+	}
+
+	// Addresses in the same IPv6 /64 share a bucket
+	for _, s := range []string{"2001:db8::1", "2001:db8::2"} {
+		l.allow(netip.MustParseAddr(s), now)
+	}
+	if _, ok := l.allow(netip.MustParseAddr("2001:db8::3"), now); ok {
+		t.Error("IPv6 /64 wasn't limited as one client")
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	allow, err := ParseAllowlist("198.51.100.0/24, 203.0.113.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &RateLimiter{Rate: 1, Burst: 1, Allow: allow, Header: "Fly-Client-IP"}
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	get := func(remote, header string) int {
+		req := httptest.NewRequest(http.MethodGet, "/jsn?go-get=1", nil)
+		req.RemoteAddr = remote
+		if header != "" {
+			req.Header.Set("Fly-Client-IP", header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get("192.0.2.1:1234", ""); code != http.StatusOK {
+		t.Errorf("first request: got %d", code)
+	}
+	if code := get("192.0.2.1:1234", ""); code != http.StatusTooManyRequests {
+		t.Errorf("second request: got %d, want 429", code)
+	}
+
+	// Behind the proxy, clients are told apart by the header
+	if code := get("192.0.2.1:1234", "192.0.2.50"); code != http.StatusOK {
+		t.Errorf("proxied request: got %d", code)
+	}
+
+	for range 3 {
+		if code := get("198.51.100.9:1234", ""); code != http.StatusOK {
+			t.Errorf("allowlisted prefix: got %d", code)
+		}
+		if code := get("203.0.113.7:1234", ""); code != http.StatusOK {
+			t.Errorf("allowlisted IP: got %d", code)
+		}
+	}
+}
+
+func TestRateLimiterForwardedFor(t *testing.T) {
+	l := &RateLimiter{Rate: 1, Burst: 1, Header: "X-Forwarded-For"}
+	get := func(forwarded ...string) netip.Addr {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		for _, f := range forwarded {
+			req.Header.Add("X-Forwarded-For", f)
+		}
+		addr, _ := l.clientIP(req)
+		return addr
+	}
+
+	// Clients can put anything first; the proxy appends who it saw
+	for _, tc := range []struct {
+		forwarded []string
+		want      string
+	}{
+		{[]string{"192.0.2.50"}, "192.0.2.50"},
+		{[]string{"203.0.113.1, 192.0.2.50"}, "192.0.2.50"},
+		{[]string{"203.0.113.1", "192.0.2.50"}, "192.0.2.50"},
+		{[]string{"not an ip"}, "10.0.0.1"},
+		{nil, "10.0.0.1"},
+	} {
+		if got := get(tc.forwarded...); got != netip.MustParseAddr(tc.want) {
+			t.Errorf("X-Forwarded-For %q: got %s, want %s", tc.forwarded, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a-h/templ"
+	"pkg.jsn.cam/jsn/internal/markdown"
+	"pkg.jsn.cam/jsn/jass"
+)
+
+const (
+	// readmeTTL is how long a rendered README is served before refetching
+	readmeTTL = time.Hour
+	// readmeErrorTTL is how long a failed fetch is remembered, so a missing
+	// README doesn't hit the provider on every page view
+	readmeErrorTTL = 5 * time.Minute
+	// maxReadmeSize caps how much of a README is rendered
+	maxReadmeSize = 512 << 10
+)
+
+type readme struct {
+	html    string
+	err     error
+	fetched time.Time
+}
+
+var (
+	readmeHTTP  = &http.Client{Timeout: 10 * time.Second}
+	readmeCache sync.Map // import path -> *readme
+	readmeMu    sync.Mutex
+)
+
+// README returns the repo's README rendered to HTML, fetching it from the
+// provider if the cached copy is missing or stale. A stale copy is served
+// if the provider can't be reached.
+func (r Repo) README(ctx context.Context) (string, error) {
+	cached, _ := readmeCache.Load(r.ImportPath())
+	if c, ok := cached.(*readme); ok && c.fresh() {
+		return c.html, c.err
+	}
+
+	// One fetch at a time; whoever waited gets the fresh copy
+	readmeMu.Lock()
+	defer readmeMu.Unlock()
+	if c, ok := readmeCache.Load(r.ImportPath()); ok && c.(*readme).fresh() {
+		return c.(*readme).html, c.(*readme).err
+	}
+
+	src, err := r.fetchReadme(ctx)
+	if err != nil {
+		if c, ok := cached.(*readme); ok && c.err == nil {
+			return c.html, nil
+		}
+		readmeCache.Store(r.ImportPath(), &readme{err: err, fetched: time.Now()})
+		return "", err
+	}
+
+	linkBase, rawBase := r.readmeBases()
+	html := markdown.Render(src, linkBase, rawBase, r.dir())
+	readmeCache.Store(r.ImportPath(), &readme{html: html, fetched: time.Now()})
+	if c, ok := cached.(*readme); !ok || c.html != html {
+		pageVersion.Add(1)
+	}
+	return html, nil
+}
+
+func (c *readme) fresh() bool {
+	ttl := readmeTTL
+	if c.err != nil {
+		ttl = readmeErrorTTL
+	}
+	return time.Since(c.fetched) < ttl
+}
+
+// dir returns the module's directory within the repository
+func (r Repo) dir() string {
+	if r.Subdir != "" {
+		return r.Subdir
+	}
+	_, dir, _ := strings.Cut(r.Repo, "/")
+	return dir
+}
+
+// fetchReadme downloads the raw README markdown for the module's directory
+func (r Repo) fetchReadme(ctx context.Context) (string, error) {
+	dir := r.dir()
+
+	var u, token, accept string
+	switch r.Kind {
+	case "github":
+		api := "https://api.github.com"
+		if r.Domain != "github.com" {
+			api = "https://" + r.Domain + "/api/v3"
+		}
+		u = api + "/repos/" + r.User + "/" + r.source() + "/readme"
+		if dir != "" {
+			u += "/" + dir
+		}
+		token, accept = *githubToken, "application/vnd.github.raw"
+	case "gitea":
+		u = "https://" + r.Domain + "/api/v1/repos/" + r.User + "/" + r.source() + "/raw/" + strings.TrimPrefix(dir+"/README.md", "/")
+	case "gitlab":
+		project := url.PathEscape(r.User + "/" + r.source())
+		file := url.PathEscape(strings.TrimPrefix(dir+"/README.md", "/"))
+		u = "https://" + r.Domain + "/api/v4/projects/" + project + "/repository/files/" + file + "/raw?ref=HEAD"
+	default:
+		return "", fmt.Errorf("no README API for provider %q", r.Kind)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "pkg.jsn.cam")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := readmeHTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxReadmeSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read README: %v", err)
+	}
+	return string(body), nil
+}
+
+// readmeBases returns the repository root URLs relative links and images
+// in the README are resolved against
+func (r Repo) readmeBases() (linkBase, rawBase string) {
+	ref := r.ref()
+
+	switch r.Kind {
+	case "gitea":
+		return r.URL() + "/src/branch/" + ref, r.URL() + "/raw/branch/" + ref
+	case "gitlab":
+		return r.URL() + "/-/blob/" + ref, r.URL() + "/-/raw/" + ref
+	default:
+		return r.URL() + "/blob/" + ref, r.URL() + "/raw/" + ref
+	}
+}
+
+// detailHandler shows browsers the repo's detail page with its README
+// rather than redirecting them to pkg.go.dev. go get requests are passed
+// on to next.
+func (r Repo) detailHandler(next http.Handler, lg *slog.Logger) http.Handler {
+	// Rendered on first view rather than at load, as that needs the README
+	page := newCachedPage(func(ctx context.Context) templ.Component {
+		readme, _ := r.README(ctx)
+		return jass.Base(r.ImportPath(), openGraph(r), nil, Detail(r, readme), nil)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.FormValue("go-get") == "1" {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		// Refresh the README if it's stale, which bumps pageVersion if it
+		// changed
+		if _, err := r.README(req.Context()); err != nil {
+			lg.Debug("can't fetch README", "repo", r, "err", err)
+		}
+		page.ServeHTTP(w, req)
+	})
+}
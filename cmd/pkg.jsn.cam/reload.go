@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce coalesces the burst of events editors produce when saving
+const reloadDebounce = 250 * time.Millisecond
+
+// Site serves the handlers built from the config file and swaps them out
+// atomically when the file changes. Requests already in flight finish on
+// the mux they started with.
+type Site struct {
+	path string
+	lg   *slog.Logger
+
+	mu    sync.Mutex                                // serializes reloads
+	muxes atomic.Pointer[map[string]*http.ServeMux] // by vanity domain
+	repos atomic.Pointer[[]Repo]
+
+	// enricher, if set, is asked to fetch metadata after each reload
+	enricher *Enricher
+	// linkChecker, if set, is asked to check repo links after each reload
+	linkChecker *LinkChecker
+}
+
+// NewSite loads the config at path and builds the initial handlers
+func NewSite(path string, lg *slog.Logger) (*Site, error) {
+	s := &Site{path: path, lg: lg}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler, picking the domain's handlers by Host.
+// Unknown hosts get -domain, see canonicalHost for the exceptions.
+func (s *Site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	muxes := *s.muxes.Load()
+	mux, ok := muxes[host]
+	if !ok {
+		canonical, advise := canonicalHost(host, muxes, r.FormValue("go-get") == "1")
+		mux = muxes[canonical]
+		if advise {
+			wrongHost(w, r, host, canonical, mux, s.lg)
+			return
+		}
+	}
+	mux.ServeHTTP(w, r)
+}
+
+// Domains returns the vanity domains currently served, sorted
+func (s *Site) Domains() []string {
+	muxes := *s.muxes.Load()
+	domains := make([]string, 0, len(muxes))
+	for d := range muxes {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// Reload re-reads the config and replaces the served handlers. On error the
+// current handlers stay in place.
+func (s *Site) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	config, err := LoadConfig(s.path, s.lg)
+	if err != nil {
+		return err
+	}
+
+	repos := BuildRepos(config, s.lg)
+
+	s.lg.Debug("loaded repos", "count", len(repos))
+	for i, repo := range repos {
+		s.lg.Debug("loaded repo", "index", i, "repo", repo)
+	}
+
+	wildcards := WildcardProviders(config, s.lg)
+	for d, wildcard := range wildcards {
+		s.lg.Debug("serving unconfigured repos from wildcard provider", "domain", d, "provider", wildcard)
+	}
+
+	// Every domain mentioned anywhere gets its own handlers; -domain always
+	// has some so unknown hosts can fall back to it
+	byDomain := map[string][]Repo{*domain: nil}
+	for _, repo := range repos {
+		byDomain[repo.VanityDomain] = append(byDomain[repo.VanityDomain], repo)
+	}
+	links := make(map[string]map[string]Link)
+	for path, link := range config.Links {
+		if links[link.Domain] == nil {
+			links[link.Domain] = make(map[string]Link)
+		}
+		links[link.Domain][path] = link
+		if _, ok := byDomain[link.Domain]; !ok {
+			byDomain[link.Domain] = nil
+		}
+	}
+	for d := range wildcards {
+		if _, ok := byDomain[d]; !ok {
+			byDomain[d] = nil
+		}
+	}
+
+	var before []Repo
+	if prev := s.repos.Load(); prev != nil {
+		before = *prev
+	}
+	recordChanges(before, repos, time.Now())
+
+	muxes := make(map[string]*http.ServeMux, len(byDomain))
+	for d, domainRepos := range byDomain {
+		wildcard, ok := wildcards[d]
+		muxes[d] = NewMux(d, domainRepos, links[d], config.Pages, wildcard, ok, s.lg.With("vanity", d))
+	}
+
+	s.muxes.Store(&muxes)
+	s.repos.Store(&repos)
+
+	if s.enricher != nil {
+		s.enricher.Kick()
+	}
+	if s.linkChecker != nil {
+		s.linkChecker.Kick()
+	}
+	return nil
+}
+
+// Repos returns the currently configured repos
+func (s *Site) Repos() []Repo {
+	return *s.repos.Load()
+}
+
+// Watch reloads the config on SIGHUP and whenever the file changes, until
+// ctx is done. Configs embedded in the binary or read from the environment
+// are only reloaded on SIGHUP.
+func (s *Site) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if onDisk(s.path) {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			s.lg.Error("can't watch config, only reloading on SIGHUP", "err", err)
+		} else {
+			defer watcher.Close()
+
+			// Watch the directory rather than the file so that editors and
+			// config management replacing the file by rename are noticed.
+			if err := watcher.Add(filepath.Dir(s.path)); err != nil {
+				s.lg.Error("can't watch config, only reloading on SIGHUP", "err", err)
+			}
+			events, errs = watcher.Events, watcher.Errors
+		}
+	}
+
+	name := filepath.Clean(s.path)
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			s.reload("signal")
+		case ev := <-events:
+			if filepath.Clean(ev.Name) != name || !ev.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				continue
+			}
+			debounce.Reset(reloadDebounce)
+		case <-debounce.C:
+			s.reload("file change")
+		case err := <-errs:
+			s.lg.Error("config watcher error", "err", err)
+		}
+	}
+}
+
+func (s *Site) reload(reason string) {
+	if err := s.Reload(); err != nil {
+		s.lg.Error("can't reload config, keeping the previous one", "reason", reason, "err", err)
+		return
+	}
+	s.lg.Info("reloaded config", "reason", reason)
+}
@@ -4,27 +4,115 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 
-	"pkg.jsn.cam/jsn/internal/vanity"
+	"pkg.jsn.cam/jsn/vanity"
 )
 
 // Repo represents a repository with its metadata
 type Repo struct {
-	Kind        string
-	Domain      string
-	User        string
+	// VanityDomain is the domain the repo is served on, e.g. "pkg.jsn.cam"
+	VanityDomain string
+	// Kind is the provider name and Domain the forge's domain
+	Kind   string
+	Domain string
+	User   string
+	// Repo is the path under the vanity domain, e.g. "jsn" or "jsn/cmd/fsdiff"
 	Repo        string
 	Description string
+	Branch      string
+	// Source is the repository name on the forge, the first element of Repo
+	// if unset
+	Source string
+	// Subdir is the module's directory within Source, for modules whose
+	// import path doesn't mirror the repository layout
+	Subdir string
+	// Redirect is where browsers are sent: RedirectPage (the default),
+	// RedirectProvider, RedirectDocs or an http(s) URL
+	Redirect string
+	// Scheme is how the go tool clones the repo: SchemeHTTPS (the default)
+	// or SchemeSSH, for private repos cloned with the user's SSH key
+	Scheme string
+	// Deprecated is the deprecation message, if the repo is deprecated
+	Deprecated string
+	// Replacement is the import path to use instead
+	Replacement string
+	// Retracted lists retracted versions or [low, high] ranges
+	Retracted []string
+	// Tags are the repo's topics, lower case
+	Tags []string
+	// Archived is set in the config; the provider may also report the
+	// repo archived, see IsArchived
+	Archived bool
+	// ArchivedGone answers go get for the repo with 410 Gone once it's
+	// archived, pointing at Replacement
+	ArchivedGone bool
 }
 
 // URL returns the full URL to the repository
 func (r Repo) URL() string {
-	return fmt.Sprintf("https://%s/%s/%s", r.Domain, r.User, r.Repo)
+	return fmt.Sprintf("https://%s/%s/%s", r.Domain, r.User, r.source())
+}
+
+// Clone schemes for the go-import meta tag
+const (
+	SchemeHTTPS = "https"
+	SchemeSSH   = "ssh"
+)
+
+// validScheme reports whether s is a known clone scheme. The empty string
+// means SchemeHTTPS.
+func validScheme(s string) bool {
+	return s == "" || s == SchemeHTTPS || s == SchemeSSH
+}
+
+// cloneURL returns the URL the go tool clones the repository from. Source
+// links keep using URL, so they work in a browser either way.
+func (r Repo) cloneURL() string {
+	if r.Scheme == SchemeSSH {
+		return fmt.Sprintf("ssh://git@%s/%s/%s", r.Domain, r.User, r.source())
+	}
+	return r.URL()
+}
+
+// source returns the repository name on the forge
+func (r Repo) source() string {
+	if r.Source != "" {
+		return r.Source
+	}
+	name, _, _ := strings.Cut(r.Repo, "/")
+	return name
+}
+
+// importPrefix returns the import path that the repository root, or Subdir
+// if set, is served at. The go tool requires it to be a prefix of every
+// package path it asks about.
+func (r Repo) importPrefix(domain string) string {
+	if r.Subdir != "" {
+		return domain + "/" + r.Repo
+	}
+	name, _, _ := strings.Cut(r.Repo, "/")
+	return domain + "/" + name
+}
+
+// ImportPath returns the repo's import path, e.g. "pkg.jsn.cam/jsn"
+func (r Repo) ImportPath() string {
+	return r.VanityDomain + "/" + r.Repo
+}
+
+// statsKey names the repo in go-get counts: the bare path on -domain, so
+// counts from before multiple domains keep working, and the import path on
+// other domains
+func (r Repo) statsKey() string {
+	if r.VanityDomain == *domain {
+		return r.Repo
+	}
+	return r.ImportPath()
 }
 
 // GodocURL returns the URL to view the package documentation on pkg.go.dev
 func (r Repo) GodocURL() string {
-	return fmt.Sprintf("https://pkg.go.dev/%s/%s", *domain, r.Repo)
+	return "https://pkg.go.dev/" + r.ImportPath()
 }
 
 // GodocBadge returns the URL to the pkg.go.dev badge for this repository
@@ -32,28 +120,239 @@ func (r Repo) GodocBadge() string {
 	return fmt.Sprintf("https://pkg.go.dev/badge/%s/%s/%s.svg", r.Domain, r.User, r.Repo)
 }
 
+// IsDeprecated reports whether the repo should no longer be used
+func (r Repo) IsDeprecated() bool {
+	return r.Deprecated != "" || r.Replacement != ""
+}
+
+// IsArchived reports whether the repo is archived, per the config or the
+// provider's metadata
+func (r Repo) IsArchived() bool {
+	if r.Archived {
+		return true
+	}
+	m := r.Metadata()
+	return m != nil && m.Archived
+}
+
+// ArchivedNotice returns the message shown for an archived repo
+func (r Repo) ArchivedNotice() string {
+	notice := r.ImportPath() + " is archived and no longer maintained."
+	if r.Replacement != "" {
+		notice += " Use " + r.Replacement + " instead."
+	}
+	return notice
+}
+
+// archivedGone answers go get with 410 Gone when the repo is archived and
+// ArchivedGone is set. The go command shows the text, so users learn what
+// to move to. It's checked per request as metadata arrives after startup.
+func (r Repo) archivedGone(next http.Handler) http.Handler {
+	if !r.ArchivedGone {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.FormValue("go-get") == "1" && r.IsArchived() {
+			http.Error(w, r.ArchivedNotice(), http.StatusGone)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// DeprecationNotice returns the message shown to users of a deprecated repo
+func (r Repo) DeprecationNotice() string {
+	notice := r.Deprecated
+	if r.Replacement != "" {
+		if notice != "" && !strings.HasSuffix(notice, ".") {
+			notice += "."
+		}
+		notice = strings.TrimSpace(notice + " Use " + r.Replacement + " instead.")
+	}
+	return notice
+}
+
+// GoModNotice returns the Deprecated comment and retract directives for the
+// repo's go.mod, for tooling that keeps them in sync with the config
+func (r Repo) GoModNotice(domain string) string {
+	var b strings.Builder
+	if r.IsDeprecated() {
+		b.WriteString("// Deprecated: " + r.DeprecationNotice() + "\n")
+	}
+	b.WriteString("module " + domain + "/" + r.Repo + "\n")
+
+	if len(r.Retracted) > 0 {
+		b.WriteString("\nretract (\n")
+		for _, v := range r.Retracted {
+			b.WriteString("\t" + v + "\n")
+		}
+		b.WriteString(")\n")
+	}
+	return b.String()
+}
+
 // LogValue implements slog.LogValuer to provide structured logging
 func (r Repo) LogValue() slog.Value {
 	return slog.GroupValue(
+		slog.String("vanity", r.VanityDomain),
 		slog.String("kind", r.Kind),
 		slog.String("domain", r.Domain),
 		slog.String("user", r.User),
 		slog.String("repo", r.Repo),
+		slog.String("source", r.source()),
+		slog.String("subdir", r.Subdir),
+		slog.String("redirect", r.Redirect),
+		slog.String("scheme", r.Scheme),
 	)
 }
 
-// RegisterHandlers registers HTTP handlers for this repository
-func (r Repo) RegisterHandlers(mux *http.ServeMux, domain string, lg *slog.Logger) {
+// Handler returns the vanity import handler for this repository, or nil
+// if the provider kind is unknown. Besides go-import it emits go-source so
+// pkg.go.dev and editors can link to directories, files and lines.
+func (r Repo) Handler(domain string) http.Handler {
+	var style vanity.SourceStyle
 	switch r.Kind {
 	case "gitea":
-		mux.Handle("/"+r.Repo, vanity.GogsHandler(domain+"/"+r.Repo, r.Domain, r.User, r.Repo, "https"))
-		mux.Handle("/"+r.Repo+"/", vanity.GogsHandler(domain+"/"+r.Repo, r.Domain, r.User, r.Repo, "https"))
+		style = vanity.WithGogsStyleSource
 	case "github":
-		mux.Handle("/"+r.Repo, vanity.GitHubHandler(domain+"/"+r.Repo, r.User, r.Repo, "https"))
-		mux.Handle("/"+r.Repo+"/", vanity.GitHubHandler(domain+"/"+r.Repo, r.User, r.Repo, "https"))
+		style = vanity.WithGitHubStyleSource
 	case "gitlab":
-		mux.Handle("/"+r.Repo, vanity.GitHubHandler(domain+"/"+r.Repo, r.User, r.Repo, "https"))
-		mux.Handle("/"+r.Repo+"/", vanity.GitHubHandler(domain+"/"+r.Repo, r.User, r.Repo, "https"))
+		style = vanity.WithGitLabStyleSource
+	default:
+		return nil
 	}
+
+	return vanity.New(r.importPrefix(domain), r.URL(),
+		vanity.WithCloneURL(r.cloneURL()),
+		vanity.WithBranch(r.ref()),
+		vanity.WithSubdir(r.Subdir),
+		vanity.WithSourceStyle(style),
+		vanity.WithRedirector(r.redirector(domain)),
+	)
+}
+
+// Redirect targets for browsers
+const (
+	// RedirectPage shows the package page for the repo itself and
+	// pkg.go.dev for packages below it
+	RedirectPage = "page"
+	// RedirectProvider sends browsers to the source on the forge
+	RedirectProvider = "provider"
+	// RedirectDocs sends browsers to pkg.go.dev
+	RedirectDocs = "pkg.go.dev"
+)
+
+// validRedirect reports whether s is a known redirect target or an http(s)
+// URL. The empty string means RedirectPage.
+func validRedirect(s string) bool {
+	switch s {
+	case "", RedirectPage, RedirectProvider, RedirectDocs:
+		return true
+	}
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
+
+// redirector returns where browsers asking for a package of this repo are
+// sent, per Redirect
+func (r Repo) redirector(domain string) vanity.Redirector {
+	return func(pkg string) string {
+		// pkg is the request host and path; keep what's below the repo
+		_, path, _ := strings.Cut(pkg, "/")
+		sub := strings.Trim(strings.TrimPrefix(path, r.Repo), "/")
+
+		switch r.Redirect {
+		case "", RedirectPage, RedirectDocs:
+			return "https://pkg.go.dev/" + strings.TrimSuffix(domain+"/"+r.Repo+"/"+sub, "/")
+		case RedirectProvider:
+			return r.treeURL(sub)
+		default:
+			return r.Redirect
+		}
+	}
+}
+
+// treeURL returns the forge's page for the directory sub of the module
+func (r Repo) treeURL(sub string) string {
+	dir := strings.Trim(r.dir()+"/"+sub, "/")
+	if dir == "" {
+		return r.URL()
+	}
+
+	switch r.Kind {
+	case "gitea":
+		return r.URL() + "/src/branch/" + r.ref() + "/" + dir
+	case "gitlab":
+		return r.URL() + "/-/tree/" + r.ref() + "/" + dir
+	default:
+		return r.URL() + "/tree/" + r.ref() + "/" + dir
+	}
+}
+
+// ref returns the branch source links point at
+func (r Repo) ref() string {
+	if r.Branch == "" {
+		return "master"
+	}
+	return r.Branch
+}
+
+// RegisterHandlers registers HTTP handlers for this repository
+func (r Repo) RegisterHandlers(mux *http.ServeMux, domain string, lg *slog.Logger) {
+	h := r.Handler(domain)
+	if h == nil {
+		return
+	}
+	h = countGoGets(r.statsKey(), r.archivedGone(h))
+	root := h
+	if r.Redirect == "" || r.Redirect == RedirectPage {
+		root = r.detailHandler(h, lg)
+	}
+	mux.Handle("/"+r.Repo, root)
+	mux.Handle("/"+r.Repo+"/", h)
 	lg.Debug("registered repo handler", "repo", r)
 }
+
+// WildcardHandler serves any repository of the wildcard provider that
+// isn't configured explicitly, falling back to notFound for paths that
+// can't be repository names
+func WildcardHandler(provider Repo, domain string, notFound http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name, _, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+		if !validRepoName(name) {
+			notFound.ServeHTTP(w, req)
+			return
+		}
+
+		repo := provider
+		repo.Repo = name
+		repo.Handler(domain).ServeHTTP(w, req)
+	})
+}
+
+// validRepoPath reports whether path, like "jsn/cmd/fsdiff", is made of
+// valid repository names
+func validRepoPath(path string) bool {
+	for name := range strings.SplitSeq(path, "/") {
+		if !validRepoName(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// validRepoName reports whether name is usable as a repository name on the
+// common forges
+func validRepoName(name string) bool {
+	if name == "" || name[0] == '.' || len(name) > 100 {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
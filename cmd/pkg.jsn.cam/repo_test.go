@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestArchived(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	config := `[repo.github]
+username = "JasonLovesDoggo"
+url = "github.com"
+default = true
+archived_gone = true
+
+[old]
+archived = true
+replacement = "pkg.jsn.cam/new"
+
+[kept]
+archived = true
+archived_gone = false
+
+[live]
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	site, err := NewSite(path, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(url string) (int, string) {
+		rec := httptest.NewRecorder()
+		site.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		body, _ := io.ReadAll(rec.Result().Body)
+		return rec.Code, string(body)
+	}
+
+	if code, body := get("https://pkg.jsn.cam/old/sub?go-get=1"); code != http.StatusGone || !strings.Contains(body, "Use pkg.jsn.cam/new instead") {
+		t.Errorf("archived go get: got %d %q", code, body)
+	}
+	if code, _ := get("https://pkg.jsn.cam/kept?go-get=1"); code != http.StatusOK {
+		t.Errorf("archived_gone = false: got %d", code)
+	}
+	if code, _ := get("https://pkg.jsn.cam/live?go-get=1"); code != http.StatusOK {
+		t.Errorf("live repo: got %d", code)
+	}
+
+	// The provider reporting it archived is enough
+	var live Repo
+	for _, r := range site.Repos() {
+		if r.Repo == "live" {
+			live = r
+		}
+	}
+	repoMeta.Store(live.URL(), &RepoMeta{Archived: true})
+	t.Cleanup(func() { repoMeta.Delete(live.URL()) })
+	if code, _ := get("https://pkg.jsn.cam/live?go-get=1"); code != http.StatusGone {
+		t.Errorf("live repo archived upstream: got %d, want 410", code)
+	}
+
+	if _, body := get("https://pkg.jsn.cam/.jsn.badge/status/old"); !strings.Contains(body, "archived") {
+		t.Errorf("status badge: %s", body)
+	}
+	if _, body := get("https://pkg.jsn.cam/"); !strings.Contains(body, `<img alt="Archived" src="/.jsn.badge/status/old">`) {
+		t.Errorf("index has no archived notice:\n%s", body)
+	}
+}
+
+func TestInvalidRepoPaths(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	write := func(repos string) {
+		t.Helper()
+		config := "[repo.github]\nusername = \"JasonLovesDoggo\"\nurl = \"github.com\"\ndefault = true\n\n" + repos
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("[good]\n[\"jsn/cmd/fsdiff\"]\n")
+	site, err := NewSite(path, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys that would make ServeMux panic are skipped rather than crashing
+	// the server on reload
+	write("[good]\n[\"foo bar\"]\n[\"x/{y}\"]\n[\"x/../y\"]\n[\"jsn/cmd/fsdiff\"]\n")
+	if err := site.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range site.Repos() {
+		names = append(names, r.Repo)
+	}
+	slices.Sort(names)
+	if want := []string{"good", "jsn/cmd/fsdiff"}; !slices.Equal(names, want) {
+		t.Errorf("repos = %q, want %q", names, want)
+	}
+}
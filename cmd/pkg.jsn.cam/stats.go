@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/a-h/templ"
+)
+
+// statsSince is when go-get counting started, carried over from the stats
+// file if there is one
+var statsSince = time.Now()
+
+// goGetClients counts go-get=1 requests per client, see goGetClient
+var goGetClients sync.Map // client -> *atomic.Int64
+
+// statsDays is how many days of daily go-get counts are kept
+const statsDays = 90
+
+var (
+	// requestCount counts every request, statusCounts by response status
+	requestCount atomic.Int64
+	statusCounts sync.Map // "200 OK" -> *atomic.Int64
+
+	// goGetDays counts go-get=1 requests per UTC day
+	goGetDays sync.Map // "2006-01-02" -> *atomic.Int64
+)
+
+// stats is the stats file and /api/stats format
+type stats struct {
+	Since    time.Time        `json:"since"`
+	Total    int64            `json:"total"`
+	Repos    map[string]int64 `json:"repos"`
+	Clients  map[string]int64 `json:"clients"`
+	Requests int64            `json:"requests"`
+	Statuses map[string]int64 `json:"statuses"`
+	Days     map[string]int64 `json:"days"`
+}
+
+// snapshotStats returns the current go-get counts
+func snapshotStats() stats {
+	s := stats{
+		Since:    statsSince,
+		Repos:    make(map[string]int64),
+		Clients:  make(map[string]int64),
+		Requests: requestCount.Load(),
+		Statuses: make(map[string]int64),
+		Days:     make(map[string]int64),
+	}
+	goGetCounts.Range(func(k, v any) bool {
+		n := v.(*atomic.Int64).Load()
+		s.Repos[k.(string)] = n
+		s.Total += n
+		return true
+	})
+	goGetClients.Range(func(k, v any) bool {
+		s.Clients[k.(string)] = v.(*atomic.Int64).Load()
+		return true
+	})
+	statusCounts.Range(func(k, v any) bool {
+		s.Statuses[k.(string)] = v.(*atomic.Int64).Load()
+		return true
+	})
+	goGetDays.Range(func(k, v any) bool {
+		s.Days[k.(string)] = v.(*atomic.Int64).Load()
+		return true
+	})
+	return s
+}
+
+// addCount adds n to the counter for key in m
+func addCount(m *sync.Map, key string, n int64) (created bool) {
+	c, loaded := m.LoadOrStore(key, new(atomic.Int64))
+	c.(*atomic.Int64).Add(n)
+	return !loaded
+}
+
+// countRequest records a response for the stats page
+func countRequest(code int) {
+	requestCount.Add(1)
+	addCount(&statusCounts, strconv.Itoa(code)+" "+http.StatusText(code), 1)
+}
+
+// countGoGetDay records a go-get request on the day of t, dropping days
+// older than statsDays when a new one starts
+func countGoGetDay(t time.Time) {
+	if !addCount(&goGetDays, t.UTC().Format(time.DateOnly), 1) {
+		return
+	}
+	cutoff := t.UTC().AddDate(0, 0, -statsDays).Format(time.DateOnly)
+	goGetDays.Range(func(k, _ any) bool {
+		if k.(string) < cutoff {
+			goGetDays.Delete(k)
+		}
+		return true
+	})
+}
+
+var goVersionRe = regexp.MustCompile(`go1\.\d+`)
+
+// goGetClient classifies the User-Agent of a go-get request as one of a
+// few known proxies, the go command itself ("go"), or "other", and pulls
+// out the Go release if the client reports one. cmd/go sends the plain
+// Go-http-client User-Agent, so direct fetches have no version.
+func goGetClient(ua string) (client, goVersion string) {
+	goVersion = goVersionRe.FindString(ua)
+	if goVersion == "" {
+		goVersion = "unknown"
+	}
+
+	switch {
+	case strings.Contains(ua, "GoModuleMirror"):
+		client = "proxy.golang.org"
+	case strings.Contains(ua, "pkgsite"):
+		client = "pkg.go.dev"
+	case strings.Contains(ua, "goproxy.cn"):
+		client = "goproxy.cn"
+	case strings.Contains(ua, "goproxy.io"):
+		client = "goproxy.io"
+	case strings.Contains(strings.ToLower(ua), "athens"):
+		client = "athens"
+	case strings.HasPrefix(ua, "Go-http-client/"):
+		client = "go"
+	case ua == "":
+		client = "none"
+	default:
+		client = "other"
+	}
+	return client, goVersion
+}
+
+// countGoGetClient records which client made a go-get request
+func countGoGetClient(ua string) {
+	client, goVersion := goGetClient(ua)
+	goGetClientRequests.WithLabelValues(client, goVersion).Inc()
+
+	n, _ := goGetClients.LoadOrStore(client, new(atomic.Int64))
+	n.(*atomic.Int64).Add(1)
+}
+
+type countRow struct {
+	Name  string
+	Count int64
+}
+
+// countRows sorts counts for the stats page, highest first
+func countRows(counts map[string]int64) []countRow {
+	rows := make([]countRow, 0, len(counts))
+	for name, n := range counts {
+		rows = append(rows, countRow{name, n})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Count != rows[j].Count {
+			return rows[i].Count > rows[j].Count
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return rows
+}
+
+// LoadStats restores go-get counts saved by SaveStats. A missing file is
+// not an error, counting just starts from zero.
+func LoadStats(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var s stats
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("failed to parse stats: %v", err)
+	}
+
+	if !s.Since.IsZero() {
+		statsSince = s.Since
+	}
+	for repo, n := range s.Repos {
+		c, _ := goGetCounts.LoadOrStore(repo, new(atomic.Int64))
+		c.(*atomic.Int64).Add(n)
+	}
+	for client, n := range s.Clients {
+		c, _ := goGetClients.LoadOrStore(client, new(atomic.Int64))
+		c.(*atomic.Int64).Add(n)
+	}
+	requestCount.Add(s.Requests)
+	for status, n := range s.Statuses {
+		addCount(&statusCounts, status, n)
+	}
+	for day, n := range s.Days {
+		addCount(&goGetDays, day, n)
+	}
+	return nil
+}
+
+// SaveStats writes the go-get counts to path, replacing it atomically
+func SaveStats(path string) error {
+	data, err := json.MarshalIndent(snapshotStats(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".stats-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// PersistStats saves the go-get counts to path every interval while they
+// change, and once more when ctx is done. At most one interval of counts is
+// lost if the process is killed.
+func PersistStats(ctx context.Context, path string, every time.Duration, lg *slog.Logger) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	var last []byte
+	save := func() {
+		// Only the counts matter for deciding whether to write
+		snap := snapshotStats()
+		current, _ := json.Marshal([]any{snap.Repos, snap.Clients, snap.Requests, snap.Statuses, snap.Days})
+		if bytes.Equal(current, last) {
+			return
+		}
+		if err := SaveStats(path); err != nil {
+			lg.Error("can't save stats", "path", path, "err", err)
+			return
+		}
+		last = current
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			save()
+			return
+		case <-ticker.C:
+			save()
+		}
+	}
+}
+
+// downloads formats the go-get count of repo for the index page
+func downloads(repo Repo) string {
+	n := goGets(repo.statsKey())
+	if n == 1 {
+		return "1 download"
+	}
+	return strconv.FormatInt(n, 10) + " downloads"
+}
+
+// statsChartDays is how many days the stats page chart shows
+const statsChartDays = 30
+
+// dailyChart draws go-get requests for each of the last n days, up to today,
+// as an SVG bar chart
+func dailyChart(days map[string]int64, n int) templ.Component {
+	const width, height, label = 600, 120, 16
+
+	today := time.Now().UTC()
+	counts := make([]int64, n)
+	var peak int64 = 1
+	for i := range counts {
+		counts[i] = days[today.AddDate(0, 0, i-n+1).Format(time.DateOnly)]
+		peak = max(peak, counts[i])
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" role="img" aria-label="go get requests per day">`, width, height+label)
+	bar := width / n
+	for i, c := range counts {
+		h := int(c * (height - 1) / peak)
+		if c > 0 {
+			h = max(h, 2)
+		}
+		day := today.AddDate(0, 0, i-n+1).Format(time.DateOnly)
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="currentColor"><title>%s: %d</title></rect>`,
+			i*bar+1, height-h, bar-2, h, day, c)
+	}
+	fmt.Fprintf(&b, `<text x="0" y="%d" font-size="12" fill="currentColor">%s</text>`, height+label-2, today.AddDate(0, 0, 1-n).Format(time.DateOnly))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="12" fill="currentColor" text-anchor="end">%s</text>`, width, height+label-2, today.Format(time.DateOnly))
+	b.WriteString(`</svg>`)
+	return templ.Raw(b.String())
+}
+
+// statsAPI serves the go-get counts as JSON
+func statsAPI() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(snapshotStats())
+	})
+}
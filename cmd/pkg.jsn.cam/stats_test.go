@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoGetClient(t *testing.T) {
+	for _, tt := range []struct {
+		ua, client, goVersion string
+	}{
+		{"Go-http-client/1.1", "go", "unknown"},
+		{"Go-http-client/2.0", "go", "unknown"},
+		{"GoModuleMirror/1.0 (+https://proxy.golang.org)", "proxy.golang.org", "unknown"},
+		{"pkgsite (+https://pkg.go.dev/about#adding-a-package)", "pkg.go.dev", "unknown"},
+		{"goproxy.cn/1.0 go1.22.3", "goproxy.cn", "go1.22"},
+		{"Athens/v0.14.0 go1.21.5", "athens", "go1.21"},
+		{"", "none", "unknown"},
+		{"curl/8.0", "other", "unknown"},
+	} {
+		client, goVersion := goGetClient(tt.ua)
+		if client != tt.client || goVersion != tt.goVersion {
+			t.Errorf("goGetClient(%q) = %q, %q, want %q, %q", tt.ua, client, goVersion, tt.client, tt.goVersion)
+		}
+	}
+}
+
+func TestGoGetDays(t *testing.T) {
+	// Other tests make go-get requests too
+	goGetDays.Clear()
+	t.Cleanup(func() { goGetDays.Clear() })
+
+	// Starting today drops the old day
+	now := time.Now().UTC()
+	old := now.AddDate(0, 0, -statsDays-1)
+	countGoGetDay(old)
+	countGoGetDay(now)
+	countGoGetDay(now)
+	countGoGetDay(now.AddDate(0, 0, -1))
+
+	days := snapshotStats().Days
+	if _, ok := days[old.Format(time.DateOnly)]; ok {
+		t.Errorf("day older than %d days kept: %v", statsDays, days)
+	}
+	if n := days[now.Format(time.DateOnly)]; n != 2 {
+		t.Errorf("today = %d, want 2", n)
+	}
+
+	// Days carry over a restart
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if err := SaveStats(path); err != nil {
+		t.Fatal(err)
+	}
+	goGetDays.Clear()
+	if err := LoadStats(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := snapshotStats().Days; len(got) != 2 || got[now.Format(time.DateOnly)] != 2 {
+		t.Errorf("after reload got %v", got)
+	}
+
+	var b strings.Builder
+	if err := dailyChart(days, statsChartDays).Render(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(b.String(), "<rect"); n != statsChartDays {
+		t.Errorf("chart has %d bars, want %d", n, statsChartDays)
+	}
+	if want := "<title>" + now.Format(time.DateOnly) + ": 2</title>"; !strings.Contains(b.String(), want) {
+		t.Errorf("chart is missing %s", want)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/sumdb/note"
+	"pkg.jsn.cam/jsn/internal/middleware"
+)
+
+// SumGolangOrgKey is the verifier key the go command has built in for
+// sum.golang.org
+const SumGolangOrgKey = "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ux18htTTAD8OuAn8"
+
+// maxSumDBResponse caps how much of an upstream response is relayed
+const maxSumDBResponse = 10 << 20
+
+// SumDBProxy relays the checksum database protocol to one database, so
+// hosts that can only reach the vanity domain can still verify modules.
+// Signed tree heads are checked against the pinned key before they're
+// passed on; tiles aren't signed, the go command checks them against the
+// tree.
+type SumDBProxy struct {
+	// Name is the database name, e.g. "sum.golang.org"
+	Name string
+	// Upstream is the database URL, https://<Name> by default
+	Upstream string
+	HTTP     *http.Client
+
+	verifier note.Verifier
+	lg       *slog.Logger
+}
+
+// NewSumDBProxy creates a proxy for the database with the verifier key
+// vkey, fetching from upstream or https://<name> if that's empty
+func NewSumDBProxy(vkey, upstream string, lg *slog.Logger) (*SumDBProxy, error) {
+	v, err := note.NewVerifier(vkey)
+	if err != nil {
+		return nil, fmt.Errorf("verifier key %q: %v", vkey, err)
+	}
+	if upstream == "" {
+		upstream = "https://" + v.Name()
+	}
+	return &SumDBProxy{
+		Name:     v.Name(),
+		Upstream: strings.TrimSuffix(upstream, "/"),
+		HTTP:     &http.Client{Timeout: 30 * time.Second},
+		verifier: v,
+		lg:       lg,
+	}, nil
+}
+
+// sumDBProxy is set by -sumdb
+var sumDBProxy *SumDBProxy
+
+// Handler serves <prefix><name>/supported, /latest, /lookup/... and
+// /tile/... as described in the GOPROXY protocol's sumdb section
+func (p *SumDBProxy) Handler(prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), prefix), "/")
+		if name != p.Name || strings.Contains(rest, "..") {
+			// The go command then talks to the database directly
+			http.NotFound(w, r)
+			return
+		}
+
+		var signed bool
+		switch {
+		case rest == "supported":
+			w.WriteHeader(http.StatusOK)
+			return
+		case rest == "latest", strings.HasPrefix(rest, "lookup/"):
+			signed = true
+		case strings.HasPrefix(rest, "tile/"):
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		body, resp, err := p.fetch(r, rest)
+		if err != nil {
+			p.lg.ErrorContext(r.Context(), "can't reach checksum database", "path", rest, "err", err)
+			middleware.Error(w, r, "checksum database unavailable", http.StatusBadGateway)
+			return
+		}
+		if resp.StatusCode != http.StatusOK {
+			// Pass along "not found" and the like, the go command reports them
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(resp.StatusCode)
+			w.Write(body)
+			return
+		}
+
+		if signed {
+			if err := verifyTree(p.verifier, body); err != nil {
+				sumDBRejected.Inc()
+				p.lg.ErrorContext(r.Context(), "checksum database response failed verification", "path", rest, "err", err)
+				middleware.Error(w, r, "checksum database response failed verification", http.StatusBadGateway)
+				return
+			}
+		}
+
+		for _, h := range []string{"Content-Type", "Cache-Control"} {
+			if v := resp.Header.Get(h); v != "" {
+				w.Header().Set(h, v)
+			}
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body)
+	})
+}
+
+func (p *SumDBProxy) fetch(r *http.Request, path string) ([]byte, *http.Response, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, p.Upstream+"/"+path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", "pkg.jsn.cam")
+
+	resp, err := p.HTTP.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSumDBResponse))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp, nil
+}
+
+// treeHeader starts the signed tree head in /latest and lookup responses
+const treeHeader = "go.sum database tree\n"
+
+// verifyTree checks that the signed tree head at the end of body is signed
+// by v
+func verifyTree(v note.Verifier, body []byte) error {
+	i := bytes.Index(body, []byte(treeHeader))
+	if i < 0 {
+		return errors.New("no signed tree head")
+	}
+	_, err := note.Open(body[i:], note.VerifierList(v))
+	return err
+}
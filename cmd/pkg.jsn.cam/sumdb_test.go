@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/rand"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/mod/sumdb/note"
+)
+
+// testSigner returns a verifier key for name and a function signing notes
+// with it
+func testSigner(t *testing.T, name string) (string, func(text string) string) {
+	skey, vkey, err := note.GenerateKey(rand.Reader, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := note.NewSigner(skey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(text string) string {
+		msg, err := note.Sign(&note.Note{Text: text}, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(msg)
+	}
+	return vkey, sign
+}
+
+func TestSumDBProxyKey(t *testing.T) {
+	p, err := NewSumDBProxy(SumGolangOrgKey, "", slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "sum.golang.org" || p.Upstream != "https://sum.golang.org" {
+		t.Errorf("got %s at %s", p.Name, p.Upstream)
+	}
+
+	for _, bad := range []string{"", "sum.golang.org", "sum.golang.org+033de0ae+AAAA", "sum.golang.org+00000000+Ac4zctda0e5eza+HJyk9SxEdh+s3Ux18htTTAD8OuAn8"} {
+		if _, err := NewSumDBProxy(bad, "", slog.Default()); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func TestSumDBProxy(t *testing.T) {
+	vkey, sign := testSigner(t, "sum.example.com")
+	_, forge := testSigner(t, "sum.example.com")
+
+	tree := "go.sum database tree\n42\nAAAA\n"
+	latest := sign(tree)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			io.WriteString(w, latest)
+		case "/lookup/pkg.jsn.cam/jsn@v1.0.0":
+			io.WriteString(w, "7\npkg.jsn.cam/jsn v1.0.0 h1:x=\n\n"+latest)
+		case "/lookup/pkg.jsn.cam/evil@v1.0.0":
+			io.WriteString(w, "8\npkg.jsn.cam/evil v1.0.0 h1:y=\n\n"+forge(tree))
+		case "/tile/8/0/000":
+			io.WriteString(w, "hashes")
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	p, err := NewSumDBProxy(vkey, upstream.URL, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := p.Handler("/sumdb/")
+
+	for _, tt := range []struct {
+		path string
+		want int
+	}{
+		{"/sumdb/sum.example.com/supported", http.StatusOK},
+		{"/sumdb/sum.golang.org/supported", http.StatusNotFound},
+		{"/sumdb/sum.example.com/latest", http.StatusOK},
+		{"/sumdb/sum.example.com/lookup/pkg.jsn.cam/jsn@v1.0.0", http.StatusOK},
+		{"/sumdb/sum.example.com/lookup/pkg.jsn.cam/evil@v1.0.0", http.StatusBadGateway},
+		{"/sumdb/sum.example.com/lookup/pkg.jsn.cam/missing@v1.0.0", http.StatusNotFound},
+		{"/sumdb/sum.example.com/tile/8/0/000", http.StatusOK},
+		{"/sumdb/sum.example.com/../latest", http.StatusNotFound},
+		{"/sumdb/sum.example.com/other", http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/a-h/templ"
+	"pkg.jsn.cam/jsn/jass"
+)
+
+// validTag reports whether tag, already lower-cased, is usable as a topic:
+// letters, digits and "-", "+", "." like GitHub topics and "c++"
+func validTag(tag string) bool {
+	if tag == "" || len(tag) > 50 {
+		return false
+	}
+	for _, c := range tag {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-', c == '+', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeTags lower-cases and trims tags, dropping duplicates. Invalid
+// tags are returned separately so the config loader can warn about them.
+func normalizeTags(tags []string) (valid, invalid []string) {
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		switch {
+		case !validTag(tag):
+			invalid = append(invalid, tag)
+		case !slices.Contains(valid, tag):
+			valid = append(valid, tag)
+		}
+	}
+	return valid, invalid
+}
+
+// tagURL is the page listing the repos tagged tag
+func tagURL(tag string) string {
+	return "/.jsn.tag/" + url.PathEscape(tag)
+}
+
+// tagChips renders tags as links to their pages, or nothing without tags
+func tagChips(tags []string) templ.Component {
+	if len(tags) == 0 {
+		return templ.NopComponent
+	}
+	var b strings.Builder
+	b.WriteString("<p>")
+	for i, tag := range tags {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(&b, `<a href="%s"><code>#%s</code></a>`, tagURL(tag), html.EscapeString(tag))
+	}
+	b.WriteString("</p>")
+	return templ.Raw(b.String())
+}
+
+// tagHandler serves /.jsn.tag/{tag...}, the repos with a tag, and
+// /.jsn.tag/ listing every tag in use
+func tagHandler(repos []Repo, notFound http.Handler) http.Handler {
+	byTag := make(map[string][]Repo)
+	for _, repo := range repos {
+		for _, tag := range repo.Tags {
+			byTag[tag] = append(byTag[tag], repo)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tag := r.PathValue("tag")
+		if tag == "" {
+			var b strings.Builder
+			b.WriteString("<section><ul>")
+			for _, tag := range slices.Sorted(maps.Keys(byTag)) {
+				fmt.Fprintf(&b, `<li><a href="%s"><code>#%s</code></a> <small>%d</small></li>`,
+					tagURL(tag), html.EscapeString(tag), len(byTag[tag]))
+			}
+			b.WriteString("</ul></section>")
+			templ.Handler(jass.Simple("Topics", templ.Raw(b.String()))).ServeHTTP(w, r)
+			return
+		}
+
+		tagged, ok := byTag[tag]
+		if !ok {
+			notFound.ServeHTTP(w, r)
+			return
+		}
+		var b strings.Builder
+		b.WriteString("<section><ul>")
+		for _, repo := range tagged {
+			fmt.Fprintf(&b, `<li><a href="/%s">%s</a> %s</li>`,
+				html.EscapeString(anchor(repo.Repo)), html.EscapeString(repo.Repo), html.EscapeString(repo.Summary()))
+		}
+		b.WriteString(`</ul><p><a href="/.jsn.tag/">All topics</a></p></section>`)
+		templ.Handler(jass.Simple("Packages tagged #"+tag, templ.Raw(b.String()))).ServeHTTP(w, r)
+	})
+}
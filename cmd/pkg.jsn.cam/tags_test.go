@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	valid, invalid := normalizeTags([]string{"CLI", " http ", "cli", "c++", "two words", ""})
+	if want := []string{"cli", "http", "c++"}; !slices.Equal(valid, want) {
+		t.Errorf("valid = %q, want %q", valid, want)
+	}
+	if want := []string{"two words", ""}; !slices.Equal(invalid, want) {
+		t.Errorf("invalid = %q, want %q", invalid, want)
+	}
+}
+
+func TestTags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	config := testConfig + `tags = ["Monorepo", "cli"]
+[abacus]
+tags = ["http"]
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	site, err := NewSite(path, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(url string) (int, string) {
+		rec := httptest.NewRecorder()
+		site.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		body, _ := io.ReadAll(rec.Result().Body)
+		return rec.Code, string(body)
+	}
+
+	if _, body := get("https://pkg.jsn.cam/"); !strings.Contains(body, `<a href="/.jsn.tag/monorepo"><code>#monorepo</code></a>`) {
+		t.Errorf("index has no tag chips:\n%s", body)
+	}
+	if code, body := get("https://pkg.jsn.cam/.jsn.tag/cli"); code != http.StatusOK || !strings.Contains(body, `href="/#jsn"`) || strings.Contains(body, "abacus") {
+		t.Errorf("tag page: got %d\n%s", code, body)
+	}
+	if code, body := get("https://pkg.jsn.cam/.jsn.tag/"); code != http.StatusOK || !strings.Contains(body, "#http") {
+		t.Errorf("topics page: got %d\n%s", code, body)
+	}
+	if code, _ := get("https://pkg.jsn.cam/.jsn.tag/nope"); code != http.StatusNotFound {
+		t.Errorf("unknown tag: got %d, want 404", code)
+	}
+
+	_, body := get("https://pkg.jsn.cam/api/repos?tag=http")
+	var resp struct{ Repos []apiRepo }
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Repos) != 1 || resp.Repos[0].Name != "abacus" || !slices.Equal(resp.Repos[0].Tags, []string{"http"}) {
+		t.Errorf("/api/repos?tag=http = %+v", resp.Repos)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"pkg.jsn.cam/jsn/internal/middleware"
+)
+
+// maxWebhookBody caps the size of a webhook payload
+const maxWebhookBody = 1 << 20
+
+// GitWebhook updates the git checkout the config lives in when GitHub
+// reports a push to it, and reloads the site if the config changed. This
+// lets the config be managed by pushing to its repo instead of editing it
+// on the server.
+type GitWebhook struct {
+	// Secret is the webhook secret payloads are signed with
+	Secret []byte
+	// Dir is the git work tree to pull
+	Dir string
+
+	site *Site
+	lg   *slog.Logger
+	mu   sync.Mutex // one pull at a time
+}
+
+// NewGitWebhook creates a webhook pulling dir and reloading site
+func NewGitWebhook(secret, dir string, site *Site, lg *slog.Logger) *GitWebhook {
+	return &GitWebhook{Secret: []byte(secret), Dir: dir, site: site, lg: lg}
+}
+
+// ServeHTTP implements http.Handler for GitHub's push and ping events
+func (h *GitWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "can't read body", http.StatusBadRequest)
+		return
+	}
+	if !h.validSignature(r.Header.Get("X-Hub-Signature-256"), body) {
+		webhookRequests.WithLabelValues("bad_signature").Inc()
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Header.Get("X-GitHub-Event") {
+	case "push":
+	case "ping":
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		webhookRequests.WithLabelValues("ignored").Inc()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	changed, err := h.pull(r.Context())
+	if err != nil {
+		webhookRequests.WithLabelValues("error").Inc()
+		h.lg.ErrorContext(r.Context(), "can't update config from git", "dir", h.Dir, "err", err)
+		middleware.Error(w, r, "can't update config", http.StatusInternalServerError)
+		return
+	}
+	if !changed {
+		webhookRequests.WithLabelValues("unchanged").Inc()
+		fmt.Fprintln(w, "config unchanged")
+		return
+	}
+
+	if err := h.site.Reload(); err != nil {
+		webhookRequests.WithLabelValues("error").Inc()
+		h.lg.ErrorContext(r.Context(), "can't reload config, keeping the previous one", "reason", "webhook", "err", err)
+		http.Error(w, "can't reload config: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	webhookRequests.WithLabelValues("reloaded").Inc()
+	h.lg.InfoContext(r.Context(), "reloaded config", "reason", "webhook")
+	fmt.Fprintln(w, "config reloaded")
+}
+
+// validSignature checks a "sha256=<hex>" HMAC of body
+func (h *GitWebhook) validSignature(header string, body []byte) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, h.Secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// pull fast-forwards the checkout and reports whether the config file
+// changed
+func (h *GitWebhook) pull(ctx context.Context) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	before, _ := os.ReadFile(h.site.path)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "-C", h.Dir, "pull", "--ff-only", "--quiet")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return false, fmt.Errorf("failed to pull: %v: %s", err, bytes.TrimSpace(out))
+	}
+
+	after, err := os.ReadFile(h.site.path)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(before, after), nil
+}
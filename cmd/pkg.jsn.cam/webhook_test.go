@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `[repo.github]
+username = "JasonLovesDoggo"
+url = "github.com"
+default = true
+
+[jsn]
+`
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	args = append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestGitWebhook(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	// An upstream repo with the config, and the server's checkout of it
+	upstream := t.TempDir()
+	git(t, upstream, "init", "--quiet")
+	if err := os.WriteFile(filepath.Join(upstream, "config.toml"), []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, upstream, "add", "config.toml")
+	git(t, upstream, "commit", "--quiet", "-m", "config")
+
+	checkout := filepath.Join(t.TempDir(), "config")
+	git(t, ".", "clone", "--quiet", upstream, checkout)
+
+	site, err := NewSite(filepath.Join(checkout, "config.toml"), slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewGitWebhook("s3cret", checkout, site, slog.Default())
+
+	deliver := func(event, secret string) int {
+		body := `{"ref":"refs/heads/main"}`
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+
+		req := httptest.NewRequest(http.MethodPost, "/.jsn.webhook", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := deliver("push", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("bad signature: got %d, want 401", code)
+	}
+	if code := deliver("ping", "s3cret"); code != http.StatusNoContent {
+		t.Errorf("ping: got %d, want 204", code)
+	}
+	if code := deliver("push", "s3cret"); code != http.StatusOK {
+		t.Errorf("unchanged push: got %d, want 200", code)
+	}
+	if len(site.Repos()) != 1 {
+		t.Fatalf("got %d repos before the push, want 1", len(site.Repos()))
+	}
+
+	// Pushing a new package reloads the config
+	if err := os.WriteFile(filepath.Join(upstream, "config.toml"), []byte(testConfig+"[abacus]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, upstream, "commit", "--quiet", "-am", "add abacus")
+
+	if code := deliver("push", "s3cret"); code != http.StatusOK {
+		t.Errorf("push: got %d, want 200", code)
+	}
+	if len(site.Repos()) != 2 {
+		t.Errorf("got %d repos after the push, want 2", len(site.Repos()))
+	}
+}
@@ -1,121 +1,274 @@
 package main
 
 import (
-	"errors"
+	"bufio"
 	"flag"
 	"fmt"
 	"os"
-	"os/exec"
-	"strconv"
+	"os/user"
+	"slices"
 	"strings"
+	"syscall"
 
 	"pkg.jsn.cam/jsn/internal"
 )
 
 var (
-	force   = flag.Bool("f", false, "Force kill the process (SIGKILL instead of SIGTERM)")
-	list    = flag.Bool("l", false, "List processes using the port but don't kill them")
-	verbose = flag.Bool("v", false, "Verbose output")
+	force       = flag.Bool("f", false, "Force kill the process (SIGKILL instead of SIGTERM)")
+	list        = flag.Bool("l", false, "List processes using the port but don't kill them")
+	interactive = flag.Bool("i", false, "List processes using the port and ask before killing each one")
+	verbose     = flag.Bool("v", false, "Verbose output")
+	proto       = flag.String("proto", "all", "Only match sockets of this protocol: tcp, udp or all")
+	state       = flag.String("state", "all", "Only match sockets in this state: listen, established or all")
+	owner       = flag.String("user", "", "Only handle processes of this user name or UID; other users' processes on the port are reported")
 )
 
+// ownerUID is the UID of -user, or empty for anyone.
+var ownerUID string
+
+// socketFilter selects the sockets whose processes are found, by protocol
+// (tcp or udp) and state (listen or established). "all" matches anything.
+type socketFilter struct {
+	proto, state string
+}
+
+func (f socketFilter) matches(proto, state string) bool {
+	return (f.proto == "all" || f.proto == proto) && (f.state == "all" || f.state == state)
+}
+
 func main() {
+	// Bad flags exit with exitError rather than the flag package's 2, which means a partial failure here
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		os.Exit(exitError)
+	}
 	internal.HandleStartup()
 
 	if flag.NArg() == 0 {
 		printUsage()
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
-	for _, arg := range flag.Args() {
-		port, err := strconv.Atoi(arg)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %q is not a valid port number\n", arg)
-			os.Exit(1)
-		}
+	filter := socketFilter{proto: strings.ToLower(*proto), state: strings.ToLower(*state)}
+	if !slices.Contains([]string{"tcp", "udp", "all"}, filter.proto) {
+		fmt.Fprintf(os.Stderr, "Error: -proto must be tcp, udp or all, not %q\n", *proto)
+		os.Exit(exitError)
+	}
+	if !slices.Contains([]string{"listen", "established", "all"}, filter.state) {
+		fmt.Fprintf(os.Stderr, "Error: -state must be listen, established or all, not %q\n", *state)
+		os.Exit(exitError)
+	}
 
-		if err := handlePort(port); err != nil {
-			fmt.Fprintf(os.Stderr, "Error handling port %d: %v\n", port, err)
-			os.Exit(1)
+	if *owner != "" {
+		u, err := user.Lookup(*owner)
+		if err != nil {
+			if u, err = user.LookupId(*owner); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: unknown user %q\n", *owner)
+				os.Exit(exitError)
+			}
 		}
+		ownerUID = u.Uid
 	}
-}
 
-func handlePort(port int) error {
-	pids, err := findPIDsByPort(port)
+	finder, err := newPortFinder(filter)
 	if err != nil {
-		return fmt.Errorf("failed to find PIDs using port %d: %w", port, err)
-	}
-
-	if len(pids) == 0 {
-		fmt.Printf("No processes found using port %d\n", port)
-		return nil
+		fmt.Fprintf(os.Stderr, "Error: failed to read sockets: %v\n", err)
+		os.Exit(exitError)
 	}
 
-	for _, pid := range pids {
-		procInfo, err := getProcessInfo(pid)
+	// A process using several of the ports is only handled once. Errors are
+	// reported as they happen, and the remaining ports still handled.
+	seen := map[int]bool{}
+	var total portResult
+	errs := 0
+	for _, arg := range flag.Args() {
+		ports, err := parsePorts(arg)
 		if err != nil {
-			return fmt.Errorf("failed to get process info for PID %s: %w", pid, err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
 		}
 
-		if *list {
-			fmt.Printf("PID %s: %s\n", pid, procInfo)
-		} else {
-			signal := "TERM"
-			if *force {
-				signal = "KILL"
-			}
-
-			if *verbose {
-				fmt.Printf("Killing process %s (%s) with SIG%s\n", pid, procInfo, signal)
-			} else {
-				fmt.Printf("Killing process %s with SIG%s\n", pid, signal)
+		found, argErrs := 0, 0
+		for _, port := range ports {
+			result, err := handlePort(finder, port, seen)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error handling port %d: %v\n", port, err)
+				argErrs++
 			}
-
-			cmd := exec.Command("kill", fmt.Sprintf("-%s", signal), pid)
-			if err := cmd.Run(); err != nil {
-				return fmt.Errorf("failed to kill process %s: %w", pid, err)
+			if result.found > 0 {
+				fmt.Printf("Port %d: %s\n", port, result)
 			}
+			found += result.found
+			total.add(result)
+		}
+		errs += argErrs
+		if found == 0 && argErrs == 0 {
+			fmt.Printf("No processes found using port %s\n", arg)
 		}
 	}
+	os.Exit(exitCode(total, errs))
+}
 
-	return nil
+// Exit codes, so scripts can tell what happened.
+const (
+	exitKilled  = 0 // every process found was killed, skipped at the -i prompt or listed with -l
+	exitNone    = 1 // no processes were found on any of the ports
+	exitPartial = 2 // some processes were handled, but others couldn't be killed or belong to other users
+	exitError   = 3 // bad arguments, or nothing could be handled
+)
+
+// exitCode returns the exit code for the totals of all the ports and the
+// number of ports whose processes couldn't be looked up.
+func exitCode(total portResult, errs int) int {
+	problems := total.failed + total.leftAlone + errs
+	handled := total.listed + total.killed + total.skipped
+	switch {
+	case problems > 0 && handled > 0:
+		return exitPartial
+	case problems > 0:
+		return exitError
+	case total.found == 0:
+		return exitNone
+	}
+	return exitKilled
 }
 
-func findPIDsByPort(port int) ([]string, error) {
-	// Use lsof to find processes using the port
-	cmd := exec.Command("lsof", "-i", fmt.Sprintf(":%d", port), "-t")
-	output, err := cmd.Output()
-	if err != nil {
-		// lsof returns error if no processes found, which isn't an error for us
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
-			return []string{}, nil
+// portResult counts what happened to the processes found on a port.
+type portResult struct {
+	// found includes processes already handled on an earlier port.
+	found                   int
+	listed, killed, skipped int
+	// failed couldn't be killed; leftAlone belong to other users than -user.
+	failed, leftAlone int
+}
+
+func (r *portResult) add(o portResult) {
+	r.found += o.found
+	r.listed += o.listed
+	r.killed += o.killed
+	r.skipped += o.skipped
+	r.failed += o.failed
+	r.leftAlone += o.leftAlone
+}
+
+func (r portResult) String() string {
+	var parts []string
+	for _, c := range []struct {
+		n    int
+		what string
+	}{{r.listed, "listed"}, {r.killed, "killed"}, {r.skipped, "skipped"}, {r.failed, "failed"}, {r.leftAlone, "left alone"}} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.what))
 		}
-		return nil, err
 	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("%d found, already handled on another port", r.found)
+	}
+	return strings.Join(parts, ", ")
+}
 
-	pids := strings.Split(strings.TrimSpace(string(output)), "\n")
-	var result []string
-	for _, pid := range pids {
-		if pid != "" {
-			result = append(result, pid)
-		}
+// handlePort lists or kills the processes finder finds on port, skipping
+// those already seen, and reports what happened to them.
+func handlePort(finder *portFinder, port int, seen map[int]bool) (portResult, error) {
+	procs, err := finder.processes(port)
+	if err != nil {
+		return portResult{}, fmt.Errorf("failed to find PIDs using port %d: %w", port, err)
 	}
 
+	result := portResult{found: len(procs)}
+	var fresh []portProcess
+	var infos, others []*processInfo
+	for _, p := range procs {
+		if seen[p.pid] {
+			continue
+		}
+		seen[p.pid] = true
+		info, err := getProcessInfo(p.pid)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error handling port %d: failed to get process info for PID %d: %v\n", port, p.pid, err)
+			result.failed++
+			continue
+		}
+		if ownerUID != "" && info.uid != ownerUID {
+			others = append(others, info)
+			continue
+		}
+		fresh = append(fresh, p)
+		infos = append(infos, info)
+	}
+	handleProcesses(port, fresh, infos, &result)
+	if len(others) > 0 {
+		result.leftAlone = len(others)
+		var list []string
+		for _, info := range others {
+			list = append(list, fmt.Sprintf("%d (%s, %s)", info.pid, info.user, info.name))
+		}
+		also := ""
+		if len(fresh) > 0 {
+			also = "also "
+		}
+		fmt.Fprintf(os.Stderr, "Error handling port %d: port %d is %sused by other users' processes, which were left alone: %s\n", port, port, also, strings.Join(list, ", "))
+	}
 	return result, nil
 }
 
-func getProcessInfo(pid string) (string, error) {
-	cmd := exec.Command("ps", "-p", pid, "-o", "comm=")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
+// handleProcesses lists or kills the processes found on port, counting them in result.
+func handleProcesses(port int, fresh []portProcess, infos []*processInfo, result *portResult) {
+	if len(fresh) == 0 {
+		return
+	}
+
+	if *list || *interactive {
+		printProcessTable(os.Stdout, port, fresh, infos)
+	}
+	if *list {
+		result.listed += len(fresh)
+		return
 	}
-	return strings.TrimSpace(string(output)), nil
+
+	for i, p := range fresh {
+		info := infos[i]
+		if *interactive && !confirm(fmt.Sprintf("Kill process %d (%s)? [y/N] ", p.pid, info.name)) {
+			result.skipped++
+			continue
+		}
+
+		signal, name := syscall.SIGTERM, "TERM"
+		if *force {
+			signal, name = syscall.SIGKILL, "KILL"
+		}
+
+		if *verbose {
+			fmt.Printf("Killing process %d (%s) with SIG%s\n", p.pid, info.name, name)
+		} else {
+			fmt.Printf("Killing process %d with SIG%s\n", p.pid, name)
+		}
+
+		if err := signalProcess(p.pid, signal); err != nil {
+			fmt.Fprintf(os.Stderr, "Error handling port %d: failed to kill process %d: %v\n", port, p.pid, err)
+			result.failed++
+			continue
+		}
+		result.killed++
+	}
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes or no question on stdin, defaulting to no.
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, "Usage: portkill [options] port [port...]\n\n")
+	fmt.Fprintf(os.Stderr, "Usage: portkill [options] port|first-last|service [...]\n\n")
 	fmt.Fprintf(os.Stderr, "Options:\n")
 	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nExit status: 0 if every process found was handled, 1 if none were found,\n")
+	fmt.Fprintf(os.Stderr, "2 if some were handled but others failed, 3 on errors.\n")
 }
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		total portResult
+		errs  int
+		want  int
+	}{
+		{"killed", portResult{found: 2, killed: 2}, 0, exitKilled},
+		{"listed", portResult{found: 1, listed: 1}, 0, exitKilled},
+		{"skipped", portResult{found: 1, skipped: 1}, 0, exitKilled},
+		{"nothing", portResult{}, 0, exitNone},
+		{"partial", portResult{found: 2, killed: 1, failed: 1}, 0, exitPartial},
+		{"other users", portResult{found: 2, killed: 1, leftAlone: 1}, 0, exitPartial},
+		{"lookup failed", portResult{found: 1, killed: 1}, 1, exitPartial},
+		{"all failed", portResult{found: 1, failed: 1}, 0, exitError},
+		{"error only", portResult{}, 1, exitError},
+	} {
+		if got := exitCode(tc.total, tc.errs); got != tc.want {
+			t.Errorf("%s: exitCode = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPortResultString(t *testing.T) {
+	for r, want := range map[portResult]string{
+		{found: 3, killed: 2, failed: 1}:     "2 killed, 1 failed",
+		{found: 1, listed: 1}:                "1 listed",
+		{found: 2, skipped: 1, leftAlone: 1}: "1 skipped, 1 left alone",
+		{found: 1}:                           "1 found, already handled on another port",
+	} {
+		if got := r.String(); got != want {
+			t.Errorf("%+v: String = %q, want %q", r, got, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// parsePorts expands a port argument: a port number, a range like 8000-8100,
+// or a service name from /etc/services like https.
+func parsePorts(arg string) ([]int, error) {
+	// Service names may have dashes too, like http-alt
+	if first, last, ok := strings.Cut(arg, "-"); ok && isNumber(first) && isNumber(last) {
+		lo, err := parsePort(first)
+		if err != nil {
+			return nil, err
+		}
+		hi, err := parsePort(last)
+		if err != nil {
+			return nil, err
+		}
+		if lo > hi {
+			return nil, fmt.Errorf("%q is not a valid port range", arg)
+		}
+		ports := make([]int, 0, hi-lo+1)
+		for port := lo; port <= hi; port++ {
+			ports = append(ports, port)
+		}
+		return ports, nil
+	}
+
+	if isNumber(arg) {
+		port, err := parsePort(arg)
+		if err != nil {
+			return nil, err
+		}
+		return []int{port}, nil
+	}
+
+	var ports []int
+	for _, network := range []string{"tcp", "udp"} {
+		port, err := net.DefaultResolver.LookupPort(context.Background(), network, arg)
+		if err == nil && !slices.Contains(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("%q is not a port number, range or service name", arg)
+	}
+	return ports, nil
+}
+
+// isNumber reports whether s is a decimal number, like a port or the end of a
+// range of them.
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("%q is not a valid port number", s)
+	}
+	return port, nil
+}
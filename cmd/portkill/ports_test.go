@@ -0,0 +1,29 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePorts(t *testing.T) {
+	for _, tc := range []struct {
+		arg  string
+		want []int
+	}{
+		{"8080", []int{8080}},
+		{"8000-8003", []int{8000, 8001, 8002, 8003}},
+		{"22-22", []int{22}},
+		{"https", []int{443}},
+		{"http-alt", []int{8080}},
+	} {
+		got, err := parsePorts(tc.arg)
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("parsePorts(%q) = %v, %v, want %v", tc.arg, got, err, tc.want)
+		}
+	}
+	for _, arg := range []string{"0", "65536", "-1", "8100-8000", "80-", "80-x", "no-such-service-here"} {
+		if ports, err := parsePorts(arg); err == nil {
+			t.Errorf("parsePorts(%q) = %v, want an error", arg, ports)
+		}
+	}
+}
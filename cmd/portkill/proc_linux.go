@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"pkg.jsn.cam/jsn/internal/procnet"
+)
+
+// portFinder finds the processes using ports. It reads the socket tables
+// once, and the /proc/<pid>/fd links once a port has sockets, however many
+// ports it's asked about.
+type portFinder struct {
+	filter  socketFilter
+	sockets []procnet.Socket
+	owners  map[uint64][]int
+}
+
+func newPortFinder(filter socketFilter) (*portFinder, error) {
+	sockets, err := procnet.Read()
+	if err != nil {
+		return nil, err
+	}
+	return &portFinder{filter: filter, sockets: sockets}, nil
+}
+
+// processes returns the processes with a socket matching the filter whose
+// local or remote port is port, like lsof -i :port, ordered by PID.
+func (f *portFinder) processes(port int) ([]portProcess, error) {
+	matching := map[uint64]procnet.Socket{}
+	for _, s := range f.sockets {
+		// Sockets in TIME_WAIT have no inode and belong to no one
+		if s.Inode != 0 && (int(s.Local.Port()) == port || int(s.Remote.Port()) == port) && f.filter.matches(strings.TrimSuffix(s.Proto, "6"), s.State) {
+			matching[s.Inode] = s
+		}
+	}
+	if len(matching) == 0 {
+		return []portProcess{}, nil
+	}
+
+	if f.owners == nil {
+		owners, err := procnet.Owners()
+		if err != nil {
+			return nil, err
+		}
+		f.owners = owners
+	}
+	owners := f.owners
+	byPID := map[int]*portProcess{}
+	for inode, s := range matching {
+		addr := s.Local.String()
+		if s.State == "established" {
+			addr += "->" + s.Remote.String()
+		}
+		for _, pid := range owners[inode] {
+			if byPID[pid] == nil {
+				byPID[pid] = &portProcess{pid: pid}
+			}
+			if !slices.Contains(byPID[pid].addrs, addr) {
+				byPID[pid].addrs = append(byPID[pid].addrs, addr)
+			}
+		}
+	}
+	if len(byPID) == 0 && os.Geteuid() != 0 {
+		var users []string
+		for _, s := range matching {
+			name := s.UID
+			if u, err := user.LookupId(s.UID); err == nil {
+				name = u.Username
+			}
+			if !slices.Contains(users, name) {
+				users = append(users, name)
+			}
+		}
+		slices.Sort(users)
+		return nil, fmt.Errorf("port %d is in use by processes of %s; run as root to see them", port, strings.Join(users, ", "))
+	}
+	result := make([]portProcess, 0, len(byPID))
+	for _, p := range byPID {
+		slices.Sort(p.addrs)
+		result = append(result, *p)
+	}
+	slices.SortFunc(result, func(a, b portProcess) int { return a.pid - b.pid })
+	return result, nil
+}
+
+// clockTicks is USER_HZ, the unit of times in /proc/<pid>/stat, which is 100
+// on every architecture Linux supports.
+const clockTicks = 100
+
+func getProcessInfo(pid int) (*processInfo, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+	info := &processInfo{pid: pid}
+
+	comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+	if err != nil {
+		return nil, err
+	}
+	info.name = strings.TrimSpace(string(comm))
+
+	// Arguments are NUL-terminated; kernel threads have none
+	cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	info.command = strings.Join(strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00"), " ")
+	if info.command == "" {
+		info.command = "[" + info.name + "]"
+	}
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	info.uid = strconv.FormatUint(uint64(fi.Sys().(*syscall.Stat_t).Uid), 10)
+	info.user = info.uid
+	if u, err := user.LookupId(info.uid); err == nil {
+		info.user = u.Username
+	}
+
+	if started, err := processStart(dir); err == nil {
+		info.uptime = time.Since(started)
+	}
+	return info, nil
+}
+
+// processStart returns when the process in dir started, from its start time in
+// clock ticks since boot and the boot time in /proc/stat.
+func processStart(dir string) (time.Time, error) {
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	// The name in parentheses may contain spaces; starttime is the 22nd field,
+	// the 20th after it
+	i := strings.LastIndexByte(string(stat), ')')
+	fields := strings.Fields(string(stat[i+1:]))
+	if i < 0 || len(fields) < 20 {
+		return time.Time{}, fmt.Errorf("bad %s/stat", dir)
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad %s/stat", dir)
+	}
+
+	procStat, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for line := range strings.SplitSeq(string(procStat), "\n") {
+		if btime, ok := strings.CutPrefix(line, "btime "); ok {
+			boot, err := strconv.ParseInt(btime, 10, 64)
+			if err != nil {
+				break
+			}
+			return time.Unix(boot, 0).Add(time.Duration(ticks) * time.Second / clockTicks), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no boot time in /proc/stat")
+}
+
+func signalProcess(pid int, signal syscall.Signal) error {
+	return syscall.Kill(pid, signal)
+}
@@ -0,0 +1,105 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// portFinder asks lsof for the processes using ports, as there's no /proc
+// to read. lsof only knows the states of TCP sockets, so filtering by state
+// leaves UDP sockets out.
+type portFinder struct {
+	filter socketFilter
+}
+
+func newPortFinder(filter socketFilter) (*portFinder, error) {
+	return &portFinder{filter: filter}, nil
+}
+
+// processes returns the processes with a socket matching the filter on port.
+func (f *portFinder) processes(port int) ([]portProcess, error) {
+	// Numeric addresses and ports, one field per line: p<pid> then n<address> per socket
+	args := []string{"-n", "-P", "-F", "pn"}
+	if f.filter.state == "all" {
+		if f.filter.proto == "all" {
+			args = append(args, "-i", fmt.Sprintf(":%d", port))
+		} else {
+			args = append(args, "-i", fmt.Sprintf("%s:%d", f.filter.proto, port))
+		}
+	} else {
+		if f.filter.proto == "udp" {
+			return nil, errors.New("-state needs -proto tcp or all here, as lsof only knows TCP states")
+		}
+		args = append(args, "-i", fmt.Sprintf("tcp:%d", port), "-s", "TCP:"+strings.ToUpper(f.filter.state))
+	}
+	cmd := exec.Command("lsof", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		// lsof returns error if no processes found, which isn't an error for us
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return []portProcess{}, nil
+		}
+		return nil, err
+	}
+
+	var result []portProcess
+	for line := range strings.Lines(string(output)) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		switch value := line[1:]; line[0] {
+		case 'p':
+			pid, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected lsof output %q", line)
+			}
+			result = append(result, portProcess{pid: pid})
+		case 'n':
+			if len(result) > 0 && !slices.Contains(result[len(result)-1].addrs, value) {
+				result[len(result)-1].addrs = append(result[len(result)-1].addrs, value)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func getProcessInfo(pid int) (*processInfo, error) {
+	info := &processInfo{pid: pid}
+	output, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "uid=,user=,etime=,command=").Output()
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("unexpected ps output %q", output)
+	}
+	info.uid, info.user, info.command = fields[0], fields[1], strings.Join(fields[3:], " ")
+	info.uptime, _ = parseEtime(fields[2])
+
+	output, err = exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+	info.name = filepath.Base(strings.TrimSpace(string(output)))
+	return info, nil
+}
+
+func signalProcess(pid int, signal syscall.Signal) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(signal)
+}
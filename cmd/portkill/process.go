@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+)
+
+// portProcess is a process with sockets on a port.
+type portProcess struct {
+	pid int
+	// addrs are the sockets' addresses: local, and ->remote when connected.
+	addrs []string
+}
+
+// processInfo describes a process for the -l and -i tables.
+type processInfo struct {
+	pid int
+	// name is the executable name, command the full command line.
+	name, command string
+	uid, user     string
+	uptime        time.Duration
+}
+
+// printProcessTable writes a table of the processes using port.
+func printProcessTable(w io.Writer, port int, procs []portProcess, infos []*processInfo) {
+	fmt.Fprintf(w, "Port %d:\n", port)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  PID\tUSER\tUPTIME\tADDRESS\tCOMMAND")
+	for i, p := range procs {
+		info := infos[i]
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%s\t%s\n", p.pid, info.user, formatUptime(info.uptime), strings.Join(p.addrs, ","), oneLine(info.command))
+	}
+	tw.Flush()
+}
+
+// oneLine replaces control characters, like newlines in arguments, with spaces.
+func oneLine(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// formatUptime formats d briefly, e.g. 3d4h, 2h5m or 42s.
+func formatUptime(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "?"
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd%dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%dm", d/time.Hour, d%time.Hour/time.Minute)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm%ds", d/time.Minute, d%time.Minute/time.Second)
+	}
+	return fmt.Sprintf("%ds", d/time.Second)
+}
+
+// parseEtime parses the elapsed time printed by ps -o etime, [[dd-]hh:]mm:ss.
+func parseEtime(s string) (time.Duration, error) {
+	var d time.Duration
+	days, rest, ok := strings.Cut(s, "-")
+	if ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("bad elapsed time %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		rest = days
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) < 2 || len(parts) > 3 || ok && len(parts) != 3 {
+		return 0, fmt.Errorf("bad elapsed time %q", s)
+	}
+	units := []time.Duration{time.Second, time.Minute, time.Hour}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("bad elapsed time %q", s)
+		}
+		d += time.Duration(n) * units[len(parts)-1-i]
+	}
+	return d, nil
+}
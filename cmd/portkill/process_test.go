@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEtime(t *testing.T) {
+	for _, tc := range []struct {
+		etime string
+		want  time.Duration
+	}{
+		{"00:42", 42 * time.Second},
+		{"12:05", 12*time.Minute + 5*time.Second},
+		{"03:00:01", 3*time.Hour + time.Second},
+		{"2-01:00:00", 49 * time.Hour},
+	} {
+		if got, err := parseEtime(tc.etime); err != nil || got != tc.want {
+			t.Errorf("parseEtime(%q) = %v, %v, want %v", tc.etime, got, err, tc.want)
+		}
+	}
+	for _, bad := range []string{"", "42", "2-05:00", "a:b", "1:2:3:4"} {
+		if _, err := parseEtime(bad); err == nil {
+			t.Errorf("parseEtime(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                              "?",
+		42 * time.Second:               "42s",
+		5*time.Minute + 30*time.Second: "5m30s",
+		2*time.Hour + 5*time.Minute:    "2h5m",
+		76 * time.Hour:                 "3d4h",
+	} {
+		if got := formatUptime(d); got != want {
+			t.Errorf("formatUptime(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestPrintProcessTable(t *testing.T) {
+	var sb strings.Builder
+	printProcessTable(&sb, 8080,
+		[]portProcess{{pid: 42, addrs: []string{"127.0.0.1:8080"}}},
+		[]*processInfo{{pid: 42, name: "python3", command: "python3 -m http.server 8080", user: "jason", uptime: time.Minute}})
+	want := "Port 8080:\n" +
+		"  PID  USER   UPTIME  ADDRESS         COMMAND\n" +
+		"  42   jason  1m0s    127.0.0.1:8080  python3 -m http.server 8080\n"
+	if sb.String() != want {
+		t.Errorf("table:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
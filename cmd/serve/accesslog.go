@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// accessLog is where combined format access logs are written.
+var accessLog io.Writer = os.Stderr
+
+// responseRecorder captures the status code and body size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	code  int
+	bytes int64
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, code: http.StatusOK}
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	rr.code = code
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *responseRecorder) Write(p []byte) (int, error) {
+	n, err := rr.ResponseWriter.Write(p)
+	rr.bytes += int64(n)
+	return n, err
+}
+
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// loggingMiddleware logs each request once it's been answered, as slog
+// attributes or, if format is "combined", in the Apache combined format.
+func loggingMiddleware(next http.Handler, format string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rr := newResponseRecorder(w)
+		start := time.Now()
+		next.ServeHTTP(rr, r)
+
+		if format == "combined" {
+			fmt.Fprintln(accessLog, combinedLogLine(r, rr.code, rr.bytes, start))
+			return
+		}
+		slog.InfoContext(r.Context(), "request",
+			"remote", r.RemoteAddr,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rr.code,
+			"bytes", rr.bytes,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			"user_agent", r.UserAgent(),
+		)
+	})
+}
+
+// combinedLogLine formats a request in the Apache combined log format.
+func combinedLogLine(r *http.Request, code int, bytes int64, start time.Time) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	user := "-"
+	if u, _, ok := r.BasicAuth(); ok && u != "" {
+		user = u
+	}
+	size := "-"
+	if bytes > 0 {
+		size = strconv.FormatInt(bytes, 10)
+	}
+	return fmt.Sprintf("%s - %s [%s] %s %d %s %s %s",
+		host, user, start.Format("02/Jan/2006:15:04:05 -0700"),
+		strconv.Quote(r.Method+" "+r.RequestURI+" "+r.Proto), code, size,
+		strconv.Quote(orDash(r.Referer())), strconv.Quote(orDash(r.UserAgent())))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestCombinedLogLine(t *testing.T) {
+	r := httptest.NewRequest("GET", "/a%20b.txt?x=1", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("Referer", "http://example.com/")
+	r.Header.Set("User-Agent", `curl/8.0 "quoted"`)
+	r.SetBasicAuth("frank", "secret")
+	start := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600))
+
+	got := combinedLogLine(r, 200, 2326, start)
+	want := `192.0.2.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /a%20b.txt?x=1 HTTP/1.1" 200 2326 "http://example.com/" "curl/8.0 \"quoted\""`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	r = httptest.NewRequest("HEAD", "/", nil)
+	got = combinedLogLine(r, 304, 0, start)
+	want = `192.0.2.1 - - [10/Oct/2000:13:55:36 -0700] "HEAD / HTTP/1.1" 304 - "-" "-"`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	defer func(w io.Writer) { accessLog = w }(accessLog)
+	var buf bytes.Buffer
+	accessLog = &buf
+
+	h := loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "short and stout")
+	}), "combined")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/pot", nil))
+
+	if !regexp.MustCompile(`^192\.0\.2\.1 - - \[.*\] "GET /pot HTTP/1\.1" 418 15 "-" "-"\n$`).Match(buf.Bytes()) {
+		t.Errorf("logged %q", buf.String())
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checksums caches the SHA-256 of the files of a directory, until they change.
+type checksums struct {
+	root  http.FileSystem
+	cache sync.Map // name -> fileSum
+}
+
+type fileSum struct {
+	modTime time.Time
+	size    int64
+	sum     string
+}
+
+// sum returns the hex SHA-256 of the regular file name, hashing it unless
+// it's cached and the file's size and modification time haven't changed.
+func (c *checksums) sum(name string) (string, bool) {
+	f, err := c.root.Open(name)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return "", false
+	}
+
+	if v, ok := c.cache.Load(name); ok {
+		if cs := v.(fileSum); cs.modTime.Equal(fi.ModTime()) && cs.size == fi.Size() {
+			return cs.sum, true
+		}
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", false
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	c.cache.Store(name, fileSum{modTime: fi.ModTime(), size: fi.Size(), sum: sum})
+	return sum, true
+}
+
+// checksumMiddleware adds the SHA-256 of files from root to their responses
+// as X-Checksum-SHA256, and as their ETag so that resumed downloads with
+// If-Range only continue the same file. A request for <file>.sha256 that
+// doesn't exist is answered with the checksum in sha256sum's format.
+func checksumMiddleware(root http.FileSystem, next http.Handler) http.Handler {
+	c := &checksums{root: root}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name := path.Clean("/" + r.URL.Path)
+		if file, ok := strings.CutSuffix(name, ".sha256"); ok && !exists(root, name) {
+			if sum, ok := c.sum(file); ok {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.Header().Set("X-Content-Type-Options", "nosniff")
+				io.WriteString(w, sum+"  "+path.Base(file)+"\n")
+				return
+			}
+		}
+
+		if sum, ok := c.sum(name); ok {
+			w.Header().Set("X-Checksum-SHA256", sum)
+			w.Header().Set("ETag", `"`+sum+`"`)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func exists(root http.FileSystem, name string) bool {
+	f, err := root.Open(name)
+	if err != nil {
+		return !errors.Is(err, fs.ErrNotExist)
+	}
+	f.Close()
+	return true
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestChecksumMiddleware(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.tar":       {Data: []byte("hello\n"), ModTime: time.Unix(1, 0)},
+		"dist/b.sha256": {Data: []byte("published\n")},
+		"dist/b":        {Data: []byte("b")},
+		"dist/c/.keep":  {},
+	}
+	srv := httptest.NewServer(checksumMiddleware(http.FS(fsys), listingHandler(http.FS(fsys), "", false)))
+	defer srv.Close()
+
+	get := func(path string, header ...string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	const hello = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	resp, _ := get("/app.tar")
+	if got := resp.Header.Get("X-Checksum-SHA256"); got != hello {
+		t.Errorf("X-Checksum-SHA256 = %q, want %q", got, hello)
+	}
+	if got := resp.Header.Get("ETag"); got != `"`+hello+`"` {
+		t.Errorf("ETag = %q", got)
+	}
+	if _, body := get("/app.tar.sha256"); body != hello+"  app.tar\n" {
+		t.Errorf("/app.tar.sha256 = %q", body)
+	}
+
+	// Resuming the same file continues it, a changed one starts over
+	if resp, body := get("/app.tar", "Range", "bytes=3-", "If-Range", `"`+hello+`"`); resp.StatusCode != http.StatusPartialContent || body != "lo\n" {
+		t.Errorf("resuming got %s %q", resp.Status, body)
+	}
+	fsys["app.tar"] = &fstest.MapFile{Data: []byte("HELLO\n"), ModTime: time.Unix(2, 0)}
+	if resp, body := get("/app.tar", "Range", "bytes=3-", "If-Range", `"`+hello+`"`); resp.StatusCode != http.StatusOK || body != "HELLO\n" {
+		t.Errorf("resuming a changed file got %s %q", resp.Status, body)
+	}
+	if resp, _ := get("/app.tar"); resp.Header.Get("X-Checksum-SHA256") == hello {
+		t.Error("checksum not recomputed after the file changed")
+	}
+
+	if _, body := get("/dist/b.sha256"); body != "published\n" {
+		t.Errorf("existing .sha256 file got %q", body)
+	}
+	for _, path := range []string{"/dist/", "/dist/c.sha256", "/missing.sha256"} {
+		resp, _ := get(path)
+		if path != "/dist/" && resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: got %s, want 404", path, resp.Status)
+		}
+		if resp.Header.Get("X-Checksum-SHA256") != "" {
+			t.Errorf("%s has a checksum", path)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// minCompressSize is the smallest response worth compressing.
+const minCompressSize = 1024
+
+// incompressibleTypes are content types, or prefixes of them ending in /,
+// that are already compressed.
+var incompressibleTypes = []string{
+	"image/", "video/", "audio/", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-bzip2", "application/x-xz",
+	"application/zstd", "application/x-7z-compressed", "application/vnd.rar", "application/x-rar-compressed",
+	"application/pdf", "application/octet-stream",
+}
+
+// compressible reports whether responses of contentType are worth compressing.
+// SVG is the one image format that is text.
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "image/svg+xml" {
+		return true
+	}
+	return !slices.ContainsFunc(incompressibleTypes, func(t string) bool {
+		return mediaType == t || strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)
+	})
+}
+
+// negotiateEncoding picks brotli or gzip from an Accept-Encoding header, by
+// their quality values and preferring brotli, or "" if neither is accepted.
+func negotiateEncoding(acceptEncoding string) string {
+	quality := map[string]float64{}
+	for part := range strings.SplitSeq(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		quality[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range []string{"br", "gzip"} {
+		q, ok := quality[enc]
+		if !ok {
+			q, ok = quality["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+var (
+	gzipWriters   = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	brotliWriters = sync.Pool{New: func() any { return brotli.NewWriterLevel(io.Discard, 5) }}
+)
+
+// compressMiddleware compresses responses with brotli or gzip when the client
+// accepts it, unless they are small, already compressed, or partial.
+func compressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		// Compressing would change the byte offsets a range refers to
+		if encoding == "" || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter decides whether to compress a response when its header is
+// written, then compresses the body if so.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+
+	wroteHeader bool
+	enc         interface {
+		io.WriteCloser
+		Flush() error
+	}
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	h := cw.Header()
+	size, err := strconv.Atoi(h.Get("Content-Length"))
+	small := err == nil && size < minCompressSize
+	if status == http.StatusOK && h.Get("Content-Encoding") == "" && !small && compressible(h.Get("Content-Type")) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+		// The compressed body isn't byte for byte the file a strong ETag names
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+		switch cw.encoding {
+		case "br":
+			bw := brotliWriters.Get().(*brotli.Writer)
+			bw.Reset(cw.ResponseWriter)
+			cw.enc = bw
+		case "gzip":
+			gw := gzipWriters.Get().(*gzip.Writer)
+			gw.Reset(cw.ResponseWriter)
+			cw.enc = gw
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.enc == nil {
+		return cw.ResponseWriter.Write(p)
+	}
+	return cw.enc.Write(p)
+}
+
+// Flush sends what has been compressed so far.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Close finishes the compressed body and returns the encoder to its pool.
+func (cw *compressWriter) Close() error {
+	if cw.enc == nil {
+		return nil
+	}
+	err := cw.enc.Close()
+	switch enc := cw.enc.(type) {
+	case *brotli.Writer:
+		brotliWriters.Put(enc)
+	case *gzip.Writer:
+		gzipWriters.Put(enc)
+	}
+	cw.enc = nil
+	return err
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
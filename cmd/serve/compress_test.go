@@ -0,0 +1,103 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                        "",
+		"gzip":                    "gzip",
+		"gzip, deflate, br":       "br",
+		"br;q=0.5, gzip":          "gzip",
+		"br;q=0, gzip;q=0":        "",
+		"*":                       "br",
+		"identity":                "",
+		"GZIP;q=0.8, *;q=0.1":     "gzip",
+		"deflate, br;q=1.0, zstd": "br",
+	} {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompressible(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		"text/html; charset=utf-8": true,
+		"application/javascript":   true,
+		"image/svg+xml":            true,
+		"image/png":                false,
+		"video/mp4":                false,
+		"font/woff2":               false,
+		"application/zip":          false,
+		"":                         true,
+	} {
+		if got := compressible(contentType); got != want {
+			t.Errorf("compressible(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	page := strings.Repeat("<p>hello, world</p>\n", 200)
+	files := fstest.MapFS{
+		"page.html": {Data: []byte(page)},
+		"small.txt": {Data: []byte("tiny")},
+		"photo.png": {Data: []byte(strings.Repeat("\x89PNG", 500))},
+	}
+	handler := compressMiddleware(http.FileServerFS(files))
+
+	get := func(path string, header http.Header) *http.Response {
+		r := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	res := get("/page.html", http.Header{"Accept-Encoding": {"gzip"}})
+	if res.Header.Get("Content-Encoding") != "gzip" || res.Header.Get("Content-Length") != "" {
+		t.Fatalf("headers %v, want gzip without a length", res.Header)
+	}
+	zr, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, err := io.ReadAll(zr); err != nil || string(body) != page {
+		t.Errorf("decompressed body is %d bytes, %v", len(body), err)
+	}
+	if !strings.Contains(res.Header.Get("Vary"), "Accept-Encoding") {
+		t.Errorf("Vary = %q", res.Header.Get("Vary"))
+	}
+
+	for _, tc := range []struct {
+		path   string
+		header http.Header
+	}{
+		{"/page.html", nil},
+		{"/page.html", http.Header{"Accept-Encoding": {"gzip"}, "Range": {"bytes=0-99"}}},
+		{"/small.txt", http.Header{"Accept-Encoding": {"gzip"}}},
+		{"/photo.png", http.Header{"Accept-Encoding": {"gzip"}}},
+	} {
+		if res := get(tc.path, tc.header); res.Header.Get("Content-Encoding") != "" {
+			t.Errorf("%s with %v was compressed", tc.path, tc.header)
+		}
+	}
+
+	handler = compressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeFileFS(w, r, files, "page.html")
+	}))
+	if res := get("/page.html", http.Header{"Accept-Encoding": {"gzip"}}); res.Header.Get("ETag") != `W/"v1"` {
+		t.Errorf("compressed ETag = %q, want it weak", res.Header.Get("ETag"))
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// parseEnvInject parses comma-separated -env-inject paths.
+func parseEnvInject(s string) ([]string, error) {
+	var paths []string
+	for p := range strings.SplitSeq(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("-env-inject %s: paths must start with /", p)
+		}
+		paths = append(paths, path.Clean(p))
+	}
+	return paths, nil
+}
+
+// envInjectMiddleware renders the files of root served at paths, with
+// prefix being where root is served, as text/template templates of the
+// environment, like {{.API_URL}}, on every request. Unset variables are
+// empty. Other requests are left to next.
+func envInjectMiddleware(next http.Handler, root http.FileSystem, prefix string, paths []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		if r.Method != http.MethodGet && r.Method != http.MethodHead || !slices.Contains(paths, path.Join(prefix, name)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		out, err := renderEnv(root, name)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			http.NotFound(w, r)
+			return
+		case err != nil:
+			log.Printf("-env-inject %s: %v", path.Join(prefix, name), err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		// The environment isn't something conditional requests can validate
+		w.Header().Set("Cache-Control", "no-store")
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(out))
+	})
+}
+
+func renderEnv(root http.FileSystem, name string) ([]byte, error) {
+	f, err := root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	src, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	t, err := template.New(name).Option("missingkey=zero").Parse(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, env); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEnvInject(t *testing.T) {
+	t.Setenv("API_URL", "https://api.example.com")
+	fsys := fstest.MapFS{
+		"config.js":     {Data: []byte(`window.config={api:"{{js .API_URL}}",flag:"{{.SERVE_TEST_UNSET}}"};`)},
+		"app/config.js": {Data: []byte(`{{.API_URL}}`)},
+		"broken.js":     {Data: []byte(`{{.API_URL`)},
+		"other.js":      {Data: []byte(`{{.API_URL}}`)},
+	}
+	paths, err := parseEnvInject("/config.js, /docs/app/config.js,/broken.js,/missing.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(prefix, path string) *httptest.ResponseRecorder {
+		h := envInjectMiddleware(http.FileServerFS(fsys), http.FS(fsys), prefix, paths)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := serve("", "/config.js")
+	if want := `window.config={api:"https://api.example.com",flag:""};`; w.Body.String() != want {
+		t.Errorf("/config.js = %q, want %q", w.Body, want)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/javascript; charset=utf-8" || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("/config.js headers %v", w.Header())
+	}
+	if w := serve("/docs", "/app/config.js"); w.Body.String() != "https://api.example.com" {
+		t.Errorf("/docs/app/config.js = %q", w.Body)
+	}
+	if w := serve("", "/other.js"); w.Body.String() != "{{.API_URL}}" {
+		t.Errorf("/other.js = %q, want it untouched", w.Body)
+	}
+	if w := serve("", "/broken.js"); w.Code != http.StatusInternalServerError {
+		t.Errorf("/broken.js got %d", w.Code)
+	}
+	if w := serve("", "/missing.js"); w.Code != http.StatusNotFound {
+		t.Errorf("/missing.js got %d", w.Code)
+	}
+
+	if _, err := parseEnvInject("config.js"); err == nil {
+		t.Error("relative path accepted")
+	}
+}
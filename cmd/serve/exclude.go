@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// exclusion decides which files are neither listed nor served.
+type exclusion struct {
+	// dotfiles hides names starting with a dot, except .well-known
+	dotfiles bool
+	// patterns are path.Match globs. Those without a slash match any
+	// element of a path, the others whole paths from the served directory.
+	patterns []string
+}
+
+// newExclusion parses comma-separated -exclude patterns.
+func newExclusion(dotfiles bool, patterns string) (exclusion, error) {
+	ex := exclusion{dotfiles: dotfiles}
+	for p := range strings.SplitSeq(patterns, ",") {
+		p = strings.Trim(strings.TrimSpace(p), "/")
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return exclusion{}, err
+		}
+		ex.patterns = append(ex.patterns, p)
+	}
+	return ex, nil
+}
+
+// excluded reports whether name, a slash separated path from the served
+// directory, or any directory it is in is excluded.
+func (ex exclusion) excluded(name string) bool {
+	rel := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if rel == "" {
+		return false
+	}
+	elems := strings.Split(rel, "/")
+	for i, elem := range elems {
+		if ex.dotfiles && strings.HasPrefix(elem, ".") && elem != ".well-known" {
+			return true
+		}
+		for _, p := range ex.patterns {
+			target := elem
+			if strings.Contains(p, "/") {
+				target = strings.Join(elems[:i+1], "/")
+			}
+			if ok, _ := path.Match(p, target); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fs hides the excluded files of root, which don't exist as far as the
+// file server and listings are concerned.
+func (ex exclusion) fs(root http.FileSystem) http.FileSystem {
+	if !ex.dotfiles && len(ex.patterns) == 0 {
+		return root
+	}
+	return hidingFS{root, ex}
+}
+
+type hidingFS struct {
+	http.FileSystem
+	ex exclusion
+}
+
+func (h hidingFS) Open(name string) (http.File, error) {
+	if h.ex.excluded(name) {
+		return nil, fs.ErrNotExist
+	}
+	f, err := h.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return hidingFile{f, h.ex, name}, nil
+}
+
+type hidingFile struct {
+	http.File
+	ex   exclusion
+	name string
+}
+
+// Readdir leaves out excluded entries, reading on so that asking for n > 0
+// entries only returns none at the end of the directory.
+func (f hidingFile) Readdir(n int) ([]fs.FileInfo, error) {
+	var kept []fs.FileInfo
+	for {
+		infos, err := f.File.Readdir(n)
+		for _, fi := range infos {
+			if !f.ex.excluded(path.Join(f.name, fi.Name())) {
+				kept = append(kept, fi)
+			}
+		}
+		if n <= 0 || len(kept) > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
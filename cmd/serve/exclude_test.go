@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExcluded(t *testing.T) {
+	ex, err := newExclusion(true, "node_modules, *.log,/build/*.map,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"/":                          false,
+		"/index.html":                false,
+		"/.env":                      true,
+		"/.git/config":               true,
+		"/src/.hidden/x.js":          true,
+		"/.well-known/security.txt":  false,
+		"/node_modules/react/x.js":   true,
+		"/app/node_modules":          true,
+		"/debug.log":                 true,
+		"/logs/debug.log.txt":        false,
+		"/build/app.js.map":          true,
+		"/build/app.js":              false,
+		"/src/build/app.js.map":      false,
+		"/a/../.env":                 true,
+		"node_modules_backup/a.html": false,
+	} {
+		if got := ex.excluded(name); got != want {
+			t.Errorf("excluded(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	if (exclusion{}).excluded("/.env") {
+		t.Error("dotfiles hidden with -hide-dotfiles=false")
+	}
+	if _, err := newExclusion(false, "[unclosed"); err == nil {
+		t.Error("bad pattern accepted")
+	}
+}
+
+func TestExclusionFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.txt":                {Data: []byte("hi")},
+		".env":                     {Data: []byte("SECRET=1")},
+		"node_modules/x/index.js":  {Data: []byte("js")},
+		".well-known/security.txt": {Data: []byte("security")},
+		"sub/README.md":            {Data: []byte("# Sub")},
+		"sub/.hidden/README.md":    {Data: []byte("# Hidden")},
+	}
+	ex, _ := newExclusion(true, "node_modules")
+	srv := httptest.NewServer(listingHandler(ex.fs(http.FS(fsys)), "", true))
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	for _, path := range []string{"/.env", "/node_modules/x/index.js", "/node_modules/", "/sub/.hidden/README.md", "/sub/.hidden/"} {
+		if code, _ := get(path); code != http.StatusNotFound {
+			t.Errorf("%s: got %d, want 404", path, code)
+		}
+	}
+	if code, body := get("/.well-known/security.txt"); code != http.StatusOK || body != "security" {
+		t.Errorf("/.well-known/security.txt: got %d %q", code, body)
+	}
+
+	_, root := get("/")
+	for _, hidden := range []string{".env", "node_modules"} {
+		if strings.Contains(root, hidden) {
+			t.Errorf("/ lists %s:\n%s", hidden, root)
+		}
+	}
+	if !strings.Contains(root, "index.txt") || !strings.Contains(root, ".well-known/") {
+		t.Errorf("/ is missing files:\n%s", root)
+	}
+
+	// Readdir in batches skips past excluded entries
+	f, err := ex.fs(http.FS(fsys)).Open("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var names []string
+	for {
+		infos, err := f.Readdir(1)
+		for _, fi := range infos {
+			names = append(names, fi.Name())
+		}
+		if err != nil {
+			break
+		}
+	}
+	if got := strings.Join(names, " "); got != ".well-known index.txt sub" {
+		t.Errorf("Readdir(1) got %s", got)
+	}
+}
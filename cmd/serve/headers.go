@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// headers collects "Name: value" response headers from a flag given several
+// times or, as newlines can't be typed in flags, with newline-separated
+// headers from the environment.
+type headers http.Header
+
+func (h *headers) String() string {
+	var s []string
+	for name, values := range *h {
+		for _, v := range values {
+			s = append(s, name+": "+v)
+		}
+	}
+	return strings.Join(s, "\n")
+}
+
+func (h *headers) Set(v string) error {
+	if *h == nil {
+		*h = headers{}
+	}
+	for line := range strings.SplitSeq(v, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("%q isn't a Name: value header", line)
+		}
+		http.Header(*h).Add(textproto.CanonicalMIMEHeaderKey(name), strings.TrimSpace(value))
+	}
+	return nil
+}
+
+// headersMiddleware adds the -header headers and the Cache-Control asked
+// for to responses. With noCache, responses aren't to be stored at all and
+// conditional requests are answered in full, so every reload gets the
+// files as they are now.
+func headersMiddleware(next http.Handler, extra headers, cacheControl string, noCache bool) http.Handler {
+	if noCache {
+		cacheControl = "no-store"
+	}
+	if len(extra) == 0 && cacheControl == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		for name, values := range extra {
+			h[name] = append(h[name], values...)
+		}
+		if cacheControl != "" {
+			h.Set("Cache-Control", cacheControl)
+		}
+		if noCache {
+			r.Header.Del("If-Modified-Since")
+			r.Header.Del("If-None-Match")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
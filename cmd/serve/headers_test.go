@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestHeadersSet(t *testing.T) {
+	var h headers
+	if err := h.Set("x-frame-options: DENY"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Set("Link: </a.css>; rel=preload, </b.js>; rel=preload\nX-Empty:"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Set("Link: </c.css>; rel=preload"); err != nil {
+		t.Fatal(err)
+	}
+	got := http.Header(h)
+	if got.Get("X-Frame-Options") != "DENY" || len(got.Values("Link")) != 2 || got.Get("Link") != "</a.css>; rel=preload, </b.js>; rel=preload" {
+		t.Errorf("got %v", got)
+	}
+	if _, ok := got["X-Empty"]; !ok {
+		t.Errorf("empty header missing from %v", got)
+	}
+	for _, bad := range []string{"no colon", ": value", "Two words: x"} {
+		if err := h.Set(bad); err == nil {
+			t.Errorf("Set(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestHeadersMiddleware(t *testing.T) {
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("a"), ModTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	files := http.FileServerFS(fsys)
+	var extra headers
+	extra.Set("X-Foo: bar")
+
+	get := func(h http.Handler) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/a.txt", nil)
+		r.Header.Set("If-Modified-Since", "Tue, 02 Jan 2024 00:00:00 GMT")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get(headersMiddleware(files, extra, "public, max-age=60", false))
+	if w.Code != http.StatusNotModified || w.Header().Get("X-Foo") != "bar" || w.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Errorf("-cache-control got %d %v", w.Code, w.Header())
+	}
+
+	w = get(headersMiddleware(files, nil, "", true))
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("-no-cache got %d %v", w.Code, w.Header())
+	}
+
+	w = get(headersMiddleware(files, extra, "", false))
+	if w.Header().Get("X-Foo") != "bar" || w.Header().Get("Cache-Control") != "" {
+		t.Errorf("-header got %v", w.Header())
+	}
+	// Responses don't share the configured slices
+	w.Header().Add("X-Foo", "baz")
+	if len(extra["X-Foo"]) != 1 {
+		t.Errorf("-header headers changed to %v", extra)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := range 3 {
+		if ok, _ := l.allow("a"); !ok {
+			t.Fatalf("request %d of the burst refused", i+1)
+		}
+	}
+	if ok, wait := l.allow("a"); ok || wait != 500*time.Millisecond {
+		t.Errorf("over the burst got %v, wait %v", ok, wait)
+	}
+	if ok, _ := l.allow("b"); !ok {
+		t.Error("another address was limited too")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.allow("a"); !ok {
+		t.Error("refilled token refused")
+	}
+	if ok, _ := l.allow("a"); ok {
+		t.Error("allowed faster than the rate")
+	}
+
+	now = now.Add(2 * time.Minute)
+	l.allow("c")
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("refilled bucket kept")
+	}
+
+	h := rateLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), l)
+	for range 4 {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code == http.StatusTooManyRequests {
+			if w.Header().Get("Retry-After") != "1" {
+				t.Errorf("Retry-After = %q", w.Header().Get("Retry-After"))
+			}
+			return
+		}
+	}
+	t.Error("burst from one address never got 429")
+}
+
+func TestLimitConns(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := limitConns(inner, 1)
+	defer ln.Close()
+	accepted := make(chan net.Conn)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	first, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	conn := <-accepted
+
+	// A second connection is closed while the first is open
+	second, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := second.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("second connection read %v, want EOF", err)
+	}
+
+	conn.Close()
+	third, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer third.Close()
+	select {
+	case c := <-accepted:
+		c.Close()
+	case <-time.After(5 * time.Second):
+		t.Error("connection after the first closed wasn't accepted")
+	}
+}
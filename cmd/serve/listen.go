@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// listenAddress returns the address to listen on for -listen. Addresses
+// without a host are bound to localhost only, unless public.
+func listenAddress(addr string, public bool) (string, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if path == "" {
+			return "", errors.New("unix: needs a socket path")
+		}
+		return addr, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("-listen %s: %w", addr, err)
+	}
+	if host == "" && !public {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// serverURL describes where a listener can be reached, with the port it was
+// given for port 0. Wildcard addresses are described by localhost, with a
+// note that they are reachable from elsewhere too.
+func serverURL(addr net.Addr, scheme string) string {
+	if addr.Network() == "unix" {
+		return "unix:" + addr.String()
+	}
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	note := ""
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host, note = "localhost", " and every other interface"
+	} else if ip != nil && ip.IsLoopback() {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port) + note
+}
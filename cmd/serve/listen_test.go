@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pkg.jsn.cam/jsn/internal/httpserver"
+)
+
+func TestListenAddress(t *testing.T) {
+	for _, tt := range []struct {
+		addr   string
+		public bool
+		want   string
+	}{
+		{":3000", false, "localhost:3000"},
+		{":3000", true, ":3000"},
+		{":0", false, "localhost:0"},
+		{"0.0.0.0:80", false, "0.0.0.0:80"},
+		{"[::1]:8080", true, "[::1]:8080"},
+		{"unix:/run/serve.sock", false, "unix:/run/serve.sock"},
+	} {
+		got, err := listenAddress(tt.addr, tt.public)
+		if err != nil || got != tt.want {
+			t.Errorf("listenAddress(%q, %v) = %q, %v, want %q", tt.addr, tt.public, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"3000", "unix:", "localhost"} {
+		if _, err := listenAddress(bad, false); err == nil {
+			t.Errorf("listenAddress(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestListen(t *testing.T) {
+	ln, err := httpserver.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if got := serverURL(ln.Addr(), "http"); !strings.HasPrefix(got, "http://localhost:") || strings.HasSuffix(got, ":0") {
+		t.Errorf("serverURL = %q", got)
+	}
+
+	sock := filepath.Join(t.TempDir(), "serve.sock")
+	ln, err = httpserver.Listen("unix:" + sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if got := serverURL(ln.Addr(), "http"); got != "unix:"+sock {
+		t.Errorf("serverURL = %q", got)
+	}
+}
+
+func TestServerURL(t *testing.T) {
+	for addr, want := range map[string]string{
+		"0.0.0.0:80":     "https://localhost:80 and every other interface",
+		"[::]:443":       "https://localhost:443 and every other interface",
+		"[::1]:8443":     "https://localhost:8443",
+		"192.0.2.1:8443": "https://192.0.2.1:8443",
+	} {
+		tcp, err := net.ResolveTCPAddr("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := serverURL(tcp, "https"); got != want {
+			t.Errorf("serverURL(%s) = %q, want %q", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"pkg.jsn.cam/jsn/internal/markdown"
+	"pkg.jsn.cam/jsn/jass"
+)
+
+//go:generate go tool templ generate
+
+// maxReadmeSize caps how much of a directory's README is rendered
+const maxReadmeSize = 512 << 10
+
+// readmeNames are the READMEs rendered above a listing, in order of preference.
+var readmeNames = []string{"readme.md", "readme.markdown"}
+
+// listing is a directory listing page.
+type listing struct {
+	// Path is the directory's URL path, ending in a slash
+	Path    string
+	Crumbs  []crumb
+	Entries []entry
+	// Sort is the column entries are sorted by, and Desc whether it's reversed
+	Sort string
+	Desc bool
+	// Readme is the directory's README rendered to HTML, if any
+	Readme string
+}
+
+// crumb links to a directory above, or at, the listed one.
+type crumb struct {
+	Name, Href string
+}
+
+type entry struct {
+	Name    string
+	Dir     bool
+	Size    int64
+	ModTime time.Time
+}
+
+// listingHandler serves files from root like http.FileServer, but lists
+// directories without an index.html on a jass page with sortable columns,
+// breadcrumbs and, if readme is set, their README rendered above. prefix
+// is the path root is mounted at, with the request's path stripped of it.
+func listingHandler(root http.FileSystem, prefix string, readme bool) http.Handler {
+	files := http.FileServer(root)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The file server redirects directories to their trailing slash
+		// and serves index.html itself
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			files.ServeHTTP(w, r)
+			return
+		}
+		name := path.Clean("/" + r.URL.Path)
+		f, err := root.Open(name)
+		if err != nil {
+			files.ServeHTTP(w, r)
+			return
+		}
+		defer f.Close()
+		if fi, err := f.Stat(); err != nil || !fi.IsDir() {
+			files.ServeHTTP(w, r)
+			return
+		}
+		if index, err := root.Open(path.Join(name, "index.html")); err == nil {
+			index.Close()
+			files.ServeHTTP(w, r)
+			return
+		}
+
+		infos, err := f.Readdir(-1)
+		if err != nil {
+			http.Error(w, "Error reading directory", http.StatusInternalServerError)
+			return
+		}
+		l := newListing(prefix+strings.TrimSuffix(name, "/")+"/", infos, r.URL.Query())
+		if readme {
+			l.Readme = readREADME(root, name, l.Entries)
+		}
+		templ.Handler(jass.Base("Index of "+l.Path, nil, breadcrumbs(l.Crumbs), listingBody(l), nil)).ServeHTTP(w, r)
+	})
+}
+
+// newListing builds the listing of dir, sorted as asked by the query's sort
+// (name, size or modified) and order (asc or desc) parameters.
+func newListing(dir string, infos []os.FileInfo, query url.Values) listing {
+	l := listing{Path: dir, Sort: query.Get("sort"), Desc: query.Get("order") == "desc"}
+	if !slices.Contains([]string{"name", "size", "modified"}, l.Sort) {
+		l.Sort = "name"
+	}
+
+	l.Crumbs = []crumb{{Name: "/", Href: "/"}}
+	href := "/"
+	for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		href += part + "/"
+		l.Crumbs = append(l.Crumbs, crumb{Name: part + "/", Href: (&url.URL{Path: href}).String()})
+	}
+
+	for _, fi := range infos {
+		l.Entries = append(l.Entries, entry{Name: fi.Name(), Dir: fi.IsDir(), Size: fi.Size(), ModTime: fi.ModTime()})
+	}
+	// Directories come first whichever way the files are sorted
+	slices.SortFunc(l.Entries, func(a, b entry) int {
+		if a.Dir != b.Dir {
+			if a.Dir {
+				return -1
+			}
+			return 1
+		}
+		var c int
+		switch l.Sort {
+		case "size":
+			c = cmp.Compare(a.Size, b.Size)
+		case "modified":
+			c = a.ModTime.Compare(b.ModTime)
+		}
+		if c == 0 {
+			c = cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+		if l.Desc {
+			c = -c
+		}
+		return c
+	})
+	return l
+}
+
+// readREADME returns the README among entries of dir rendered to HTML, or ""
+// if there is none or it can't be read.
+func readREADME(root http.FileSystem, dir string, entries []entry) string {
+	for _, want := range readmeNames {
+		for _, e := range entries {
+			if e.Dir || strings.ToLower(e.Name) != want {
+				continue
+			}
+			f, err := root.Open(path.Join(dir, e.Name))
+			if err != nil {
+				return ""
+			}
+			defer f.Close()
+			src, err := io.ReadAll(io.LimitReader(f, maxReadmeSize))
+			if err != nil {
+				return ""
+			}
+			// Relative links already resolve against the directory
+			return markdown.Render(string(src), "", "", "")
+		}
+	}
+	return ""
+}
+
+// sortHref links to the listing sorted by column, reversing the order if
+// it's already sorted by it.
+func (l listing) sortHref(column string) string {
+	order := "asc"
+	if l.Sort == column && !l.Desc {
+		order = "desc"
+	}
+	return "?sort=" + column + "&order=" + order
+}
+
+// arrow marks the column the listing is sorted by.
+func (l listing) arrow(column string) string {
+	switch {
+	case l.Sort != column:
+		return ""
+	case l.Desc:
+		return " ↓"
+	}
+	return " ↑"
+}
+
+func (e entry) Label() string {
+	if e.Dir {
+		return e.Name + "/"
+	}
+	return e.Name
+}
+
+// Href links to the entry relative to its directory. Names with a colon
+// get a ./ so they aren't taken for a scheme.
+func (e entry) Href() string {
+	return (&url.URL{Path: e.Label()}).String()
+}
+
+func (e entry) SizeString() string {
+	if e.Dir {
+		return "-"
+	}
+	return formatSize(e.Size)
+}
+
+// formatSize formats a file size with binary units.
+func formatSize(n int64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	size, unit := float64(n)/1024, 0
+	for size >= 1024 && unit < 4 {
+		size /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", size, []string{"KiB", "MiB", "GiB", "TiB", "PiB"}[unit])
+}
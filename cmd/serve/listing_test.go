@@ -0,0 +1,141 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestNewListing(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"b.txt":     {Data: []byte("bb"), ModTime: day},
+		"A.txt":     {Data: []byte("aaaa"), ModTime: day.Add(time.Hour)},
+		"c.txt":     {Data: []byte("c"), ModTime: day.Add(-time.Hour)},
+		"sub/x.txt": {Data: []byte("x")},
+	}
+	entries, err := fsys.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var infos []os.FileInfo
+	for _, e := range entries {
+		fi, _ := e.Info()
+		infos = append(infos, fi)
+	}
+
+	for query, want := range map[string]string{
+		"":                        "sub A.txt b.txt c.txt",
+		"sort=name&order=desc":    "sub c.txt b.txt A.txt",
+		"sort=size":               "sub c.txt b.txt A.txt",
+		"sort=modified":           "sub c.txt b.txt A.txt",
+		"sort=modified&order=asc": "sub c.txt b.txt A.txt",
+		"sort=size&order=desc":    "sub A.txt b.txt c.txt",
+		"sort=bogus":              "sub A.txt b.txt c.txt",
+	} {
+		q, _ := url.ParseQuery(query)
+		l := newListing("/a b/c/", infos, q)
+		var names []string
+		for _, e := range l.Entries {
+			names = append(names, e.Name)
+		}
+		if got := strings.Join(names, " "); got != want {
+			t.Errorf("%q: got %s, want %s", query, got, want)
+		}
+	}
+
+	l := newListing("/a b/c/", infos, nil)
+	if len(l.Crumbs) != 3 || l.Crumbs[1] != (crumb{"a b/", "/a%20b/"}) || l.Crumbs[2] != (crumb{"c/", "/a%20b/c/"}) {
+		t.Errorf("crumbs %v", l.Crumbs)
+	}
+	if got := l.sortHref("name"); got != "?sort=name&order=desc" {
+		t.Errorf("sortHref(name) = %q", got)
+	}
+	if got := l.sortHref("size"); got != "?sort=size&order=asc" {
+		t.Errorf("sortHref(size) = %q", got)
+	}
+}
+
+func TestEntryHref(t *testing.T) {
+	for e, want := range map[entry]string{
+		{Name: "a b.txt"}:        "a%20b.txt",
+		{Name: "c:d"}:            "./c:d",
+		{Name: "dir", Dir: true}: "dir/",
+		{Name: "#?.txt"}:         "%23%3F.txt",
+	} {
+		if got := e.Href(); got != want {
+			t.Errorf("%q: Href() = %q, want %q", e.Name, got, want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:             "0 B",
+		1023:          "1023 B",
+		1024:          "1.0 KiB",
+		1536:          "1.5 KiB",
+		5 << 20:       "5.0 MiB",
+		3 << 30:       "3.0 GiB",
+		1<<50 + 1<<49: "1.5 PiB",
+	} {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestListingHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/README.md":   {Data: []byte("# Docs\n\nSee [the guide](guide.txt).")},
+		"docs/guide.txt":   {Data: []byte("guide")},
+		"site/index.html":  {Data: []byte("<p>index</p>")},
+		"<script>.txt":     {Data: []byte("x")},
+		"plain/nothing.md": {Data: []byte("# Not a README")},
+	}
+	srv := httptest.NewServer(listingHandler(http.FS(fsys), "", true))
+	defer srv.Close()
+
+	get := func(path string) string {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	root := get("/")
+	for _, want := range []string{"Index of /", `href="docs/"`, "&lt;script&gt;.txt", "?sort=size&amp;order=asc"} {
+		if !strings.Contains(root, want) {
+			t.Errorf("/ doesn't contain %q:\n%s", want, root)
+		}
+	}
+	if strings.Contains(root, `href="../"`) {
+		t.Error("/ links to its parent")
+	}
+
+	docs := get("/docs/")
+	for _, want := range []string{`<a href="/docs/">docs/</a>`, `href="../"`, `<h1 id="docs">Docs</h1>`, `<a href="guide.txt"`, "5 B"} {
+		if !strings.Contains(docs, want) {
+			t.Errorf("/docs/ doesn't contain %q:\n%s", want, docs)
+		}
+	}
+	if strings.Contains(get("/plain/"), "Not a README") {
+		t.Error("/plain/ rendered a file that isn't a README")
+	}
+	if got := get("/site/"); got != "<p>index</p>" {
+		t.Errorf("/site/ = %q, want its index.html", got)
+	}
+	if got := get("/docs/guide.txt"); got != "guide" {
+		t.Errorf("/docs/guide.txt = %q", got)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// livePath is where the injected client connects to hear about changes.
+const livePath = "/.serve/live"
+
+// liveDebounce coalesces the burst of events editors and build tools produce
+const liveDebounce = 100 * time.Millisecond
+
+// liveScript reloads the page when told to, and when the server comes back
+// after going away, since whatever it serves has likely changed.
+const liveScript = `<script>(function(){var p=location.protocol==="https:"?"wss:":"ws:",up=false;` +
+	`function c(){var s=new WebSocket(p+"//"+location.host+"` + livePath + `");` +
+	`s.onopen=function(){if(up)location.reload();up=true};` +
+	`s.onmessage=function(){location.reload()};` +
+	`s.onclose=function(){setTimeout(c,1000)}}c()})();</script>`
+
+// liveReload tells the connected browsers to reload.
+type liveReload struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+	// done is closed to disconnect the browsers when shutting down
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newLiveReload() *liveReload {
+	return &liveReload{clients: map[chan struct{}]struct{}{}, done: make(chan struct{})}
+}
+
+// close disconnects the browsers, which reload once they can reconnect.
+func (l *liveReload) close() {
+	l.closeOnce.Do(func() { close(l.done) })
+}
+
+// reload tells every connected browser to reload.
+func (l *liveReload) reload() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for c := range l.clients {
+		select {
+		case c <- struct{}{}:
+		default: // a reload is already pending
+		}
+	}
+}
+
+// watch reloads the browsers whenever something under root that ex doesn't
+// exclude changes, unless the watcher fails to start. New directories are
+// watched as they appear.
+func (l *liveReload) watch(root string, ex exclusion) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	excluded := func(path string) bool {
+		rel, err := filepath.Rel(root, path)
+		return err == nil && ex.excluded(filepath.ToSlash(rel))
+	}
+	add := func(dir string) {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if excluded(path) {
+				return filepath.SkipDir
+			}
+			if err := watcher.Add(path); err != nil {
+				log.Printf("Not watching %s for changes: %v", path, err)
+			}
+			return nil
+		})
+	}
+	add(root)
+
+	go func() {
+		defer watcher.Close()
+		debounce := time.NewTimer(liveDebounce)
+		debounce.Stop()
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if excluded(ev.Name) {
+					continue
+				}
+				if ev.Has(fsnotify.Create) {
+					if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+						add(ev.Name)
+					}
+				}
+				if ev.Has(fsnotify.Write | fsnotify.Create | fsnotify.Remove | fsnotify.Rename) {
+					debounce.Reset(liveDebounce)
+				}
+			case <-debounce.C:
+				l.reload()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Live reload watcher error: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// websocketAccept returns the Sec-WebSocket-Accept value for a
+// Sec-WebSocket-Key, as described in RFC 6455 section 4.2.2.
+func websocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerHas reports whether a comma-separated header contains token.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for part := range strings.SplitSeq(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ServeHTTP accepts a websocket connection and sends it a "reload" text
+// message on each change. Nothing the browser sends is needed, so it's
+// only read to notice the connection closing.
+func (l *liveReload) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" ||
+		!headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "expected a websocket upgrade", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "can't upgrade this connection", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+	// The connection lives on past the server's request timeouts
+	conn.SetDeadline(time.Time{})
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	changed := make(chan struct{}, 1)
+	l.mu.Lock()
+	l.clients[changed] = struct{}{}
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		delete(l.clients, changed)
+		l.mu.Unlock()
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, rw)
+		close(closed)
+	}()
+
+	// An unmasked text frame; servers must not mask theirs
+	frame := append([]byte{0x81, byte(len("reload"))}, "reload"...)
+	for {
+		select {
+		case <-closed:
+			return
+		case <-l.done:
+			return
+		case <-changed:
+			if _, err := conn.Write(frame); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// injectMiddleware adds the live reload client to HTML pages.
+func injectMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// The script changes the page's length, so ranges of it can't be served
+		r.Header.Del("Range")
+		iw := &injectWriter{ResponseWriter: w}
+		defer iw.Close()
+		next.ServeHTTP(iw, r)
+	})
+}
+
+// injectWriter buffers successful HTML responses to add the live reload
+// client before </body>, passing anything else through.
+type injectWriter struct {
+	http.ResponseWriter
+
+	wroteHeader bool
+	html        *bytes.Buffer
+}
+
+func (iw *injectWriter) WriteHeader(status int) {
+	if iw.wroteHeader {
+		return
+	}
+	iw.wroteHeader = true
+
+	h := iw.Header()
+	mediaType, _, _ := strings.Cut(h.Get("Content-Type"), ";")
+	if status == http.StatusOK && h.Get("Content-Encoding") == "" && strings.EqualFold(strings.TrimSpace(mediaType), "text/html") {
+		iw.html = &bytes.Buffer{}
+		// Pages must be fetched again to get the script, not revalidated
+		h.Del("ETag")
+		h.Del("Last-Modified")
+		h.Set("Cache-Control", "no-store")
+		return
+	}
+	iw.ResponseWriter.WriteHeader(status)
+}
+
+func (iw *injectWriter) Write(p []byte) (int, error) {
+	if !iw.wroteHeader {
+		if iw.Header().Get("Content-Type") == "" {
+			iw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		iw.WriteHeader(http.StatusOK)
+	}
+	if iw.html != nil {
+		return iw.html.Write(p)
+	}
+	return iw.ResponseWriter.Write(p)
+}
+
+// Close writes a buffered page with the script added.
+func (iw *injectWriter) Close() error {
+	if iw.html == nil {
+		return nil
+	}
+	page := iw.html.Bytes()
+	iw.html = nil
+
+	i := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if i < 0 {
+		i = len(page)
+	}
+	body := make([]byte, 0, len(page)+len(liveScript))
+	body = append(append(append(body, page[:i]...), liveScript...), page[i:]...)
+
+	iw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	iw.ResponseWriter.WriteHeader(http.StatusOK)
+	_, err := iw.ResponseWriter.Write(body)
+	return err
+}
+
+func (iw *injectWriter) Unwrap() http.ResponseWriter {
+	return iw.ResponseWriter
+}
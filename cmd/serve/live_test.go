@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestWebsocketAccept(t *testing.T) {
+	// The example from RFC 6455
+	if got, want := websocketAccept("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("websocketAccept = %q, want %q", got, want)
+	}
+}
+
+func TestInjectMiddleware(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.html": {Data: []byte("<html><body><p>hi</p></BODY></html>")},
+		"bare.html": {Data: []byte("<p>hi</p>")},
+		"app.js":    {Data: []byte("console.log(1)")},
+	}
+	srv := httptest.NewServer(injectMiddleware(http.FileServerFS(fsys)))
+	defer srv.Close()
+
+	for path, want := range map[string]string{
+		"/page.html": "<html><body><p>hi</p>" + liveScript + "</BODY></html>",
+		"/bare.html": "<p>hi</p>" + liveScript,
+		"/app.js":    "console.log(1)",
+	} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		req.Header.Set("Range", "bytes=0-3")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != want {
+			t.Errorf("%s: got %q, want %q", path, body, want)
+		}
+		if resp.ContentLength != int64(len(want)) {
+			t.Errorf("%s: Content-Length %d, want %d", path, resp.ContentLength, len(want))
+		}
+	}
+}
+
+func TestLiveReload(t *testing.T) {
+	lr := newLiveReload()
+	srv := httptest.NewServer(lr)
+	defer srv.Close()
+
+	if resp, err := http.Get(srv.URL); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("plain GET got %s, want 400", resp.Status)
+	}
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	io.WriteString(conn, "GET "+livePath+" HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake got %s %v", resp.Status, resp.Header)
+	}
+
+	// The client registers after the handshake is flushed
+	for {
+		lr.mu.Lock()
+		n := len(lr.clients)
+		lr.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	lr.reload()
+
+	frame := make([]byte, 8)
+	if _, err := io.ReadFull(br, frame); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x81\x06reload"; string(frame) != want {
+		t.Errorf("frame %q, want %q", frame, want)
+	}
+}
+
+func TestLiveReloadClose(t *testing.T) {
+	lr := newLiveReload()
+	srv := httptest.NewServer(lr)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET "+livePath+" HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(conn)
+	if _, err := http.ReadResponse(br, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shutting down disconnects the browsers, twice is fine
+	lr.close()
+	lr.close()
+	if _, err := br.ReadByte(); err != io.EOF {
+		t.Errorf("read after close got %v, want EOF", err)
+	}
+}
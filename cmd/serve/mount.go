@@ -0,0 +1,87 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// mount serves dir under prefix, for requests to host or to any host if
+// host is empty.
+type mount struct {
+	host, prefix, dir string
+}
+
+// parseMount parses a -mount route. Its path may start with a host, as in
+// docs.localhost/=./docs, to only serve the directory on that host.
+func parseMount(r route) (mount, error) {
+	host, prefix := "", r.path
+	if i := strings.Index(prefix, "/"); i > 0 {
+		host, prefix = prefix[:i], prefix[i:]
+	}
+	if fi, err := os.Stat(r.dest); err != nil {
+		return mount{}, fmt.Errorf("-mount %s: %w", r.path, err)
+	} else if !fi.IsDir() {
+		return mount{}, fmt.Errorf("-mount %s: %s isn't a directory", r.path, r.dest)
+	}
+	return mount{host: strings.ToLower(host), prefix: strings.TrimSuffix(prefix, "/"), dir: r.dest}, nil
+}
+
+// mountHandler serves root at / and each mount under its prefix, with the
+// handlers serveDir returns for a directory and the prefix it's under.
+// Mounts for the request's host win over those for any host, then the
+// longest prefix.
+func mountHandler(root string, mounts []mount, serveDir func(dir, prefix string) http.Handler) http.Handler {
+	type served struct {
+		mount
+		handler http.Handler
+	}
+	var ms []served
+	for _, m := range mounts {
+		ms = append(ms, served{m, http.StripPrefix(m.prefix, serveDir(m.dir, m.prefix))})
+	}
+	slices.SortStableFunc(ms, func(a, b served) int {
+		if (a.host == "") != (b.host == "") {
+			if a.host != "" {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(len(b.prefix), len(a.prefix))
+	})
+	files := serveDir(root, "")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+		for _, m := range ms {
+			if m.host != "" && m.host != host {
+				continue
+			}
+			rest, ok := strings.CutPrefix(r.URL.Path, m.prefix)
+			if !ok || rest != "" && !strings.HasPrefix(rest, "/") {
+				continue
+			}
+			if rest == "" {
+				// Relative links in the directory need the trailing slash
+				target := m.prefix + "/"
+				if r.URL.RawQuery != "" {
+					target += "?" + r.URL.RawQuery
+				}
+				http.Redirect(w, r, target, http.StatusMovedPermanently)
+				return
+			}
+			m.handler.ServeHTTP(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMountHandler(t *testing.T) {
+	tmp := t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		path := filepath.Join(tmp, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("site/a.txt", "site")
+	write("docs/a.txt", "docs")
+	write("docs/api/a.txt", "docs api")
+	write("api/a.txt", "api")
+	write("vhost/a.txt", "vhost")
+
+	var rs routes
+	rs.Set("/docs=" + filepath.Join(tmp, "docs") + ",/docs/api/=" + filepath.Join(tmp, "api") + ",Docs.localhost/=" + filepath.Join(tmp, "vhost"))
+	var ms []mount
+	for _, r := range rs {
+		m, err := parseMount(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ms = append(ms, m)
+	}
+	if ms[2].host != "docs.localhost" || ms[2].prefix != "" || ms[1].prefix != "/docs/api" {
+		t.Errorf("parsed %+v", ms)
+	}
+	srv := httptest.NewServer(mountHandler(filepath.Join(tmp, "site"), ms, func(dir, prefix string) http.Handler {
+		return listingHandler(http.Dir(dir), prefix, false)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	get := func(host, path string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		req.Host = host
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	for _, tt := range []struct{ host, path, want string }{
+		{"localhost", "/a.txt", "site"},
+		{"localhost", "/docs/a.txt", "docs"},
+		{"localhost", "/docs/api/a.txt", "api"},
+		{"localhost", "/docsa.txt", "404 page not found\n"},
+		{"docs.localhost:3000", "/a.txt", "vhost"},
+		{"DOCS.localhost", "/a.txt", "vhost"},
+		{"docs.localhost", "/docs/a.txt", "404 page not found\n"},
+	} {
+		if _, body := get(tt.host, tt.path); body != tt.want {
+			t.Errorf("%s%s: got %q, want %q", tt.host, tt.path, body, tt.want)
+		}
+	}
+
+	if resp, _ := get("localhost", "/docs?sort=size"); resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "/docs/?sort=size" {
+		t.Errorf("/docs got %s to %s", resp.Status, resp.Header.Get("Location"))
+	}
+	if _, body := get("localhost", "/docs/api/"); !strings.Contains(body, "Index of /docs/api/") || !strings.Contains(body, `<a href="/docs/">docs/</a>`) {
+		t.Errorf("/docs/api/ listing:\n%s", body)
+	}
+
+	for _, bad := range []string{"/x=" + filepath.Join(tmp, "missing"), "/x=" + filepath.Join(tmp, "site/a.txt")} {
+		var rs routes
+		rs.Set(bad)
+		if _, err := parseMount(rs[0]); err == nil {
+			t.Errorf("-mount %s accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// routes collects path=destination mappings from a flag given several
+// times or with comma-separated mappings. Paths start with a slash, or for
+// -mount with a host.
+type routes []route
+
+type route struct {
+	path, dest string
+}
+
+func (rs *routes) String() string {
+	var s []string
+	for _, r := range *rs {
+		s = append(s, r.path+"="+r.dest)
+	}
+	return strings.Join(s, ",")
+}
+
+func (rs *routes) Set(v string) error {
+	for m := range strings.SplitSeq(v, ",") {
+		path, dest, ok := strings.Cut(strings.TrimSpace(m), "=")
+		if !ok || !strings.Contains(path, "/") || dest == "" {
+			return fmt.Errorf("%q isn't a /path=destination mapping", m)
+		}
+		*rs = append(*rs, route{path: path, dest: dest})
+	}
+	return nil
+}
+
+// proxy is a -proxy route, forwarding requests under prefix to target.
+type proxy struct {
+	prefix  string
+	handler http.Handler
+}
+
+// newProxy forwards requests for prefix and the paths below it to target,
+// keeping their path, which is added to target's. Websocket upgrades are
+// passed through. The Host header is target's, as development servers
+// often check it.
+func newProxy(r route) (proxy, error) {
+	if !strings.HasPrefix(r.path, "/") {
+		return proxy{}, fmt.Errorf("-proxy %s: paths must start with /", r.path)
+	}
+	target, err := url.Parse(r.dest)
+	if err != nil {
+		return proxy{}, fmt.Errorf("-proxy %s: %w", r.path, err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" || target.Host == "" {
+		return proxy{}, fmt.Errorf("-proxy %s: %q isn't an http or https URL", r.path, r.dest)
+	}
+	return proxy{
+		prefix: strings.TrimSuffix(r.path, "/"),
+		handler: &httputil.ReverseProxy{
+			Rewrite: func(pr *httputil.ProxyRequest) {
+				pr.SetURL(target)
+				pr.SetXForwarded()
+			},
+		},
+	}, nil
+}
+
+// matches reports whether path is the proxy's prefix or below it.
+func (p proxy) matches(path string) bool {
+	rest, ok := strings.CutPrefix(path, p.prefix)
+	return ok && (rest == "" || strings.HasPrefix(rest, "/"))
+}
+
+// routeMiddleware serves the -mock paths from their files and forwards the
+// -proxy prefixes to their servers, the longest matching prefix winning.
+// Anything else goes to next.
+func routeMiddleware(next http.Handler, proxies, mocks routes) (http.Handler, error) {
+	var ps []proxy
+	for _, r := range proxies {
+		p, err := newProxy(r)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, p)
+	}
+	slices.SortFunc(ps, func(a, b proxy) int { return len(b.prefix) - len(a.prefix) })
+
+	files := map[string]string{}
+	for _, m := range mocks {
+		if !strings.HasPrefix(m.path, "/") {
+			return nil, fmt.Errorf("-mock %s: paths must start with /", m.path)
+		}
+		if fi, err := os.Stat(m.dest); err != nil {
+			return nil, fmt.Errorf("-mock %s: %w", m.path, err)
+		} else if fi.IsDir() {
+			return nil, fmt.Errorf("-mock %s: %s is a directory", m.path, m.dest)
+		}
+		files[m.path] = m.dest
+	}
+	if len(ps) == 0 && len(files) == 0 {
+		return next, nil
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if file, ok := files[r.URL.Path]; ok {
+			serveMock(w, r, file)
+			return
+		}
+		for _, p := range ps {
+			if p.matches(r.URL.Path) {
+				if headerHas(r.Header, "Connection", "upgrade") {
+					// Upgraded connections live on past the server's request timeouts
+					rc := http.NewResponseController(w)
+					rc.SetReadDeadline(time.Time{})
+					rc.SetWriteDeadline(time.Time{})
+				}
+				p.handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	}), nil
+}
+
+// serveMock answers any method with the file, which is read on each request
+// so it can be edited while serving.
+func serveMock(w http.ResponseWriter, r *http.Request, file string) {
+	f, err := os.Open(file)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, os.ErrNotExist) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeContent(w, r, file, fi.ModTime(), f)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoutesSet(t *testing.T) {
+	var rs routes
+	if err := rs.Set("/api=http://localhost:8081, /auth=http://localhost:9000"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.Set("/ws=http://localhost:8082"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rs.String(), "/api=http://localhost:8081,/auth=http://localhost:9000,/ws=http://localhost:8082"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	for _, bad := range []string{"api=http://x", "/api", "/api=", "=http://x"} {
+		if err := rs.Set(bad); err == nil {
+			t.Errorf("Set(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRouteMiddleware(t *testing.T) {
+	backend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name+" "+r.Host+" "+r.URL.Path+" "+r.Header.Get("X-Forwarded-Host"))
+		}))
+	}
+	api, users := backend("api"), backend("users")
+	defer api.Close()
+	defer users.Close()
+	live := httptest.NewServer(newLiveReload())
+	defer live.Close()
+
+	mock := filepath.Join(t.TempDir(), "me.json")
+	os.WriteFile(mock, []byte(`{"name":"me"}`), 0o644)
+
+	static := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "static") })
+	var proxies, mocks routes
+	proxies.Set("/api=" + api.URL + ",/api/users/=" + users.URL + "/v2,/.serve=" + live.URL)
+	mocks.Set("/api/me=" + mock)
+	h, err := routeMiddleware(static, proxies, mocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	for path, want := range map[string]string{
+		"/api":         "api " + strings.TrimPrefix(api.URL, "http://") + " /api " + host,
+		"/api/x":       "api " + strings.TrimPrefix(api.URL, "http://") + " /api/x " + host,
+		"/api/users/1": "users " + strings.TrimPrefix(users.URL, "http://") + " /v2/api/users/1 " + host,
+		"/apiary":      "static",
+		"/index.html":  "static",
+		"/api/me":      `{"name":"me"}`,
+	} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != want {
+			t.Errorf("%s: got %q, want %q", path, body, want)
+		}
+	}
+
+	// Websockets are passed through
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET "+livePath+" HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("websocket upgrade through the proxy got %s", resp.Status)
+	}
+
+	for _, bad := range []string{"/x=localhost:8081", "/x=ftp://host", "host/x=http://x"} {
+		var rs routes
+		rs.Set(bad)
+		if _, err := routeMiddleware(static, rs, nil); err == nil {
+			t.Errorf("-proxy %s accepted", bad)
+		}
+	}
+	var missing routes
+	missing.Set("/x=" + filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := routeMiddleware(static, nil, missing); err == nil {
+		t.Error("-mock of a missing file accepted")
+	}
+}
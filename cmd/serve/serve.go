@@ -1,36 +1,277 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"flag"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/crypto/acme/autocert"
 	"pkg.jsn.cam/jsn/internal"
+	"pkg.jsn.cam/jsn/internal/httpserver"
+	"pkg.jsn.cam/jsn/internal/metrics"
+	"pkg.jsn.cam/jsn/internal/middleware"
+	"pkg.jsn.cam/jsn/internal/run"
 )
 
 var (
-	port    = flag.String("port", "3000", "port to use")
-	dir     = flag.String("dir", ".", "directory to serve")
-	verbose = flag.Bool("v", false, "enable verbose logging")
+	listenOn        = flag.String("listen", ":3000", "address to listen on: host:port, :port (:0 picks a free port) or unix:/path/to.sock")
+	public          = flag.Bool("public", false, "bind a -listen address without a host to every interface rather than only localhost")
+	dir             = flag.String("dir", ".", "directory to serve")
+	verbose         = flag.Bool("v", false, "log every request")
+	logFormat       = flag.String("log-format", "json", "format of -v request logs: json, or combined for the Apache combined log format")
+	exposeMetrics   = flag.Bool("metrics", false, "expose Prometheus metrics at /metrics")
+	compress        = flag.Bool("compress", true, "compress responses with brotli or gzip when the client accepts it")
+	cacheControl    = flag.String("cache-control", "", "Cache-Control header for files, like \"public, max-age=3600\"")
+	noCache         = flag.Bool("no-cache", false, "tell browsers not to cache files, and answer conditional requests in full")
+	hideDotfiles    = flag.Bool("hide-dotfiles", true, "neither list nor serve files and directories whose names start with a dot, except .well-known")
+	exclude         = flag.String("exclude", "", "comma-separated glob patterns of files and directories to neither list nor serve, like node_modules,*.log or build/*.map")
+	envInject       = flag.String("env-inject", "", "comma-separated paths of files to render as Go templates of the environment variables when served, like /config.js containing {{.API_URL}}")
+	readme          = flag.Bool("readme", true, "render a directory's README.md above its listing")
+	checksum        = flag.Bool("checksum", false, "send the SHA-256 of files as X-Checksum-SHA256 and their ETag, and answer <file>.sha256 with it; hashed on first request and again when files change")
+	live            = flag.Bool("live", false, "reload pages open in the browser when files in -dir change")
+	qrCode          = flag.Bool("qr", false, "print a QR code of the URL other devices on the network can open, to connect from a phone; needs -public or a -listen address on the network")
+	share           = flag.Bool("share", false, "serve the QR code of -qr's URL at "+sharePath+" too")
+	useTLS          = flag.Bool("tls", false, "serve HTTPS with a self-signed certificate for localhost, kept in the user cache directory")
+	certFile        = flag.String("cert", "", "serve HTTPS with this certificate file (PEM); needs -key")
+	keyFile         = flag.String("key", "", "private key file (PEM) for -cert")
+	autocertHosts   = flag.String("autocert", "", "serve HTTPS with Let's Encrypt certificates for these comma-separated hosts; needs port 443 or 80 reachable from the internet, so a -listen address without a host binds to every interface")
+	autocertEmail   = flag.String("autocert-email", "", "contact email for the -autocert ACME account")
+	autocertCache   = flag.String("autocert-cache", "", "directory to keep -autocert certificates in (default in the user cache directory)")
+	readTimeout     = flag.Duration("read-timeout", time.Minute, "how long a client may take to send a request, body included")
+	writeTimeout    = flag.Duration("write-timeout", 10*time.Minute, "how long a response may take to send, long enough for big files over slow links; 0 for no limit")
+	idleTimeout     = flag.Duration("idle-timeout", 2*time.Minute, "how long idle keep-alive connections are kept open")
+	maxHeaderBytes  = flag.Int("max-header-bytes", 64<<10, "largest request header accepted, in bytes")
+	maxConns        = flag.Int("max-conns", 0, "most connections open at once from one IP address, beyond which new ones are closed; 0 for no limit")
+	rateLimit       = flag.Float64("rate-limit", 0, "most requests a second from one IP address on average, beyond which they get 429 Too Many Requests; 0 for no limit")
+	rateBurst       = flag.Int("rate-burst", 50, "how many requests one IP address may make at once under -rate-limit, like a page and its assets")
+	shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second, "how long to let requests in flight finish on SIGINT or SIGTERM")
 )
 
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("%s %s %s", r.RemoteAddr, r.Method, r.URL.Path)
-		next.ServeHTTP(w, r)
-	})
+// mounts, proxies and mocks are -mount, -proxy and -mock routes.
+var mounts, proxies, mocks routes
+
+// extraHeaders are the -header headers.
+var extraHeaders headers
+
+func init() {
+	flag.Var(&mounts, "mount", "serve a directory under a path as well as -dir at /, like /docs=./docs, or for one host, like docs.localhost/=./docs; may be repeated or comma-separated")
+	flag.Var(&proxies, "proxy", "forward requests under a path to a server, like /api=http://localhost:8081; websockets included, may be repeated or comma-separated")
+	flag.Var(&mocks, "mock", "answer requests for a path with a file, like /api/user=mocks/user.json; may be repeated or comma-separated")
+	flag.Var(&extraHeaders, "header", "add a header to file responses, like 'X-Frame-Options: DENY'; may be repeated")
+}
+
+// cacheDir returns the directory serve keeps certificates in.
+func cacheDir(name string) string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "serve", name)
 }
 
 func main() {
 	internal.HandleStartup()
+	// Log lines for a request carry its ID
+	slog.SetDefault(slog.New(middleware.RequestIDHandler{Handler: slog.Default().Handler()}))
+	if *logFormat != "json" && *logFormat != "combined" {
+		log.Fatalf("-log-format must be json or combined, not %q", *logFormat)
+	}
+	if *noCache && *cacheControl != "" {
+		log.Fatal("-no-cache and -cache-control can't be used together")
+	}
+	if (*certFile == "") != (*keyFile == "") {
+		log.Fatal("-cert and -key must be used together")
+	}
+	addr, err := listenAddress(*listenOn, *public || *autocertHosts != "")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ex, err := newExclusion(*hideDotfiles, *exclude)
+	if err != nil {
+		log.Fatalf("bad -exclude pattern: %v", err)
+	}
+	injected, err := parseEnvInject(*envInject)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var ms []mount
+	dirs := []string{*dir}
+	for _, r := range mounts {
+		m, err := parseMount(r)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ms = append(ms, m)
+		dirs = append(dirs, m.dir)
+	}
 
-	var handler = http.FileServer(http.Dir(*dir))
+	// Not http.DefaultServeMux, which has the debug endpoints on it
+	mux := http.NewServeMux()
+	var handler = mountHandler(*dir, ms, func(dir, prefix string) http.Handler {
+		root := ex.fs(http.Dir(dir))
+		h := listingHandler(root, prefix, *readme)
+		if *checksum {
+			h = checksumMiddleware(root, h)
+		}
+		if len(injected) > 0 {
+			h = envInjectMiddleware(h, root, prefix, injected)
+		}
+		return h
+	})
+	var lr *liveReload
+	if *live {
+		lr = newLiveReload()
+		for _, d := range dirs {
+			if err := lr.watch(d, ex); err != nil {
+				log.Fatalf("failed to watch %s for changes: %v", d, err)
+			}
+		}
+		mux.Handle(livePath, lr)
+		handler = injectMiddleware(handler)
+	}
+	if *compress {
+		handler = compressMiddleware(handler)
+	}
+	handler = headersMiddleware(handler, extraHeaders, *cacheControl, *noCache)
+	handler, err = routeMiddleware(handler, proxies, mocks)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *rateLimit > 0 {
+		handler = rateLimitMiddleware(handler, newRateLimiter(*rateLimit, *rateBurst))
+	}
+	handler = middleware.RecoverMiddleware(slog.Default(), handler)
 	if *verbose {
-		handler = loggingMiddleware(handler)
+		handler = loggingMiddleware(handler, *logFormat)
+	}
+	if *exposeMetrics {
+		mux.Handle("/metrics", promhttp.Handler())
+		handler = metrics.New(metrics.Options{}).Wrap(handler)
+	}
+
+	mux.Handle("/", handler)
+	ln, err := httpserver.Listen(addr)
+	if err != nil {
+		log.Fatal(err)
 	}
+	if *maxConns > 0 {
+		ln = limitConns(ln, *maxConns)
+	}
+	srv := &httpserver.Server{
+		Handler:         middleware.RequestIDMiddleware("", slog.Default(), mux),
+		Listeners:       []net.Listener{ln},
+		ReadTimeout:     *readTimeout,
+		WriteTimeout:    *writeTimeout,
+		IdleTimeout:     *idleTimeout,
+		MaxHeaderBytes:  *maxHeaderBytes,
+		ShutdownTimeout: *shutdownTimeout,
+	}
+	if lr != nil {
+		// Hijacked connections are left to their handlers by Shutdown
+		srv.OnShutdown = append(srv.OnShutdown, lr.close)
+	}
+	scheme := "http"
+
+	switch {
+	case *autocertHosts != "":
+		cache := *autocertCache
+		if cache == "" {
+			cache = cacheDir("acme")
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(strings.Split(*autocertHosts, ",")...),
+			Email:      *autocertEmail,
+			Cache:      autocert.DirCache(cache),
+		}
+		srv.TLSConfig = m.TLSConfig()
 
-	http.Handle("/", handler)
+		// HTTP-01 challenges need port 80; TLS-ALPN-01 ones are answered on -listen if it's port 443
+		if challengeLn, err := httpserver.Listen(":80"); err != nil {
+			log.Printf("Not answering HTTP-01 challenges on port 80: %v", err)
+		} else {
+			challenges := &httpserver.Server{
+				Handler:         m.HTTPHandler(nil),
+				Listeners:       []net.Listener{challengeLn},
+				ReadTimeout:     *readTimeout,
+				WriteTimeout:    *writeTimeout,
+				IdleTimeout:     *idleTimeout,
+				MaxHeaderBytes:  *maxHeaderBytes,
+				ShutdownTimeout: *shutdownTimeout,
+			}
+			served := make(chan error, 1)
+			go func() { served <- challenges.ListenAndServe(run.Context()) }()
+			run.OnShutdown("HTTP-01 challenges", func(ctx context.Context) error {
+				select {
+				case err := <-served:
+					return err
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+		}
+		scheme = "https"
+		log.Printf("Serving %s on %s with certificates for %s", *dir, serverURL(ln.Addr(), scheme), *autocertHosts)
 
-	log.Printf("Serving %s on port %s", *dir, *port)
-	log.Fatal(http.ListenAndServe(":"+*port, nil))
+	case *certFile != "":
+		scheme = "https"
+		log.Printf("Serving %s on %s", *dir, serverURL(ln.Addr(), scheme))
+		srv.CertFile, srv.KeyFile = *certFile, *keyFile
+
+	case *useTLS:
+		cert, path, err := selfSignedCert(cacheDir("tls"))
+		if err != nil {
+			log.Fatalf("failed to set up a self-signed certificate: %v", err)
+		}
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		scheme = "https"
+		log.Printf("Serving %s on %s with a self-signed certificate; trust %s to avoid browser warnings", *dir, serverURL(ln.Addr(), scheme), path)
+
+	default:
+		log.Printf("Serving %s on %s", *dir, serverURL(ln.Addr(), scheme))
+	}
+
+	if *qrCode || *share {
+		host := ""
+		if *autocertHosts != "" {
+			host, _, _ = strings.Cut(*autocertHosts, ",")
+		}
+		url, err := shareURL(ln.Addr(), scheme, host)
+		if err != nil {
+			log.Fatalf("no URL to share: %v", err)
+		}
+		if *qrCode {
+			log.Printf("Open %s on another device, or scan:", url)
+			if err := printQR(os.Stderr, url); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if *share {
+			h, err := shareHandler(url)
+			if err != nil {
+				log.Fatal(err)
+			}
+			mux.Handle(sharePath, h)
+			log.Printf("QR code of %s at %s", url, sharePath)
+		}
+	}
+
+	// Requests in flight get to finish on SIGINT or SIGTERM
+	err = srv.ListenAndServe(run.Context())
+	if serr := run.Shutdown(); err == nil {
+		err = serr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"pkg.jsn.cam/jsn/internal/qr"
+)
+
+// sharePath is where -share serves the QR code of the share URL.
+const sharePath = "/.share"
+
+// shareURL returns the URL other devices on the network can open a listener
+// at: its own address, or this machine's address on the local network for a
+// wildcard one. A non-empty host, like an -autocert one, is used instead.
+func shareURL(addr net.Addr, scheme, host string) (string, error) {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return "", errors.New("other devices can't connect to a unix socket")
+	}
+	if host == "" {
+		ip := tcp.IP
+		switch {
+		case ip.IsLoopback():
+			return "", errors.New("other devices can't connect to localhost; use -public")
+		case ip.IsUnspecified():
+			var err error
+			if ip, err = lanIP(); err != nil {
+				return "", err
+			}
+		}
+		host = ip.String()
+	}
+	if scheme == "http" && tcp.Port != 80 || scheme == "https" && tcp.Port != 443 {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), fmt.Sprint(tcp.Port))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return scheme + "://" + host + "/", nil
+}
+
+// lanIP returns this machine's private IPv4 address on the local network,
+// or failing that any IPv4 address it has other than loopback.
+func lanIP() (net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var found net.IP
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.To4() == nil || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipnet.IP.IsPrivate() {
+			return ipnet.IP, nil
+		}
+		if found == nil {
+			found = ipnet.IP
+		}
+	}
+	if found == nil {
+		return nil, errors.New("no network address other devices could connect to")
+	}
+	return found, nil
+}
+
+// printQR prints the QR code of url to f, black on white if f is a terminal
+// so that it scans whatever the terminal's colors.
+func printQR(f *os.File, url string) error {
+	code, err := qr.Encode(url)
+	if err != nil {
+		return err
+	}
+	text := code.Text(2)
+	if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		text = strings.ReplaceAll("\x1b[30;47m"+text, "\n", "\x1b[0m\n\x1b[30;47m")
+		text = strings.TrimSuffix(text, "\x1b[30;47m")
+	}
+	_, err = f.WriteString(text)
+	return err
+}
+
+// shareHandler serves the QR code of url as an SVG image.
+func shareHandler(url string) (http.Handler, error) {
+	code, err := qr.Encode(url)
+	if err != nil {
+		return nil, err
+	}
+	svg := []byte(code.SVG(4))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(svg)
+	}), nil
+}
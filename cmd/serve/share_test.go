@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShareURL(t *testing.T) {
+	for _, tt := range []struct {
+		addr         net.Addr
+		scheme, host string
+		want         string
+	}{
+		{&net.TCPAddr{IP: net.IPv4(192, 168, 1, 20), Port: 3000}, "http", "", "http://192.168.1.20:3000/"},
+		{&net.TCPAddr{IP: net.IPv4(192, 168, 1, 20), Port: 80}, "http", "", "http://192.168.1.20/"},
+		{&net.TCPAddr{IP: net.IPv4(192, 168, 1, 20), Port: 80}, "https", "", "https://192.168.1.20:80/"},
+		{&net.TCPAddr{IP: net.ParseIP("fd00::1"), Port: 443}, "https", "", "https://[fd00::1]/"},
+		{&net.TCPAddr{IP: net.ParseIP("fd00::1"), Port: 3000}, "http", "", "http://[fd00::1]:3000/"},
+		{&net.TCPAddr{IP: net.IPv4zero, Port: 443}, "https", "example.com", "https://example.com/"},
+	} {
+		got, err := shareURL(tt.addr, tt.scheme, tt.host)
+		if err != nil || got != tt.want {
+			t.Errorf("shareURL(%v, %s, %q) = %q, %v, want %q", tt.addr, tt.scheme, tt.host, got, err, tt.want)
+		}
+	}
+
+	for _, addr := range []net.Addr{
+		&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3000},
+		&net.UnixAddr{Name: "/run/serve.sock", Net: "unix"},
+	} {
+		if _, err := shareURL(addr, "http", ""); err == nil {
+			t.Errorf("shareURL(%v) succeeded", addr)
+		}
+	}
+}
+
+func TestShareQR(t *testing.T) {
+	const url = "http://192.168.1.20:3000/"
+	h, err := shareHandler(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", sharePath, nil))
+	if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" || !strings.HasPrefix(w.Body.String(), "<svg") {
+		t.Errorf("got %s:\n%s", ct, w.Body)
+	}
+
+	// Escape codes are only for terminals
+	f, err := os.Create(filepath.Join(t.TempDir(), "qr.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := printQR(f, url); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := os.ReadFile(f.Name())
+	if lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n"); len(lines) != 15 || strings.Contains(string(out), "\x1b") {
+		t.Errorf("printed %d lines:\n%s", len(lines), out)
+	}
+}
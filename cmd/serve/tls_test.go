@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+func TestGenerateSelfSigned(t *testing.T) {
+	certPEM, keyPEM, err := generateSelfSigned([]string{"localhost", "127.0.0.1", "devbox"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "devbox"} {
+		if err := cert.Leaf.VerifyHostname(host); err != nil {
+			t.Errorf("not valid for %s: %v", host, err)
+		}
+	}
+	if err := cert.Leaf.VerifyHostname("example.com"); err == nil {
+		t.Error("valid for example.com")
+	}
+}
+
+func TestSelfSignedCertIsKept(t *testing.T) {
+	dir := t.TempDir()
+	first, path, err := selfSignedCert(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, path2, err := selfSignedCert(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != path2 || !bytes.Equal(first.Certificate[0], second.Certificate[0]) {
+		t.Error("certificate was regenerated")
+	}
+}
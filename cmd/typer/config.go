@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"pkg.jsn.cam/jsn/flagenv"
+)
+
+// languageConfig is a [languages.<name>] table in the config file. It changes
+// a built-in language or, with snippets, adds a new one.
+type languageConfig struct {
+	// Keywords and Exts replace the language's when set.
+	Keywords []string `toml:"keywords"`
+	Exts     []string `toml:"exts"`
+	// Comment starts a line comment, "//" by default for new languages.
+	Comment string `toml:"comment"`
+	// Header and Footer are typed at the start and end of each -new-file file.
+	Header string `toml:"header"`
+	Footer string `toml:"footer"`
+	// Snippets are templates typed as the "snippet" kind, see languageProfile.snippets.
+	Snippets []string `toml:"snippets"`
+	// Weights maps kinds of snippet to how often they're picked relative to
+	// each other; kinds left out keep a weight of 1.
+	Weights map[string]int `toml:"weights"`
+}
+
+// configured holds the names of the flags set by the config file.
+var configured = map[string]bool{}
+
+// flagGiven reports whether a flag was set on the command line, in the
+// environment or in the config file, rather than left at its default.
+func flagGiven(name string) bool {
+	given := configured[name] || os.Getenv(strings.ToUpper(flagenv.Prefix+flagKey(name))) != ""
+	flag.Visit(func(f *flag.Flag) { given = given || f.Name == name })
+	return given
+}
+
+// flagKey returns the config file key of a flag: its name, with dashes and
+// dots as underscores like flagenv's environment variables.
+func flagKey(name string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// loadConfig applies the TOML config file at path. Top-level keys set flags,
+// unless the flag was given on the command line or in the environment.
+// [languages.<name>] tables change languages.
+func loadConfig(path string) error {
+	var values map[string]any
+	if _, err := toml.DecodeFile(path, &values); err != nil {
+		return fmt.Errorf("failed to decode config %s: %v", path, err)
+	}
+
+	flags := map[string]*flag.Flag{}
+	flag.VisitAll(func(f *flag.Flag) { flags[flagKey(f.Name)] = f })
+
+	for key, v := range values {
+		if key == "languages" {
+			continue
+		}
+		f, ok := flags[key]
+		if !ok || key == "config" {
+			return fmt.Errorf("unknown key %q in config %s", key, path)
+		}
+		if flagGiven(f.Name) {
+			continue
+		}
+		if err := f.Value.Set(fmt.Sprint(v)); err != nil {
+			return fmt.Errorf("config %s: failed to set %s to %v: %v", path, key, v, err)
+		}
+		configured[f.Name] = true
+	}
+
+	var c struct {
+		Languages map[string]languageConfig `toml:"languages"`
+	}
+	md, err := toml.DecodeFile(path, &c)
+	if err != nil {
+		return fmt.Errorf("failed to decode config %s: %v", path, err)
+	}
+	if undecoded := slices.DeleteFunc(md.Undecoded(), func(k toml.Key) bool { return k[0] != "languages" }); len(undecoded) > 0 {
+		return fmt.Errorf("unknown keys in config %s: %v", path, undecoded)
+	}
+	for name, lc := range c.Languages {
+		p, err := lc.apply(name, languages[name])
+		if err != nil {
+			return fmt.Errorf("config %s: language %s: %v", path, name, err)
+		}
+		languages[name] = p
+	}
+	return nil
+}
+
+// apply returns p, which is zero for a new language, changed by the config.
+func (lc languageConfig) apply(name string, p languageProfile) (languageProfile, error) {
+	if len(lc.Keywords) > 0 {
+		p.keywords = lc.Keywords
+	}
+	if len(lc.Exts) > 0 {
+		p.exts = lc.Exts
+	}
+	if lc.Comment != "" {
+		p.comment = lc.Comment
+	} else if p.comment == "" {
+		p.comment = "//"
+	}
+	if lc.Header != "" {
+		p.header = lc.Header
+	}
+	if lc.Footer != "" {
+		p.footer = lc.Footer
+	}
+	if len(lc.Snippets) > 0 {
+		p.snippets = lc.Snippets
+		p.kinds = append(slices.Clone(p.kinds), "snippet")
+	}
+	if len(p.kinds) == 0 {
+		return p, fmt.Errorf("new languages need snippets")
+	}
+	if len(p.keywords) == 0 && slices.ContainsFunc(p.snippets, func(s string) bool { return strings.Contains(s, "{{word}}") }) {
+		return p, fmt.Errorf("snippets use {{word}} but there are no keywords")
+	}
+
+	if len(lc.Weights) > 0 {
+		p.weights = make([]int, len(p.kinds))
+		total := 0
+		for i, kind := range p.kinds {
+			w, ok := lc.Weights[kind]
+			if !ok {
+				w = 1
+			}
+			if w < 0 {
+				return p, fmt.Errorf("weight of %s is negative", kind)
+			}
+			p.weights[i] = w
+			total += w
+		}
+		for kind := range lc.Weights {
+			if !slices.Contains(p.kinds, kind) {
+				return p, fmt.Errorf("unknown kind of snippet %q, want one of %s", kind, strings.Join(p.kinds, ", "))
+			}
+		}
+		if total == 0 {
+			return p, fmt.Errorf("all weights are zero")
+		}
+	}
+	return p, nil
+}
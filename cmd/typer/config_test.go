@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, config string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "typer.toml")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	defer func(rate float64, p languageProfile) {
+		*pasteRate = rate
+		languages["go"] = p
+		delete(languages, "elixir")
+		delete(configured, "paste-rate")
+	}(*pasteRate, languages["go"])
+
+	path := writeConfig(t, `
+paste_rate = 0.25
+
+[languages.go.weights]
+const = 0
+
+[languages.elixir]
+keywords = ["user"]
+snippets = ["def {{word}} do\n  :ok\nend"]
+`)
+	if err := loadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if *pasteRate != 0.25 || !flagGiven("paste-rate") {
+		t.Errorf("paste-rate = %v, given %v", *pasteRate, flagGiven("paste-rate"))
+	}
+
+	goLang := languages["go"]
+	for i, kind := range goLang.kinds {
+		want := 1
+		if kind == "const" {
+			want = 0
+		}
+		if goLang.weights[i] != want {
+			t.Errorf("go weight of %s = %d, want %d", kind, goLang.weights[i], want)
+		}
+	}
+
+	source := randomSource{lang: languages["elixir"]}
+	if snippet, _ := source.Next(); !strings.HasPrefix(snippet, "def user do") {
+		t.Errorf("elixir snippet = %q", snippet)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	defer func(p languageProfile) { languages["go"] = p }(languages["go"])
+
+	for _, config := range []string{
+		"bogus = 1",
+		"config = \"other.toml\"",
+		"wpm = \"fast\"",
+		"[languages.go.weights]\nlambda = 2",
+		"[languages.go.weights]\nfunc = 0\nstruct = 0\nvar = 0\nconst = 0",
+		"[languages.cobol]\nkeywords = [\"move\"]",
+		"[languages.elixir]\nsnippets = [\"{{word}}\"]",
+	} {
+		if err := loadConfig(writeConfig(t, config)); err == nil {
+			t.Errorf("loadConfig(%q) succeeded", config)
+		}
+	}
+}
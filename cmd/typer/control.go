@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"pkg.jsn.cam/jsn/internal/run"
+)
+
+// checkControlAddr makes sure the control endpoint is only reachable from
+// other machines when it needs a token.
+func checkControlAddr(addr, token string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("failed to parse control address %q: %v", addr, err)
+	}
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	if token == "" {
+		return fmt.Errorf("control address %s is reachable from other machines, so it needs -control-token", addr)
+	}
+	return nil
+}
+
+// controlHandler serves the control endpoint:
+//
+//	GET  /status  whether the simulation is paused, and the session stats so far, as JSON
+//	POST /pause   pauses the simulation
+//	POST /resume  resumes it
+//	POST /stop    stops typer, like the mouse corner and the stop key
+//
+// With a token, requests must send it as "Authorization: Bearer <token>" or ?token=.
+func controlHandler(token string, sigs chan<- os.Signal) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Paused bool          `json:"paused"`
+			Stats  *sessionStats `json:"stats"`
+		}{paused.Load(), stats.snapshot()})
+	})
+	mux.HandleFunc("POST /pause", func(w http.ResponseWriter, r *http.Request) {
+		setPaused(true, "control endpoint from "+r.RemoteAddr)
+		fmt.Fprintln(w, "paused")
+	})
+	mux.HandleFunc("POST /resume", func(w http.ResponseWriter, r *http.Request) {
+		setPaused(false, "control endpoint from "+r.RemoteAddr)
+		fmt.Fprintln(w, "resumed")
+	})
+	mux.HandleFunc("POST /stop", func(w http.ResponseWriter, r *http.Request) {
+		logMessage("controlHandler: Stop requested from ", r.RemoteAddr)
+		fmt.Fprintln(w, "stopping")
+		select {
+		case sigs <- syscall.SIGTERM:
+		default: // already stopping
+		}
+	})
+	if token == "" {
+		return mux
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.URL.Query().Get("token")
+		if auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			got = auth
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "wrong or missing token", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// serveControl starts the control endpoint on addr.
+func serveControl(addr, token string, sigs chan<- os.Signal) error {
+	if err := checkControlAddr(addr, token); err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+	srv := &http.Server{Handler: controlHandler(token, sigs), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != http.ErrServerClosed {
+			logMessage("serveControl: ", err)
+		}
+	}()
+	run.OnShutdown("control endpoint", srv.Shutdown)
+	logMessage("Control endpoint listening on ", ln.Addr())
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestCheckControlAddr(t *testing.T) {
+	for _, tc := range []struct {
+		addr, token string
+		ok          bool
+	}{
+		{"127.0.0.1:8765", "", true},
+		{"localhost:8765", "", true},
+		{"[::1]:8765", "", true},
+		{":8765", "", false},
+		{"0.0.0.0:8765", "", false},
+		{"192.168.1.10:8765", "secret", true},
+		{"8765", "", false},
+	} {
+		if err := checkControlAddr(tc.addr, tc.token); (err == nil) != tc.ok {
+			t.Errorf("checkControlAddr(%q, %q) = %v", tc.addr, tc.token, err)
+		}
+	}
+}
+
+func TestControlHandler(t *testing.T) {
+	defer paused.Store(false)
+	sigs := make(chan os.Signal, 1)
+	h := controlHandler("secret", sigs)
+
+	do := func(method, target string, auth bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		if auth {
+			r.Header.Set("Authorization", "Bearer secret")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do("POST", "/pause", false); w.Code != http.StatusUnauthorized || paused.Load() {
+		t.Fatalf("pause without token: %d, paused %v", w.Code, paused.Load())
+	}
+	if w := do("POST", "/pause?token=secret", false); w.Code != http.StatusOK || !paused.Load() {
+		t.Fatalf("pause: %d, paused %v", w.Code, paused.Load())
+	}
+
+	w := do("GET", "/status", true)
+	var status struct {
+		Paused bool           `json:"paused"`
+		Stats  map[string]any `json:"stats"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil || !status.Paused || status.Stats == nil {
+		t.Errorf("status: %v, %+v", err, status)
+	}
+
+	if w := do("POST", "/resume", true); w.Code != http.StatusOK || paused.Load() {
+		t.Errorf("resume: %d, paused %v", w.Code, paused.Load())
+	}
+	if w := do("GET", "/stop", true); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /stop: %d", w.Code)
+	}
+	do("POST", "/stop", true)
+	do("POST", "/stop", true) // doesn't block once a signal is pending
+	if len(sigs) != 1 {
+		t.Errorf("stop sent %d signals", len(sigs))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// keyTap, sleep and now are what typing uses to press keys, wait and tell the time.
+// --dry-run replaces them so nothing is typed and no time passes.
+var (
+	keyTap = func(key string) { robotgo.KeyTap(key) }
+	sleep  = time.Sleep
+	now    = time.Now
+)
+
+// startDryRun makes typing print to stdout, with backspaces shown as ⌫,
+// pastes as ⎘ and shortcuts in brackets, and sleeping advance a simulated clock instead of waiting.
+// Once the clock has run for length it signals termination on sigs.
+func startDryRun(length time.Duration, sigs chan<- os.Signal) {
+	var mu sync.Mutex
+	clock := time.Now()
+	end := clock.Add(length)
+
+	keyTap = func(key string) {
+		switch key {
+		case "backspace":
+			key = "⌫"
+		case "enter":
+			key = "\n"
+		case "tab":
+			key = "\t"
+		}
+		fmt.Print(key)
+	}
+	pasteText = func(text string) {
+		fmt.Print("⎘", text)
+	}
+	pressShortcut = func(key string, modifiers []string) {
+		fmt.Printf("[%s]\n", shortcut{key: key, modifiers: modifiers})
+	}
+	now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return clock
+	}
+	sleep = func(d time.Duration) {
+		mu.Lock()
+		clock = clock.Add(d)
+		over := clock.After(end)
+		mu.Unlock()
+		if over {
+			fmt.Printf("\nDry run reached %s of simulated time.\n", length)
+			sigs <- syscall.SIGTERM
+			select {} // the program is exiting
+		}
+	}
+}
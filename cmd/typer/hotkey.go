@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// paused is set while the simulation is paused; typing and mouse movement wait for it to clear.
+var paused atomic.Bool
+
+// waitIfPaused blocks while the simulation is paused.
+func waitIfPaused() {
+	for paused.Load() {
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// setPaused pauses or resumes the simulation, reporting the change.
+func setPaused(p bool, why string) {
+	if paused.Swap(p) == p {
+		return
+	}
+	if p {
+		logMessage("Simulation paused (", why, ")")
+		fmt.Printf("\nPaused (%s).\n", why)
+	} else {
+		logMessage("Simulation resumed (", why, ")")
+		fmt.Printf("Resumed (%s).\n", why)
+	}
+}
+
+// hotkey is a key watched by monitorHotkeys, with its platform key code.
+type hotkey struct {
+	name string
+	code uint32
+}
+
+// lookupHotkey resolves a key name like "f8" for this platform. The empty name disables the hotkey.
+func lookupHotkey(name string) (*hotkey, error) {
+	if name == "" {
+		return nil, nil
+	}
+	name = strings.ToLower(name)
+	code, ok := platformKeys[name]
+	if !ok {
+		names := make([]string, 0, len(platformKeys))
+		for n := range platformKeys {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown hotkey %q, want one of %s", name, strings.Join(names, ", "))
+	}
+	return &hotkey{name: name, code: code}, nil
+}
+
+// pressed reports whether k went down since the last call, given whether it was down then.
+func (k *hotkey) pressed(wasDown *bool) bool {
+	if k == nil {
+		return false
+	}
+	down, err := keyDown(k.code)
+	if err != nil {
+		panic(err)
+	}
+	press := down && !*wasDown
+	*wasDown = down
+	return press
+}
+
+// monitorHotkeys toggles pausing when pauseKey is pressed and signals termination when stopKey is.
+func monitorHotkeys(sigs chan<- os.Signal, pauseKey, stopKey *hotkey) {
+	if pauseKey == nil && stopKey == nil {
+		return
+	}
+	logMessage("monitorHotkeys goroutine started.")
+	defer func() {
+		if r := recover(); r != nil {
+			logMessage("PANIC in monitorHotkeys:", r)
+		}
+		logMessage("monitorHotkeys goroutine stopped.")
+	}()
+
+	var pauseDown, stopDown bool
+	for {
+		if pauseKey.pressed(&pauseDown) {
+			setPaused(!paused.Load(), pauseKey.name+" pressed")
+		}
+		if stopKey.pressed(&stopDown) {
+			logMessage("monitorHotkeys: ", stopKey.name, " pressed. Signaling termination.")
+			fmt.Printf("\n%s pressed. Terminating...\n", strings.ToUpper(stopKey.name))
+			sigs <- syscall.SIGTERM
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
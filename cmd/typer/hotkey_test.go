@@ -0,0 +1,25 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestLookupHotkey(t *testing.T) {
+	if k, err := lookupHotkey(""); k != nil || err != nil {
+		t.Errorf(`lookupHotkey("") = %v, %v, want nil, nil`, k, err)
+	}
+	if _, err := lookupHotkey("nosuchkey"); err == nil {
+		t.Error("unknown key: no error")
+	}
+	switch runtime.GOOS {
+	case "linux", "windows", "darwin":
+		k, err := lookupHotkey("F8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k.name != "f8" || k.code != platformKeys["f8"] {
+			t.Errorf("lookupHotkey(F8) = %+v", k)
+		}
+	}
+}
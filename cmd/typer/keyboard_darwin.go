@@ -0,0 +1,69 @@
+package main
+
+/*
+#cgo LDFLAGS: -framework ApplicationServices
+#include <ApplicationServices/ApplicationServices.h>
+*/
+import "C"
+
+// platformKeys maps hotkey names to macOS virtual key codes. Mac keyboards
+// have no Pause or Scroll Lock, but F13 to F15 sit where they would be.
+var platformKeys = map[string]uint32{
+	"f1": 0x7a, "f2": 0x78, "f3": 0x63, "f4": 0x76, "f5": 0x60, "f6": 0x61,
+	"f7": 0x62, "f8": 0x64, "f9": 0x65, "f10": 0x6d, "f11": 0x67, "f12": 0x6f,
+	"f13": 0x69, "f14": 0x6b, "f15": 0x71,
+}
+
+// keyDown reports whether the key is held down, whichever app has focus.
+func keyDown(code uint32) (bool, error) {
+	return bool(C.CGEventSourceKeyState(C.kCGEventSourceStateCombinedSessionState, C.CGKeyCode(code))), nil
+}
+
+// keyboardState returns the virtual key codes of every key held down.
+func keyboardState() ([]uint32, error) {
+	var down []uint32
+	for code := uint32(0); code < 0x80; code++ {
+		if d, _ := keyDown(code); d {
+			down = append(down, code)
+		}
+	}
+	return down, nil
+}
+
+// keyKind classifies a virtual key code for recording.
+func keyKind(code uint32) (shift, modifier bool) {
+	switch code {
+	case 0x38, 0x3c: // kVK_Shift, kVK_RightShift
+		return true, false
+	case 0x37, 0x36, 0x3a, 0x3d, 0x3b, 0x3e: // Command, Option, Control
+		return false, true
+	}
+	return false, false
+}
+
+// ansiKeys are the US layout characters of the ANSI virtual key codes.
+var ansiKeys = map[uint32]rune{
+	0x00: 'a', 0x01: 's', 0x02: 'd', 0x03: 'f', 0x04: 'h', 0x05: 'g', 0x06: 'z', 0x07: 'x',
+	0x08: 'c', 0x09: 'v', 0x0b: 'b', 0x0c: 'q', 0x0d: 'w', 0x0e: 'e', 0x0f: 'r', 0x10: 'y',
+	0x11: 't', 0x12: '1', 0x13: '2', 0x14: '3', 0x15: '4', 0x16: '6', 0x17: '5', 0x18: '=',
+	0x19: '9', 0x1a: '7', 0x1b: '-', 0x1c: '8', 0x1d: '0', 0x1e: ']', 0x1f: 'o', 0x20: 'u',
+	0x21: '[', 0x22: 'i', 0x23: 'p', 0x25: 'l', 0x26: 'j', 0x27: '\'', 0x28: 'k', 0x29: ';',
+	0x2a: '\\', 0x2b: ',', 0x2c: '/', 0x2d: 'n', 0x2e: 'm', 0x2f: '.', 0x31: ' ', 0x32: '`',
+}
+
+// keyName returns the robotgo key for a virtual key code, as typed with or
+// without shift on a US layout, or "" for keys that aren't recorded.
+func keyName(code uint32, shift bool) string {
+	switch code {
+	case 0x24, 0x4c: // kVK_Return, kVK_ANSI_KeypadEnter
+		return "enter"
+	case 0x30:
+		return "tab"
+	case 0x33: // kVK_Delete is backspace
+		return "backspace"
+	}
+	if r, ok := ansiKeys[code]; ok {
+		return shiftedKey(r, shift)
+	}
+	return ""
+}
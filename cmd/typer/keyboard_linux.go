@@ -0,0 +1,109 @@
+package main
+
+/*
+#cgo LDFLAGS: -lX11
+#include <X11/Xlib.h>
+#include <X11/XKBlib.h>
+
+static Display *hotkey_display;
+
+// query_keymap fills keys with a bit per keycode that is held down, returning
+// -1 if the X display can't be opened.
+static int query_keymap(char keys[32]) {
+	if (hotkey_display == NULL) {
+		hotkey_display = XOpenDisplay(NULL);
+		if (hotkey_display == NULL) {
+			return -1;
+		}
+	}
+	XQueryKeymap(hotkey_display, keys);
+	return 0;
+}
+
+static KeyCode keysym_to_keycode(KeySym sym) {
+	return XKeysymToKeycode(hotkey_display, sym);
+}
+
+static KeySym keycode_to_keysym(KeyCode code, int level) {
+	return XkbKeycodeToKeysym(hotkey_display, code, 0, level);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+// platformKeys maps hotkey names to X keysyms.
+var platformKeys = map[string]uint32{"pause": 0xff13, "scrolllock": 0xff14}
+
+func init() {
+	for n := uint32(1); n <= 12; n++ {
+		platformKeys[fmt.Sprintf("f%d", n)] = 0xffbe + n - 1 // XK_F1...
+	}
+}
+
+// keymap returns the X server's bitmap of held down keycodes.
+func keymap() ([32]C.char, error) {
+	var keys [32]C.char
+	if C.query_keymap(&keys[0]) != 0 {
+		return keys, errors.New("failed to open the X display")
+	}
+	return keys, nil
+}
+
+// keyDown reports whether the key with the keysym code is held down, polling the X server's keymap.
+func keyDown(code uint32) (bool, error) {
+	keys, err := keymap()
+	if err != nil {
+		return false, err
+	}
+	kc := C.keysym_to_keycode(C.KeySym(code))
+	return kc != 0 && keys[kc/8]>>(kc%8)&1 != 0, nil
+}
+
+// keyboardState returns the keysyms of every key held down.
+func keyboardState() ([]uint32, error) {
+	keys, err := keymap()
+	if err != nil {
+		return nil, err
+	}
+	var down []uint32
+	for kc := 8; kc < 256; kc++ {
+		if keys[kc/8]>>(kc%8)&1 != 0 {
+			if sym := C.keycode_to_keysym(C.KeyCode(kc), 0); sym != 0 {
+				down = append(down, uint32(sym))
+			}
+		}
+	}
+	return down, nil
+}
+
+// keyKind classifies a keysym for recording.
+func keyKind(code uint32) (shift, modifier bool) {
+	switch code {
+	case 0xffe1, 0xffe2: // Shift_L, Shift_R
+		return true, false
+	case 0xffe3, 0xffe4, 0xffe9, 0xffea, 0xffeb, 0xffec, 0xffe7, 0xffe8: // Control, Alt, Super, Meta
+		return false, true
+	}
+	return false, false
+}
+
+// keyName returns the robotgo key for a keysym, as typed with or without
+// shift, or "" for keys that aren't recorded.
+func keyName(code uint32, shift bool) string {
+	switch code {
+	case 0xff0d, 0xff8d: // Return, KP_Enter
+		return "enter"
+	case 0xff09:
+		return "tab"
+	case 0xff08:
+		return "backspace"
+	}
+	if code >= 0x20 && code <= 0x7e {
+		return shiftedKey(rune(code), shift)
+	}
+	return ""
+}
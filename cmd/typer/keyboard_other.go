@@ -0,0 +1,26 @@
+//go:build !linux && !windows && !darwin
+
+package main
+
+import "errors"
+
+// platformKeys is empty: the keyboard can't be read on this platform.
+var platformKeys = map[string]uint32{}
+
+var errNoKeyboard = errors.New("reading the keyboard isn't supported on this platform")
+
+func keyDown(uint32) (bool, error) {
+	return false, errNoKeyboard
+}
+
+func keyboardState() ([]uint32, error) {
+	return nil, errNoKeyboard
+}
+
+func keyKind(uint32) (shift, modifier bool) {
+	return false, false
+}
+
+func keyName(uint32, bool) string {
+	return ""
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// platformKeys maps hotkey names to virtual-key codes.
+var platformKeys = map[string]uint32{"pause": 0x13, "scrolllock": 0x91}
+
+func init() {
+	for n := uint32(1); n <= 12; n++ {
+		platformKeys[fmt.Sprintf("f%d", n)] = 0x70 + n - 1 // VK_F1...
+	}
+}
+
+var getAsyncKeyState = windows.NewLazySystemDLL("user32.dll").NewProc("GetAsyncKeyState")
+
+// keyDown reports whether the key is held down, whichever window has focus.
+func keyDown(code uint32) (bool, error) {
+	r, _, _ := getAsyncKeyState.Call(uintptr(code))
+	return r&0x8000 != 0, nil
+}
+
+// keyboardState returns the virtual-key codes of every key held down.
+func keyboardState() ([]uint32, error) {
+	var down []uint32
+	for vk := uint32(0x08); vk <= 0xfe; vk++ {
+		if d, _ := keyDown(vk); d {
+			down = append(down, vk)
+		}
+	}
+	return down, nil
+}
+
+// keyKind classifies a virtual-key code for recording.
+func keyKind(code uint32) (shift, modifier bool) {
+	switch code {
+	case 0x10, 0xa0, 0xa1: // VK_SHIFT, VK_LSHIFT, VK_RSHIFT
+		return true, false
+	case 0x11, 0x12, 0xa2, 0xa3, 0xa4, 0xa5, 0x5b, 0x5c: // Control, Menu (Alt), Windows
+		return false, true
+	}
+	return false, false
+}
+
+// oemKeys are the US layout characters of the punctuation virtual keys.
+var oemKeys = map[uint32]rune{
+	0xba: ';', 0xbb: '=', 0xbc: ',', 0xbd: '-', 0xbe: '.', 0xbf: '/',
+	0xc0: '`', 0xdb: '[', 0xdc: '\\', 0xdd: ']', 0xde: '\'',
+}
+
+// keyName returns the robotgo key for a virtual-key code, as typed with or
+// without shift on a US layout, or "" for keys that aren't recorded.
+func keyName(code uint32, shift bool) string {
+	switch {
+	case code == 0x0d:
+		return "enter"
+	case code == 0x09:
+		return "tab"
+	case code == 0x08:
+		return "backspace"
+	case code == 0x20:
+		return " "
+	case code >= 'A' && code <= 'Z':
+		return shiftedKey(rune(code-'A'+'a'), shift)
+	case code >= '0' && code <= '9':
+		return shiftedKey(rune(code), shift)
+	}
+	if r, ok := oemKeys[code]; ok {
+		return shiftedKey(r, shift)
+	}
+	return ""
+}
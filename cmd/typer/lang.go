@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// languageProfile describes how to generate random snippets in one language.
+type languageProfile struct {
+	// keywords are the language's keywords and common identifiers, used for names.
+	keywords []string
+	// exts are the file extensions of the language, used to pick -corpus files.
+	exts []string
+	// comment starts a line comment, used for prose.
+	comment string
+	// header and footer make the snippets typed into a new file a complete
+	// program with -new-file.
+	header, footer string
+	// kinds names the kinds of snippet the language has, and weights says how
+	// often each is picked; nil weights pick them equally often.
+	kinds   []string
+	weights []int
+	// generate returns a random snippet of kinds[kind], ending in a blank line.
+	generate func(p languageProfile, kind int) string
+	// snippets are templates from the config file, picked as the "snippet"
+	// kind, with each {{word}} replaced by a random keyword.
+	snippets []string
+}
+
+// languages maps --lang values to their profiles.
+var languages = map[string]languageProfile{
+	"go": {
+		keywords: goKeywords, exts: []string{".go"}, comment: "//", kinds: []string{"func", "struct", "var", "const"},
+		header: "package main\n\n", footer: "func main() {\n}\n",
+		generate: func(_ languageProfile, kind int) string { return generateRandomGoCode(kind) },
+	},
+	"python": {
+		keywords: pythonKeywords, exts: []string{".py"}, comment: "#", kinds: []string{"function", "class", "dict"},
+		footer:   "if __name__ == \"__main__\":\n    pass\n",
+		generate: generatePython,
+	},
+	"typescript": {
+		keywords: typescriptKeywords, exts: []string{".ts", ".tsx"}, comment: "//", kinds: []string{"interface", "function", "map"},
+		footer:   "export {};\n",
+		generate: generateTypeScript,
+	},
+	"rust": {
+		keywords: rustKeywords, exts: []string{".rs"}, comment: "//", kinds: []string{"struct", "function", "loop"},
+		footer:   "fn main() {\n}\n",
+		generate: generateRust,
+	},
+	"sql": {
+		keywords: sqlKeywords, exts: []string{".sql"}, comment: "--", kinds: []string{"select", "join", "update"},
+		header: "BEGIN;\n\n", footer: "COMMIT;\n",
+		generate: generateSQL,
+	},
+}
+
+// languageNames returns the supported --lang values, sorted.
+func languageNames() []string {
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// randomSource endlessly generates random snippets in one language.
+type randomSource struct {
+	lang languageProfile
+}
+
+func (s randomSource) Next() (string, bool) {
+	kind := s.lang.pickKind()
+	if s.lang.kinds[kind] == "snippet" {
+		return s.lang.fillSnippet(pick(s.lang.snippets)), true
+	}
+	return s.lang.generate(s.lang, kind), true
+}
+
+// pickKind returns the index of a random kind of snippet, following the weights.
+func (p languageProfile) pickKind() int {
+	if p.weights == nil {
+		return rand.Intn(len(p.kinds))
+	}
+	total := 0
+	for _, w := range p.weights {
+		total += w
+	}
+	n := rand.Intn(total)
+	for i, w := range p.weights {
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	return len(p.kinds) - 1
+}
+
+// fillSnippet replaces each {{word}} in template with a random keyword and
+// makes sure it ends in a blank line.
+func (p languageProfile) fillSnippet(template string) string {
+	parts := strings.Split(template, "{{word}}")
+	var sb strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			sb.WriteString(pick(p.keywords))
+		}
+		sb.WriteString(part)
+	}
+	return strings.TrimRight(sb.String(), "\n") + "\n\n"
+}
+
+var pythonKeywords = []string{
+	"data", "result", "value", "items", "config", "user", "request", "response", "client",
+	"session", "cache", "path", "name", "count", "index", "record", "payload", "buffer",
+	"handler", "parser", "token", "queue", "worker", "event", "status", "message", "report",
+}
+
+var typescriptKeywords = []string{
+	"user", "state", "props", "data", "items", "config", "options", "result", "response",
+	"request", "event", "handler", "value", "query", "route", "store", "token", "session",
+	"payload", "status", "message", "callback", "element", "component", "service", "cache",
+}
+
+var rustKeywords = []string{
+	"buf", "data", "len", "idx", "config", "state", "reader", "writer", "conn", "stream",
+	"token", "parser", "entry", "node", "value", "result", "input", "output", "path",
+	"name", "count", "item", "queue", "worker", "handle", "cache", "frame", "header",
+}
+
+var sqlKeywords = []string{
+	"users", "orders", "products", "sessions", "events", "accounts", "invoices", "payments",
+	"customers", "items", "logs", "teams", "projects", "tasks", "comments", "tags",
+}
+
+// sqlColumns are column names used in generated SQL.
+var sqlColumns = []string{
+	"id", "name", "email", "status", "created_at", "updated_at", "amount", "user_id",
+	"title", "total", "quantity", "price", "deleted_at", "owner_id", "kind",
+}
+
+// pick returns a random element of words.
+func pick(words []string) string {
+	return words[rand.Intn(len(words))]
+}
+
+// camel joins words in camelCase, e.g. "user", "status" becomes "userStatus".
+func camel(words ...string) string {
+	var sb strings.Builder
+	for i, w := range words {
+		if i > 0 && w != "" {
+			r := []rune(w)
+			r[0] = unicode.ToUpper(r[0])
+			w = string(r)
+		}
+		sb.WriteString(w)
+	}
+	return sb.String()
+}
+
+// pascal joins words in PascalCase, e.g. "user", "status" becomes "UserStatus".
+func pascal(words ...string) string {
+	return camel(append([]string{""}, words...)...)
+}
+
+// generatePython generates a random Python function or class.
+func generatePython(p languageProfile, kind int) string {
+	a, b := pick(p.keywords), pick(p.keywords)
+	switch kind {
+	case 0:
+		return fmt.Sprintf("def process_%s(%s):\n    if not %s:\n        return None\n    %s = []\n    for item in %s:\n        %s.append(item)\n    return %s\n\n\n",
+			a, a, a, b, a, b, b)
+	case 1:
+		return fmt.Sprintf("class %sManager:\n    def __init__(self, %s):\n        self.%s = %s\n        self.%s = {}\n\n    def get(self, key):\n        return self.%s.get(key)\n\n\n",
+			pascal(a), a, a, a, b, b)
+	default:
+		return fmt.Sprintf("%s_%s = {\n    \"%s\": %d,\n    \"%s\": %q,\n}\n\n", strings.ToUpper(a), strings.ToUpper(b), a, rand.Intn(1000), b, "sample_"+a)
+	}
+}
+
+// generateTypeScript generates a random TypeScript function, interface or constant.
+func generateTypeScript(p languageProfile, kind int) string {
+	a, b := pick(p.keywords), pick(p.keywords)
+	switch kind {
+	case 0:
+		return fmt.Sprintf("export interface %s {\n  id: number;\n  %s: string;\n  %s?: boolean;\n}\n\n", pascal(a), b, camel("is", a))
+	case 1:
+		return fmt.Sprintf("export async function %s(%s: string): Promise<%s | undefined> {\n  const %s = await fetch(`/api/${%s}`);\n  if (!%s.ok) {\n    return undefined;\n  }\n  return %s.json();\n}\n\n",
+			camel("fetch", a), b, pascal(a), camel(a, "response"), b, camel(a, "response"), camel(a, "response"))
+	default:
+		return fmt.Sprintf("const %s = new Map<string, number>();\n%s.set(%q, %d);\n\n", camel(a, "by", b), camel(a, "by", b), b, rand.Intn(100))
+	}
+}
+
+// generateRust generates a random Rust function or struct.
+func generateRust(p languageProfile, kind int) string {
+	a, b := pick(p.keywords), pick(p.keywords)
+	switch kind {
+	case 0:
+		return fmt.Sprintf("#[derive(Debug, Clone)]\npub struct %s {\n    pub %s: String,\n    pub %s: usize,\n}\n\n", pascal(a), a, b)
+	case 1:
+		return fmt.Sprintf("fn parse_%s(%s: &str) -> Result<usize, std::num::ParseIntError> {\n    let %s = %s.trim().parse::<usize>()?;\n    Ok(%s * %d)\n}\n\n",
+			a, b, a, b, a, rand.Intn(10)+1)
+	default:
+		return fmt.Sprintf("let mut %s = Vec::with_capacity(%d);\nfor %s in 0..%d {\n    %s.push(%s);\n}\n\n", a, rand.Intn(64)+1, b, rand.Intn(10)+1, a, b)
+	}
+}
+
+// generateSQL generates a random SQL query.
+func generateSQL(p languageProfile, kind int) string {
+	table, other := pick(p.keywords), pick(p.keywords)
+	col, col2 := pick(sqlColumns), pick(sqlColumns)
+	switch kind {
+	case 0:
+		return fmt.Sprintf("SELECT %s, %s\nFROM %s\nWHERE %s IS NOT NULL\nORDER BY %s DESC\nLIMIT %d;\n\n", col, col2, table, col, col2, rand.Intn(100)+1)
+	case 1:
+		return fmt.Sprintf("SELECT t.%s, COUNT(*)\nFROM %s t\nJOIN %s o ON o.%s = t.id\nGROUP BY t.%s;\n\n", col, table, other, col2, col)
+	default:
+		return fmt.Sprintf("UPDATE %s\nSET %s = %d\nWHERE %s = %d;\n\n", table, col, rand.Intn(1000), col2, rand.Intn(1000))
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLanguages(t *testing.T) {
+	for _, name := range languageNames() {
+		source := randomSource{lang: languages[name]}
+		for range 20 {
+			snippet, ok := source.Next()
+			if !ok || strings.TrimSpace(snippet) == "" {
+				t.Fatalf("%s: generated empty snippet", name)
+			}
+			if !strings.HasSuffix(snippet, "\n\n") {
+				t.Errorf("%s: snippet doesn't end in a blank line: %q", name, snippet)
+			}
+		}
+	}
+}
+
+func TestCase(t *testing.T) {
+	if got := camel("fetch", "user", "id"); got != "fetchUserId" {
+		t.Errorf("camel = %q", got)
+	}
+	if got := pascal("user", "state"); got != "UserState" {
+		t.Errorf("pascal = %q", got)
+	}
+}
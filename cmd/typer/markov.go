@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// markovOrder is how many previous tokens the model looks at to pick the next.
+// Whitespace counts as tokens, so this is about three words of context; less
+// than that produces obvious nonsense.
+const markovOrder = 6
+
+// markovMaxFileSize skips files larger than this, which are usually generated.
+const markovMaxFileSize = 1 << 20
+
+// markovTokens splits source into identifiers, numbers, runs of spaces, a
+// newline with the indentation after it, and single other characters.
+// Keeping whitespace as tokens lets generated code keep the corpus's layout.
+var markovTokens = regexp.MustCompile(`[\p{L}_][\p{L}\p{N}_]*|[0-9]+|\n[ \t]*|[ \t]+|.`)
+
+// markovModel is a token-level Markov chain trained on a corpus of source files.
+type markovModel struct {
+	// next maps markovOrder tokens, joined with markovSep, to every token that
+	// followed them; repeats make common continuations more likely.
+	next map[string][]string
+	// starts are the token sequences that begin a block, after a blank line or
+	// at the start of a file.
+	starts [][]string
+	files  int
+	tokens int
+}
+
+const markovSep = "\x00"
+
+// trainMarkov builds a model from the files under dir whose names end in one of exts.
+func trainMarkov(dir string, exts []string) (*markovModel, error) {
+	m := &markovModel{next: map[string][]string{}}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != dir && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" || name == "target") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !slices.Contains(exts, filepath.Ext(path)) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > markovMaxFileSize {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		m.train(string(data))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %v", err)
+	}
+	if len(m.starts) == 0 {
+		return nil, fmt.Errorf("no %s files with enough code in %s", strings.Join(exts, ", "), dir)
+	}
+	return m, nil
+}
+
+// train adds the transitions in one file to the model.
+func (m *markovModel) train(source string) {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	tokens := markovTokens.FindAllString(source, -1)
+	if len(tokens) <= markovOrder {
+		return
+	}
+	m.files++
+	m.tokens += len(tokens)
+
+	for i := 0; i+markovOrder < len(tokens); i++ {
+		state := tokens[i : i+markovOrder]
+		if (i == 0 || i >= 2 && tokens[i-2] == "\n" && tokens[i-1] == "\n") && isMarkovStart(state[0]) {
+			m.starts = append(m.starts, slices.Clone(state))
+		}
+		key := strings.Join(state, markovSep)
+		m.next[key] = append(m.next[key], tokens[i+markovOrder])
+	}
+}
+
+// isMarkovStart reports whether a block may start with token: not whitespace or a closing bracket.
+func isMarkovStart(token string) bool {
+	return strings.TrimSpace(token) != "" && !strings.ContainsAny(token, ")]}")
+}
+
+// generate returns a random block, stopping at a blank line once it has at
+// least minTokens tokens, or after maxTokens.
+func (m *markovModel) generate(minTokens, maxTokens int) string {
+	tokens := slices.Clone(m.starts[rand.Intn(len(m.starts))])
+	for len(tokens) < maxTokens {
+		n := len(tokens)
+		if n >= minTokens && tokens[n-1] == "\n" && tokens[n-2] == "\n" {
+			break
+		}
+		choices := m.next[strings.Join(tokens[n-markovOrder:], markovSep)]
+		if len(choices) == 0 {
+			break
+		}
+		tokens = append(tokens, choices[rand.Intn(len(choices))])
+	}
+	return strings.TrimRight(strings.Join(tokens, ""), " \t\n") + "\n\n"
+}
+
+// markovSource endlessly generates blocks from a trained model.
+type markovSource struct {
+	model *markovModel
+}
+
+func (s markovSource) Next() (string, bool) {
+	return s.model.generate(30, 400), true
+}
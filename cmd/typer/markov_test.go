@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarkov(t *testing.T) {
+	dir := t.TempDir()
+	code := "package main\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n\nfunc other() {\n\treturn\n}\n"
+	for name, content := range map[string]string{
+		"main.go":           code,
+		"notes.txt":         "not code at all\n\nignored entirely\n",
+		".git/config.go":    "package hidden\n\nvar hidden = 1\n",
+		"sub/dir/nested.go": code,
+		"vendor/lib/dep.go": "package dep\n\nvar dep = 1\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := trainMarkov(dir, []string{".go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.files != 2 {
+		t.Errorf("trained on %d files, want 2", m.files)
+	}
+
+	source := markovSource{model: m}
+	for range 20 {
+		block, _ := source.Next()
+		if !strings.HasSuffix(block, "\n\n") {
+			t.Errorf("block doesn't end in a blank line: %q", block)
+		}
+		// Every block starts where one did in the corpus
+		if first := strings.Fields(block)[0]; first != "package" && first != "func" {
+			t.Errorf("block starts with %q: %q", first, block)
+		}
+		if strings.Contains(block, "hidden") || strings.Contains(block, "dep") || strings.Contains(block, "ignored") {
+			t.Errorf("block from a skipped file: %q", block)
+		}
+	}
+
+	if _, err := trainMarkov(dir, []string{".rs"}); err == nil {
+		t.Error("no matching files: no error")
+	}
+}
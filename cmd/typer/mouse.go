@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// mouseOptions controls how preventComputerSleep moves the mouse.
+type mouseOptions struct {
+	// Range is the furthest the mouse wanders from where it was, in pixels.
+	Range int
+	// Speed is the average speed of a movement in pixels per second.
+	Speed float64
+	// ScrollRate and ClickRate are the chances per wake-up of scrolling a little
+	// and of clicking. Both happen in Region; without one, scrolls happen where
+	// the mouse is and there are no clicks.
+	ScrollRate, ClickRate float64
+	Region                image.Rectangle
+	// Exit is the corner of the exit zone, which the mouse must never enter.
+	Exit image.Point
+}
+
+// parseRegion parses a screen region given as "x,y,width,height".
+func parseRegion(s string) (image.Rectangle, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return image.Rectangle{}, fmt.Errorf("region %q isn't x,y,width,height", s)
+	}
+	var n [4]int
+	for i, part := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || v < 0 {
+			return image.Rectangle{}, fmt.Errorf("region %q isn't x,y,width,height", s)
+		}
+		n[i] = v
+	}
+	if n[2] == 0 || n[3] == 0 {
+		return image.Rectangle{}, fmt.Errorf("region %q is empty", s)
+	}
+	return image.Rect(n[0], n[1], n[0]+n[2], n[1]+n[3]), nil
+}
+
+// bezierPath returns the points of a cubic Bézier curve from one point to
+// another, bowed to one side like a hand moving a mouse. The points bunch up
+// at both ends, so following them at a steady rate speeds up then slows down.
+func bezierPath(from, to image.Point, steps int) []image.Point {
+	dx, dy := float64(to.X-from.X), float64(to.Y-from.Y)
+	// Control points a third and two thirds of the way along, pushed sideways
+	bow := (rand.Float64() - 0.5) * 0.6
+	c1x, c1y := float64(from.X)+dx/3-dy*bow, float64(from.Y)+dy/3+dx*bow
+	bow += (rand.Float64() - 0.5) * 0.2
+	c2x, c2y := float64(from.X)+dx*2/3-dy*bow, float64(from.Y)+dy*2/3+dx*bow
+
+	path := make([]image.Point, 0, steps+1)
+	for i := range steps + 1 {
+		t := (1 - math.Cos(math.Pi*float64(i)/float64(steps))) / 2
+		u := 1 - t
+		x := u*u*u*float64(from.X) + 3*u*u*t*c1x + 3*u*t*t*c2x + t*t*t*float64(to.X)
+		y := u*u*u*float64(from.Y) + 3*u*u*t*c1y + 3*u*t*t*c2y + t*t*t*float64(to.Y)
+		path = append(path, image.Pt(int(math.Round(x)), int(math.Round(y))))
+	}
+	path[steps] = to
+	return path
+}
+
+// keepOut moves p out of the exit zone, which is everything above and to the
+// left of exit, and onto the screen if its size is known.
+func keepOut(p, exit image.Point, screen image.Rectangle) image.Point {
+	if p.X < exit.X && p.Y < exit.Y {
+		p.X = exit.X
+	}
+	if !screen.Empty() {
+		p.X = max(screen.Min.X, min(p.X, screen.Max.X-1))
+		p.Y = max(screen.Min.Y, min(p.Y, screen.Max.Y-1))
+	}
+	return p
+}
+
+// moveMouse moves the mouse to the point along a curved path, at around o.Speed.
+func moveMouse(to image.Point, o mouseOptions) {
+	x, y := robotgo.Location()
+	from := image.Pt(x, y)
+	w, h := robotgo.GetScreenSize()
+	screen := image.Rect(0, 0, w, h)
+
+	distance := math.Hypot(float64(to.X-from.X), float64(to.Y-from.Y))
+	if distance < 1 {
+		return
+	}
+	duration := time.Duration(distance / o.Speed * (0.7 + rand.Float64()*0.6) * float64(time.Second))
+	steps := max(int(distance/4), 5)
+	for _, p := range bezierPath(from, to, steps)[1:] {
+		p = keepOut(p, o.Exit, screen)
+		robotgo.Move(p.X, p.Y)
+		time.Sleep(duration / time.Duration(steps))
+	}
+}
+
+// randomPoint returns a random point in r.
+func randomPoint(r image.Rectangle) image.Point {
+	return image.Pt(r.Min.X+rand.Intn(r.Dx()), r.Min.Y+rand.Intn(r.Dy()))
+}
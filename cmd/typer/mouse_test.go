@@ -0,0 +1,59 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestParseRegion(t *testing.T) {
+	r, err := parseRegion("100, 0,400,30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Rect(100, 0, 500, 30); r != want {
+		t.Errorf("parseRegion = %v, want %v", r, want)
+	}
+	for _, s := range []string{"", "1,2,3", "a,b,c,d", "1,2,0,4", "-1,2,3,4"} {
+		if _, err := parseRegion(s); err == nil {
+			t.Errorf("parseRegion(%q) succeeded", s)
+		}
+	}
+}
+
+func TestBezierPath(t *testing.T) {
+	from, to := image.Pt(200, 300), image.Pt(260, 240)
+	for range 50 {
+		path := bezierPath(from, to, 20)
+		if len(path) != 21 || path[0] != from || path[20] != to {
+			t.Fatalf("path = %v, want 21 points from %v to %v", path, from, to)
+		}
+		// The bow stays close to the straight line
+		bounds := image.Rect(from.X, from.Y, to.X, to.Y).Inset(-30)
+		for _, p := range path {
+			if !p.In(bounds) {
+				t.Fatalf("point %v strays outside %v", p, bounds)
+			}
+		}
+		// Steps are shorter at the ends than in the middle
+		step := func(i int) int { d := path[i+1].Sub(path[i]); return d.X*d.X + d.Y*d.Y }
+		if step(0) >= step(10) || step(19) >= step(10) {
+			t.Errorf("steps don't slow down at the ends: %v", path)
+		}
+	}
+}
+
+func TestKeepOut(t *testing.T) {
+	exit, screen := image.Pt(50, 50), image.Rect(0, 0, 800, 600)
+	for _, tc := range []struct{ p, want image.Point }{
+		{image.Pt(10, 10), image.Pt(50, 10)},
+		{image.Pt(10, 60), image.Pt(10, 60)},
+		{image.Pt(900, -5), image.Pt(799, 0)},
+	} {
+		if got := keepOut(tc.p, exit, screen); got != tc.want {
+			t.Errorf("keepOut(%v) = %v, want %v", tc.p, got, tc.want)
+		}
+	}
+	if got := keepOut(image.Pt(900, 10), exit, image.Rectangle{}); got != image.Pt(900, 10) {
+		t.Errorf("keepOut without a screen size = %v", got)
+	}
+}
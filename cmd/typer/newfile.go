@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// pressShortcut presses key with the modifiers held. --dry-run replaces it.
+var pressShortcut = func(key string, modifiers []string) {
+	if len(modifiers) == 0 {
+		robotgo.KeyTap(key)
+		return
+	}
+	robotgo.KeyTap(key, modifiers)
+}
+
+// shortcut is one key press in a key sequence, e.g. ctrl+shift+n.
+type shortcut struct {
+	key       string
+	modifiers []string
+}
+
+func (s shortcut) String() string {
+	return strings.Join(append(append([]string{}, s.modifiers...), s.key), "+")
+}
+
+// modifierKeys are the keys that can be held in a shortcut.
+var modifierKeys = []string{"ctrl", "alt", "shift", "cmd"}
+
+// parseKeySequence parses shortcuts separated by spaces, each being keys
+// joined with +, e.g. "ctrl+k ctrl+n" or "cmd+s enter".
+func parseKeySequence(s string) ([]shortcut, error) {
+	var seq []shortcut
+	for field := range strings.FieldsSeq(strings.ToLower(s)) {
+		keys := strings.Split(field, "+")
+		sc := shortcut{key: keys[len(keys)-1]}
+		if sc.key == "" {
+			return nil, fmt.Errorf("shortcut %q has no key", field)
+		}
+		for _, m := range keys[:len(keys)-1] {
+			if m == "command" {
+				m = "cmd"
+			}
+			if !slices.Contains(modifierKeys, m) {
+				return nil, fmt.Errorf("shortcut %q: %q isn't one of %s", field, m, strings.Join(modifierKeys, ", "))
+			}
+			sc.modifiers = append(sc.modifiers, m)
+		}
+		seq = append(seq, sc)
+	}
+	return seq, nil
+}
+
+// fileWorkflow makes each burst type a whole file: it opens a new one in the
+// editor first and finishes it at the end of the burst.
+type fileWorkflow struct {
+	// open and close are pressed before and after each burst.
+	open, close []shortcut
+	// header and footer are typed at the start and end of the file.
+	header, footer string
+}
+
+// press presses the shortcuts in seq with short pauses, as a person would.
+func press(seq []shortcut) {
+	for _, sc := range seq {
+		waitIfPaused()
+		pressShortcut(sc.key, sc.modifiers)
+		sleep(300*time.Millisecond + randDuration(500*time.Millisecond))
+	}
+}
+
+// start opens a new file and types the header.
+func (w *fileWorkflow) start(p typingProfile) {
+	logMessage("fileWorkflow: Opening a new file")
+	press(w.open)
+	// Wait for the editor to open the file
+	sleep(time.Second + randDuration(time.Second))
+	if w.header != "" {
+		humanType(w.header, p)
+	}
+}
+
+// finish types the footer and presses the close shortcuts.
+func (w *fileWorkflow) finish(p typingProfile) {
+	if w.footer != "" {
+		humanType(w.footer, p)
+	}
+	sleep(500*time.Millisecond + randDuration(time.Second))
+	press(w.close)
+}
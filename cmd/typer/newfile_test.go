@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKeySequence(t *testing.T) {
+	seq, err := parseKeySequence(" Ctrl+K  ctrl+shift+n enter command+s ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, sc := range seq {
+		got = append(got, sc.String())
+	}
+	if want := "ctrl+k ctrl+shift+n enter cmd+s"; strings.Join(got, " ") != want {
+		t.Errorf("parseKeySequence = %q, want %q", got, want)
+	}
+	if seq, err := parseKeySequence(""); err != nil || len(seq) != 0 {
+		t.Errorf("empty sequence = %v, %v", seq, err)
+	}
+	for _, s := range []string{"ctrl+", "hyper+n", "n+ctrl"} {
+		if _, err := parseKeySequence(s); err == nil {
+			t.Errorf("parseKeySequence(%q) succeeded", s)
+		}
+	}
+}
+
+func TestFileWorkflow(t *testing.T) {
+	defer func(tap func(string), press func(string, []string), sl func(time.Duration)) {
+		keyTap, pressShortcut, sleep = tap, press, sl
+	}(keyTap, pressShortcut, sleep)
+
+	var sb strings.Builder
+	keyTap = func(key string) { sb.WriteString(key) }
+	pressShortcut = func(key string, modifiers []string) {
+		sb.WriteString("[" + shortcut{key: key, modifiers: modifiers}.String() + "]")
+	}
+	sleep = func(time.Duration) {}
+
+	openKeys, _ := parseKeySequence("ctrl+n")
+	closeKeys, _ := parseKeySequence("ctrl+s ctrl+w")
+	w := &fileWorkflow{open: openKeys, close: closeKeys, header: "package main\n\n", footer: "func main() {\n}\n"}
+	p := typingProfiles["careful"]
+	p.TypoRate, p.RetypeRate = 0, 0
+	w.start(p)
+	sb.WriteString("...")
+	w.finish(p)
+	if want := "[ctrl+n]package main\n\n...func main() {\n}\n[ctrl+s][ctrl+w]"; sb.String() != want {
+		t.Errorf("typed %q, want %q", sb.String(), want)
+	}
+}
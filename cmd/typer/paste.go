@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math/rand"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// pasteText puts text on the clipboard and presses the paste shortcut,
+// putting back whatever was on the clipboard before. --dry-run replaces it.
+var pasteText = func(text string) {
+	previous, err := robotgo.ReadAll()
+	if err != nil {
+		logMessage("pasteText: failed to read the clipboard: ", err)
+	}
+	if err := robotgo.WriteAll(text); err != nil {
+		logMessage("pasteText: failed to write the clipboard: ", err)
+		return
+	}
+	robotgo.KeyTap("v", shortcutModifier())
+	time.Sleep(200 * time.Millisecond) // let the editor read the clipboard before restoring it
+	if err == nil {
+		robotgo.WriteAll(previous)
+	}
+}
+
+// shortcutModifier returns the modifier of the platform's shortcuts: cmd on
+// macOS, ctrl elsewhere.
+func shortcutModifier() string {
+	if runtime.GOOS == "darwin" {
+		return "cmd"
+	}
+	return "ctrl"
+}
+
+// splitForPaste splits block at a line boundary in its second half, giving the
+// part to paste and the rest to type, or "", block if it's a single line.
+func splitForPaste(block string) (paste, rest string) {
+	lines := strings.SplitAfter(block, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) < 2 {
+		return "", block
+	}
+	at := len(lines)/2 + rand.Intn(len(lines)-len(lines)/2)
+	if at == 0 {
+		at = 1
+	}
+	return strings.Join(lines[:at], ""), strings.Join(lines[at:], "")
+}
+
+// pasteAndType types block like a person reusing code from elsewhere: it pauses
+// as if copying, pastes most of the block, then types the rest. With chance
+// p.PasteEditRate it first deletes the end of the pasted text and retypes it,
+// as if adjusting what was pasted.
+func pasteAndType(block string, p typingProfile) {
+	paste, rest := splitForPaste(block)
+	if paste == "" {
+		humanType(block, p)
+		return
+	}
+
+	// Selecting and copying the code somewhere else
+	sleep(time.Second + randDuration(2*time.Second))
+	waitIfPaused()
+
+	// Leave the line breaks at the end to type, so edits stay on the last pasted line
+	trimmed := strings.TrimRight(paste, "\n")
+	paste, rest = trimmed, paste[len(trimmed):]+rest
+	pasteText(paste)
+	stats.pasted(len([]rune(paste)))
+	sleep(300*time.Millisecond + randDuration(700*time.Millisecond))
+
+	if rand.Float64() < p.PasteEditRate {
+		lastLine := paste[strings.LastIndexByte(paste, '\n')+1:]
+		n := min(len([]rune(strings.TrimLeft(lastLine, " \t"))), rand.Intn(8)+1)
+		for range n {
+			keyTap("backspace")
+			sleep(time.Duration(rand.Intn(20)+40) * time.Millisecond)
+		}
+		r := []rune(paste)
+		rest = string(r[len(r)-n:]) + rest
+	}
+	humanType(rest, p)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitForPaste(t *testing.T) {
+	block := "func main() {\n\tfmt.Println(1)\n\tfmt.Println(2)\n}\n\n"
+	for range 50 {
+		paste, rest := splitForPaste(block)
+		if paste+rest != block {
+			t.Fatalf("split %q + %q", paste, rest)
+		}
+		if paste == "" || !strings.HasSuffix(paste, "\n") {
+			t.Fatalf("paste %q doesn't end at a line boundary", paste)
+		}
+	}
+	if paste, rest := splitForPaste("x := 1\n"); paste != "" || rest != "x := 1\n" {
+		t.Errorf("single line split into %q + %q", paste, rest)
+	}
+}
+
+func TestPasteAndType(t *testing.T) {
+	defer func(tap, paste func(string), sl func(time.Duration)) {
+		keyTap, pasteText, sleep = tap, paste, sl
+	}(keyTap, pasteText, sleep)
+
+	// Rebuild what ends up in the editor
+	var buf []rune
+	keyTap = func(key string) {
+		if key == "backspace" {
+			buf = buf[:len(buf)-1]
+		} else {
+			buf = append(buf, []rune(key)...)
+		}
+	}
+	pasteText = func(text string) { buf = append(buf, []rune(text)...) }
+	sleep = func(time.Duration) {}
+
+	block := "func main() {\n\tfmt.Println(1)\n\tfmt.Println(2)\n}\n\n"
+	p := typingProfiles["frantic"]
+	p.PasteEditRate = 1
+	for range 50 {
+		buf = nil
+		pasteAndType(block, p)
+		if string(buf) != block {
+			t.Fatalf("editor has %q, want %q", string(buf), block)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// proseSource mixes comments, TODOs and commit-message-like notes in with the
+// snippets of another source, the way people write prose between code.
+type proseSource struct {
+	snippetSource
+	lang languageProfile
+	// rate is the chance of prose instead of the next snippet.
+	rate float64
+}
+
+func (s proseSource) Next() (string, bool) {
+	if rand.Float64() < s.rate {
+		return s.lang.prose(), true
+	}
+	return s.snippetSource.Next()
+}
+
+// Templates for prose. {{word}} is replaced by a random keyword of the language,
+// {{verb}}, {{thing}} and {{reason}} by words from the lists below, and
+// {{Verb}} by a capitalised verb.
+var (
+	commentTemplates = []string{
+		"{{Verb}} {{thing}} before {{word}} is used, {{reason}}.",
+		"{{word}} may be nil here, so {{verb}} it first.",
+		"Keep this in sync with {{word}}.",
+		"We {{verb}} {{thing}} here rather than in {{word}} {{reason}}.",
+		"Note: {{thing}} can change between calls to {{word}}.",
+		"{{word}} is only set once {{thing}} has been loaded.",
+		"Don't {{verb}} {{thing}} here; {{word}} already does.",
+	}
+	todoTemplates = []string{
+		"TODO: {{verb}} {{thing}} in {{word}}",
+		"TODO: remove once {{word}} is migrated",
+		"FIXME: {{word}} breaks on {{thing}}",
+		"TODO: {{verb}} {{thing}} {{reason}}",
+		"XXX: {{word}} shouldn't need to {{verb}} {{thing}}",
+	}
+	summaryTemplates = []string{
+		"{{Verb}} {{thing}} in {{word}}",
+		"Fix {{word}} when {{thing}} is empty",
+		"Refactor {{word}} to {{verb}} {{thing}} once",
+		"Don't {{verb}} {{thing}} twice in {{word}}",
+	}
+	bodyTemplates = []string{
+		"Previously {{word}} would {{verb}} {{thing}} on every call, which showed up in profiles.",
+		"This changes {{word}} to {{verb}} {{thing}} {{reason}}.",
+		"{{thing}} is now handled by {{word}} instead.",
+		"No behaviour change for existing callers.",
+		"The old path is kept until {{word}} has been updated.",
+	}
+
+	proseVerbs  = []string{"handle", "cache", "validate", "parse", "skip", "log", "reuse", "check", "flush", "close", "reset", "sort", "load"}
+	proseThings = []string{
+		"the request", "the config", "empty input", "the connection", "stale entries", "the result",
+		"duplicate keys", "the old format", "timeouts", "the response body", "errors", "the buffer",
+	}
+	proseReasons = []string{
+		"so callers don't have to", "because the API can return nothing", "since this runs on every request",
+		"otherwise the tests are flaky", "to keep the hot path cheap", "until the migration is done",
+		"as the spec requires",
+	}
+)
+
+// prose returns a comment, a TODO or a commit-message-like note in p's comment
+// syntax, ending in a blank line.
+func (p languageProfile) prose() string {
+	var lines []string
+	switch rand.Intn(3) {
+	case 0:
+		var text []string
+		for range rand.Intn(2) + 1 {
+			text = append(text, p.fillProse(pick(commentTemplates)))
+		}
+		lines = wrapWords(strings.Join(text, " "), 72)
+	case 1:
+		lines = []string{p.fillProse(pick(todoTemplates))}
+	default:
+		lines = []string{p.fillProse(pick(summaryTemplates)), ""}
+		var body []string
+		for range rand.Intn(2) + 1 {
+			body = append(body, p.fillProse(pick(bodyTemplates)))
+		}
+		lines = append(lines, wrapWords(strings.Join(body, " "), 72)...)
+	}
+
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(strings.TrimRight(p.comment+" "+line, " "))
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// fillProse replaces each placeholder in template with a random word.
+func (p languageProfile) fillProse(template string) string {
+	keywords := p.keywords
+	if len(keywords) == 0 {
+		keywords = []string{"this"}
+	}
+	for placeholder, words := range map[string][]string{
+		"{{word}}": keywords, "{{verb}}": proseVerbs, "{{thing}}": proseThings, "{{reason}}": proseReasons,
+	} {
+		for strings.Contains(template, placeholder) {
+			template = strings.Replace(template, placeholder, pick(words), 1)
+		}
+	}
+	for strings.Contains(template, "{{Verb}}") {
+		template = strings.Replace(template, "{{Verb}}", pascal(pick(proseVerbs)), 1)
+	}
+	// Sentences may start with a placeholder
+	return strings.ToUpper(template[:1]) + template[1:]
+}
+
+// wrapWords splits text into lines of at most width characters, breaking
+// between words.
+func wrapWords(text string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
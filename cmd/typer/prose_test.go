@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProse(t *testing.T) {
+	for _, name := range languageNames() {
+		p := languages[name]
+		for range 50 {
+			prose := p.prose()
+			if !strings.HasSuffix(prose, "\n\n") || strings.Contains(prose, "{{") {
+				t.Fatalf("%s: bad prose %q", name, prose)
+			}
+			for line := range strings.SplitSeq(strings.TrimSuffix(prose, "\n\n"), "\n") {
+				if !strings.HasPrefix(line, p.comment) || len(line) > len(p.comment)+1+72 {
+					t.Errorf("%s: bad line %q", name, line)
+				}
+			}
+		}
+	}
+}
+
+func TestProseSource(t *testing.T) {
+	code := randomSource{lang: languages["python"]}
+	if s, _ := (proseSource{snippetSource: code, lang: languages["python"], rate: 1}).Next(); !strings.HasPrefix(s, "# ") {
+		t.Errorf("rate 1 gave %q, want prose", s)
+	}
+	if s, _ := (proseSource{snippetSource: code, lang: languages["python"], rate: 0}).Next(); strings.HasPrefix(s, "# ") {
+		t.Errorf("rate 0 gave %q, want code", s)
+	}
+}
+
+func TestWrapWords(t *testing.T) {
+	got := wrapWords("one two three four", 9)
+	if want := []string{"one two", "three", "four"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("wrapWords = %q, want %q", got, want)
+	}
+}
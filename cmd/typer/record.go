@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"syscall"
+	"time"
+	"unicode"
+)
+
+// session is a recorded typing session, as saved by typer record.
+type session struct {
+	Recorded time.Time   `json:"recorded"`
+	Keys     []keystroke `json:"keys"`
+}
+
+// keystroke is one recorded key press.
+type keystroke struct {
+	// Key is what robotgo.KeyTap types: a character, "enter", "tab" or "backspace".
+	Key string `json:"key"`
+	// DelayMS is the time since the previous key press in milliseconds.
+	DelayMS int64 `json:"delay_ms"`
+}
+
+// usShifted maps the US layout's unshifted characters to their shifted ones, except letters.
+var usShifted = map[rune]rune{
+	'1': '!', '2': '@', '3': '#', '4': '$', '5': '%', '6': '^', '7': '&', '8': '*', '9': '(', '0': ')',
+	'-': '_', '=': '+', '[': '{', ']': '}', '\\': '|', ';': ':', '\'': '"', ',': '<', '.': '>', '/': '?', '`': '~',
+}
+
+// shiftedKey returns the key typed by the unshifted character r, with or without shift held.
+func shiftedKey(r rune, shift bool) string {
+	if shift {
+		if s, ok := usShifted[r]; ok {
+			return string(s)
+		}
+		return string(unicode.ToUpper(r))
+	}
+	return string(r)
+}
+
+// recordSession records key presses until stopKey is pressed or ctx is done,
+// then writes them to path. Keys pressed with Control, Alt
+// or Command held are shortcuts rather than typing, so they're left out.
+func recordSession(ctx context.Context, path string, stopKey *hotkey) error {
+	s := session{Recorded: time.Now()}
+	var held []uint32
+	var last time.Time
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			logMessage("recordSession: ", context.Cause(ctx), ", stopping.")
+			break loop
+		default:
+		}
+
+		down, err := keyboardState()
+		if err != nil {
+			return fmt.Errorf("failed to read the keyboard: %v", err)
+		}
+		var shift, modifier bool
+		for _, code := range down {
+			s, m := keyKind(code)
+			shift, modifier = shift || s, modifier || m
+		}
+		for _, code := range down {
+			if slices.Contains(held, code) {
+				continue
+			}
+			if stopKey != nil && code == stopKey.code {
+				logMessage("recordSession: ", stopKey.name, " pressed, stopping.")
+				break loop
+			}
+			key := keyName(code, shift)
+			if key == "" || modifier {
+				continue
+			}
+			now := time.Now()
+			var delay time.Duration
+			if !last.IsZero() {
+				delay = now.Sub(last)
+			}
+			last = now
+			s.Keys = append(s.Keys, keystroke{Key: key, DelayMS: delay.Milliseconds()})
+		}
+		held = down
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write session: %v", err)
+	}
+	logMessage("recordSession: Saved ", len(s.Keys), " key presses to ", path)
+	fmt.Printf("Saved %d key presses to %s.\n", len(s.Keys), path)
+	return nil
+}
+
+// loadSession reads a session saved by recordSession.
+func loadSession(path string) (*session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %v", err)
+	}
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %v", path, err)
+	}
+	if len(s.Keys) == 0 {
+		return nil, fmt.Errorf("session %s has no key presses", path)
+	}
+	return &s, nil
+}
+
+// replaySession types the session's keys with their recorded timing divided by
+// speed, then signals termination on sigs.
+func replaySession(s *session, speed float64, sigs chan<- os.Signal) {
+	logMessage("replaySession goroutine started: ", len(s.Keys), " key presses recorded ", s.Recorded.Format(time.DateTime))
+	defer func() {
+		if r := recover(); r != nil {
+			logMessage("PANIC in replaySession:", r)
+		}
+		logMessage("replaySession goroutine stopped.")
+	}()
+
+	started := now()
+	for _, k := range s.Keys {
+		sleep(time.Duration(float64(k.DelayMS) * float64(time.Millisecond) / speed))
+		waitIfPaused()
+		keyTap(k.Key)
+	}
+	stats.addActive(now().Sub(started))
+	fmt.Println("\nFinished replaying the session. Terminating...")
+	sigs <- syscall.SIGTERM
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShiftedKey(t *testing.T) {
+	for _, test := range []struct {
+		r     rune
+		shift bool
+		want  string
+	}{
+		{'a', false, "a"},
+		{'a', true, "A"},
+		{'9', true, "("},
+		{'\'', true, `"`},
+		{' ', true, " "},
+	} {
+		if got := shiftedKey(test.r, test.shift); got != test.want {
+			t.Errorf("shiftedKey(%q, %v) = %q, want %q", test.r, test.shift, got, test.want)
+		}
+	}
+}
+
+func TestReplaySession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	data := `{"recorded":"2026-01-02T03:04:05Z","keys":[{"key":"h","delay_ms":0},{"key":"i","delay_ms":100},{"key":"enter","delay_ms":400}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := loadSession(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(tap func(string), sl func(time.Duration)) { keyTap, sleep = tap, sl }(keyTap, sleep)
+	var typed []string
+	var slept time.Duration
+	keyTap = func(key string) { typed = append(typed, key) }
+	sleep = func(d time.Duration) { slept += d }
+
+	sigs := make(chan os.Signal, 1)
+	replaySession(s, 2, sigs)
+	if got := strings.Join(typed, ","); got != "h,i,enter" {
+		t.Errorf("typed %s", got)
+	}
+	if slept != 250*time.Millisecond {
+		t.Errorf("slept %s at double speed, want 250ms", slept)
+	}
+	if len(sigs) != 1 {
+		t.Error("no termination signal after the replay")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"keys":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSession(path); err == nil {
+		t.Error("empty session: no error")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stats collects what happened during the session for the report written on exit.
+var stats = &sessionStats{}
+
+// sessionStats counts typing activity. Times come from now, so they are
+// simulated under --dry-run.
+type sessionStats struct {
+	mu sync.Mutex
+
+	Started time.Time `json:"started"`
+	Ended   time.Time `json:"ended"`
+	// Active is the time spent typing, Idle the rest.
+	Active time.Duration `json:"-"`
+	Idle   time.Duration `json:"-"`
+	// Bursts is the number of completed typing bursts.
+	Bursts int `json:"bursts"`
+	// Blocks is the number of blocks of text typed.
+	Blocks int `json:"blocks"`
+	// Characters is the length of the text typed; Keystrokes counts every key
+	// pressed, including typos and backspaces.
+	Characters int `json:"characters"`
+	Keystrokes int `json:"keystrokes"`
+	// Typos is the number of wrong keys pressed on purpose.
+	Typos int `json:"typos"`
+	// Pasted is the number of characters pasted rather than typed.
+	Pasted int `json:"pasted"`
+}
+
+func (s *sessionStats) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Started = now()
+}
+
+// counting wraps tap to count every key pressed.
+func (s *sessionStats) counting(tap func(string)) func(string) {
+	return func(key string) {
+		s.mu.Lock()
+		s.Keystrokes++
+		s.mu.Unlock()
+		tap(key)
+	}
+}
+
+// typed records a block of text typed in d with the given number of typos.
+func (s *sessionStats) typed(characters, typos int, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Blocks++
+	s.Characters += characters
+	s.Typos += typos
+	s.Active += d
+}
+
+// pasted records characters pasted from the clipboard.
+func (s *sessionStats) pasted(characters int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Pasted += characters
+}
+
+// addActive records time spent typing outside humanType, e.g. replaying a session.
+func (s *sessionStats) addActive(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Active += d
+}
+
+func (s *sessionStats) burstDone() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Bursts++
+}
+
+// snapshot returns a copy of the stats so far, as if the session ended now.
+func (s *sessionStats) snapshot() *sessionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ended := now()
+	return &sessionStats{
+		Started: s.Started, Ended: ended, Active: s.Active, Idle: max(ended.Sub(s.Started)-s.Active, 0),
+		Bursts: s.Bursts, Blocks: s.Blocks, Characters: s.Characters, Keystrokes: s.Keystrokes, Typos: s.Typos,
+		Pasted: s.Pasted,
+	}
+}
+
+// finish stops the clock and returns a copy of the final stats.
+func (s *sessionStats) finish() *sessionStats {
+	final := s.snapshot()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Ended, s.Idle = final.Ended, final.Idle
+	return final
+}
+
+// WPM is the typing speed while active, in words of five characters per minute.
+func (s *sessionStats) WPM() float64 {
+	if s.Active <= 0 {
+		return 0
+	}
+	return float64(s.Characters) / 5 / s.Active.Minutes()
+}
+
+func (s *sessionStats) String() string {
+	return fmt.Sprintf("%d characters (%d keystrokes, %d typos, %d pasted) in %d blocks over %d bursts; active %s, idle %s, %.0f WPM",
+		s.Characters, s.Keystrokes, s.Typos, s.Pasted, s.Blocks, s.Bursts, s.Active.Round(time.Second), s.Idle.Round(time.Second), s.WPM())
+}
+
+// reportFields are the columns of a CSV report, in order.
+var reportFields = []string{
+	"started", "ended", "active_seconds", "idle_seconds", "bursts", "blocks", "characters", "keystrokes", "typos", "wpm", "pasted",
+}
+
+func (s *sessionStats) record() []string {
+	return []string{
+		s.Started.Format(time.RFC3339), s.Ended.Format(time.RFC3339),
+		strconv.FormatFloat(s.Active.Seconds(), 'f', 1, 64), strconv.FormatFloat(s.Idle.Seconds(), 'f', 1, 64),
+		strconv.Itoa(s.Bursts), strconv.Itoa(s.Blocks), strconv.Itoa(s.Characters), strconv.Itoa(s.Keystrokes),
+		strconv.Itoa(s.Typos), strconv.FormatFloat(s.WPM(), 'f', 1, 64), strconv.Itoa(s.Pasted),
+	}
+}
+
+// MarshalJSON adds the durations in seconds and the WPM to the counters.
+func (s *sessionStats) MarshalJSON() ([]byte, error) {
+	type counters sessionStats
+	return json.Marshal(struct {
+		*counters
+		ActiveSeconds float64 `json:"active_seconds"`
+		IdleSeconds   float64 `json:"idle_seconds"`
+		WPM           float64 `json:"wpm"`
+	}{(*counters)(s), s.Active.Seconds(), s.Idle.Seconds(), s.WPM()})
+}
+
+// writeReport writes the stats to path: as JSON, or for a .csv file as a row
+// appended to it, with a header if the file is new, so sessions accumulate.
+func (s *sessionStats) writeReport(path string) error {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open report: %v", err)
+		}
+		defer f.Close()
+
+		w := csv.NewWriter(f)
+		if info, err := f.Stat(); err == nil && info.Size() == 0 {
+			w.Write(reportFields)
+		}
+		w.Write(s.record())
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("failed to write report: %v", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteReport(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := &sessionStats{
+		Started: start, Ended: start.Add(10 * time.Minute),
+		Active: 2 * time.Minute, Idle: 8 * time.Minute,
+		Bursts: 1, Blocks: 4, Characters: 1000, Keystrokes: 1040, Typos: 20,
+	}
+	if wpm := s.WPM(); wpm != 100 {
+		t.Errorf("WPM = %v, want 100", wpm)
+	}
+
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "report.json")
+	if err := s.writeReport(jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["characters"] != 1000.0 || got["active_seconds"] != 120.0 || got["wpm"] != 100.0 {
+		t.Errorf("JSON report: %s", data)
+	}
+
+	// CSV reports get one header and a row per session
+	csvPath := filepath.Join(dir, "report.csv")
+	for range 2 {
+		if err := s.writeReport(csvPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Open(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][0] != "started" || rows[2][6] != "1000" {
+		t.Errorf("CSV report: %q", rows)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// snippetSource supplies the blocks of text typed during bursts.
+type snippetSource interface {
+	// Next returns the next block to type, or false when there is nothing left.
+	Next() (string, bool)
+}
+
+// fileSource types user-provided content block by block, in order.
+type fileSource struct {
+	name   string
+	blocks []string
+	next   int
+	typed  int
+	total  int
+}
+
+// newFileSource reads the content to type from path, or from stdin if path is "-".
+// If startMarker is set, only the lines after the first line containing it are typed;
+// if endMarker is set, typing stops before the first following line containing it.
+func newFileSource(path, startMarker, endMarker string) (*fileSource, error) {
+	var data []byte
+	var err error
+	name := path
+	if path == "-" {
+		name = "stdin"
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", name, err)
+	}
+
+	content, err := betweenMarkers(string(data), startMarker, endMarker)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("%s: nothing to type", name)
+	}
+
+	return &fileSource{
+		name:   name,
+		blocks: splitBlocks(content),
+		total:  len([]rune(content)),
+	}, nil
+}
+
+// Next returns the next block of the file and prints how much has been typed so far.
+func (s *fileSource) Next() (string, bool) {
+	if s.next > 0 {
+		fmt.Printf("Progress: %d/%d characters of %s (%.1f%%)\n", s.typed, s.total, s.name, s.Progress()*100)
+	}
+	if s.next >= len(s.blocks) {
+		return "", false
+	}
+	block := s.blocks[s.next]
+	s.next++
+	s.typed += len([]rune(block))
+	return block, true
+}
+
+// Progress returns the fraction of the content handed out so far, from 0 to 1.
+func (s *fileSource) Progress() float64 {
+	return float64(s.typed) / float64(s.total)
+}
+
+// betweenMarkers returns the lines of content after the line containing start
+// and before the line containing end. Empty markers select from the beginning or to the end.
+func betweenMarkers(content, start, end string) (string, error) {
+	if start != "" {
+		i := strings.Index(content, start)
+		if i < 0 {
+			return "", fmt.Errorf("start marker %q not found", start)
+		}
+		nl := strings.IndexByte(content[i:], '\n')
+		if nl < 0 {
+			return "", nil
+		}
+		content = content[i+nl+1:]
+	}
+	if end != "" {
+		i := strings.Index(content, end)
+		if i < 0 {
+			return "", fmt.Errorf("end marker %q not found", end)
+		}
+		content = content[:strings.LastIndexByte(content[:i], '\n')+1]
+	}
+	return content, nil
+}
+
+// splitBlocks splits content into blocks ending after each run of blank lines,
+// so pauses between blocks fall where a person would stop to think.
+// Joining the blocks gives back content unchanged.
+func splitBlocks(content string) []string {
+	var blocks []string
+	var b strings.Builder
+	prevBlank := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		blank := strings.TrimSpace(line) == ""
+		if !blank && prevBlank && b.Len() > 0 {
+			blocks = append(blocks, b.String())
+			b.Reset()
+		}
+		b.WriteString(line)
+		prevBlank = blank
+	}
+	if b.Len() > 0 {
+		blocks = append(blocks, b.String())
+	}
+	return blocks
+}
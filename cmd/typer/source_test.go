@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitBlocks(t *testing.T) {
+	content := "package main\n\nimport \"fmt\"\n\n\nfunc main() {\n\tfmt.Println()\n}\n"
+	want := []string{"package main\n\n", "import \"fmt\"\n\n\n", "func main() {\n\tfmt.Println()\n}\n"}
+	got := splitBlocks(content)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitBlocks = %q, want %q", got, want)
+	}
+	if strings.Join(got, "") != content {
+		t.Error("blocks don't join back to the content")
+	}
+}
+
+func TestBetweenMarkers(t *testing.T) {
+	content := "header\n// typer:start\na\nb\n// typer:end\nfooter\n"
+	for _, test := range []struct {
+		start, end, want string
+	}{
+		{"", "", content},
+		{"typer:start", "", "a\nb\n// typer:end\nfooter\n"},
+		{"", "typer:end", "header\n// typer:start\na\nb\n"},
+		{"typer:start", "typer:end", "a\nb\n"},
+	} {
+		got, err := betweenMarkers(content, test.start, test.end)
+		if err != nil {
+			t.Errorf("%q, %q: %v", test.start, test.end, err)
+		} else if got != test.want {
+			t.Errorf("%q, %q: got %q, want %q", test.start, test.end, got, test.want)
+		}
+	}
+	if _, err := betweenMarkers(content, "missing", ""); err == nil {
+		t.Error("missing start marker: no error")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	content := "package main\n\nfunc main() {}\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newFileSource(path, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var typed strings.Builder
+	for {
+		block, ok := s.Next()
+		if !ok {
+			break
+		}
+		typed.WriteString(block)
+	}
+	if typed.String() != content {
+		t.Errorf("typed %q, want %q", typed.String(), content)
+	}
+	if s.Progress() != 1 {
+		t.Errorf("progress = %v, want 1", s.Progress())
+	}
+}
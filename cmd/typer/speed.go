@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// typingProfile controls how fast and how sloppily humanType types.
+type typingProfile struct {
+	// WPM is the average typing speed in words (five characters) per minute.
+	WPM float64
+	// TypoRate is the chance per character of hitting a nearby key first.
+	TypoRate float64
+	// CorrectionRate is the chance a typo is fixed right away; otherwise it is
+	// noticed a few characters later and those are deleted along with it.
+	CorrectionRate float64
+	// RetypeRate is the chance per character of deleting and retyping it.
+	RetypeRate float64
+	// WordPause and LinePause are the longest extra pauses after a space or a newline.
+	WordPause, LinePause time.Duration
+	// ThinkRate is the chance of pausing up to ThinkPause after a block.
+	ThinkRate  float64
+	ThinkPause time.Duration
+	// PasteRate is the chance per block of pasting most of it instead of typing
+	// it, and PasteEditRate the chance of then retyping the end of the paste.
+	PasteRate, PasteEditRate float64
+}
+
+// typingProfiles are the presets selectable with --profile.
+var typingProfiles = map[string]typingProfile{
+	"careful": {
+		WPM: 55, TypoRate: 0.01, CorrectionRate: 1, RetypeRate: 0.002,
+		WordPause: 150 * time.Millisecond, LinePause: 600 * time.Millisecond,
+		ThinkRate: 0.4, ThinkPause: 800 * time.Millisecond,
+	},
+	"average": {
+		WPM: 160, TypoRate: 0.02, CorrectionRate: 1, RetypeRate: 0.005,
+		WordPause: 70 * time.Millisecond, LinePause: 200 * time.Millisecond,
+		ThinkRate: 0.2, ThinkPause: 300 * time.Millisecond,
+	},
+	"frantic": {
+		WPM: 220, TypoRate: 0.05, CorrectionRate: 0.6, RetypeRate: 0.01,
+		WordPause: 30 * time.Millisecond, LinePause: 100 * time.Millisecond,
+		ThinkRate: 0.05, ThinkPause: 200 * time.Millisecond,
+	},
+}
+
+// typingProfileNames returns the --profile presets, sorted.
+func typingProfileNames() []string {
+	names := make([]string, 0, len(typingProfiles))
+	for name := range typingProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// charDelay returns how long to wait after typing char: a random amount around
+// the WPM's time per character, plus a pause after spaces and newlines.
+func (p typingProfile) charDelay(char rune) time.Duration {
+	perChar := time.Duration(float64(time.Minute) / (p.WPM * 5))
+	delay := perChar*2/5 + randDuration(perChar*6/5)
+	switch char {
+	case ' ':
+		delay += randDuration(p.WordPause)
+	case '\n':
+		delay += p.LinePause/3 + randDuration(p.LinePause*2/3)
+	}
+	return delay
+}
+
+// randDuration returns a random duration in [0, max), or 0 if max isn't positive.
+func randDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCharDelay(t *testing.T) {
+	p := typingProfile{WPM: 60, WordPause: 100 * time.Millisecond, LinePause: 300 * time.Millisecond}
+	perChar := 200 * time.Millisecond // 60 WPM is 300 characters a minute
+	for range 100 {
+		if d := p.charDelay('a'); d < perChar*2/5 || d >= perChar*8/5 {
+			t.Fatalf("letter delay %s out of range", d)
+		}
+		if d := p.charDelay(' '); d >= perChar*8/5+p.WordPause {
+			t.Fatalf("space delay %s out of range", d)
+		}
+		if d := p.charDelay('\n'); d < perChar*2/5+p.LinePause/3 {
+			t.Fatalf("newline delay %s too short", d)
+		}
+	}
+}
+
+func TestTypingProfiles(t *testing.T) {
+	for _, name := range typingProfileNames() {
+		p := typingProfiles[name]
+		if p.WPM <= 0 {
+			t.Errorf("%s: WPM %v", name, p.WPM)
+		}
+		for _, rate := range []float64{p.TypoRate, p.CorrectionRate, p.RetypeRate, p.ThinkRate} {
+			if rate < 0 || rate > 1 {
+				t.Errorf("%s: rate %v out of range", name, rate)
+			}
+		}
+	}
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"image"
 	"math/rand"
 	"os"
-	"os/signal"
 	"strings"
 	"syscall"
 	"time"
@@ -14,6 +16,7 @@ import (
 	"github.com/dave/jennifer/jen"
 	"github.com/go-vgo/robotgo"
 	"pkg.jsn.cam/jsn/internal"
+	"pkg.jsn.cam/jsn/internal/run"
 
 	_ "github.com/go-vgo/robotgo/base"  // Blank import for robotgo C sources
 	_ "github.com/go-vgo/robotgo/key"   // Blank import for robotgo C sources
@@ -27,7 +30,7 @@ func initLogger() {
 
 // logMessage writes a message to the console with a timestamp.
 func logMessage(v ...any) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05.000")
+	timestamp := now().Format("2006-01-02 15:04:05.000")
 	fmt.Printf("%s: %s\n", timestamp, fmt.Sprint(v...))
 }
 
@@ -67,8 +70,9 @@ func min(a, b int) int {
 	return b
 }
 
-// humanType simulates human-like typing of the given text.
-func humanType(text string) {
+// humanType simulates human-like typing of the given text at the speed and
+// error rate of the typing profile p.
+func humanType(text string, p typingProfile) {
 	logMessage("humanType: Starting to type text of length ", len(text))
 	defer func() {
 		if r := recover(); r != nil {
@@ -76,33 +80,46 @@ func humanType(text string) {
 		}
 	}()
 
-	for _, char := range text {
-		if rand.Intn(100) < 2 {
+	started, typos := now(), 0
+	defer func() { stats.typed(len([]rune(text)), typos, now().Sub(started)) }()
+
+	runes := []rune(text)
+	for i, char := range runes {
+		waitIfPaused()
+		if rand.Float64() < p.TypoRate {
 			if near, ok := nearbyKeys[unicode.ToLower(char)]; ok && len(near) > 0 {
 				wrongChar := near[rand.Intn(len(near))]
-				robotgo.KeyTap(string(wrongChar))
-				time.Sleep(time.Duration(rand.Intn(40)+60) * time.Millisecond)
-				robotgo.KeyTap("backspace")
-				time.Sleep(time.Duration(rand.Intn(20)+40) * time.Millisecond)
+				keyTap(string(wrongChar))
+				typos++
+				sleep(time.Duration(rand.Intn(40)+60) * time.Millisecond)
+
+				// Uncorrected typos are noticed a few characters later, before the end of the line
+				ahead := 0
+				if rand.Float64() >= p.CorrectionRate {
+					for n := rand.Intn(3) + 1; ahead < n && i+1+ahead < len(runes) && runes[i+1+ahead] != '\n'; {
+						keyTap(string(runes[i+1+ahead]))
+						sleep(p.charDelay(runes[i+1+ahead]))
+						ahead++
+					}
+					sleep(time.Duration(rand.Intn(150)+150) * time.Millisecond)
+				}
+				for range ahead + 1 {
+					keyTap("backspace")
+					sleep(time.Duration(rand.Intn(20)+40) * time.Millisecond)
+				}
 			}
 		}
-		robotgo.KeyTap(string(char))
-		if rand.Intn(200) < 1 && char != ' ' && char != '\n' {
-			time.Sleep(time.Duration(rand.Intn(80)+70) * time.Millisecond)
-			robotgo.KeyTap("backspace")
-			time.Sleep(time.Duration(rand.Intn(40)+50) * time.Millisecond)
-			robotgo.KeyTap(string(char))
-		}
-		delay := rand.Intn(90) + 30
-		if char == ' ' {
-			delay += rand.Intn(70)
-		} else if char == '\n' {
-			delay += rand.Intn(130) + 70
+		keyTap(string(char))
+		if rand.Float64() < p.RetypeRate && char != ' ' && char != '\n' {
+			sleep(time.Duration(rand.Intn(80)+70) * time.Millisecond)
+			keyTap("backspace")
+			sleep(time.Duration(rand.Intn(40)+50) * time.Millisecond)
+			keyTap(string(char))
 		}
-		time.Sleep(time.Duration(delay) * time.Millisecond)
+		sleep(p.charDelay(char))
 	}
-	if rand.Intn(100) < 20 {
-		time.Sleep(time.Duration(rand.Intn(200)+100) * time.Millisecond)
+	if rand.Float64() < p.ThinkRate {
+		sleep(p.ThinkPause/3 + randDuration(p.ThinkPause*2/3))
 	}
 }
 
@@ -229,8 +246,9 @@ func sanitizeTypeName(keyword string) string {
 	return cleanName // Return the processed custom type name.
 }
 
-// generateRandomGoCode generates a random snippet of Go code.
-func generateRandomGoCode() string {
+// generateRandomGoCode generates a random snippet of Go code: a function (elementType 0),
+// struct (1), var declaration (2) or const declaration (3).
+func generateRandomGoCode(elementType int) string {
 	defer func() {
 		if r := recover(); r != nil {
 			logMessage("PANIC in generateRandomGoCode:", r)
@@ -238,7 +256,6 @@ func generateRandomGoCode() string {
 	}()
 
 	var codeSnippet jen.Code
-	elementType := rand.Intn(4)
 
 	switch elementType {
 	case 0: // Function
@@ -451,7 +468,9 @@ func generateRandomGoCode() string {
 }
 
 // preventComputerSleep periodically moves the mouse and presses a key to prevent sleep/screensaver.
-func preventComputerSleep() {
+// The mouse wanders along curved paths and now and then scrolls or clicks, as
+// set by o, before going back to where it was.
+func preventComputerSleep(o mouseOptions) {
 	logMessage("preventComputerSleep goroutine started.")
 	defer func() {
 		if r := recover(); r != nil {
@@ -465,25 +484,49 @@ func preventComputerSleep() {
 		maxSleep := 40 * time.Second
 		sleepDuration := minSleep + time.Duration(rand.Int63n(int64(maxSleep-minSleep)))
 		time.Sleep(sleepDuration)
+		if paused.Load() {
+			continue
+		}
 
-		dx := rand.Intn(15) + 10
-		dy := rand.Intn(15) + 10
+		x, y := robotgo.Location()
+		start := image.Pt(x, y)
+		dx := rand.Intn(o.Range/2+1) + o.Range/2
+		dy := rand.Intn(o.Range/2+1) + o.Range/2
 		if rand.Intn(2) == 0 {
 			dx = -dx
 		}
 		if rand.Intn(2) == 0 {
 			dy = -dy
 		}
-
-		currentX, currentY := robotgo.Location()
-		robotgo.MoveRelative(dx, dy)
+		moveMouse(start.Add(image.Pt(dx, dy)), o)
 		time.Sleep(time.Duration(rand.Intn(100)+50) * time.Millisecond)
 
 		robotgo.KeyTap("shift")
 		time.Sleep(time.Duration(rand.Intn(100)+50) * time.Millisecond)
 
-		finalX, finalY := robotgo.Location()
-		robotgo.MoveRelative(currentX-finalX, currentY-finalY)
+		if rand.Float64() < o.ScrollRate {
+			if !o.Region.Empty() {
+				moveMouse(randomPoint(o.Region), o)
+			}
+			// Scroll down a little and back, so the view ends up where it was
+			n := rand.Intn(3) + 1
+			for range n {
+				robotgo.Scroll(0, -1)
+				time.Sleep(time.Duration(rand.Intn(150)+100) * time.Millisecond)
+			}
+			time.Sleep(time.Duration(rand.Intn(1500)+500) * time.Millisecond)
+			for range n {
+				robotgo.Scroll(0, 1)
+				time.Sleep(time.Duration(rand.Intn(150)+100) * time.Millisecond)
+			}
+		}
+		if !o.Region.Empty() && rand.Float64() < o.ClickRate {
+			moveMouse(randomPoint(o.Region), o)
+			time.Sleep(time.Duration(rand.Intn(300)+100) * time.Millisecond)
+			robotgo.Click()
+		}
+
+		moveMouse(start, o)
 	}
 }
 
@@ -510,7 +553,8 @@ func monitorMouseExitCondition(sigs chan<- os.Signal, thresholdX, thresholdY int
 }
 
 // generateCodeInBursts manages the cycle of active coding bursts and pauses.
-func generateCodeInBursts(maxIntervalBetweenBursts, maxBurstDuration, intervalBetweenTyping time.Duration) {
+// When source runs out of text it signals termination on sigs.
+func generateCodeInBursts(source snippetSource, files *fileWorkflow, typing typingProfile, sigs chan<- os.Signal, maxIntervalBetweenBursts, maxBurstDuration, intervalBetweenTyping time.Duration) {
 	logMessage("generateCodeInBursts goroutine started.")
 	iterationCount := 0
 	defer func() {
@@ -533,13 +577,30 @@ func generateCodeInBursts(maxIntervalBetweenBursts, maxBurstDuration, intervalBe
 		}
 		logMessage("generateCodeInBursts: Active coding burst for approximately ", burstDuration)
 		fmt.Printf("Starting coding burst for about %s...\n", burstDuration.Round(time.Second))
-		endTime := time.Now().Add(burstDuration)
+		endTime := now().Add(burstDuration)
+		if files != nil {
+			files.start(typing)
+		}
 
 		burstCodeBlockCount := 0
-		for time.Now().Before(endTime) {
+		for now().Before(endTime) {
+			waitIfPaused()
+			codeToType, ok := source.Next()
+			if !ok {
+				logMessage("generateCodeInBursts: Source exhausted after ", burstCodeBlockCount, " code blocks in burst #", iterationCount)
+				if files != nil {
+					files.finish(typing)
+				}
+				fmt.Println("\nFinished typing all content. Terminating...")
+				sigs <- syscall.SIGTERM
+				return
+			}
 			burstCodeBlockCount++
-			codeToType := generateRandomGoCode()
-			humanType(codeToType)
+			if rand.Float64() < typing.PasteRate {
+				pasteAndType(codeToType, typing)
+			} else {
+				humanType(codeToType, typing)
+			}
 
 			interCodePauseBase := intervalBetweenTyping
 			if interCodePauseBase < 500*time.Millisecond {
@@ -551,13 +612,17 @@ func generateCodeInBursts(maxIntervalBetweenBursts, maxBurstDuration, intervalBe
 			}
 
 			fmt.Printf("Brief pause for %s...\n", interCodePause.Round(time.Second))
-			time.Sleep(interCodePause)
+			sleep(interCodePause)
 
-			if time.Now().After(endTime) {
+			if now().After(endTime) {
 				logMessage("generateCodeInBursts: Burst time ended during inter-code pause.")
 				break
 			}
 		}
+		if files != nil {
+			files.finish(typing)
+		}
+		stats.burstDone()
 		logMessage("generateCodeInBursts: Burst cycle #", iterationCount, " ended. Typed ", burstCodeBlockCount, " code blocks.")
 		fmt.Printf("Coding burst #%d finished. Typed %d code blocks.\n", iterationCount, burstCodeBlockCount)
 
@@ -572,10 +637,50 @@ func generateCodeInBursts(maxIntervalBetweenBursts, maxBurstDuration, intervalBe
 
 		logMessage("generateCodeInBursts: Pausing between bursts for ", pauseDuration)
 		fmt.Printf("Taking a break for about %s before next coding burst...\n", pauseDuration.Round(time.Second))
-		time.Sleep(pauseDuration)
+		sleep(pauseDuration)
 	}
 }
 
+var (
+	configFile            = flag.String("config", "", "TOML file setting flags, named with underscores (paste_rate = 0.2), and [languages.<name>] tables")
+	intervalRange         = flag.Duration("interval-range", 8*time.Minute, "Maximum PAUSE duration between typing bursts (e.g., 30m, 1h)")
+	burstRange            = flag.Duration("burst-range", 7*time.Minute, "Maximum active typing burst duration (e.g., 5m, 15m)")
+	intervalBetweenTyping = flag.Duration("interval-between-typing", 7*time.Second, "Base interval between typing new code blocks within a burst (e.g., 5s, 10s)")
+	exitCoordinateX       = flag.Int("exit-x", 50, "X-coordinate threshold for mouse exit zone (top-left corner)")
+	exitCoordinateY       = flag.Int("exit-y", 50, "Y-coordinate threshold for mouse exit zone (top-left corner)")
+	profileName           = flag.String("profile", "average", "Typing speed and accuracy preset: "+strings.Join(typingProfileNames(), ", "))
+	wpm                   = flag.Float64("wpm", 0, "Typing speed in words per minute (overrides the profile)")
+	typoRate              = flag.Float64("typo-rate", 0, "Chance per character of hitting a nearby key first, 0 to 1 (overrides the profile)")
+	correctionRate        = flag.Float64("correction-rate", 0, "Chance a typo is fixed right away rather than a few characters later, 0 to 1 (overrides the profile)")
+	wordPause             = flag.Duration("word-pause", 0, "Longest extra pause after a space (overrides the profile)")
+	linePause             = flag.Duration("line-pause", 0, "Longest extra pause after a newline (overrides the profile)")
+	pasteRate             = flag.Float64("paste-rate", 0, "Chance per block of pasting most of it from the clipboard instead of typing it, 0 to 1")
+	pasteEditRate         = flag.Float64("paste-edit-rate", 0.5, "With -paste-rate, chance of deleting and retyping the end of a paste, 0 to 1")
+	dryRun                = flag.Bool("dry-run", false, "Print the code and timing that would be typed instead of typing it, without waiting in between")
+	dryRunFor             = flag.Duration("dry-run-for", 20*time.Minute, "With -dry-run, how much simulated time to preview")
+	pauseKeyName          = flag.String("pause-key", "f8", "Key that pauses and resumes the simulation from any window (empty disables)")
+	stopKeyName           = flag.String("stop-key", "f9", "Key that stops the simulation from any window (empty disables)")
+	lang                  = flag.String("lang", "go", "Language of the generated code: "+strings.Join(languageNames(), ", "))
+	corpus                = flag.String("corpus", "", "Generate code resembling the source files in this directory instead of random snippets")
+	corpusExt             = flag.String("corpus-ext", "", "With -corpus, comma-separated file extensions to learn from (default the -lang extensions)")
+	file                  = flag.String("file", "", "Type the contents of this file instead of random Go code (- reads stdin)")
+	startMarker           = flag.String("start-marker", "", "With -file, only type the lines after the first line containing this marker")
+	report                = flag.String("report", "", "Write session statistics to this file on exit: JSON, or a row appended to it if it ends in .csv")
+	replaySpeed           = flag.Float64("replay-speed", 1, "With replay, how many times faster than recorded to type")
+	endMarker             = flag.String("end-marker", "", "With -file, stop typing before the line containing this marker")
+	newFile               = flag.Bool("new-file", false, "Open a new file in the editor before each burst and type a whole program into it")
+	newFileKeys           = flag.String("new-file-keys", shortcutModifier()+"+n", "With -new-file, shortcuts that open a new file, separated by spaces (e.g. \"ctrl+k ctrl+n\")")
+	endFileKeys           = flag.String("end-file-keys", "", "With -new-file, shortcuts pressed after each burst, e.g. to save or close the file")
+	proseRate             = flag.Float64("prose-rate", 0.1, "Chance of typing a comment, TODO or commit-message-like note instead of a block of generated code, 0 to 1")
+	controlAddr           = flag.String("control-addr", "", "Serve GET /status and POST /pause, /resume and /stop on this address (e.g. 127.0.0.1:8765)")
+	controlToken          = flag.String("control-token", "", "With -control-addr, token requests must send; required unless the address is loopback")
+	mouseRange            = flag.Int("mouse-range", 25, "Furthest the mouse wanders while keeping the computer awake, in pixels")
+	mouseSpeed            = flag.Float64("mouse-speed", 500, "Average speed of mouse movements in pixels per second")
+	scrollRate            = flag.Float64("scroll-rate", 0.1, "Chance of scrolling a little and back each time the mouse moves, 0 to 1")
+	clickRate             = flag.Float64("click-rate", 0.2, "With -safe-region, chance of clicking in it each time the mouse moves, 0 to 1")
+	safeRegion            = flag.String("safe-region", "", "Screen region x,y,width,height where clicks and scrolls change nothing, e.g. the editor's title bar")
+)
+
 func main() {
 	internal.HandleStartup()
 	initLogger()
@@ -588,29 +693,238 @@ func main() {
 		logMessage("-------------------- Program Exiting --------------------")
 	}()
 
-	intervalRange := flag.Duration("interval-range", 8*time.Minute, "Maximum PAUSE duration between typing bursts (e.g., 30m, 1h)")
-	burstRange := flag.Duration("burst-range", 7*time.Minute, "Maximum active typing burst duration (e.g., 5m, 15m)")
-	intervalBetweenTyping := flag.Duration("interval-between-typing", 7*time.Second, "Base interval between typing new code blocks within a burst (e.g., 5s, 10s)")
-	exitCoordinateX := flag.Int("exit-x", 50, "X-coordinate threshold for mouse exit zone (top-left corner)")
-	exitCoordinateY := flag.Int("exit-y", 50, "Y-coordinate threshold for mouse exit zone (top-left corner)")
-	flag.Parse()
+	if *configFile != "" {
+		if err := loadConfig(*configFile); err != nil {
+			logMessage("ERROR: ", err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		logMessage("Loaded config from ", *configFile)
+	}
+
+	profile, ok := languages[*lang]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown language %q, want one of %s\n", *lang, strings.Join(languageNames(), ", "))
+		os.Exit(2)
+	}
+	var source snippetSource = randomSource{lang: profile}
+	if *corpus != "" {
+		exts := profile.exts
+		if *corpusExt != "" {
+			exts = nil
+			for ext := range strings.SplitSeq(*corpusExt, ",") {
+				exts = append(exts, "."+strings.TrimPrefix(strings.TrimSpace(ext), "."))
+			}
+		}
+		model, err := trainMarkov(*corpus, exts)
+		if err != nil {
+			logMessage("ERROR: ", err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		logMessage("Trained on ", model.tokens, " tokens from ", model.files, " files in ", *corpus)
+		source = markovSource{model: model}
+	}
+	if *proseRate > 0 {
+		source = proseSource{snippetSource: source, lang: profile, rate: *proseRate}
+	}
+
+	typing, ok := typingProfiles[*profileName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown profile %q, want one of %s\n", *profileName, strings.Join(typingProfileNames(), ", "))
+		os.Exit(2)
+	}
+	// Flags given on the command line, in the environment or in the config file override the profile
+	flag.VisitAll(func(f *flag.Flag) {
+		if !flagGiven(f.Name) {
+			return
+		}
+		switch f.Name {
+		case "wpm":
+			typing.WPM = *wpm
+		case "typo-rate":
+			typing.TypoRate = *typoRate
+		case "correction-rate":
+			typing.CorrectionRate = *correctionRate
+		case "word-pause":
+			typing.WordPause = *wordPause
+		case "line-pause":
+			typing.LinePause = *linePause
+		}
+	})
+	typing.PasteRate, typing.PasteEditRate = *pasteRate, *pasteEditRate
+	if typing.WPM <= 0 {
+		fmt.Fprintln(os.Stderr, "-wpm must be positive")
+		os.Exit(2)
+	}
+	mouse := mouseOptions{
+		Range: *mouseRange, Speed: *mouseSpeed, ScrollRate: *scrollRate, ClickRate: *clickRate,
+		Exit: image.Pt(*exitCoordinateX, *exitCoordinateY),
+	}
+	if mouse.Range < 2 || mouse.Speed <= 0 {
+		fmt.Fprintln(os.Stderr, "-mouse-range must be at least 2 and -mouse-speed positive")
+		os.Exit(2)
+	}
+	if *safeRegion != "" {
+		var err error
+		if mouse.Region, err = parseRegion(*safeRegion); err != nil {
+			fmt.Fprintln(os.Stderr, "-safe-region:", err)
+			os.Exit(2)
+		}
+		if mouse.Region.Overlaps(image.Rectangle{Max: mouse.Exit}) {
+			fmt.Fprintln(os.Stderr, "-safe-region overlaps the exit zone")
+			os.Exit(2)
+		}
+	}
+	var files *fileWorkflow
+	if *newFile {
+		files = &fileWorkflow{}
+		var err error
+		if files.open, err = parseKeySequence(*newFileKeys); err != nil {
+			fmt.Fprintln(os.Stderr, "-new-file-keys:", err)
+			os.Exit(2)
+		}
+		if files.close, err = parseKeySequence(*endFileKeys); err != nil {
+			fmt.Fprintln(os.Stderr, "-end-file-keys:", err)
+			os.Exit(2)
+		}
+		if *file == "" {
+			files.header, files.footer = profile.header, profile.footer
+		}
+	}
+	pauseKey, err := lookupHotkey(*pauseKeyName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "-pause-key:", err)
+		os.Exit(2)
+	}
+	stopKey, err := lookupHotkey(*stopKeyName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "-stop-key:", err)
+		os.Exit(2)
+	}
+	logMessage("Typing profile ", *profileName, ": ", fmt.Sprintf("%+v", typing))
+	if *file != "" {
+		fs, err := newFileSource(*file, *startMarker, *endMarker)
+		if err != nil {
+			logMessage("ERROR: ", err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		logMessage("Typing ", fs.total, " characters in ", len(fs.blocks), " blocks from ", fs.name)
+		source = fs
+	}
+
+	// SIGINT and SIGTERM end ctx; typer's own ways of stopping, like the
+	// mouse corner or the stop key, send on sigs
+	ctx := run.Context()
+	sigs := make(chan os.Signal, 1)
+
+	// typer record FILE saves real typing; typer replay FILE types it again
+	var replay *session
+	switch flag.Arg(0) {
+	case "":
+	case "record":
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "usage: typer [flags] record session.json")
+			os.Exit(2)
+		}
+		fmt.Printf("Recording key presses to %s until Ctrl+C", flag.Arg(1))
+		if stopKey != nil {
+			fmt.Printf(" or %s", strings.ToUpper(stopKey.name))
+		}
+		fmt.Println(". Shortcuts with Control, Alt or Command are not recorded.")
+		err := recordSession(ctx, flag.Arg(1), stopKey)
+		run.Shutdown()
+		if err != nil {
+			logMessage("ERROR: ", err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	case "replay":
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "usage: typer [flags] replay session.json")
+			os.Exit(2)
+		}
+		if *replaySpeed <= 0 {
+			fmt.Fprintln(os.Stderr, "-replay-speed must be positive")
+			os.Exit(2)
+		}
+		var err error
+		if replay, err = loadSession(flag.Arg(1)); err != nil {
+			logMessage("ERROR: ", err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, want record or replay\n", flag.Arg(0))
+		os.Exit(2)
+	}
 
 	logMessage("Flags: interval-range=", *intervalRange, ", burst-range=", *burstRange,
-		", interval-between-typing=", *intervalBetweenTyping, ", exit-x=", *exitCoordinateX, ", exit-y=", *exitCoordinateY)
+		", interval-between-typing=", *intervalBetweenTyping, ", exit-x=", *exitCoordinateX, ", exit-y=", *exitCoordinateY, ", lang=", *lang, ", corpus=", *corpus, ", file=", *file, ", dry-run=", *dryRun)
 
 	fmt.Printf("Configuration: Max pause between bursts: %s, Max burst duration: %s, Interval in burst: %s\n", *intervalRange, *burstRange, *intervalBetweenTyping)
 	fmt.Printf("To exit: Press Ctrl+C, or move mouse to screen coordinates x < %d and y < %d.\n", *exitCoordinateX, *exitCoordinateY)
-	fmt.Println("Starting simulation in 3 seconds...")
-	time.Sleep(3 * time.Second)
+	if pauseKey != nil {
+		fmt.Printf("To pause or resume: Press %s.\n", strings.ToUpper(pauseKey.name))
+	}
+	if stopKey != nil {
+		fmt.Printf("To stop from any window: Press %s.\n", strings.ToUpper(stopKey.name))
+	}
 
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	if *controlAddr != "" {
+		if err := serveControl(*controlAddr, *controlToken, sigs); err != nil {
+			logMessage("ERROR: ", err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("To control remotely: POST to http://%s/pause, /resume or /stop.\n", *controlAddr)
+	}
 
-	go preventComputerSleep()
-	go generateCodeInBursts(*intervalRange, *burstRange, *intervalBetweenTyping)
-	go monitorMouseExitCondition(sigs, *exitCoordinateX, *exitCoordinateY)
+	if *dryRun {
+		// Nothing is typed, so the mouse and hotkeys aren't watched either
+		fmt.Printf("Dry run: previewing %s of simulated time. Times in the log are simulated.\n", *dryRunFor)
+		startDryRun(*dryRunFor, sigs)
+	} else {
+		fmt.Println("Starting simulation in 3 seconds...")
+		time.Sleep(3 * time.Second)
 
-	receivedSignal := <-sigs
+		go preventComputerSleep(mouse)
+		go monitorMouseExitCondition(sigs, *exitCoordinateX, *exitCoordinateY)
+		go monitorHotkeys(sigs, pauseKey, stopKey)
+	}
+	keyTap = stats.counting(keyTap)
+	stats.start()
+	if replay != nil {
+		go replaySession(replay, *replaySpeed, sigs)
+	} else {
+		go generateCodeInBursts(source, files, typing, sigs, *intervalRange, *burstRange, *intervalBetweenTyping)
+	}
+
+	var receivedSignal os.Signal
+	select {
+	case <-ctx.Done():
+		receivedSignal = syscall.SIGTERM
+		var sig run.SignalError
+		if errors.As(context.Cause(ctx), &sig) {
+			receivedSignal = sig.Signal
+		}
+	case receivedSignal = <-sigs:
+	}
 	logMessage("Termination signal received: ", receivedSignal.String())
 	fmt.Println("\nTermination signal (", receivedSignal.String(), ") received. Exiting program gracefully.")
+
+	final := stats.finish()
+	logMessage("Session: ", final)
+	fmt.Println("Session:", final)
+	if *report != "" {
+		if err := final.writeReport(*report); err != nil {
+			logMessage("ERROR: ", err)
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			fmt.Println("Wrote session report to", *report)
+		}
+	}
+	run.Shutdown()
 }
@@ -0,0 +1,105 @@
+package flagenv
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+)
+
+// sources maps the environment variables LoadFiles set to the file they
+// came from.
+var sources = map[string]string{}
+
+// DefaultFiles returns the env files a command loads, from lowest to highest
+// precedence: .env, .env.local, then .env.<command> and .env.<command>.local
+// for settings of that command only. The .local files are meant to be kept
+// out of version control.
+func DefaultFiles(command string) []string {
+	return []string{".env", ".env.local", ".env." + command, ".env." + command + ".local"}
+}
+
+// LoadFiles sets environment variables from env files, with later files
+// overriding earlier ones and variables already in the environment overriding
+// them all. With a Prefix, only variables starting with it are loaded, so one
+// .env can hold the settings of several commands. Missing files are skipped.
+func LoadFiles(files ...string) error {
+	values := map[string]string{}
+	from := map[string]string{}
+	for _, file := range files {
+		vars, err := godotenv.Read(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		for name, val := range vars {
+			if !strings.HasPrefix(name, strings.ToUpper(Prefix)) {
+				continue
+			}
+			values[name], from[name] = val, file
+		}
+	}
+
+	for name, val := range values {
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		if err := os.Setenv(name, val); err != nil {
+			return err
+		}
+		sources[name] = from[name]
+	}
+	return nil
+}
+
+// PrintSources writes each flag of set with its environment variable, its
+// value and where the value came from: the command line, the environment, an
+// env file, one of the other sources flags were set from by name, or the
+// flag's default. Values of flags whose names suggest secrets are left out.
+func PrintSources(w io.Writer, prefix string, set *flag.FlagSet, other map[string]string) error {
+	explicit := map[string]bool{}
+	set.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FLAG\tVARIABLE\tVALUE\tSOURCE")
+	set.VisitAll(func(f *flag.Flag) {
+		name := envName(prefix, f.Name)
+		source := "default"
+		switch {
+		case explicit[f.Name]:
+			source = "command line"
+		case os.Getenv(name) != "":
+			source = "environment"
+			if file, ok := sources[name]; ok {
+				source = file
+			}
+		case other[f.Name] != "":
+			source = other[f.Name]
+		}
+		value := strconv.Quote(f.Value.String())
+		if secret(f.Name) && f.Value.String() != "" {
+			value = "[redacted]"
+		}
+		fmt.Fprintf(tw, "-%s\t%s\t%s\t%s\n", f.Name, name, value, source)
+	})
+	return tw.Flush()
+}
+
+func secret(flagName string) bool {
+	name := strings.ToLower(flagName)
+	for _, s := range []string{"password", "secret", "token", "credential"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
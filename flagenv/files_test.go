@@ -0,0 +1,99 @@
+package flagenv_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/facebookgo/ensure"
+	"pkg.jsn.cam/jsn/flagenv"
+)
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		ensure.Nil(t, os.WriteFile(path, []byte(data), 0o600))
+		return path
+	}
+	files := []string{
+		write(".env", "TESTLOADFILES_A=env\nTESTLOADFILES_B=env\nTESTLOADFILES_C=env\nOTHER_TOOL=x\n"),
+		write(".env.local", "TESTLOADFILES_B=local\n"),
+		filepath.Join(dir, ".env.missing"),
+		write(".env.cmd", "TESTLOADFILES_C=cmd\nTESTLOADFILES_PASSWORD=hunter2\n"),
+	}
+	t.Setenv("TESTLOADFILES_A", "real")
+	for _, name := range []string{"TESTLOADFILES_B", "TESTLOADFILES_C", "TESTLOADFILES_PASSWORD"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	flagenv.Prefix = "testloadfiles_"
+	defer func() { flagenv.Prefix = "" }()
+	ensure.Nil(t, flagenv.LoadFiles(files...))
+	ensure.DeepEqual(t, os.Getenv("TESTLOADFILES_A"), "real")
+	ensure.DeepEqual(t, os.Getenv("TESTLOADFILES_B"), "local")
+	ensure.DeepEqual(t, os.Getenv("TESTLOADFILES_C"), "cmd")
+	if _, ok := os.LookupEnv("OTHER_TOOL"); ok {
+		t.Error("OTHER_TOOL loaded without the prefix")
+	}
+
+	s := flag.NewFlagSet("TestLoadFiles", flag.PanicOnError)
+	s.String("a", "", "")
+	s.String("b", "", "")
+	s.String("c", "", "")
+	s.String("d", "default", "")
+	s.String("password", "", "")
+	s.Parse([]string{"-c", "flag"})
+	ensure.Nil(t, flagenv.ParseSet(flagenv.Prefix, s))
+
+	var out bytes.Buffer
+	ensure.Nil(t, flagenv.PrintSources(&out, flagenv.Prefix, s, nil))
+	for _, line := range []string{
+		`-a\s+TESTLOADFILES_A\s+"real"\s+environment`,
+		`-b\s+TESTLOADFILES_B\s+"local"\s+` + regexp.QuoteMeta(files[1]),
+		`-c\s+TESTLOADFILES_C\s+"flag"\s+command line`,
+		`-d\s+TESTLOADFILES_D\s+"default"\s+default`,
+		`-password\s+TESTLOADFILES_PASSWORD\s+\[redacted\]\s+` + regexp.QuoteMeta(files[3]),
+	} {
+		if !regexp.MustCompile(`(?m)^` + line + `$`).Match(out.Bytes()) {
+			t.Errorf("no line matching %s in:\n%s", line, &out)
+		}
+	}
+}
+
+func TestPrintSourcesOther(t *testing.T) {
+	s := flag.NewFlagSet("TestPrintSourcesOther", flag.PanicOnError)
+	s.String("listen", "", "")
+	s.String("token", "", "")
+	s.String("debug", "off", "")
+	s.Parse([]string{"-debug", "on"})
+	// Like flagfolder and flagconfyg, which leave the flags unvisited
+	ensure.Nil(t, s.Lookup("listen").Value.Set(":8080"))
+	ensure.Nil(t, s.Lookup("token").Value.Set("hunter2"))
+
+	// Other sources name where the flags they set came from, but the
+	// command line still says it set its own
+	other := map[string]string{"listen": "jsn.config:3", "token": "/run/secrets/token", "debug": "jsn.config:4"}
+	var out bytes.Buffer
+	ensure.Nil(t, flagenv.PrintSources(&out, "testprintsourcesother_", s, other))
+	for _, line := range []string{
+		`-listen\s+TESTPRINTSOURCESOTHER_LISTEN\s+":8080"\s+jsn\.config:3`,
+		`-token\s+TESTPRINTSOURCESOTHER_TOKEN\s+\[redacted\]\s+/run/secrets/token`,
+		`-debug\s+TESTPRINTSOURCESOTHER_DEBUG\s+"on"\s+command line`,
+	} {
+		if !regexp.MustCompile(`(?m)^` + line + `$`).Match(out.Bytes()) {
+			t.Errorf("no line matching %s in:\n%s", line, &out)
+		}
+	}
+}
+
+func TestLoadFilesBadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	ensure.Nil(t, os.WriteFile(path, []byte("A='unterminated\n"), 0o600))
+	ensure.Err(t, flagenv.LoadFiles(path), regexp.MustCompile(`failed to read .*\.env`))
+}
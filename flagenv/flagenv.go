@@ -13,6 +13,11 @@ import (
 // Specify a prefix for environment variables.
 var Prefix = ""
 
+// Strict makes parsing fail on environment variables that start with the
+// prefix but don't match any flag, which are usually typos. It has no
+// effect without a prefix.
+var Strict = false
+
 func contains(list []*flag.Flag, f *flag.Flag) bool {
 	for _, i := range list {
 		if i == f {
@@ -32,18 +37,15 @@ func ParseSet(prefix string, set *flag.FlagSet) error {
 	})
 
 	var err error
+	known := map[string]bool{}
 	set.VisitAll(func(f *flag.Flag) {
+		name := envName(prefix, f.Name)
+		known[name] = true
 		if err != nil {
 			return
 		}
 		all = append(all, f)
 		if !contains(explicit, f) {
-			name := strings.Replace(f.Name, ".", "_", -1)
-			name = strings.Replace(name, "-", "_", -1)
-			if prefix != "" {
-				name = prefix + name
-			}
-			name = strings.ToUpper(name)
 			val := os.Getenv(name)
 			if val != "" {
 				if ferr := f.Value.Set(val); ferr != nil {
@@ -52,7 +54,40 @@ func ParseSet(prefix string, set *flag.FlagSet) error {
 			}
 		}
 	})
-	return err
+	if err != nil || !Strict || prefix == "" {
+		return err
+	}
+
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, strings.ToUpper(prefix)) && !known[name] {
+			return fmt.Errorf("unknown environment variable %q: no flag %q", name, flagName(prefix, name))
+		}
+	}
+	return nil
+}
+
+// Lookup returns the value ParseSet would set a flag to from the
+// environment, if there is one.
+func Lookup(prefix, flagName string) (string, bool) {
+	val := os.Getenv(envName(prefix, flagName))
+	return val, val != ""
+}
+
+// envName returns the environment variable for a flag.
+func envName(prefix, flagName string) string {
+	name := strings.Replace(flagName, ".", "_", -1)
+	name = strings.Replace(name, "-", "_", -1)
+	if prefix != "" {
+		name = prefix + name
+	}
+	return strings.ToUpper(name)
+}
+
+// flagName guesses the flag an environment variable was meant for.
+func flagName(prefix, envName string) string {
+	name := strings.TrimPrefix(envName, strings.ToUpper(prefix))
+	return strings.Replace(strings.ToLower(name), "_", "-", -1)
 }
 
 // Parse will set each defined flag from its corresponding environment
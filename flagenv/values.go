@@ -0,0 +1,167 @@
+package flagenv
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// fields splits a value on commas and whitespace, so both FOO="a,b" and
+// FOO="a b" hold two values.
+func fields(v string) []string {
+	return strings.FieldsFunc(v, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// Slice is a flag.Value holding values of type T, given separated by commas
+// or whitespace. The first Set replaces the default, later ones append, so
+// the flag can be repeated on the command line or given once in the
+// environment.
+type Slice[T any] struct {
+	p     *[]T
+	parse func(string) (T, error)
+	set   bool
+}
+
+// NewSlice returns a Slice storing values parsed by parse in p.
+func NewSlice[T any](p *[]T, parse func(string) (T, error)) *Slice[T] {
+	return &Slice[T]{p: p, parse: parse}
+}
+
+func (s *Slice[T]) String() string {
+	if s == nil || s.p == nil {
+		return ""
+	}
+	var out []string
+	for _, v := range *s.p {
+		out = append(out, fmt.Sprint(v))
+	}
+	return strings.Join(out, ",")
+}
+
+func (s *Slice[T]) Set(v string) error {
+	if !s.set {
+		*s.p = nil
+		s.set = true
+	}
+	for _, f := range fields(v) {
+		val, err := s.parse(f)
+		if err != nil {
+			return err
+		}
+		*s.p = append(*s.p, val)
+	}
+	return nil
+}
+
+// Strings defines a string slice flag with the specified name, default
+// value, and usage string. See Slice for how values are given.
+func Strings(name string, value []string, usage string) *[]string {
+	p := slices.Clone(value)
+	flag.CommandLine.Var(NewSlice(&p, func(s string) (string, error) { return s, nil }), name, usage)
+	return &p
+}
+
+// Ints defines an int slice flag with the specified name, default value,
+// and usage string. See Slice for how values are given.
+func Ints(name string, value []int, usage string) *[]int {
+	p := slices.Clone(value)
+	flag.CommandLine.Var(NewSlice(&p, strconv.Atoi), name, usage)
+	return &p
+}
+
+// Durations defines a duration slice flag with the specified name, default
+// value, and usage string. Each value is parsed like a Duration.
+func Durations(name string, value []time.Duration, usage string) *[]time.Duration {
+	p := slices.Clone(value)
+	flag.CommandLine.Var(NewSlice(&p, parseDuration), name, usage)
+	return &p
+}
+
+// Duration is a flag.Value like the one flag.Duration uses, that also takes
+// a bare number of seconds, as environment variables like TIMEOUT=30 often
+// are.
+type Duration time.Duration
+
+func (d *Duration) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *Duration) Set(v string) error {
+	parsed, err := parseDuration(v)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func parseDuration(v string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	return time.ParseDuration(v)
+}
+
+// DurationVar defines a Duration flag with the specified name, default
+// value, and usage string, storing its value in p.
+func DurationVar(p *time.Duration, name string, value time.Duration, usage string) {
+	*p = value
+	flag.CommandLine.Var((*Duration)(p), name, usage)
+}
+
+// Map is a flag.Value holding key=value pairs, given separated by commas or
+// whitespace. Like Slice, the first Set replaces the default and later ones
+// add to it, with later values of a key winning.
+type Map struct {
+	m   map[string]string
+	set bool
+}
+
+// NewMap returns a Map storing pairs in m, which must not be nil.
+func NewMap(m map[string]string) *Map {
+	return &Map{m: m}
+}
+
+func (m *Map) String() string {
+	if m == nil || m.m == nil {
+		return ""
+	}
+	var out []string
+	for _, k := range slices.Sorted(maps.Keys(m.m)) {
+		out = append(out, k+"="+m.m[k])
+	}
+	return strings.Join(out, ",")
+}
+
+func (m *Map) Set(v string) error {
+	if !m.set {
+		clear(m.m)
+		m.set = true
+	}
+	for _, f := range fields(v) {
+		k, val, ok := strings.Cut(f, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("%q isn't a key=value pair", f)
+		}
+		m.m[k] = val
+	}
+	return nil
+}
+
+// StringMap defines a Map flag with the specified name, default value, and
+// usage string.
+func StringMap(name string, value map[string]string, usage string) map[string]string {
+	m := maps.Clone(value)
+	if m == nil {
+		m = map[string]string{}
+	}
+	flag.CommandLine.Var(NewMap(m), name, usage)
+	return m
+}
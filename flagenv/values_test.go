@@ -0,0 +1,83 @@
+package flagenv_test
+
+import (
+	"flag"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/ensure"
+	"pkg.jsn.cam/jsn/flagenv"
+)
+
+func TestSlice(t *testing.T) {
+	const name = "TestSlice"
+	s := flag.NewFlagSet(name, flag.PanicOnError)
+	hosts := []string{"default"}
+	ports := []int{}
+	s.Var(flagenv.NewSlice(&hosts, func(s string) (string, error) { return s, nil }), "hosts", "")
+	s.Var(flagenv.NewSlice(&ports, strconv.Atoi), "ports", "")
+
+	// Repeated on the command line, appending after the default is replaced
+	s.Parse([]string{"-hosts", "a,b", "-hosts", "c"})
+	t.Setenv(named(name, "hosts"), "ignored")
+	t.Setenv(named(name, "ports"), "80, 443\t8080")
+	ensure.Nil(t, flagenv.ParseSet(name, s))
+	ensure.DeepEqual(t, hosts, []string{"a", "b", "c"})
+	ensure.DeepEqual(t, ports, []int{80, 443, 8080})
+	ensure.DeepEqual(t, s.Lookup("ports").Value.String(), "80,443,8080")
+
+	t.Setenv(named(name, "ports"), "80,http")
+	ports = nil
+	s = flag.NewFlagSet(name, flag.PanicOnError)
+	s.Var(flagenv.NewSlice(&ports, strconv.Atoi), "ports", "")
+	ensure.Err(t, flagenv.ParseSet(name, s), regexp.MustCompile(`failed to set flag "ports" with value "80,http"`))
+}
+
+func TestDuration(t *testing.T) {
+	const name = "TestDuration"
+	for env, want := range map[string]time.Duration{
+		"30":    30 * time.Second,
+		"1.5":   1500 * time.Millisecond,
+		"2m30s": 150 * time.Second,
+	} {
+		s := flag.NewFlagSet(name, flag.PanicOnError)
+		d := flagenv.Duration(time.Minute)
+		s.Var(&d, "timeout", "")
+		t.Setenv(named(name, "timeout"), env)
+		ensure.Nil(t, flagenv.ParseSet(name, s))
+		ensure.DeepEqual(t, time.Duration(d), want)
+	}
+}
+
+func TestMap(t *testing.T) {
+	const name = "TestMap"
+	s := flag.NewFlagSet(name, flag.PanicOnError)
+	labels := map[string]string{"env": "dev"}
+	s.Var(flagenv.NewMap(labels), "labels", "")
+	t.Setenv(named(name, "labels"), "team=infra,tier=web team=platform")
+	ensure.Nil(t, flagenv.ParseSet(name, s))
+	ensure.DeepEqual(t, labels, map[string]string{"team": "platform", "tier": "web"})
+	ensure.DeepEqual(t, s.Lookup("labels").Value.String(), "team=platform,tier=web")
+
+	ensure.Err(t, flagenv.NewMap(map[string]string{}).Set("team"), regexp.MustCompile(`"team" isn't a key=value pair`))
+}
+
+func TestStrict(t *testing.T) {
+	const name = "TestStrict_"
+	s := flag.NewFlagSet(name, flag.PanicOnError)
+	s.String("listen-addr", "", "")
+	t.Setenv(named(name, "listen_addr"), ":8080")
+	t.Setenv(named(name, "listen_adr"), ":9090")
+
+	ensure.Nil(t, flagenv.ParseSet(name, s))
+	flagenv.Strict = true
+	defer func() { flagenv.Strict = false }()
+	ensure.Err(t, flagenv.ParseSet(name, s),
+		regexp.MustCompile(`unknown environment variable "TESTSTRICT_LISTEN_ADR": no flag "listen-adr"`))
+
+	os.Unsetenv(named(name, "listen_adr"))
+	ensure.Nil(t, flagenv.ParseSet(name, s))
+}
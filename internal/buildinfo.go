@@ -2,15 +2,62 @@ package internal
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"runtime"
 	"runtime/debug"
 
 	"pkg.jsn.cam/jsn"
+	"pkg.jsn.cam/jsn/internal/httpserver"
 )
 
+// build is what the build info says about the running program's source.
+type build struct {
+	Version  string `json:"version"`
+	Revision string `json:"revision,omitempty"`
+	Time     string `json:"time,omitempty"`
+	Modified bool   `json:"modified"`
+}
+
+// buildOf returns what bi says about the program's source, which may be nil.
+func buildOf(bi *debug.BuildInfo) build {
+	b := build{Version: jsn.Version}
+	if bi == nil {
+		return b
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.Revision = s.Value
+		case "vcs.time":
+			b.Time = s.Value
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+	return b
+}
+
+// printVersion writes what -version shows.
+func printVersion(w io.Writer, program string, bi *debug.BuildInfo) {
+	b := buildOf(bi)
+	fmt.Fprintf(w, "%s %s\n", program, b.Version)
+	if b.Revision != "" {
+		fmt.Fprintf(w, "revision: %s\n", b.Revision)
+	}
+	if b.Time != "" {
+		fmt.Fprintf(w, "built:    %s\n", b.Time)
+	}
+	if b.Revision != "" {
+		fmt.Fprintf(w, "modified: %t\n", b.Modified)
+	}
+	fmt.Fprintf(w, "go:       %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
 func init() {
-	http.HandleFunc("/.jsn/debug/buildinfo", func(w http.ResponseWriter, r *http.Request) {
+	httpserver.HandleDebug("buildinfo", "GET shows the build info and version as JSON", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		bi, ok := debug.ReadBuildInfo()
 		if !ok {
 			slog.Error("can't read build info")
@@ -20,11 +67,11 @@ func init() {
 
 		if err := json.NewEncoder(w).Encode(struct {
 			BuildInfo *debug.BuildInfo `json:"build_info"`
-			Version   string           `json:"version"`
-		}{bi, jsn.Version}); err != nil {
+			build
+		}{bi, buildOf(bi)}); err != nil {
 			slog.Error("can't encode build info", "err", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-	})
+	}))
 }
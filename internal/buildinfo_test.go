@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"pkg.jsn.cam/jsn"
+)
+
+func TestPrintVersion(t *testing.T) {
+	var b strings.Builder
+	printVersion(&b, "serve", &debug.BuildInfo{Settings: []debug.BuildSetting{
+		{Key: "vcs.revision", Value: "0123456789abcdef"},
+		{Key: "vcs.time", Value: "2026-10-01T12:00:00Z"},
+		{Key: "vcs.modified", Value: "true"},
+	}})
+	for _, want := range []string{"serve " + jsn.Version + "\n", "revision: 0123456789abcdef\n", "built:    2026-10-01T12:00:00Z\n", "modified: true\n", "go:       go"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("no %q in:\n%s", want, b.String())
+		}
+	}
+
+	// Without VCS info, there's nothing to say about the source
+	b.Reset()
+	printVersion(&b, "serve", nil)
+	if strings.Contains(b.String(), "revision") || strings.Contains(b.String(), "modified") {
+		t.Errorf("printed VCS info without any:\n%s", b.String())
+	}
+}
@@ -0,0 +1,117 @@
+// Package flagconfyg sets flags from a configuration file of flag names and
+// values:
+//
+//	// Lines starting with // or # are comments
+//	listen ":8080"
+//	hide-dotfiles false
+//	v
+//	header (
+//		"X-Frame-Options: DENY"
+//		`X-Robots-Tag: noindex`
+//	)
+//
+// Values are Go quoted strings or the rest of the line. A boolean flag alone
+// is set to true, and a block sets a flag once per line, for flags that
+// collect values.
+package flagconfyg
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseFile sets the flags of set from the file at path. Flags in given are
+// left alone and the flags set are added to it, with the file and line they
+// were set on.
+func ParseFile(path string, set *flag.FlagSet, given map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return Parse(path, data, set, given)
+}
+
+// Parse sets the flags of set from the contents of the configuration file
+// named name, like ParseFile.
+func Parse(name string, data []byte, set *flag.FlagSet, given map[string]string) error {
+	flags := map[string]*flag.Flag{}
+	// Names match with dashes or underscores, like flagenv's variables
+	set.VisitAll(func(f *flag.Flag) { flags[strings.ReplaceAll(f.Name, "_", "-")] = f })
+
+	// Flags are only skipped for being given before this file, so that
+	// blocks set them more than once
+	setHere := map[string]string{}
+	n := 0
+	apply := func(f *flag.Flag, raw string) error {
+		if _, ok := given[f.Name]; ok {
+			return nil
+		}
+		v := raw
+		if strings.HasPrefix(raw, `"`) || strings.HasPrefix(raw, "`") {
+			var err error
+			if v, err = strconv.Unquote(raw); err != nil {
+				return fmt.Errorf("bad quoted value %s", raw)
+			}
+		}
+		if err := f.Value.Set(v); err != nil {
+			return fmt.Errorf("failed to set flag %q: %v", f.Name, err)
+		}
+		setHere[f.Name] = fmt.Sprintf("%s:%d", name, n)
+		return nil
+	}
+
+	var block *flag.Flag
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if block != nil {
+			if line == ")" {
+				block = nil
+			} else if err := apply(block, line); err != nil {
+				return fmt.Errorf("%s:%d: %v", name, n, err)
+			}
+			continue
+		}
+
+		key, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		f, ok := flags[strings.ReplaceAll(key, "_", "-")]
+		if !ok {
+			return fmt.Errorf("%s:%d: no flag %q", name, n, key)
+		}
+		switch rest {
+		case "(":
+			block = f
+			continue
+		case "":
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				return fmt.Errorf("%s:%d: flag %q needs a value", name, n, key)
+			}
+			rest = "true"
+		}
+		if err := apply(f, rest); err != nil {
+			return fmt.Errorf("%s:%d: %v", name, n, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("%s:%d: %v", name, n, err)
+	}
+	if block != nil {
+		return fmt.Errorf("%s:%d: block of %q isn't closed", name, n, block.Name)
+	}
+
+	for flagName, source := range setHere {
+		given[flagName] = source
+	}
+	return nil
+}
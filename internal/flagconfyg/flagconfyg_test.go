@@ -0,0 +1,81 @@
+package flagconfyg
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// list collects the values of a repeated flag.
+type list []string
+
+func (l *list) String() string     { return strings.Join(*l, ",") }
+func (l *list) Set(v string) error { *l = append(*l, v); return nil }
+
+func TestParse(t *testing.T) {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	listen := set.String("listen", ":3000", "")
+	verbose := set.Bool("v", false, "")
+	dotfiles := set.Bool("hide-dotfiles", true, "")
+	name := set.String("name", "", "")
+	var headers list
+	set.Var(&headers, "header", "")
+	rate := set.Float64("paste_rate", 0, "")
+
+	given := map[string]string{"name": "command line"}
+	err := Parse("test.config", []byte(`// Comments start with // or #
+# like this
+listen ":8080"
+v
+
+hide-dotfiles false
+name ignored
+header (
+	"X-Frame-Options: DENY"
+	`+"`X-Robots-Tag: noindex`"+`
+)
+paste-rate 0.5
+`), set, given)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *listen != ":8080" || !*verbose || *dotfiles || *name != "" || *rate != 0.5 {
+		t.Errorf("got listen %q, v %v, hide-dotfiles %v, name %q, paste_rate %v", *listen, *verbose, *dotfiles, *name, *rate)
+	}
+	if strings.Join(headers, "|") != "X-Frame-Options: DENY|X-Robots-Tag: noindex" {
+		t.Errorf("headers %q", headers)
+	}
+	if given["listen"] != "test.config:3" || given["header"] != "test.config:10" || given["name"] != "command line" {
+		t.Errorf("given %v", given)
+	}
+
+	for src, want := range map[string]string{
+		"lisen :8080":     `bad:1: no flag "lisen"`,
+		"\nlisten":        `bad:2: flag "listen" needs a value`,
+		`listen ":8080`:   "bad:1: bad quoted value",
+		"v maybe":         `bad:1: failed to set flag "v"`,
+		"header (\n\"a\"": `bad:2: block of "header" isn't closed`,
+	} {
+		err := Parse("bad", []byte(src), set, map[string]string{})
+		if err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("Parse(%q) = %v, want %s", src, err, want)
+		}
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	listen := set.String("listen", "", "")
+	path := filepath.Join(t.TempDir(), "serve.config")
+	if err := os.WriteFile(path, []byte("listen :8080\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ParseFile(path, set, map[string]string{}); err != nil || *listen != ":8080" {
+		t.Errorf("got %q, %v", *listen, err)
+	}
+	if err := ParseFile(path+".missing", set, map[string]string{}); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v", err)
+	}
+}
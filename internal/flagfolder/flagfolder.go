@@ -0,0 +1,62 @@
+// Package flagfolder sets flags from a folder of files, one per flag, like
+// the secrets Docker and Kubernetes mount to /run/secrets.
+package flagfolder
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// key normalizes a flag or file name so that api-token, api.token,
+// API_TOKEN and, with the prefix "myapp_", MYAPP_API_TOKEN all match.
+func key(prefix, name string) string {
+	name = strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToLower(name))
+	return strings.TrimPrefix(name, strings.ToLower(prefix))
+}
+
+// ParseSet sets the flags of set that have a file in dir to the file's
+// contents, without trailing newlines. Files are named after flags or their
+// flagenv environment variables, with prefix. Flags in given are left alone
+// and the flags set are added to it, with the file they were set from.
+// Files matching no flag are ignored, as the folder may hold secrets for
+// other programs, and so is a missing dir.
+func ParseSet(dir, prefix string, set *flag.FlagSet, given map[string]string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	flags := map[string]*flag.Flag{}
+	set.VisitAll(func(f *flag.Flag) { flags[key(prefix, f.Name)] = f })
+	for _, e := range entries {
+		f, ok := flags[key(prefix, e.Name())]
+		if !ok {
+			continue
+		}
+		if _, ok := given[f.Name]; ok {
+			continue
+		}
+		// Mounted secrets are often symlinks into a hidden directory
+		path := filepath.Join(dir, e.Name())
+		if fi, err := os.Stat(path); err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := f.Value.Set(strings.TrimRight(string(data), "\r\n")); err != nil {
+			return fmt.Errorf("failed to set flag %q from %s: %v", f.Name, path, err)
+		}
+		given[f.Name] = path
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package flagfolder
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSet(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"api-token":        "s3cret\n",
+		"MYAPP_DB_URL":     "postgres://db\r\n",
+		"listen":           ":9090",
+		"other_service_pw": "not ours",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Kubernetes mounts secrets as symlinks into a timestamped directory
+	os.Mkdir(filepath.Join(dir, "..data"), 0o700)
+	os.WriteFile(filepath.Join(dir, "..data", "port"), []byte("8080"), 0o600)
+	if err := os.Symlink(filepath.Join("..data", "port"), filepath.Join(dir, "port")); err != nil {
+		t.Fatal(err)
+	}
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	token := set.String("api-token", "", "")
+	dbURL := set.String("db.url", "", "")
+	listen := set.String("listen", ":3000", "")
+	port := set.Int("port", 0, "")
+	given := map[string]string{"listen": "environment"}
+	if err := ParseSet(dir, "myapp_", set, given); err != nil {
+		t.Fatal(err)
+	}
+	if *token != "s3cret" || *dbURL != "postgres://db" || *listen != ":3000" || *port != 8080 {
+		t.Errorf("got api-token %q, db.url %q, listen %q, port %d", *token, *dbURL, *listen, *port)
+	}
+	if given["api-token"] != filepath.Join(dir, "api-token") || given["listen"] != "environment" {
+		t.Errorf("given %v", given)
+	}
+
+	if err := ParseSet(filepath.Join(dir, "missing"), "", set, map[string]string{}); err != nil {
+		t.Errorf("missing dir: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "port"), []byte("http"), 0o600)
+	err := ParseSet(dir, "", set, map[string]string{})
+	if err == nil || !strings.Contains(err.Error(), `failed to set flag "port"`) || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("bad value: %v", err)
+	}
+}
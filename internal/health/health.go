@@ -0,0 +1,183 @@
+// Package health collects the checks that say whether a program is working,
+// and serves their results as JSON for load balancers and orchestrators.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Kind is what a check says about the program when it fails.
+type Kind int
+
+const (
+	// Live checks fail when the program is stuck and should be restarted.
+	Live Kind = iota
+	// Ready checks fail when the program can't do its job right now, like
+	// when an upstream is unreachable, and shouldn't be sent traffic.
+	// Readiness includes the Live checks.
+	Ready
+)
+
+// Timeout is how long each check gets, unless the request's context ends
+// sooner.
+const Timeout = 5 * time.Second
+
+// A Check returns an error saying what's wrong, or nil if nothing is.
+type Check func(ctx context.Context) error
+
+// Registry is a set of named checks.
+type Registry struct {
+	mu     sync.Mutex
+	checks []namedCheck
+}
+
+type namedCheck struct {
+	name  string
+	kind  Kind
+	check Check
+}
+
+// Default is the Registry the package-level functions use.
+var Default = &Registry{}
+
+// Register adds a check to Default.
+func Register(kind Kind, name string, check Check) { Default.Register(kind, name, check) }
+
+// Handler serves Default's checks.
+func Handler(kind Kind) http.Handler { return Default.Handler(kind) }
+
+// Register adds a check named name. Registering a name again replaces the
+// check.
+func (r *Registry) Register(kind Kind, name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, c := range r.checks {
+		if c.name == name {
+			r.checks[i] = namedCheck{name, kind, check}
+			return
+		}
+	}
+	r.checks = append(r.checks, namedCheck{name, kind, check})
+}
+
+// Report is the result of running checks.
+type Report struct {
+	// Status is "ok" if every check passed, or "failing".
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Result is the result of one check.
+type Result struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// OK reports whether every check passed.
+func (rep Report) OK() bool { return rep.Status == "ok" }
+
+// Run runs the checks of kind, and the Live ones for Ready, at the same
+// time.
+func (r *Registry) Run(ctx context.Context, kind Kind) Report {
+	r.mu.Lock()
+	var checks []namedCheck
+	for _, c := range r.checks {
+		if c.kind <= kind {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.Unlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, c.check)
+		}()
+	}
+	wg.Wait()
+
+	rep := Report{Status: "ok", Checks: map[string]Result{}}
+	for i, c := range checks {
+		rep.Checks[c.name] = results[i]
+		if results[i].Status != "ok" {
+			rep.Status = "failing"
+		}
+	}
+	return rep
+}
+
+func run(ctx context.Context, check Check) (res Result) {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	start := time.Now()
+	defer func() {
+		if v := recover(); v != nil {
+			res = Result{Status: "failing", Error: fmt.Sprint("panic: ", v)}
+		}
+		res.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+	}()
+
+	if err := check(ctx); err != nil {
+		return Result{Status: "failing", Error: err.Error()}
+	}
+	return Result{Status: "ok"}
+}
+
+// Handler serves the report of running the checks of kind, with status 503
+// Service Unavailable if any failed.
+func (r *Registry) Handler(kind Kind) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rep := r.Run(req.Context(), kind)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !rep.OK() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(rep)
+	})
+}
+
+// WritableDir checks that files can be created in dir.
+func WritableDir(dir string) Check {
+	return func(context.Context) error {
+		f, err := os.CreateTemp(dir, ".health-*")
+		if err != nil {
+			return err
+		}
+		name := f.Name()
+		return errors.Join(f.Close(), os.Remove(name))
+	}
+}
+
+// Reachable checks that a GET of url with client gets a response that
+// isn't a server error.
+func Reachable(client *http.Client, url string) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%s: %s", url, resp.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := &Registry{}
+	r.Register(Live, "loop", func(context.Context) error { return nil })
+	r.Register(Ready, "upstream", func(context.Context) error { return errors.New("connection refused") })
+	r.Register(Ready, "panics", func(context.Context) error { panic("oops") })
+	r.Register(Ready, "panics", func(context.Context) error { return nil })
+
+	for _, tt := range []struct {
+		kind   Kind
+		code   int
+		checks map[string]string
+	}{
+		{Live, http.StatusOK, map[string]string{"loop": ""}},
+		{Ready, http.StatusServiceUnavailable, map[string]string{"loop": "", "upstream": "connection refused", "panics": ""}},
+	} {
+		rec := httptest.NewRecorder()
+		r.Handler(tt.kind).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		var rep Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &rep); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != tt.code || rep.OK() != (tt.code == http.StatusOK) || len(rep.Checks) != len(tt.checks) {
+			t.Errorf("kind %d: %d %s", tt.kind, rec.Code, rec.Body)
+		}
+		for name, wantErr := range tt.checks {
+			if res := rep.Checks[name]; res.Error != wantErr || (res.Status == "ok") != (wantErr == "") {
+				t.Errorf("kind %d: check %s = %+v, want error %q", tt.kind, name, res, wantErr)
+			}
+		}
+	}
+
+	r.Register(Live, "stuck", func(context.Context) error { panic("stuck") })
+	if rep := r.Run(context.Background(), Live); rep.OK() || rep.Checks["stuck"].Error != "panic: stuck" {
+		t.Errorf("panicking check: %+v", rep)
+	}
+}
+
+func TestWritableDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := WritableDir(dir)(context.Background()); err != nil {
+		t.Error(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("left %d files behind", len(entries))
+	}
+	if err := WritableDir(filepath.Join(dir, "missing"))(context.Background()); err == nil {
+		t.Error("missing dir is writable")
+	}
+}
+
+func TestReachable(t *testing.T) {
+	code := http.StatusNotFound
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(code) }))
+	defer srv.Close()
+	check := Reachable(srv.Client(), srv.URL)
+	if err := check(context.Background()); err != nil {
+		t.Errorf("404: %v", err)
+	}
+	code = http.StatusBadGateway
+	if err := check(context.Background()); err == nil {
+		t.Error("502 is reachable")
+	}
+	srv.Close()
+	if err := check(context.Background()); err == nil {
+		t.Error("closed server is reachable")
+	}
+}
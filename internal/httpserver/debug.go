@@ -0,0 +1,224 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DebugPrefix is where the debug endpoints registered with HandleDebug are.
+const DebugPrefix = "/.jsn/debug/"
+
+var (
+	debugMu     sync.Mutex
+	debugRoutes = map[string]string{}
+)
+
+// HandleDebug registers h on http.DefaultServeMux at DebugPrefix+name, which
+// may have wildcards like ServeMux patterns do, to be served on -debug-listen.
+// The index at DebugPrefix lists it with usage, which says what it does.
+func HandleDebug(name, usage string, h http.Handler) {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	http.Handle(DebugPrefix+name, h)
+	debugRoutes[DebugPrefix+name] = usage
+}
+
+// maxProfileTime is the longest a block or mutex profile samples for.
+const maxProfileTime = time.Minute
+
+var (
+	// gcPercent is what GOGC was last set to, since it can't be read without
+	// setting it. It starts as the GOGC environment variable says.
+	gcPercentMu sync.Mutex
+	gcPercent   = gogc(os.Getenv("GOGC"))
+
+	// sampleMu lets one block or mutex profile sample at a time, so each
+	// puts back the rate from before any of them.
+	sampleMu sync.Mutex
+	// blockRate is the block profile rate outside of sampling, which the
+	// runtime can't be asked for.
+	blockRate int
+)
+
+// gogc returns the GC percentage the runtime takes from a GOGC environment
+// variable of s: a number, -1 for "off", or 100.
+func gogc(s string) int {
+	if s == "off" {
+		return -1
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return 100
+}
+
+// SetBlockProfileRate is runtime.SetBlockProfileRate, for programs that want
+// blocking profiled all along, so the block profile endpoint puts the rate
+// back after sampling.
+func SetBlockProfileRate(rate int) {
+	sampleMu.Lock()
+	defer sampleMu.Unlock()
+	blockRate = rate
+	runtime.SetBlockProfileRate(rate)
+}
+
+func init() {
+	http.HandleFunc("GET "+DebugPrefix+"{$}", func(w http.ResponseWriter, r *http.Request) {
+		debugMu.Lock()
+		routes := make([]string, 0, len(debugRoutes))
+		for route, usage := range debugRoutes {
+			routes = append(routes, route+"\t"+usage)
+		}
+		debugMu.Unlock()
+		slices.Sort(routes)
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, route := range routes {
+			fmt.Fprintln(w, route)
+		}
+		fmt.Fprintln(w, "/debug/pprof/\tnet/http/pprof's profiles and traces")
+		fmt.Fprintln(w, "/debug/vars\texpvar's variables as JSON")
+	})
+
+	HandleDebug("gc", "POST runs a garbage collection and returns freed memory to the OS", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "POST to run a garbage collection", http.StatusMethodNotAllowed)
+			return
+		}
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		debug.FreeOSMemory()
+		took := time.Since(start)
+		runtime.ReadMemStats(&after)
+
+		slog.Info("ran garbage collection", "heap_before", before.HeapAlloc, "heap_after", after.HeapAlloc, "took", took)
+		fmt.Fprintf(w, "heap %d -> %d bytes, released to OS %d -> %d bytes, took %s\n",
+			before.HeapAlloc, after.HeapAlloc, before.HeapReleased, after.HeapReleased, took)
+	}))
+
+	HandleDebug("profile/{name}", "GET writes the heap, allocs, goroutine, block, mutex or threadcreate profile; ?gc=1 collects garbage first, ?seconds=N samples block or mutex contention for N seconds, ?debug=1 is text", http.HandlerFunc(serveProfile))
+
+	HandleDebug("gomaxprocs", "GET shows GOMAXPROCS, POST of a positive number sets it", intSetting(
+		func() int { return runtime.GOMAXPROCS(0) },
+		func(n int) error {
+			if n < 1 {
+				return errors.New("GOMAXPROCS must be positive")
+			}
+			runtime.GOMAXPROCS(n)
+			return nil
+		},
+	))
+
+	HandleDebug("gc-percent", "GET shows GOGC, POST sets it, -1 turning the collector off", intSetting(
+		func() int {
+			gcPercentMu.Lock()
+			defer gcPercentMu.Unlock()
+			return gcPercent
+		},
+		func(n int) error {
+			gcPercentMu.Lock()
+			defer gcPercentMu.Unlock()
+			debug.SetGCPercent(n)
+			gcPercent = n
+			return nil
+		},
+	))
+}
+
+// serveProfile writes the runtime/pprof profile named in the request.
+func serveProfile(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p := pprof.Lookup(name)
+	if p == nil || !slices.Contains([]string{"heap", "allocs", "goroutine", "block", "mutex", "threadcreate"}, name) {
+		http.Error(w, fmt.Sprintf("no profile %q", name), http.StatusNotFound)
+		return
+	}
+	q := r.URL.Query()
+	debugLevel, _ := strconv.Atoi(q.Get("debug"))
+
+	if gc, _ := strconv.Atoi(q.Get("gc")); gc > 0 && (name == "heap" || name == "allocs") {
+		runtime.GC()
+	}
+	if s := q.Get("seconds"); s != "" {
+		if name != "block" && name != "mutex" {
+			http.Error(w, "seconds is for the block and mutex profiles", http.StatusBadRequest)
+			return
+		}
+		secs, err := strconv.Atoi(s)
+		if err != nil || secs < 1 || time.Duration(secs)*time.Second > maxProfileTime {
+			http.Error(w, fmt.Sprintf("seconds must be between 1 and %d", int(maxProfileTime.Seconds())), http.StatusBadRequest)
+			return
+		}
+		// Sampling slows the program down, so it's only on while asked for
+		sampleMu.Lock()
+		defer sampleMu.Unlock()
+		if name == "block" {
+			runtime.SetBlockProfileRate(1)
+			defer runtime.SetBlockProfileRate(blockRate)
+		} else {
+			old := runtime.SetMutexProfileFraction(1)
+			defer runtime.SetMutexProfileFraction(old)
+		}
+		select {
+		case <-time.After(time.Duration(secs) * time.Second):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if debugLevel > 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".pprof"))
+	}
+	if err := p.WriteTo(w, debugLevel); err != nil {
+		slog.Error("can't write profile", "profile", name, "err", err)
+	}
+}
+
+// intSetting serves a number: GET shows what get returns, and POST of a
+// number sets it with set and shows the old and new values.
+func intSetting(get func() int, set func(int) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			fmt.Fprintln(w, get())
+		case http.MethodPost:
+			data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 64))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				http.Error(w, "not a number: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			old := get()
+			if err := set(n); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			slog.Info("changed runtime setting", "path", r.URL.Path, "from", old, "to", n)
+			fmt.Fprintf(w, "%d -> %d\n", old, n)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "GET or POST", http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDebugEndpoints(t *testing.T) {
+	HandleDebug("test", "GET answers tests", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do(http.MethodGet, DebugPrefix, "")
+	for _, want := range []string{DebugPrefix + "test\tGET answers tests\n", DebugPrefix + "gc\t", DebugPrefix + "profile/{name}\t", "/debug/pprof/\t"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("index doesn't list %q:\n%s", want, rec.Body)
+		}
+	}
+
+	if rec := do(http.MethodGet, DebugPrefix+"gc", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET gc: %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, DebugPrefix+"gc", ""); rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "heap ") {
+		t.Errorf("POST gc: %d %q", rec.Code, rec.Body)
+	}
+
+	if rec := do(http.MethodGet, DebugPrefix+"profile/goroutine?debug=1", ""); !strings.Contains(rec.Body.String(), "TestDebugEndpoints") {
+		t.Errorf("goroutine profile doesn't have this test:\n%s", rec.Body)
+	}
+	if rec := do(http.MethodGet, DebugPrefix+"profile/heap?gc=1", ""); rec.Header().Get("Content-Disposition") != `attachment; filename="heap.pprof"` || rec.Body.Len() == 0 {
+		t.Errorf("heap profile: %v, %d bytes", rec.Header(), rec.Body.Len())
+	}
+	for _, path := range []string{"profile/cpu", "profile/heap?seconds=1", "profile/block?seconds=600"} {
+		if rec := do(http.MethodGet, DebugPrefix+path, ""); rec.Code < 400 {
+			t.Errorf("%s: %d", path, rec.Code)
+		}
+	}
+
+	procs := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(procs)
+	if rec := do(http.MethodGet, DebugPrefix+"gomaxprocs", ""); rec.Body.String() != strconv.Itoa(procs)+"\n" {
+		t.Errorf("GET gomaxprocs = %q, want %d", rec.Body, procs)
+	}
+	if rec := do(http.MethodPost, DebugPrefix+"gomaxprocs", "1\n"); rec.Code != http.StatusOK || runtime.GOMAXPROCS(0) != 1 {
+		t.Errorf("POST gomaxprocs: %d %q", rec.Code, rec.Body)
+	}
+	for _, body := range []string{"0", "many"} {
+		if rec := do(http.MethodPost, DebugPrefix+"gomaxprocs", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("POST gomaxprocs %q: %d", body, rec.Code)
+		}
+	}
+
+	old := strings.TrimSpace(do(http.MethodGet, DebugPrefix+"gc-percent", "").Body.String())
+	defer do(http.MethodPost, DebugPrefix+"gc-percent", old)
+	if rec := do(http.MethodPost, DebugPrefix+"gc-percent", "250"); rec.Body.String() != old+" -> 250\n" {
+		t.Errorf("POST gc-percent: %q", rec.Body)
+	}
+	if rec := do(http.MethodGet, DebugPrefix+"gc-percent", ""); rec.Body.String() != "250\n" {
+		t.Errorf("GET gc-percent after setting = %q", rec.Body)
+	}
+}
+
+func TestGOGC(t *testing.T) {
+	for _, tc := range []struct {
+		env  string
+		want int
+	}{
+		{"", 100},
+		{"50", 50},
+		{"off", -1},
+		{"lots", 100},
+	} {
+		if got := gogc(tc.env); got != tc.want {
+			t.Errorf("gogc(%q) = %d, want %d", tc.env, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package httpserver serves HTTP with the timeouts, graceful shutdown and
+// debug endpoints the commands here all want.
+package httpserver
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var debugListen = flag.String("debug-listen", "", "address to serve the pprof, expvar and /.jsn/debug endpoints on, like localhost:6060 (empty disables)")
+
+// Listen listens on addr, which is one of:
+//
+//   - "host:port" or ":port" on TCP, both IPv4 and IPv6 for a wildcard host
+//   - "tcp4:host:port" or "tcp6:host:port" on one address family only
+//   - "unix:<path>" on a unix socket
+//
+// A socket left at a unix socket's path by an unclean exit is replaced, but
+// anything else there is left alone.
+func Listen(addr string) (net.Listener, error) {
+	network, rest, ok := strings.Cut(addr, ":")
+	switch {
+	case ok && (network == "tcp" || network == "tcp4" || network == "tcp6"):
+		return net.Listen(network, rest)
+	case ok && network == "unix":
+		if rest == "" {
+			return nil, errors.New("unix: needs a socket path")
+		}
+		if fi, err := os.Lstat(rest); err == nil && fi.Mode().Type() == fs.ModeSocket {
+			if err := os.Remove(rest); err != nil {
+				return nil, fmt.Errorf("failed to remove stale socket: %v", err)
+			}
+		}
+		return net.Listen("unix", rest)
+	default:
+		return net.Listen("tcp", addr)
+	}
+}
+
+// Server serves Handler until it's told to stop, then shuts down gracefully.
+type Server struct {
+	Handler http.Handler
+	// Addr is listened on with Listen, unless Listeners are given.
+	Addr      string
+	Listeners []net.Listener
+
+	// TLSConfig, or CertFile and KeyFile, make the server serve HTTPS.
+	TLSConfig         *tls.Config
+	CertFile, KeyFile string
+
+	// ReadHeaderTimeout is 10 seconds if zero; the others are as for
+	// http.Server, with zero meaning no limit.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// ShutdownTimeout is how long requests in flight get to finish when
+	// stopping, 10 seconds if zero.
+	ShutdownTimeout time.Duration
+	// OnShutdown are called as shutdown starts, to close connections
+	// Shutdown doesn't track, like hijacked ones.
+	OnShutdown []func()
+
+	// Logger is slog.Default() if nil.
+	Logger *slog.Logger
+}
+
+// ListenAndServe serves until ctx is done, like run.Context() on SIGINT or
+// SIGTERM, or a listener fails, whose error it returns. Requests in flight
+// then get ShutdownTimeout to finish. With -debug-listen, the endpoints
+// registered on http.DefaultServeMux are served there too, once per
+// process.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	lg := cmp.Or(s.Logger, slog.Default())
+	lns := s.Listeners
+	if len(lns) == 0 {
+		ln, err := Listen(s.Addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", s.Addr, err)
+		}
+		lns = []net.Listener{ln}
+	}
+
+	srv := &http.Server{
+		Handler:           s.Handler,
+		TLSConfig:         s.TLSConfig,
+		ReadHeaderTimeout: cmp.Or(s.ReadHeaderTimeout, 10*time.Second),
+		ReadTimeout:       s.ReadTimeout,
+		WriteTimeout:      s.WriteTimeout,
+		IdleTimeout:       s.IdleTimeout,
+		MaxHeaderBytes:    s.MaxHeaderBytes,
+	}
+	for _, f := range s.OnShutdown {
+		srv.RegisterOnShutdown(f)
+	}
+	useTLS := s.TLSConfig != nil || s.CertFile != ""
+
+	errs := make(chan error, len(lns))
+	for _, ln := range lns {
+		go func() {
+			if useTLS {
+				errs <- srv.ServeTLS(ln, s.CertFile, s.KeyFile)
+			} else {
+				errs <- srv.Serve(ln)
+			}
+		}()
+	}
+	debugOnce.Do(func() { serveDebug(lg) })
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+
+	timeout := cmp.Or(s.ShutdownTimeout, 10*time.Second)
+	lg.Info("shutting down, waiting for requests in flight", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if serr := srv.Shutdown(shutdownCtx); serr != nil {
+		lg.Warn("not all requests finished", "err", serr)
+	}
+	return err
+}
+
+var debugOnce sync.Once
+
+// serveDebug serves http.DefaultServeMux, where net/http/pprof, expvar and
+// package internal register their handlers, on -debug-listen.
+func serveDebug(lg *slog.Logger) {
+	if *debugListen == "" {
+		return
+	}
+	ln, err := Listen(*debugListen)
+	if err != nil {
+		lg.Error("can't serve debug endpoints", "addr", *debugListen, "err", err)
+		return
+	}
+	lg.Info("serving debug endpoints", "addr", ln.Addr().String())
+	srv := &http.Server{Handler: http.DefaultServeMux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+}
@@ -0,0 +1,115 @@
+package httpserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListen(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:0", "tcp4:127.0.0.1:0"} {
+		ln, err := Listen(addr)
+		if err != nil {
+			t.Fatalf("Listen(%q): %v", addr, err)
+		}
+		if ln.Addr().Network() != "tcp" {
+			t.Errorf("Listen(%q) on %s", addr, ln.Addr().Network())
+		}
+		ln.Close()
+	}
+
+	dir := t.TempDir()
+	sock := filepath.Join(dir, "serve.sock")
+	ln, err := Listen("unix:" + sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A socket left behind by an unclean exit is replaced
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	ln, err = Listen("unix:" + sock)
+	if err != nil {
+		t.Fatalf("stale socket: %v", err)
+	}
+	ln.Close()
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen("unix:" + file); err == nil {
+		t.Error("listened over a regular file")
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("regular file removed: %v", err)
+	}
+	if _, err := Listen("unix:"); err == nil {
+		t.Error("listened on an empty socket path")
+	}
+}
+
+func TestListenAndServe(t *testing.T) {
+	ln, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	shutdown := make(chan struct{})
+	s := &Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			io.WriteString(w, "done")
+		}),
+		Listeners:  []net.Listener{ln},
+		OnShutdown: []func(){func() { close(shutdown) }},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- s.ListenAndServe(ctx) }()
+
+	body := make(chan string)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+	cancel()
+	<-shutdown
+	// The request in flight finishes before ListenAndServe returns
+	close(release)
+	if got := <-body; got != "done" {
+		t.Errorf("request in flight got %q", got)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("ListenAndServe: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenAndServe didn't return after cancel")
+	}
+	if _, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		t.Error("still listening after shutdown")
+	}
+}
+
+func TestListenAndServeListenError(t *testing.T) {
+	err := (&Server{Addr: "unix:"}).ListenAndServe(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to listen on unix:") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -2,32 +2,40 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
+	"log"
 	stdslog "log/slog"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 
 	"pkg.jsn.cam/jsn"
 
 	"github.com/posener/complete"
 	"go4.org/legal"
 	"pkg.jsn.cam/jsn/flagenv"
+	"pkg.jsn.cam/jsn/internal/flagconfyg"
+	"pkg.jsn.cam/jsn/internal/flagfolder"
 	"pkg.jsn.cam/jsn/internal/manpage"
+	"pkg.jsn.cam/jsn/internal/run"
 	"pkg.jsn.cam/jsn/internal/slog"
 
 	// Debug routes
 	_ "expvar"
 	_ "net/http/pprof"
-
-	// Older projects use .env files, shim in compatibility
-	_ "github.com/joho/godotenv/autoload"
 )
 
 var (
 	licenseShow = flag.Bool("license", false, "show software licenses?")
-	//config      = flag.String("config", configFileLocation(), "configuration file, if set (see flagconfyg(4))")
-	manpageGen = flag.Bool("manpage", false, "generate a manpage template?")
+	flagConfig  = flag.String("flag-config", configFileLocation(), "file of flag names and values to set flags from, one per line like listen \":8080\"; skipped if missing unless set")
+	secretsDir  = flag.String("secrets-dir", "/run/secrets", "directory of files named after flags to set them from, like mounted container secrets")
+	manpageGen  = flag.Bool("manpage", false, "generate a manpage template?")
+	printEnv    = flag.Bool("print-env", false, "show where each flag's value came from and exit")
+	showVersion = flag.Bool("version", false, "show the version, VCS revision and build date and exit")
 )
 
 func configFileLocation() string {
@@ -38,8 +46,6 @@ func configFileLocation() string {
 	}
 
 	dir = filepath.Join(dir, "jsn.cam", "jsn")
-	os.MkdirAll(dir, 0700)
-
 	return filepath.Join(dir, filepath.Base(os.Args[0])+".config")
 }
 
@@ -47,20 +53,48 @@ func configFileLocation() string {
 // This always loads from the following configuration sources in the following
 // order:
 //
-//   - command line flags (to get -config)
+//   - command line flags (to get -flag-config)
 //   - environment variables
-//   - any secrets mounted to /run/secrets
-//   - configuration file (if -config is set)
-//   - command line flags
+//   - .env files, see flagenv.DefaultFiles
+//   - any secrets mounted to /run/secrets (-secrets-dir), see flagfolder
+//   - configuration file (-flag-config), see flagconfyg
 //
-// This is done this way to ensure that command line flags always are the deciding
-// factor as an escape hatch, at the cost of potentially evaluating flags twice.
+// Each source only sets the flags the ones before it didn't, so command line
+// flags always are the deciding factor as an escape hatch. Sources skip flags
+// rather than the command line being parsed again, which would repeat the
+// values of flags that collect them, like flagenv.Slice.
 func HandleStartup() {
 	flag.Parse()
+	if err := flagenv.LoadFiles(flagenv.DefaultFiles(filepath.Base(os.Args[0]))...); err != nil {
+		log.Fatal(err)
+	}
 	flagenv.Parse()
-	//flagfolder.Parse()
-	flag.Parse() // parse again to ensure that the flags are the last source of truth
+
+	given := map[string]string{}
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = "command line" })
+	flag.VisitAll(func(f *flag.Flag) {
+		if _, ok := flagenv.Lookup(flagenv.Prefix, f.Name); ok {
+			given[f.Name] = "environment"
+		}
+	})
+	if err := flagfolder.ParseSet(*secretsDir, flagenv.Prefix, flag.CommandLine, given); err != nil {
+		log.Fatal(err)
+	}
+	if err := flagconfyg.ParseFile(*flagConfig, flag.CommandLine, given); err != nil {
+		// The default file is optional
+		if !errors.Is(err, fs.ErrNotExist) || given["flag-config"] != "" {
+			log.Fatal(err)
+		}
+	}
 	slog.Init()
+	// Registered first so it runs last, after whatever else logs on the way out
+	run.OnShutdown("logs", func(context.Context) error { return slog.Close() })
+
+	if *showVersion {
+		bi, _ := debug.ReadBuildInfo()
+		printVersion(os.Stdout, filepath.Base(os.Args[0]), bi)
+		os.Exit(0)
+	}
 
 	if *licenseShow {
 		fmt.Printf("Licenses for %v\n", os.Args)
@@ -73,6 +107,11 @@ func HandleStartup() {
 		os.Exit(0)
 	}
 
+	if *printEnv {
+		flagenv.PrintSources(os.Stdout, flagenv.Prefix, flag.CommandLine, given)
+		os.Exit(0)
+	}
+
 	if *manpageGen {
 		manpage.Spew()
 	}
@@ -0,0 +1,631 @@
+// Package markdown renders the common subset of GitHub flavoured markdown
+// READMEs use to safe HTML.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Render converts README markdown to HTML. Only the markup it
+// generates itself is emitted: raw HTML in the source is dropped, text is
+// escaped and links are limited to http(s), mailto and relative URLs.
+// Relative URLs are resolved against dir under linkBase (pages) and rawBase
+// (images), or against the bases themselves if they start with a slash.
+func Render(src, linkBase, rawBase, dir string) string {
+	md := &markdown{
+		refs:     make(map[string]string),
+		linkBase: linkBase,
+		rawBase:  rawBase,
+		dir:      dir,
+	}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	lines = md.collectRefs(lines)
+
+	var b strings.Builder
+	md.blocks(&b, lines)
+	return b.String()
+}
+
+type markdown struct {
+	refs     map[string]string
+	linkBase string
+	rawBase  string
+	dir      string
+}
+
+var (
+	refDefRe   = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:\s*<?(\S+?)>?(?:\s+["'(].*["')])?\s*$`)
+	headingRe  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	ruleRe     = regexp.MustCompile(`^ {0,3}([-*_])(?:\s*[-*_]){2,}\s*$`)
+	fenceRe    = regexp.MustCompile("^ {0,3}(```+|~~~+)\\s*([^`\\s]*)")
+	bulletRe   = regexp.MustCompile(`^( {0,3})([-*+])\s+(.*)$`)
+	orderedRe  = regexp.MustCompile(`^( {0,3})(\d{1,9})[.)]\s+(.*)$`)
+	tableSepRe = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	htmlLineRe = regexp.MustCompile(`^\s*</?[A-Za-z!][^>]*>?\s*$|^\s*<!--`)
+	tagRe      = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?>|<!--.*?-->`)
+)
+
+// collectRefs removes reference link definitions, remembering their URLs
+func (md *markdown) collectRefs(lines []string) []string {
+	out := lines[:0:0]
+	inFence := false
+	for _, line := range lines {
+		if fenceRe.MatchString(line) {
+			inFence = !inFence
+		}
+		if !inFence {
+			if m := refDefRe.FindStringSubmatch(line); m != nil {
+				md.refs[strings.ToLower(m[1])] = m[2]
+				continue
+			}
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+func (md *markdown) blocks(b *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case fenceRe.MatchString(line):
+			m := fenceRe.FindStringSubmatch(line)
+			fence := m[1]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			i++ // closing fence
+
+			b.WriteString("<pre><code")
+			if m[2] != "" {
+				b.WriteString(` class="language-` + html.EscapeString(m[2]) + `"`)
+			}
+			b.WriteString(">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			md.heading(b, len(m[1]), m[2])
+			i++
+
+		case ruleRe.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(q, " "))
+			}
+			b.WriteString("<blockquote>\n")
+			md.blocks(b, quote)
+			b.WriteString("</blockquote>\n")
+
+		case bulletRe.MatchString(line) || orderedRe.MatchString(line):
+			i = md.list(b, lines, i)
+
+		case strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t"):
+			var code []string
+			for ; i < len(lines) && (strings.TrimSpace(lines[i]) == "" || strings.HasPrefix(lines[i], "    ") || strings.HasPrefix(lines[i], "\t")); i++ {
+				l := strings.TrimPrefix(lines[i], "\t")
+				if l == lines[i] {
+					l = strings.TrimPrefix(l, "    ")
+				}
+				code = append(code, l)
+			}
+			b.WriteString("<pre><code>" + html.EscapeString(strings.TrimRight(strings.Join(code, "\n"), "\n")) + "</code></pre>\n")
+
+		case strings.Contains(line, "|") && i+1 < len(lines) && tableSepRe.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			i = md.table(b, lines, i)
+
+		case htmlLineRe.MatchString(line):
+			// Raw HTML blocks are dropped rather than sanitized
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+			}
+
+		default:
+			var para []string
+			for ; i < len(lines); i++ {
+				l := lines[i]
+				if strings.TrimSpace(l) == "" || fenceRe.MatchString(l) || headingRe.MatchString(l) ||
+					strings.HasPrefix(strings.TrimSpace(l), ">") || bulletRe.MatchString(l) || orderedRe.MatchString(l) {
+					break
+				}
+				// Setext headings underline the paragraph
+				if len(para) > 0 && isSetext(l) {
+					level := 1
+					if strings.TrimSpace(l)[0] == '-' {
+						level = 2
+					}
+					md.heading(b, level, strings.Join(para, " "))
+					para = nil
+					i++
+					break
+				}
+				if ruleRe.MatchString(l) {
+					break
+				}
+				para = append(para, strings.TrimSpace(l))
+			}
+			if len(para) > 0 {
+				b.WriteString("<p>" + md.inline(strings.Join(para, "\n")) + "</p>\n")
+			}
+		}
+	}
+}
+
+func isSetext(line string) bool {
+	t := strings.TrimSpace(line)
+	return t != "" && (strings.Trim(t, "=") == "" || strings.Trim(t, "-") == "")
+}
+
+func (md *markdown) heading(b *strings.Builder, level int, text string) {
+	n := strconv.Itoa(level)
+	b.WriteString("<h" + n + ` id="` + slugify(text) + `">` + md.inline(text) + "</h" + n + ">\n")
+}
+
+// list renders a bullet or ordered list starting at lines[i] and returns
+// the index after it. Item content indented past the marker is rendered as
+// blocks, which handles nested lists.
+func (md *markdown) list(b *strings.Builder, lines []string, i int) int {
+	ordered := orderedRe.MatchString(lines[i])
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+		if m := orderedRe.FindStringSubmatch(lines[i]); m[2] != "1" {
+			start, _ := strconv.Atoi(m[2])
+			b.WriteString(`<ol start="` + strconv.Itoa(start) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+
+	for i < len(lines) {
+		var m []string
+		if ordered {
+			m = orderedRe.FindStringSubmatch(lines[i])
+		} else {
+			m = bulletRe.FindStringSubmatch(lines[i])
+		}
+		if m == nil {
+			break
+		}
+
+		item := []string{m[3]}
+		indent := len(m[1]) + 2
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" {
+				// A blank line continues the item only if indented content follows
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) >= indent {
+					item = append(item, "")
+					continue
+				}
+				break
+			}
+			if leadingSpaces(l) < indent && (bulletRe.MatchString(l) || orderedRe.MatchString(l) || leadingSpaces(l) == 0 && len(item) > 0 && item[len(item)-1] == "") {
+				break
+			}
+			item = append(item, strings.TrimPrefix(l, strings.Repeat(" ", min(indent, leadingSpaces(l)))))
+		}
+
+		b.WriteString("<li>")
+		if len(item) == 1 || !hasBlockContent(item[1:]) {
+			b.WriteString(md.inline(strings.Join(item, "\n")))
+		} else {
+			b.WriteString("\n")
+			md.blocks(b, item)
+		}
+		b.WriteString("</li>\n")
+
+		// Skip blank lines between items of the same list
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" && i+1 < len(lines) && sameListKind(lines[i+1], ordered) {
+			i++
+		}
+	}
+
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func sameListKind(line string, ordered bool) bool {
+	if ordered {
+		return orderedRe.MatchString(line)
+	}
+	return bulletRe.MatchString(line)
+}
+
+func hasBlockContent(lines []string) bool {
+	for _, l := range lines {
+		if l == "" || bulletRe.MatchString(l) || orderedRe.MatchString(l) || fenceRe.MatchString(l) {
+			return true
+		}
+	}
+	return false
+}
+
+func leadingSpaces(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
+
+// table renders a GFM table starting at lines[i]
+func (md *markdown) table(b *strings.Builder, lines []string, i int) int {
+	header := splitRow(lines[i])
+	var align []string
+	for _, c := range splitRow(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(c, ":") && strings.HasSuffix(c, ":"):
+			align = append(align, "center")
+		case strings.HasSuffix(c, ":"):
+			align = append(align, "right")
+		case strings.HasPrefix(c, ":"):
+			align = append(align, "left")
+		default:
+			align = append(align, "")
+		}
+	}
+
+	cell := func(tag, text string, col int) {
+		b.WriteString("<" + tag)
+		if col < len(align) && align[col] != "" {
+			b.WriteString(` align="` + align[col] + `"`)
+		}
+		b.WriteString(">" + md.inline(text) + "</" + tag + ">")
+	}
+
+	b.WriteString("<table>\n<thead><tr>")
+	for col, h := range header {
+		cell("th", h, col)
+	}
+	b.WriteString("</tr></thead>\n<tbody>\n")
+
+	for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+		b.WriteString("<tr>")
+		for col, c := range splitRow(lines[i]) {
+			cell("td", c, col)
+		}
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+
+	var cells []string
+	var cur strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cur.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cur.String()))
+}
+
+// inline renders emphasis, code spans, links and images in text
+func (md *markdown) inline(text string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && strings.IndexByte("\\`*_{}[]()#+-.!|~<>", text[i+1]) >= 0:
+			b.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+
+		case c == '`':
+			n := countRun(text[i:], '`')
+			fence := text[i : i+n]
+			end := strings.Index(text[i+n:], fence)
+			if end < 0 {
+				b.WriteString(fence)
+				i += n
+				break
+			}
+			code := strings.TrimSpace(text[i+n : i+n+end])
+			b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+			i += n + end + n
+
+		case c == '!' && i+1 < len(text) && text[i+1] == '[':
+			if alt, dest, n, ok := md.link(text[i+1:]); ok {
+				if u, ok := md.resolve(dest, md.rawBase); ok {
+					b.WriteString(`<img src="` + html.EscapeString(u) + `" alt="` + html.EscapeString(stripMarkup(alt)) + `">`)
+				} else {
+					b.WriteString(html.EscapeString(stripMarkup(alt)))
+				}
+				i += 1 + n
+				break
+			}
+			b.WriteString("!")
+			i++
+
+		case c == '[':
+			if label, dest, n, ok := md.link(text[i:]); ok {
+				inner := md.inline(label)
+				if u, ok := md.resolve(dest, md.linkBase); ok {
+					b.WriteString(`<a href="` + html.EscapeString(u) + `" rel="nofollow">` + inner + "</a>")
+				} else {
+					b.WriteString(inner)
+				}
+				i += n
+				break
+			}
+			b.WriteString("[")
+			i++
+
+		case c == '<':
+			// Autolinks, otherwise inline HTML is dropped
+			if end := strings.IndexByte(text[i:], '>'); end > 0 {
+				inner := text[i+1 : i+end]
+				if strings.HasPrefix(inner, "http://") || strings.HasPrefix(inner, "https://") {
+					if u, ok := md.resolve(inner, ""); ok {
+						b.WriteString(`<a href="` + html.EscapeString(u) + `" rel="nofollow">` + html.EscapeString(inner) + "</a>")
+						i += end + 1
+						break
+					}
+				}
+				if loc := tagRe.FindStringIndex(text[i:]); loc != nil && loc[0] == 0 {
+					if strings.HasPrefix(strings.ToLower(text[i:]), "<br") {
+						b.WriteString("<br>")
+					}
+					i += loc[1]
+					break
+				}
+			}
+			b.WriteString("&lt;")
+			i++
+
+		case c == '*' || c == '_' || c == '~':
+			n := countRun(text[i:], c)
+			if c == '~' && n != 2 {
+				b.WriteByte(c)
+				i++
+				break
+			}
+			if n > 2 {
+				n = 2
+			}
+			marker := text[i : i+n]
+			// _ inside words is literal, as in snake_case
+			if c == '_' && i > 0 && isWordByte(text[i-1]) {
+				b.WriteString(marker)
+				i += n
+				break
+			}
+			end := closingMarker(text[i+n:], marker)
+			if end <= 0 {
+				b.WriteString(marker)
+				i += n
+				break
+			}
+
+			tag := "em"
+			switch {
+			case c == '~':
+				tag = "del"
+			case n == 2:
+				tag = "strong"
+			}
+			b.WriteString("<" + tag + ">" + md.inline(text[i+n:i+n+end]) + "</" + tag + ">")
+			i += n + end + n
+
+		case c == '\n':
+			// Two trailing spaces make a hard break
+			if strings.HasSuffix(b.String(), "  ") {
+				b.WriteString("<br>")
+			}
+			b.WriteByte('\n')
+			i++
+
+		default:
+			j := i + 1
+			for j < len(text) && strings.IndexByte("\\`![<*_~\n", text[j]) < 0 {
+				j++
+			}
+			b.WriteString(html.EscapeString(text[i:j]))
+			i = j
+		}
+	}
+
+	return b.String()
+}
+
+// link parses [label](dest), [label][ref] or [ref] at the start of s and
+// returns the number of bytes consumed
+func (md *markdown) link(s string) (label, dest string, n int, ok bool) {
+	depth := 0
+	end := -1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+		if end >= 0 {
+			break
+		}
+	}
+	if end < 0 {
+		return "", "", 0, false
+	}
+	label = s[1:end]
+	rest := s[end+1:]
+
+	switch {
+	case strings.HasPrefix(rest, "("):
+		close := matchingParen(rest)
+		if close < 0 {
+			return "", "", 0, false
+		}
+		dest = strings.TrimSpace(rest[1:close])
+		// Drop an optional title
+		if sp := strings.IndexAny(dest, " \t"); sp > 0 {
+			dest = dest[:sp]
+		}
+		dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+		return label, dest, end + 1 + close + 1, true
+
+	case strings.HasPrefix(rest, "["):
+		close := strings.IndexByte(rest, ']')
+		if close < 0 {
+			return "", "", 0, false
+		}
+		ref := rest[1:close]
+		if ref == "" {
+			ref = label
+		}
+		if d, ok := md.refs[strings.ToLower(ref)]; ok {
+			return label, d, end + 1 + close + 1, true
+		}
+
+	default:
+		if d, ok := md.refs[strings.ToLower(label)]; ok {
+			return label, d, end + 1, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// resolve checks that u is safe to link to and makes it absolute
+func (md *markdown) resolve(u, base string) (string, bool) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", false
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https", "mailto":
+		return parsed.String(), true
+	case "":
+	default:
+		return "", false
+	}
+
+	if strings.HasPrefix(u, "#") || base == "" {
+		return parsed.String(), true
+	}
+
+	// Leading slashes are relative to the repository, not the forge
+	base = strings.TrimSuffix(base, "/") + "/"
+	if strings.HasPrefix(parsed.Path, "/") {
+		parsed.Path = strings.TrimLeft(parsed.Path, "/")
+	} else if md.dir != "" {
+		base += strings.Trim(md.dir, "/") + "/"
+	}
+
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", false
+	}
+	return b.ResolveReference(parsed).String(), true
+}
+
+func matchingParen(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func closingMarker(s, marker string) int {
+	for i := 0; i+len(marker) <= len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				i += end + 1
+			}
+		case strings.HasPrefix(s[i:], marker):
+			// Closers can't follow whitespace, and _ can't close mid-word
+			if i == 0 || s[i-1] == ' ' {
+				continue
+			}
+			if marker[0] == '_' && i+len(marker) < len(s) && isWordByte(s[i+len(marker)]) {
+				continue
+			}
+			// ** must not match the first half of a longer run
+			if len(marker) == 1 && i+1 < len(s) && s[i+1] == marker[0] {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+func countRun(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// stripMarkup turns inline markdown into plain text for alt attributes
+func stripMarkup(s string) string {
+	s = tagRe.ReplaceAllString(s, "")
+	return strings.NewReplacer("*", "", "_", "", "`", "", "~~", "").Replace(s)
+}
+
+// slugify makes GitHub-style heading anchors
+func slugify(s string) string {
+	s = stripMarkup(s)
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return html.EscapeString(b.String())
+}
@@ -0,0 +1,63 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	const link, raw = "https://github.com/u/r/blob/main", "https://github.com/u/r/raw/main"
+
+	for _, tt := range []struct {
+		name, in, want string
+	}{
+		{"heading", "# Hello *world*", `<h1 id="hello-world">Hello <em>world</em></h1>`},
+		{"paragraph", "a **b** `c<d>`", "<p>a <strong>b</strong> <code>c&lt;d&gt;</code></p>"},
+		{"snake case", "use foo_bar_baz", "<p>use foo_bar_baz</p>"},
+		{"fence", "```go\nx := <-ch\n```", `<pre><code class="language-go">x := &lt;-ch</code></pre>`},
+		{"list", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>"},
+		{"nested list", "- a\n  - b", "<ul>\n<li>\n<p>a</p>\n<ul>\n<li>b</li>\n</ul>\n</li>\n</ul>"},
+		{"relative link", "[docs](docs/x.md)", `<a href="https://github.com/u/r/blob/main/docs/x.md" rel="nofollow">docs</a>`},
+		{"root link", "[license](/LICENSE)", `<a href="https://github.com/u/r/blob/main/LICENSE" rel="nofollow">`},
+		{"relative image", "![logo](logo.png)", `<img src="https://github.com/u/r/raw/main/logo.png" alt="logo">`},
+		{"reference", "[![badge][b]][b]\n\n[b]: https://example.com/b", `<a href="https://example.com/b" rel="nofollow"><img src="https://example.com/b" alt="badge"></a>`},
+		{"table", "| a | b |\n|---|--:|\n| 1 | 2 |", `<td align="right">2</td>`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.in, link, raw, "")
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("Render(%q) = %q, want it to contain %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownSanitizes(t *testing.T) {
+	for _, in := range []string{
+		"<script>alert(1)</script>",
+		"<div onclick=\"alert(1)\">\nhi\n</div>",
+		"text <img src=x onerror=alert(1)> text",
+		"[x](javascript:alert(1))",
+		"![x](javascript:alert(1))",
+		"[x](JaVaScRiPt:alert(1))",
+		"[x]: data:text/html,<script>\n\n[x]",
+		"<a href=\"javascript:alert(1)\">x</a>",
+		"[x](\"onmouseover=alert(1))",
+	} {
+		got := Render(in, "https://example.com", "https://example.com", "")
+		for _, bad := range []string{"<script", "<div", "onerror=", "onclick=", "javascript:", "JaVaScRiPt:", "data:", `"onmouseover`} {
+			if strings.Contains(got, bad) {
+				t.Errorf("Render(%q) = %q, contains %q", in, got, bad)
+			}
+		}
+	}
+}
+
+func TestRenderMarkdownSubdir(t *testing.T) {
+	got := Render("[a](a.md) [b](../b.md) [c](/c.md)", "https://x/blob/main", "https://x/raw/main", "cmd/tool")
+	for _, want := range []string{"https://x/blob/main/cmd/tool/a.md", "https://x/blob/main/cmd/b.md", "https://x/blob/main/c.md"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got %q, want it to contain %q", got, want)
+		}
+	}
+}
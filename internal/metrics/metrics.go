@@ -0,0 +1,185 @@
+// Package metrics records Prometheus metrics for the requests an HTTP server
+// answers.
+package metrics
+
+import (
+	"cmp"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefSizeBuckets are the response size histogram's buckets, in bytes, if
+// Options.SizeBuckets is nil.
+var DefSizeBuckets = []float64{100, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8}
+
+// Options configure a Middleware.
+type Options struct {
+	// Namespace prefixes the metric names, as namespace_requests_total.
+	Namespace string
+	// Registerer is prometheus.DefaultRegisterer if nil. Each Middleware
+	// registers its metrics, so only one can use a Registerer per Namespace.
+	Registerer prometheus.Registerer
+
+	// Path names a request's path label. With nil, the metrics have no path
+	// label, which keeps their cardinality lowest. Names should be few, like
+	// the ones FirstSegment and Pattern give, and past MaxPaths different
+	// names, the rest are labelled "other".
+	Path     func(*http.Request) string
+	MaxPaths int // 100 if zero
+
+	// Buckets for the duration and response size histograms, or
+	// prometheus.DefBuckets and DefSizeBuckets if nil.
+	DurationBuckets []float64
+	SizeBuckets     []float64
+}
+
+// Middleware records the number of requests by method and status code, and
+// histograms of how long they took and how big their responses were.
+type Middleware struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	size     *prometheus.HistogramVec
+
+	path     func(*http.Request) string
+	maxPaths int
+	mu       sync.Mutex
+	paths    map[string]bool
+}
+
+// New registers the metrics for a Middleware:
+//
+//   - requests_total, by method, code and path
+//   - request_duration_seconds, by method and path
+//   - response_size_bytes, the body as sent, by method and path
+func New(o Options) *Middleware {
+	var reg prometheus.Registerer = prometheus.DefaultRegisterer
+	if o.Registerer != nil {
+		reg = o.Registerer
+	}
+	f := promauto.With(reg)
+
+	durationBuckets, sizeBuckets := o.DurationBuckets, o.SizeBuckets
+	if durationBuckets == nil {
+		durationBuckets = prometheus.DefBuckets
+	}
+	if sizeBuckets == nil {
+		sizeBuckets = DefSizeBuckets
+	}
+	labels := []string{"method"}
+	if o.Path != nil {
+		labels = append(labels, "path")
+	}
+	return &Middleware{
+		requests: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.Namespace,
+			Name:      "requests_total",
+			Help:      "The total number of requests by method and response code",
+		}, append([]string{"code"}, labels...)),
+		duration: f.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.Namespace,
+			Name:      "request_duration_seconds",
+			Help:      "The duration of requests by method",
+			Buckets:   durationBuckets,
+		}, labels),
+		size: f.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.Namespace,
+			Name:      "response_size_bytes",
+			Help:      "The size of response bodies as sent, by method",
+			Buckets:   sizeBuckets,
+		}, labels),
+		path:     o.Path,
+		maxPaths: cmp.Or(o.MaxPaths, 100),
+		paths:    map[string]bool{},
+	}
+}
+
+// Wrap records the metrics for each request next answers.
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rr := &recorder{ResponseWriter: w, code: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rr, r)
+
+		labels := m.labels(r)
+		m.requests.WithLabelValues(append([]string{strconv.Itoa(rr.code)}, labels...)...).Inc()
+		m.duration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		m.size.WithLabelValues(labels...).Observe(float64(rr.bytes))
+	})
+}
+
+// labels returns the method and path label values for r.
+func (m *Middleware) labels(r *http.Request) []string {
+	labels := []string{Method(r.Method)}
+	if m.path == nil {
+		return labels
+	}
+	return append(labels, m.limitPath(m.path(r)))
+}
+
+// limitPath returns path, or "other" once maxPaths others have been seen.
+func (m *Middleware) limitPath(path string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.paths[path] {
+		return path
+	}
+	if len(m.paths) >= m.maxPaths {
+		return "other"
+	}
+	m.paths[path] = true
+	return path
+}
+
+// Method returns method if it's a standard HTTP method, and "other" if not,
+// since clients can send any.
+func Method(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+// FirstSegment labels a request by the first segment of its path, so
+// /repo/sub/pkg is "/repo".
+func FirstSegment(r *http.Request) string {
+	seg, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	return "/" + seg
+}
+
+// Pattern labels a request by the http.ServeMux pattern that matched it, or
+// "unmatched". The mux sets the pattern on the request it's given, so nothing
+// between Wrap and the mux may replace the request, as
+// http.Request.WithContext does.
+func Pattern(r *http.Request) string {
+	return cmp.Or(r.Pattern, "unmatched")
+}
+
+// recorder captures the status code and body size of a response.
+type recorder struct {
+	http.ResponseWriter
+	code  int
+	bytes int64
+}
+
+func (rr *recorder) WriteHeader(code int) {
+	rr.code = code
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *recorder) Write(p []byte) (int, error) {
+	n, err := rr.ResponseWriter.Write(p)
+	rr.bytes += int64(n)
+	return n, err
+}
+
+func (rr *recorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
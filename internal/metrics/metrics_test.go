@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLabels(t *testing.T) {
+	m := New(Options{Registerer: prometheus.NewRegistry(), Path: FirstSegment, MaxPaths: 2})
+	for _, tt := range []struct {
+		method, path string
+		want         []string
+	}{
+		{"GET", "/repo/sub/pkg?go-get=1", []string{"GET", "/repo"}},
+		{"HEAD", "/", []string{"HEAD", "/"}},
+		{"BREW", "/repo", []string{"other", "/repo"}},
+		// Past MaxPaths, new paths are lumped together
+		{"GET", "/scan/../../etc/passwd", []string{"GET", "other"}},
+		{"GET", "/", []string{"GET", "/"}},
+	} {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := m.labels(r); !slices.Equal(got, tt.want) {
+			t.Errorf("%s %s: labels %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	m = New(Options{Registerer: prometheus.NewRegistry()})
+	if got := m.labels(httptest.NewRequest("GET", "/repo", nil)); !slices.Equal(got, []string{"GET"}) {
+		t.Errorf("no Path: labels %q", got)
+	}
+}
+
+func TestPattern(t *testing.T) {
+	var pattern string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /files/{name}", func(w http.ResponseWriter, r *http.Request) {})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+		pattern = Pattern(r)
+	})
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/files/a.txt", nil))
+	if pattern != "GET /files/{name}" {
+		t.Errorf("matched: %q", pattern)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+	if pattern != "unmatched" {
+		t.Errorf("unmatched: %q", pattern)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	var rec *recorder
+	m := New(Options{Registerer: prometheus.NewRegistry()})
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec = w.(*recorder)
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+		if http.NewResponseController(w).Flush() != nil {
+			t.Error("recorder hides the ResponseWriter's Flush")
+		}
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if rec.code != http.StatusTeapot || rec.bytes != 15 || w.Body.String() != "short and stout" {
+		t.Errorf("recorded %d, %d bytes; sent %q", rec.code, rec.bytes, w.Body)
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// RecoverMiddleware answers requests whose handlers panic with a 500, and
+// logs the panic and its stack with the request's context, so with
+// RequestIDMiddleware outside it the log has the request ID the client was
+// sent. If the response had already started, the connection is dropped
+// instead, so the client can tell the response is incomplete.
+func RecoverMiddleware(lg *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(v)
+			}
+			lg.ErrorContext(r.Context(), "panic serving request",
+				"method", r.Method,
+				"host", r.Host,
+				"path", r.URL.Path,
+				"panic", fmt.Sprint(v),
+				"stack", string(debug.Stack()),
+			)
+			if sw.wrote {
+				panic(http.ErrAbortHandler)
+			}
+			// Headers meant for the response that didn't happen, like
+			// Cache-Control, shouldn't go with the error
+			h := w.Header()
+			for k := range h {
+				if k != "X-Request-Id" {
+					delete(h, k)
+				}
+			}
+			Error(sw, r, "internal server error", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	lg := slog.New(RequestIDHandler{slog.NewTextHandler(&logs, nil)})
+	h := RequestIDMiddleware("", lg, RecoverMiddleware(lg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=3600")
+		if r.URL.Path == "/late" {
+			w.Write([]byte("partial"))
+		}
+		var m map[string]int
+		m["boom"]++
+	})))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	id := rec.Header().Get("X-Request-Id")
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "(request ID "+id+")") {
+		t.Errorf("got %d %q", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Cache-Control") != "" {
+		t.Error("the handler's Cache-Control was sent with the error")
+	}
+	for _, want := range []string{`msg="panic serving request"`, "request_id=" + id, "assignment to entry in nil map", "recover_test.go", `msg="server error"`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("no %q in logs:\n%s", want, &logs)
+		}
+	}
+
+	// Once the response has started, the connection is dropped instead
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("panicked with %v, want http.ErrAbortHandler", v)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/late", nil))
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+type requestIDKey struct{}
+
+// RequestID returns the ID of the request ctx belongs to, or "" outside of
+// one.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID reports whether id, taken from a proxy's header, is safe to
+// log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// RequestIDMiddleware gives each request an ID, taken from header if a
+// proxy in front sets one, so it shows up in both the proxy's logs and
+// ours. The ID is sent back as X-Request-Id and logged with server errors.
+func RequestIDMiddleware(header string, lg *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := ""
+		if header != "" {
+			id = r.Header.Get(header)
+		}
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		w.Header().Set("X-Request-Id", id)
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		if sw.code >= http.StatusInternalServerError {
+			lg.WarnContext(ctx, "server error", "method", r.Method, "host", r.Host, "path", r.URL.Path, "status", sw.code)
+		}
+	})
+}
+
+// Error is http.Error with the request ID added, so people reporting
+// problems can quote it.
+func Error(w http.ResponseWriter, r *http.Request, msg string, code int) {
+	if id := RequestID(r.Context()); id != "" {
+		msg = fmt.Sprintf("%s (request ID %s)", msg, id)
+	}
+	http.Error(w, msg, code)
+}
+
+// RequestIDHandler adds the request ID to records logged with a request's
+// context.
+type RequestIDHandler struct {
+	slog.Handler
+}
+
+func (h RequestIDHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		rec.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h RequestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return RequestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h RequestIDHandler) WithGroup(name string) slog.Handler {
+	return RequestIDHandler{h.Handler.WithGroup(name)}
+}
+
+// statusWriter captures the status code of a response, and whether it's
+// been sent.
+type statusWriter struct {
+	http.ResponseWriter
+	code  int
+	wrote bool
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if !sw.wrote {
+		sw.code, sw.wrote = code, true
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	sw.wrote = true
+	return sw.ResponseWriter.Write(p)
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	lg := slog.New(RequestIDHandler{slog.NewTextHandler(&logs, nil)})
+
+	var seen string
+	h := RequestIDMiddleware("Fly-Request-Id", lg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+		lg.ErrorContext(r.Context(), "upstream failed")
+		Error(w, r, "upstream unavailable", http.StatusBadGateway)
+	}))
+
+	for _, tt := range []struct {
+		name, header string
+		reused       bool
+	}{
+		{"generated", "", false},
+		{"from proxy", "01JABCDEF-xyz", true},
+		{"invalid from proxy", "bad id\n", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/mod/x", nil)
+			if tt.header != "" {
+				req.Header.Set("Fly-Request-Id", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			id := rec.Header().Get("X-Request-Id")
+			if id == "" || id != seen || (id == tt.header) != tt.reused {
+				t.Fatalf("X-Request-Id = %q, handler saw %q", id, seen)
+			}
+			if !strings.Contains(rec.Body.String(), "(request ID "+id+")") {
+				t.Errorf("error body %q doesn't have the ID", rec.Body.String())
+			}
+			// The handler's line and the server error line
+			if n := strings.Count(logs.String(), "request_id="+id); n != 2 {
+				t.Errorf("ID logged %d times, want 2:\n%s", n, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package procnet reads the socket tables Linux keeps in /proc/net, and which
+// processes have the sockets open.
+package procnet
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Tables are the socket tables in /proc/net, which are also the protocols
+// of their sockets.
+var Tables = []string{"tcp", "tcp6", "udp", "udp6"}
+
+// Socket is an entry of a /proc/net socket table.
+type Socket struct {
+	Proto         string
+	Local, Remote netip.AddrPort
+	// State is "listen", "established" or, for other TCP states, their
+	// number as in include/net/tcp_states.h.
+	State string
+	// UID owns the socket.
+	UID   string
+	Inode uint64
+}
+
+// State names the state of a socket from its hex st field. UDP sockets are
+// "established" once connected and otherwise "listen", as they receive from
+// anyone.
+func State(proto, st string) string {
+	switch {
+	case st == "0A" && strings.HasPrefix(proto, "tcp"):
+		return "listen"
+	case st == "01":
+		return "established"
+	case st == "07" && strings.HasPrefix(proto, "udp"):
+		return "listen"
+	}
+	return st
+}
+
+// Read reads every socket table in /proc/net. Missing tables, e.g. tcp6
+// without IPv6, are skipped.
+func Read() ([]Socket, error) {
+	var sockets []Socket
+	for _, proto := range Tables {
+		f, err := os.Open(filepath.Join("/proc/net", proto))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		s, err := Parse(f, proto)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse /proc/net/%s: %w", proto, err)
+		}
+		sockets = append(sockets, s...)
+	}
+	return sockets, nil
+}
+
+// Parse parses a socket table like /proc/net/tcp:
+//
+//	sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//	 0: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 23456 ...
+func Parse(r io.Reader, proto string) ([]Socket, error) {
+	var sockets []Socket
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			return nil, fmt.Errorf("short line %q", scanner.Text())
+		}
+		local, err := ParseAddr(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		remote, err := ParseAddr(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad inode %q", fields[9])
+		}
+		sockets = append(sockets, Socket{Proto: proto, Local: local, Remote: remote, State: State(proto, fields[3]), UID: fields[7], Inode: inode})
+	}
+	return sockets, scanner.Err()
+}
+
+// ParseAddr parses an address like 0100007F:1F90, which is 127.0.0.1:8080
+// on little-endian machines: the IP is printed as 32-bit words in host byte
+// order, the port as a number. IPv4 addresses mapped into IPv6 are unmapped.
+func ParseAddr(addr string) (netip.AddrPort, error) {
+	hexIP, hexPort, ok := strings.Cut(addr, ":")
+	if !ok {
+		return netip.AddrPort{}, fmt.Errorf("bad address %q", addr)
+	}
+	b, err := hex.DecodeString(hexIP)
+	if err != nil || len(b) != 4 && len(b) != 16 {
+		return netip.AddrPort{}, fmt.Errorf("bad address %q", addr)
+	}
+	for i := 0; i < len(b); i += 4 {
+		word := binary.BigEndian.Uint32(b[i:])
+		binary.NativeEndian.PutUint32(b[i:], word)
+	}
+	ip, _ := netip.AddrFromSlice(b)
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("bad address %q", addr)
+	}
+	return netip.AddrPortFrom(ip.Unmap(), uint16(port)), nil
+}
+
+// Owners maps socket inodes to the PIDs with them open, by reading the
+// /proc/<pid>/fd links. Processes whose fds can't be read, which without
+// root are other users', are skipped.
+func Owners() (map[uint64][]int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	owners := map[uint64][]int{}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join("/proc", e.Name(), "fd")
+		fds, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		seen := map[uint64]bool{}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil || seen[inode] {
+				continue
+			}
+			seen[inode] = true
+			owners[inode] = append(owners[inode], pid)
+		}
+	}
+	return owners, nil
+}
@@ -0,0 +1,71 @@
+package procnet
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	table := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 23456 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:C37A 0100007F:1F90 01 00000000:00000000 02:000009AF 00000000  1000        0 76438 2 0000000000000000 20 4 0 20 -1
+`
+	sockets, err := Parse(strings.NewReader(table), "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Socket{
+		{Proto: "tcp", Local: netip.MustParseAddrPort("127.0.0.1:8080"), Remote: netip.MustParseAddrPort("0.0.0.0:0"), State: "listen", UID: "1000", Inode: 23456},
+		{Proto: "tcp", Local: netip.MustParseAddrPort("127.0.0.1:50042"), Remote: netip.MustParseAddrPort("127.0.0.1:8080"), State: "established", UID: "1000", Inode: 76438},
+	}
+	if len(sockets) != len(want) {
+		t.Fatalf("got %d sockets, want %d", len(sockets), len(want))
+	}
+	for i := range want {
+		if sockets[i] != want[i] {
+			t.Errorf("socket %d = %+v, want %+v", i, sockets[i], want[i])
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("header\n 0: nonsense\n"), "tcp"); err == nil {
+		t.Error("parsed a malformed table")
+	}
+}
+
+func TestState(t *testing.T) {
+	for _, tc := range []struct{ proto, st, want string }{
+		{"tcp", "0A", "listen"},
+		{"tcp6", "01", "established"},
+		{"tcp", "06", "06"},
+		{"udp", "07", "listen"},
+		{"udp6", "01", "established"},
+	} {
+		if got := State(tc.proto, tc.st); got != tc.want {
+			t.Errorf("State(%q, %q) = %q, want %q", tc.proto, tc.st, got, tc.want)
+		}
+	}
+}
+
+func TestParseAddr(t *testing.T) {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("addresses below are little-endian")
+	}
+	for _, tc := range []struct{ hex, want string }{
+		{"0100007F:1F90", "127.0.0.1:8080"},
+		{"00000000:0000", "0.0.0.0:0"},
+		{"00000000000000000000000001000000:01BB", "[::1]:443"},
+		{"0000000000000000FFFF00000100007F:0016", "127.0.0.1:22"},
+	} {
+		got, err := ParseAddr(tc.hex)
+		if err != nil || got.String() != tc.want {
+			t.Errorf("ParseAddr(%q) = %v, %v, want %s", tc.hex, got, err, tc.want)
+		}
+	}
+	for _, bad := range []string{"0100007F", "01007F:1F90", "0100007F:XYZ"} {
+		if _, err := ParseAddr(bad); err == nil {
+			t.Errorf("ParseAddr(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+// Package qr encodes short texts, like URLs, as QR codes.
+//
+// It only does what sharing a link needs: byte mode at error correction
+// level M, in versions 1 to 10, which hold up to 213 bytes.
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A Code is a QR code, Size modules square.
+type Code struct {
+	Size    int
+	modules []bool
+}
+
+// Black reports whether the module at column x and row y is dark.
+func (c *Code) Black(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.modules[y*c.Size+x]
+}
+
+// Text draws the code with half blocks, two rows a line, dark modules in the
+// foreground, inside a quiet zone of quiet modules.
+func (c *Code) Text(quiet int) string {
+	var b strings.Builder
+	for y := -quiet; y < c.Size+quiet; y += 2 {
+		for x := -quiet; x < c.Size+quiet; x++ {
+			switch top, bottom := c.Black(x, y), c.Black(x, y+1); {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// SVG draws the code as an SVG image with a quiet zone of quiet modules,
+// one unit a module.
+func (c *Code) SVG(quiet int) string {
+	var b strings.Builder
+	size := c.Size + 2*quiet
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, size, size)
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.Black(x, y) {
+				fmt.Fprintf(&b, "M%d %dh1v1h-1z", x+quiet, y+quiet)
+			}
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.String()
+}
+
+// ErrTooLong is returned for texts that don't fit in version 10.
+var ErrTooLong = errors.New("qr: text too long")
+
+// version is the block structure of a version at level M: blocks of data
+// codewords, each followed by ecLen error correction codewords. The last
+// long blocks have one more data codeword than the others.
+type version struct {
+	ecLen, blocks, long, dataLen int
+	align                        []int
+}
+
+var versions = [...]version{
+	1:  {10, 1, 0, 16, nil},
+	2:  {16, 1, 0, 28, []int{6, 18}},
+	3:  {26, 1, 0, 44, []int{6, 22}},
+	4:  {18, 2, 0, 32, []int{6, 26}},
+	5:  {24, 2, 0, 43, []int{6, 30}},
+	6:  {16, 4, 0, 27, []int{6, 34}},
+	7:  {18, 4, 0, 31, []int{6, 22, 38}},
+	8:  {22, 4, 2, 38, []int{6, 24, 42}},
+	9:  {22, 5, 2, 36, []int{6, 26, 46}},
+	10: {26, 5, 1, 43, []int{6, 28, 50}},
+}
+
+// capacity is the number of data codewords.
+func (v version) capacity() int {
+	return v.blocks*v.dataLen + v.long
+}
+
+// Encode returns the smallest QR code holding text.
+func Encode(text string) (*Code, error) {
+	for n := 1; n < len(versions); n++ {
+		// Mode, count (16 bits from version 10) and the bytes themselves
+		countBits := 8
+		if n >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(text) > 8*versions[n].capacity() {
+			continue
+		}
+		return encode(text, n, countBits), nil
+	}
+	return nil, fmt.Errorf("%w: %d bytes", ErrTooLong, len(text))
+}
+
+func encode(text string, n, countBits int) *Code {
+	v := versions[n]
+	var bits bitBuffer
+	bits.append(0b0100, 4)
+	bits.append(len(text), countBits)
+	for i := range len(text) {
+		bits.append(int(text[i]), 8)
+	}
+	capacity := 8 * v.capacity()
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, -len(bits)&7)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	c := newCode(n)
+	c.drawCodewords(interleave(bits.bytes(), v))
+
+	// Use the mask that leaves the fewest patterns confusing scanners
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+	return &c.Code
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, v>>i&1 == 1)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// interleave splits data into blocks, adds their error correction and
+// interleaves them in the order they're drawn.
+func interleave(data []byte, v version) []byte {
+	divisor := rsDivisor(v.ecLen)
+	var blocks, ecs [][]byte
+	for i := range v.blocks {
+		size := v.dataLen
+		if i >= v.blocks-v.long {
+			size++
+		}
+		blocks = append(blocks, data[:size])
+		ecs = append(ecs, rsRemainder(data[:size], divisor))
+		data = data[size:]
+	}
+
+	var out []byte
+	for i := range v.dataLen + 1 {
+		for _, b := range blocks {
+			if i < len(b) {
+				out = append(out, b[i])
+			}
+		}
+	}
+	for i := range v.ecLen {
+		for _, ec := range ecs {
+			out = append(out, ec[i])
+		}
+	}
+	return out
+}
+
+// gfMul multiplies in GF(2⁸) modulo x⁸ + x⁴ + x³ + x² + 1.
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of degree n,
+// highest coefficient first, without the leading 1.
+func rsDivisor(n int) []byte {
+	result := make([]byte, n)
+	result[n-1] = 1
+	root := byte(1)
+	for range n {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < n {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// code is a Code being drawn, which knows the modules data can't go in.
+type code struct {
+	Code
+	function []bool
+}
+
+func newCode(n int) *code {
+	size := 17 + 4*n
+	c := &code{Code: Code{Size: size, modules: make([]bool, size*size)}, function: make([]bool, size*size)}
+
+	for i := range size {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+	for _, p := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := p[0]+dx, p[1]+dy
+				if x >= 0 && y >= 0 && x < size && y < size {
+					d := max(abs(dx), abs(dy))
+					c.set(x, y, d != 2 && d != 4)
+				}
+			}
+		}
+	}
+	align := versions[n].align
+	for i, y := range align {
+		for j, x := range align {
+			// Except where the finder patterns are
+			if i == 0 && j == 0 || i == 0 && j == len(align)-1 || i == len(align)-1 && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	c.drawFormat(0)
+	if n >= 7 {
+		bits := versionBits(n)
+		for i := range 18 {
+			a, b := size-11+i%3, i/3
+			c.set(a, b, bits>>i&1 == 1)
+			c.set(b, a, bits>>i&1 == 1)
+		}
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// set sets a function module, which data isn't drawn over.
+func (c *code) set(x, y int, black bool) {
+	c.modules[y*c.Size+x] = black
+	c.function[y*c.Size+x] = true
+}
+
+// formatBits returns the format information for level M and mask: the
+// level's bits and the mask, BCH coded and masked.
+func formatBits(mask int) int {
+	data := 0b00<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionBits returns the BCH coded version information of versions 7 and up.
+func versionBits(n int) int {
+	rem := n
+	for range 12 {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	return n<<12 | rem
+}
+
+// drawFormat draws both copies of the format information for level M and
+// mask, and the dark module.
+func (c *code) drawFormat(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := range 6 {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	size := c.Size
+	for i := range 8 {
+		c.set(size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, size-15+i, bit(i))
+	}
+	c.set(8, size-8, true)
+}
+
+// drawCodewords draws data in the zigzag of column pairs from the bottom
+// right, skipping function modules.
+func (c *code) drawCodewords(data []byte) {
+	size, i := c.Size, 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range size {
+			y := vert
+			if upward {
+				y = size - 1 - vert
+			}
+			for x := right; x > right-2; x-- {
+				if !c.function[y*size+x] && i < len(data)*8 {
+					c.modules[y*size+x] = data[i/8]>>(7-i%8)&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules that mask selects; applying it twice
+// undoes it.
+func (c *code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip && !c.function[y*c.Size+x] {
+				c.modules[y*c.Size+x] = !c.modules[y*c.Size+x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the code is to scan: long runs of one color,
+// 2×2 blocks, look-alikes of finder patterns and imbalance between dark
+// and light.
+func (c *code) penalty() int {
+	size, p, dark := c.Size, 0, 0
+	line := func(get func(i int) bool) {
+		var row strings.Builder
+		run := 0
+		for i := range size {
+			if i > 0 && get(i) == get(i-1) {
+				run++
+			} else {
+				run = 1
+			}
+			if run == 5 {
+				p += 3
+			} else if run > 5 {
+				p++
+			}
+			if get(i) {
+				row.WriteByte('1')
+			} else {
+				row.WriteByte('0')
+			}
+		}
+		s := "0000" + row.String() + "0000"
+		p += 40 * (strings.Count(s, "10111010000") + strings.Count(s, "00001011101"))
+	}
+	for y := range size {
+		line(func(x int) bool { return c.Black(x, y) })
+		line(func(x int) bool { return c.Black(y, x) })
+		for x := range size {
+			if c.Black(x, y) {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				b := c.Black(x, y)
+				if b == c.Black(x+1, y) && b == c.Black(x, y+1) && b == c.Black(x+1, y+1) {
+					p += 3
+				}
+			}
+		}
+	}
+	total := size * size
+	p += 10 * ((abs(dark*20-total*10)+total-1)/total - 1)
+	return p
+}
@@ -0,0 +1,144 @@
+package qr
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFormatBits(t *testing.T) {
+	// From the table of format information strings for level M
+	for mask, want := range []int{
+		0b101010000010010, 0b101000100100101, 0b101111001111100, 0b101101101001011,
+		0b100010111111001, 0b100000011001110, 0b100111110010111, 0b100101010100000,
+	} {
+		if got := formatBits(mask); got != want {
+			t.Errorf("formatBits(%d) = %015b, want %015b", mask, got, want)
+		}
+	}
+	if got := versionBits(7); got != 0x07C94 {
+		t.Errorf("versionBits(7) = %#x, want 0x07c94", got)
+	}
+}
+
+func TestRSDivisor(t *testing.T) {
+	// (x - 1)(x - α) = x² + 3x + 2
+	if got := rsDivisor(2); !slices.Equal(got, []byte{3, 2}) {
+		t.Errorf("rsDivisor(2) = %v", got)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		size int
+	}{
+		{"", 21},
+		{"http://localhost", 25},
+		{"http://192.168.1.20:3000", 25},
+		{"http://192.168.100.200:3000/", 29},
+		{strings.Repeat("a", 14), 21},
+		{strings.Repeat("a", 15), 25},
+		{strings.Repeat("a", 100), 41},
+		{strings.Repeat("a", 213), 57},
+	} {
+		c, err := Encode(tt.text)
+		if err != nil {
+			t.Fatalf("Encode(%d bytes): %v", len(tt.text), err)
+		}
+		if c.Size != tt.size {
+			t.Errorf("Encode(%d bytes) is %d modules, want %d", len(tt.text), c.Size, tt.size)
+		}
+		if got := decode(t, c); got != tt.text {
+			t.Errorf("Encode(%q) decodes to %q", tt.text, got)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("a", 214)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("214 bytes: got %v, want ErrTooLong", err)
+	}
+}
+
+// decode reads c back the way a scanner would once it's found the modules.
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+	n := (c.Size - 17) / 4
+	v := versions[n]
+
+	format := 0
+	for i := range 6 {
+		if c.Black(8, i) {
+			format |= 1 << i
+		}
+	}
+	mask := slices.IndexFunc([]int{0, 1, 2, 3, 4, 5, 6, 7}, func(m int) bool {
+		return formatBits(m)&0x3F == format
+	})
+	if mask < 0 {
+		t.Fatalf("format bits %06b match no mask", format)
+	}
+
+	plain := newCode(n)
+	copy(plain.modules, c.modules)
+	plain.applyMask(mask)
+	var bits bitBuffer
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range c.Size {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = c.Size - 1 - vert
+			}
+			for x := right; x > right-2; x-- {
+				if !plain.function[y*c.Size+x] {
+					bits = append(bits, plain.Black(x, y))
+				}
+			}
+		}
+	}
+	codewords := bits.bytes()
+
+	// Undo the interleaving, checking each block's error correction
+	blocks := make([][]byte, v.blocks)
+	i := 0
+	for col := range v.dataLen + 1 {
+		for b := range blocks {
+			if col < v.dataLen || b >= v.blocks-v.long {
+				blocks[b] = append(blocks[b], codewords[i])
+				i++
+			}
+		}
+	}
+	var data []byte
+	for b, block := range blocks {
+		var ec []byte
+		for col := range v.ecLen {
+			ec = append(ec, codewords[i+col*v.blocks+b])
+		}
+		if want := rsRemainder(block, rsDivisor(v.ecLen)); !slices.Equal(ec, want) {
+			t.Errorf("block %d error correction is %x, want %x", b, ec, want)
+		}
+		data = append(data, block...)
+	}
+
+	if data[0]>>4 != 0b0100 {
+		t.Fatalf("mode %04b, want byte mode", data[0]>>4)
+	}
+	var count int
+	var rest []byte
+	if n < 10 {
+		count = int(data[0]&0xF)<<4 | int(data[1]>>4)
+		rest = data[1:]
+	} else {
+		count = int(data[0]&0xF)<<12 | int(data[1])<<4 | int(data[2]>>4)
+		rest = data[2:]
+	}
+	text := make([]byte, count)
+	for i := range text {
+		text[i] = rest[i]<<4 | rest[i+1]>>4
+	}
+	return string(text)
+}
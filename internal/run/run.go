@@ -0,0 +1,113 @@
+// Package run ties a program's lifetime to SIGINT and SIGTERM, and shuts it
+// down in order when it ends.
+//
+// A program passes Context to whatever should stop when asked to, like
+// servers, registers what has to happen on the way out with OnShutdown, and
+// calls Shutdown before returning from main:
+//
+//	srv.ListenAndServe(run.Context())
+//	run.Shutdown()
+//
+// A second signal while shutting down exits right away.
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Timeout is how long the shutdown hooks get in all.
+var Timeout = 30 * time.Second
+
+// SignalError is the cause of Context ending because of a signal.
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e SignalError) Error() string { return "received " + e.Signal.String() }
+
+var (
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	sigs   chan os.Signal
+	hooks  []hook
+	// exit is os.Exit, but not in tests.
+	exit = os.Exit
+)
+
+type hook struct {
+	name string
+	f    func(context.Context) error
+}
+
+// Context returns the program's context, which ends when it gets SIGINT or
+// SIGTERM or Stop is called. context.Cause says which.
+func Context() context.Context {
+	mu.Lock()
+	defer mu.Unlock()
+	if ctx != nil {
+		return ctx
+	}
+	ctx, cancel = context.WithCancelCause(context.Background())
+
+	sigs = make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func(cancel context.CancelCauseFunc, sigs <-chan os.Signal, exit func(int)) {
+		sig := <-sigs
+		cancel(SignalError{sig})
+		sig = <-sigs
+		slog.Warn("exiting without finishing shutdown", "signal", sig.String())
+		exit(1)
+	}(cancel, sigs, exit)
+	return ctx
+}
+
+// Stop ends the program's context with cause, as if it got a signal.
+func Stop(cause error) {
+	Context()
+	mu.Lock()
+	defer mu.Unlock()
+	cancel(cause)
+}
+
+// OnShutdown registers f to run during Shutdown. Hooks run in the reverse
+// order they were registered in, like deferred calls, so things are shut
+// down before what they depend on. f's context ends when Timeout runs out.
+func OnShutdown(name string, f func(ctx context.Context) error) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, hook{name, f})
+}
+
+// Shutdown ends the program's context if it hasn't ended, and runs the
+// shutdown hooks, one at a time. Hooks that fail are logged, and run after
+// Timeout with a context that has already ended. Shutdown returns the hooks'
+// errors, joined.
+func Shutdown() error {
+	Stop(errors.New("shutting down"))
+	mu.Lock()
+	hs := hooks
+	hooks = nil
+	mu.Unlock()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), Timeout)
+	defer cancelShutdown()
+	var errs []error
+	for i := len(hs) - 1; i >= 0; i-- {
+		h := hs[i]
+		start := time.Now()
+		if err := h.f(shutdownCtx); err != nil {
+			slog.Error("shutdown step failed", "step", h.name, "took", time.Since(start), "err", err)
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,59 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"os/signal"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// reset forgets the program's context, as if Context was never called.
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	if sigs != nil {
+		signal.Stop(sigs)
+	}
+	ctx, cancel, sigs, hooks = nil, nil, nil, nil
+}
+
+func TestShutdown(t *testing.T) {
+	reset()
+	defer func(d time.Duration) { Timeout = d }(Timeout)
+	Timeout = 50 * time.Millisecond
+
+	var order []string
+	step := func(name string, err error) {
+		OnShutdown(name, func(ctx context.Context) error {
+			order = append(order, name)
+			return err
+		})
+	}
+	step("logs", nil)
+	step("stats", errors.New("disk full"))
+	OnShutdown("slow", func(ctx context.Context) error {
+		order = append(order, "slow")
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	step("server", nil)
+
+	err := Shutdown()
+	if want := []string{"server", "slow", "stats", "logs"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("ran %v, want %v", order, want)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || err.Error() != "slow: context deadline exceeded\nstats: disk full" {
+		t.Errorf("Shutdown() = %v", err)
+	}
+	if Context().Err() == nil {
+		t.Error("Context not done after Shutdown")
+	}
+
+	// Hooks run once
+	order = nil
+	if err := Shutdown(); err != nil || order != nil {
+		t.Errorf("second Shutdown() = %v, ran %v", err, order)
+	}
+}
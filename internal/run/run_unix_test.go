@@ -0,0 +1,40 @@
+//go:build unix
+
+package run
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignal(t *testing.T) {
+	exited := make(chan int, 1)
+	defer func(f func(int)) { exit = f }(exit)
+	exit = func(code int) { exited <- code }
+	reset()
+	c := Context()
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Context not done after SIGTERM")
+	}
+	var sig SignalError
+	if !errors.As(context.Cause(c), &sig) || sig.Signal != syscall.SIGTERM {
+		t.Errorf("Cause = %v", context.Cause(c))
+	}
+
+	// A second signal doesn't wait for the shutdown
+	syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Errorf("exited with %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("didn't exit on second signal")
+	}
+}
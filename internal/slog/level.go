@@ -0,0 +1,139 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// levelAll lets every record through the handlers levelHandler wraps.
+const levelAll = slog.Level(math.MinInt)
+
+var (
+	// base is Handler without its level, for Logger to set one per
+	// component.
+	base slog.Handler
+
+	// overrides are the levels of components with their own, by name.
+	overridesMu sync.RWMutex
+	overrides   = map[string]*slog.LevelVar{}
+)
+
+// Logger returns a logger for a component of the program, whose records
+// have a component attribute and are filtered by the component's level,
+// like -slog-level scanner=DEBUG. Without a level of its own, a component
+// logs at the program's level. Logger must be called after Init.
+func Logger(component string) *slog.Logger {
+	h := base
+	if h == nil {
+		h = slog.Default().Handler()
+	}
+	return slog.New(levelHandler{h.WithAttrs([]slog.Attr{slog.String("component", component)}), componentLevel(component)})
+}
+
+// componentLevel is the level of the named component.
+type componentLevel string
+
+func (c componentLevel) Level() slog.Level {
+	overridesMu.RLock()
+	v, ok := overrides[string(c)]
+	overridesMu.RUnlock()
+	if ok {
+		return v.Level()
+	}
+	if leveler == nil {
+		return slog.LevelInfo
+	}
+	return leveler.Level()
+}
+
+// setLevels sets levels from a comma-separated list like
+// "INFO,scanner=DEBUG,http=WARN". A level alone sets the program's level,
+// name=LEVEL sets a component's, and name= drops the component's own level.
+// Nothing is set if any of the list is invalid.
+func setLevels(s string) error {
+	var program *slog.Level
+	components := map[string]*slog.Level{}
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, text, isComponent := strings.Cut(part, "=")
+		if !isComponent {
+			name, text = "", part
+		}
+		name = strings.TrimSpace(name)
+		if isComponent && name == "" {
+			return fmt.Errorf("no component name in %q", part)
+		}
+		if isComponent && strings.TrimSpace(text) == "" {
+			components[name] = nil
+			continue
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(text))); err != nil {
+			return err
+		}
+		if isComponent {
+			components[name] = &level
+		} else {
+			program = &level
+		}
+	}
+
+	if program != nil {
+		leveler.Set(*program)
+	}
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	for name, level := range components {
+		if level == nil {
+			delete(overrides, name)
+			continue
+		}
+		v, ok := overrides[name]
+		if !ok {
+			v = &slog.LevelVar{}
+			overrides[name] = v
+		}
+		v.Set(*level)
+	}
+	return nil
+}
+
+// levels returns the current levels in the form setLevels takes, with the
+// components sorted by name.
+func levels() string {
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	parts := []string{leveler.Level().String()}
+	for _, name := range slices.Sorted(maps.Keys(overrides)) {
+		parts = append(parts, name+"="+overrides[name].Level().String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// levelHandler drops the records below its level, in front of handlers that
+// let everything through.
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{h.Handler.WithAttrs(attrs), h.level}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{h.Handler.WithGroup(name), h.level}
+}
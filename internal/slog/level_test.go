@@ -0,0 +1,63 @@
+package slog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	var buf bytes.Buffer
+	leveler, base = &slog.LevelVar{}, NewPrettyHandler(&buf, &slog.HandlerOptions{Level: levelAll})
+	defer func() {
+		leveler, base = nil, nil
+		clear(overrides)
+	}()
+	lg := slog.New(levelHandler{base, leveler})
+	scanner, http := Logger("scanner"), Logger("http")
+
+	if err := setLevels("WARN, scanner=DEBUG,http=ERROR"); err != nil {
+		t.Fatal(err)
+	}
+	if got := levels(); got != "WARN,http=ERROR,scanner=DEBUG" {
+		t.Errorf("levels() = %q", got)
+	}
+	lg.Info("program info")
+	lg.Warn("program warn")
+	scanner.Debug("scanner debug")
+	http.Warn("http warn")
+	http.Error("http error")
+	for _, want := range []string{"program warn", "scanner debug component=scanner", "http error component=http"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("no %q in:\n%s", want, &buf)
+		}
+	}
+	for _, unwanted := range []string{"program info", "http warn"} {
+		if strings.Contains(buf.String(), unwanted) {
+			t.Errorf("%q logged:\n%s", unwanted, &buf)
+		}
+	}
+
+	// Dropping an override puts the component back on the program's level
+	if err := setLevels("http=,DEBUG"); err != nil {
+		t.Fatal(err)
+	}
+	if got := levels(); got != "DEBUG,scanner=DEBUG" {
+		t.Errorf("levels() = %q", got)
+	}
+	buf.Reset()
+	http.Debug("http debug")
+	if !strings.Contains(buf.String(), "http debug") {
+		t.Errorf("http debug not logged: %q", &buf)
+	}
+
+	for _, bad := range []string{"LOUD", "scanner=LOUD", "=DEBUG", "INFO,http=nope"} {
+		if err := setLevels(bad); err == nil {
+			t.Errorf("setLevels(%q) succeeded", bad)
+		}
+	}
+	if got := levels(); got != "DEBUG,scanner=DEBUG" {
+		t.Errorf("invalid lists changed the levels to %q", got)
+	}
+}
@@ -0,0 +1,132 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// LimitHandler passes on at most N records with the same message and level
+// per Interval, and drops the rest, to keep hot loops from flooding the logs.
+// Once an interval with dropped records is over, it logs a record saying how
+// many it dropped, when the next record comes along.
+type LimitHandler struct {
+	h     slog.Handler
+	state *limitState
+}
+
+type limitState struct {
+	n        int
+	interval time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	keys  map[limitKey]*limitCount
+	swept time.Time
+}
+
+type limitKey struct {
+	level slog.Level
+	msg   string
+}
+
+type limitCount struct {
+	start      time.Time
+	n          int
+	suppressed int
+	// h is the handler the last suppressed record came to, to log the
+	// summary with.
+	h slog.Handler
+}
+
+// NewLimitHandler returns a LimitHandler passing on n records with the same
+// message and level to h per interval.
+func NewLimitHandler(h slog.Handler, n int, interval time.Duration) *LimitHandler {
+	return &LimitHandler{h, &limitState{
+		n:        n,
+		interval: interval,
+		now:      time.Now,
+		keys:     map[limitKey]*limitCount{},
+	}}
+}
+
+func (h *LimitHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+func (h *LimitHandler) Handle(ctx context.Context, r slog.Record) error {
+	s := h.state
+	key := limitKey{r.Level, r.Message}
+	now := s.now()
+
+	s.mu.Lock()
+	summaries := s.sweep(now)
+	c, ok := s.keys[key]
+	if !ok || now.Sub(c.start) >= s.interval {
+		if ok && c.suppressed > 0 {
+			summaries = append(summaries, limitSummary{key, c.suppressed, c.h})
+		}
+		c = &limitCount{start: now}
+		s.keys[key] = c
+	}
+	pass := c.n < s.n
+	if pass {
+		c.n++
+	} else {
+		c.suppressed++
+		c.h = h.h
+	}
+	s.mu.Unlock()
+
+	for _, sum := range summaries {
+		sum.log(ctx, now, s.interval)
+	}
+	if !pass {
+		return nil
+	}
+	return h.h.Handle(ctx, r)
+}
+
+func (h *LimitHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LimitHandler{h.h.WithAttrs(attrs), h.state}
+}
+
+func (h *LimitHandler) WithGroup(name string) slog.Handler {
+	return &LimitHandler{h.h.WithGroup(name), h.state}
+}
+
+// sweep forgets the messages whose intervals are over, once per interval,
+// returning the summaries of the ones that had records suppressed. s.mu must
+// be held.
+func (s *limitState) sweep(now time.Time) []limitSummary {
+	if now.Sub(s.swept) < s.interval {
+		return nil
+	}
+	s.swept = now
+	var summaries []limitSummary
+	for key, c := range s.keys {
+		if now.Sub(c.start) < s.interval {
+			continue
+		}
+		if c.suppressed > 0 {
+			summaries = append(summaries, limitSummary{key, c.suppressed, c.h})
+		}
+		delete(s.keys, key)
+	}
+	return summaries
+}
+
+// limitSummary is the record saying how many of a message were suppressed.
+type limitSummary struct {
+	key        limitKey
+	suppressed int
+	h          slog.Handler
+}
+
+func (sum limitSummary) log(ctx context.Context, now time.Time, interval time.Duration) {
+	r := slog.NewRecord(now, sum.key.level, fmt.Sprintf("suppressed %d duplicates of %q", sum.suppressed, sum.key.msg), 0)
+	r.AddAttrs(slog.Int("suppressed", sum.suppressed), slog.Duration("interval", interval))
+	sum.h.Handle(ctx, r)
+}
@@ -0,0 +1,43 @@
+package slog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitHandler(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	h := NewLimitHandler(NewPrettyHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), 2, time.Second)
+	h.state.now = func() time.Time { return now }
+	lg := slog.New(h)
+
+	for i := range 5 {
+		lg.Warn("can't read file", "i", i)
+	}
+	lg.With("other", true).Info("can't read file")
+	lg.Info("different")
+	now = now.Add(time.Second)
+	lg.Warn("can't read file", "i", 5)
+
+	var got []string
+	for line := range strings.Lines(buf.String()) {
+		_, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+		got = append(got, rest)
+	}
+	want := []string{
+		`WARN  can't read file i=0`,
+		`WARN  can't read file i=1`,
+		// The level is part of the key
+		`INFO  can't read file other=true`,
+		`INFO  different`,
+		`WARN  suppressed 3 duplicates of "can't read file" suppressed=3 interval=1s`,
+		`WARN  can't read file i=5`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,354 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pkg.jsn.cam/jsn"
+)
+
+const (
+	// otlpBatchSize is the most records sent at once, and otlpInterval how
+	// long records wait for a batch to fill up.
+	otlpBatchSize = 512
+	otlpInterval  = time.Second
+	// otlpQueueSize is how many records can wait to be sent before new ones
+	// are dropped.
+	otlpQueueSize = 8192
+)
+
+// otlpEndpoint returns the OTLP/HTTP logs URL to export to: flagValue if
+// set, or else the one the standard OTEL_EXPORTER_OTLP_* variables give.
+func otlpEndpoint(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if u := os.Getenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"); u != "" {
+		return u
+	}
+	if u := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); u != "" {
+		return strings.TrimSuffix(u, "/") + "/v1/logs"
+	}
+	return ""
+}
+
+// otlpPairs parses a list like OTEL_EXPORTER_OTLP_HEADERS and
+// OTEL_RESOURCE_ATTRIBUTES: comma-separated key=value pairs, with the values
+// URL encoded.
+func otlpPairs(s string) (map[string]string, error) {
+	pairs := map[string]string{}
+	for pair := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("no = in %q", pair)
+		}
+		v, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("bad value for %s: %v", k, err)
+		}
+		pairs[strings.TrimSpace(k)] = v
+	}
+	return pairs, nil
+}
+
+// otlpExporter sends log records to an OTLP/HTTP collector as JSON, in
+// batches. Records are dropped if the collector can't keep up, rather than
+// holding up the program.
+type otlpExporter struct {
+	url      string
+	headers  map[string]string
+	resource []otlpKeyValue
+	client   *http.Client
+
+	records chan otlpRecord
+	flushes chan chan struct{}
+	dropped atomic.Int64
+	stop    sync.Once
+}
+
+// newOTLPExporter starts exporting to url, configured by the standard
+// OTEL_EXPORTER_OTLP_* and OTEL_RESOURCE_ATTRIBUTES variables.
+func newOTLPExporter(url string) (*otlpExporter, error) {
+	if p := cmpEnv("OTEL_EXPORTER_OTLP_LOGS_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"); p != "" && p != "http/json" {
+		return nil, fmt.Errorf("OTLP protocol %s isn't supported, only http/json", p)
+	}
+	headers, err := otlpPairs(cmpEnv("OTEL_EXPORTER_OTLP_LOGS_HEADERS", "OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		return nil, fmt.Errorf("bad OTLP headers: %v", err)
+	}
+	resource, err := otlpPairs(os.Getenv("OTEL_RESOURCE_ATTRIBUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("bad OTEL_RESOURCE_ATTRIBUTES: %v", err)
+	}
+	if _, ok := resource["service.name"]; !ok {
+		resource["service.name"] = filepath.Base(os.Args[0])
+	}
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		resource["service.name"] = name
+	}
+	if _, ok := resource["service.version"]; !ok {
+		resource["service.version"] = jsn.Version
+	}
+
+	e := &otlpExporter{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		records: make(chan otlpRecord, otlpQueueSize),
+		flushes: make(chan chan struct{}),
+	}
+	for k, v := range resource {
+		e.resource = append(e.resource, otlpKeyValue{k, otlpString(v)})
+	}
+	go e.run()
+	return e, nil
+}
+
+// cmpEnv returns the first of the environment variables that's set.
+func cmpEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (e *otlpExporter) run() {
+	tick := time.NewTicker(otlpInterval)
+	defer tick.Stop()
+	var batch []otlpRecord
+	for {
+		select {
+		case rec := <-e.records:
+			batch = append(batch, rec)
+			if len(batch) < otlpBatchSize {
+				continue
+			}
+		case <-tick.C:
+		case done := <-e.flushes:
+			for len(e.records) > 0 {
+				batch = append(batch, <-e.records)
+			}
+			e.send(batch)
+			batch = nil
+			close(done)
+			continue
+		}
+		e.send(batch)
+		batch = nil
+	}
+}
+
+// send posts batch to the collector. Failures are reported on stderr, since
+// logging them could fail the same way.
+func (e *otlpExporter) send(batch []otlpRecord) {
+	if n := e.dropped.Swap(0); n > 0 {
+		fmt.Fprintf(os.Stderr, "dropped %d log records the OTLP collector couldn't take in time\n", n)
+	}
+	for len(batch) > 0 {
+		n := min(len(batch), otlpBatchSize)
+		if err := e.post(batch[:n]); err != nil {
+			fmt.Fprintf(os.Stderr, "can't export %d log records: %v\n", n, err)
+		}
+		batch = batch[n:]
+	}
+}
+
+func (e *otlpExporter) post(records []otlpRecord) error {
+	body, err := json.Marshal(otlpRequest{ResourceLogs: []otlpResourceLogs{{
+		Resource: otlpResource{Attributes: e.resource},
+		ScopeLogs: []otlpScopeLogs{{
+			Scope:      otlpScope{Name: "pkg.jsn.cam/jsn/internal/slog"},
+			LogRecords: records,
+		}},
+	}}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+// Close sends the records waiting to be exported.
+func (e *otlpExporter) Close() error {
+	e.stop.Do(func() {
+		done := make(chan struct{})
+		e.flushes <- done
+		<-done
+	})
+	return nil
+}
+
+// handler returns a slog.Handler exporting to e.
+func (e *otlpExporter) handler() slog.Handler {
+	return otlpHandler{e: e}
+}
+
+// otlpHandler turns records into OTLP log records. Attributes in groups get
+// the group names as dotted prefixes, like OpenTelemetry's attribute names.
+type otlpHandler struct {
+	e      *otlpExporter
+	attrs  []otlpKeyValue
+	prefix string
+}
+
+func (h otlpHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h otlpHandler) Handle(_ context.Context, r slog.Record) error {
+	rec := otlpRecord{
+		TimeUnixNano:         strconv.FormatInt(r.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		// The OpenTelemetry slog bridge's mapping: INFO is 9, DEBUG 5, ...
+		SeverityNumber: min(max(int(r.Level)+9, 1), 24),
+		SeverityText:   r.Level.String(),
+		Body:           otlpString(r.Message),
+		Attributes:     append([]otlpKeyValue(nil), h.attrs...),
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		rec.Attributes = appendOTLPAttr(rec.Attributes, h.prefix, a)
+		return true
+	})
+	if r.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		rec.Attributes = append(rec.Attributes,
+			otlpKeyValue{"code.filepath", otlpString(f.File)},
+			otlpKeyValue{"code.lineno", otlpInt(int64(f.Line))},
+			otlpKeyValue{"code.function", otlpString(f.Function)},
+		)
+	}
+
+	select {
+	case h.e.records <- rec:
+	default:
+		h.e.dropped.Add(1)
+	}
+	return nil
+}
+
+func (h otlpHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h.attrs = append([]otlpKeyValue(nil), h.attrs...)
+	for _, a := range attrs {
+		h.attrs = appendOTLPAttr(h.attrs, h.prefix, a)
+	}
+	return h
+}
+
+func (h otlpHandler) WithGroup(name string) slog.Handler {
+	if name != "" {
+		h.prefix += name + "."
+	}
+	return h
+}
+
+func appendOTLPAttr(kvs []otlpKeyValue, prefix string, a slog.Attr) []otlpKeyValue {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+	v := otlpValue{}
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			kvs = appendOTLPAttr(kvs, prefix, ga)
+		}
+		return kvs
+	case slog.KindBool:
+		b := a.Value.Bool()
+		v.BoolValue = &b
+	case slog.KindInt64:
+		v = otlpInt(a.Value.Int64())
+	case slog.KindFloat64:
+		f := a.Value.Float64()
+		v.DoubleValue = &f
+	case slog.KindTime:
+		v = otlpString(a.Value.Time().Format(time.RFC3339Nano))
+	default:
+		v = otlpString(a.Value.String())
+	}
+	return append(kvs, otlpKeyValue{prefix + a.Key, v})
+}
+
+// The OTLP JSON encoding of ExportLogsServiceRequest, as much of it as is
+// used here.
+type (
+	otlpRequest struct {
+		ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+	}
+	otlpResourceLogs struct {
+		Resource  otlpResource    `json:"resource"`
+		ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeLogs struct {
+		Scope      otlpScope    `json:"scope"`
+		LogRecords []otlpRecord `json:"logRecords"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpRecord struct {
+		TimeUnixNano         string         `json:"timeUnixNano"`
+		ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+		SeverityNumber       int            `json:"severityNumber"`
+		SeverityText         string         `json:"severityText"`
+		Body                 otlpValue      `json:"body"`
+		Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	// otlpValue is an AnyValue, with one of its fields set. 64-bit integers
+	// are strings in JSON.
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+func otlpString(s string) otlpValue { return otlpValue{StringValue: &s} }
+
+func otlpInt(n int64) otlpValue {
+	s := strconv.FormatInt(n, 10)
+	return otlpValue{IntValue: &s}
+}
@@ -0,0 +1,118 @@
+package slog
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOTLPEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	if got := otlpEndpoint(""); got != "" {
+		t.Errorf("unset: %q", got)
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	if got := otlpEndpoint(""); got != "http://collector:4318/v1/logs" {
+		t.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT: %q", got)
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "http://logs:4318/custom")
+	if got := otlpEndpoint(""); got != "http://logs:4318/custom" {
+		t.Errorf("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT: %q", got)
+	}
+	if got := otlpEndpoint("http://flag/v1/logs"); got != "http://flag/v1/logs" {
+		t.Errorf("flag: %q", got)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		reqs []otlpRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "Bearer k=1" {
+			t.Errorf("request to %s with headers %v", r.URL.Path, r.Header)
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		reqs = append(reqs, req)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_PROTOCOL", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_HEADERS", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20k=1")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "service.name=test,deployment.environment=ci")
+	e, err := newOTLPExporter(srv.URL + "/v1/logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg := slog.New(e.handler()).With("dir", "/srv").WithGroup("http")
+	lg.Warn("slow", "ms", 1500, "ok", false, "err", errors.New("timeout"), slog.Group("req", "ratio", 0.5))
+	lg.Debug("quiet")
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	rl := reqs[0].ResourceLogs[0]
+	resource := map[string]string{}
+	for _, kv := range rl.Resource.Attributes {
+		resource[kv.Key] = *kv.Value.StringValue
+	}
+	if resource["service.name"] != "test" || resource["deployment.environment"] != "ci" {
+		t.Errorf("resource %v", resource)
+	}
+
+	recs := rl.ScopeLogs[0].LogRecords
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2", len(recs))
+	}
+	warn := recs[0]
+	if *warn.Body.StringValue != "slow" || warn.SeverityNumber != 13 || warn.SeverityText != "WARN" || recs[1].SeverityNumber != 5 {
+		t.Errorf("records %+v", recs)
+	}
+	attrs := map[string]string{}
+	for _, kv := range warn.Attributes {
+		v, _ := json.Marshal(kv.Value)
+		attrs[kv.Key] = string(v)
+	}
+	for k, want := range map[string]string{
+		"dir":            `{"stringValue":"/srv"}`,
+		"http.ms":        `{"intValue":"1500"}`,
+		"http.ok":        `{"boolValue":false}`,
+		"http.err":       `{"stringValue":"timeout"}`,
+		"http.req.ratio": `{"doubleValue":0.5}`,
+	} {
+		if attrs[k] != want {
+			t.Errorf("attribute %s = %s, want %s", k, attrs[k], want)
+		}
+	}
+	if !strings.HasSuffix(attrs["code.filepath"], `otlp_test.go"}`) {
+		t.Errorf("code.filepath = %s", attrs["code.filepath"])
+	}
+}
+
+func TestOTLPExporterProtocol(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_PROTOCOL", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	if _, err := newOTLPExporter("http://localhost:4317"); err == nil {
+		t.Error("grpc accepted")
+	}
+}
@@ -0,0 +1,198 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ANSI colors PrettyHandler uses on terminals.
+const (
+	colorDim    = "2"
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+	colorBlue   = "34"
+	colorCyan   = "36"
+)
+
+// PrettyHandler writes records for people to read at a terminal, one line
+// each:
+//
+//	15:04:05.000 INFO  serving files dir=/srv http.addr=:3000 serve/serve.go:120
+//
+// Attributes in groups are prefixed with the group names. Levels and keys
+// are colored when writing to a terminal, unless NO_COLOR is set. Only the
+// Level and AddSource options are used.
+type PrettyHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	opts  slog.HandlerOptions
+	color bool
+	// plain leaves out the time and level, for syslog, which has its own.
+	plain bool
+
+	// attrs are the formatted attributes from WithAttrs, and prefix the
+	// groups from WithGroup, like "http.".
+	attrs  []byte
+	prefix string
+}
+
+// NewPrettyHandler returns a PrettyHandler writing to w.
+func NewPrettyHandler(w io.Writer, opts *slog.HandlerOptions) *PrettyHandler {
+	h := &PrettyHandler{w: w, mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if f, ok := w.(*os.File); ok && os.Getenv("NO_COLOR") == "" {
+		fi, err := f.Stat()
+		h.color = err == nil && fi.Mode()&os.ModeCharDevice != 0
+	}
+	return h
+}
+
+func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := h.format(r)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf)
+	return err
+}
+
+// format returns the line for r.
+func (h *PrettyHandler) format(r slog.Record) []byte {
+	buf := make([]byte, 0, 256)
+	if !r.Time.IsZero() && !h.plain {
+		buf = h.paint(buf, colorDim, r.Time.Format("15:04:05.000"))
+		buf = append(buf, ' ')
+	}
+	if !h.plain {
+		buf = h.paint(buf, levelColor(r.Level), fmt.Sprintf("%-5s", r.Level))
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, r.Message...)
+
+	buf = append(buf, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf = h.appendAttr(buf, h.prefix, a)
+		return true
+	})
+
+	if h.opts.AddSource && r.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		buf = append(buf, ' ')
+		buf = h.paint(buf, colorDim, shortSource(f.File, f.Line))
+	}
+	return append(buf, '\n')
+}
+
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = slices.Clip(h.attrs)
+	for _, a := range attrs {
+		h2.attrs = h2.appendAttr(h2.attrs, h.prefix, a)
+	}
+	return &h2
+}
+
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix += name + "."
+	return &h2
+}
+
+// appendAttr appends " key=value" for a, or one for each attribute in a
+// group.
+func (h *PrettyHandler) appendAttr(buf []byte, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			buf = h.appendAttr(buf, prefix, ga)
+		}
+		return buf
+	}
+
+	buf = append(buf, ' ')
+	buf = h.paint(buf, colorCyan, prefix+a.Key+"=")
+	var s string
+	switch a.Value.Kind() {
+	case slog.KindTime:
+		s = a.Value.Time().Format(time.RFC3339Nano)
+	default:
+		s = a.Value.String()
+	}
+	if needsQuoting(s) {
+		s = strconv.Quote(s)
+	}
+	if _, ok := a.Value.Any().(error); ok {
+		return h.paint(buf, colorRed, s)
+	}
+	return append(buf, s...)
+}
+
+// paint appends s in color, if h colors its output.
+func (h *PrettyHandler) paint(buf []byte, color, s string) []byte {
+	if !h.color {
+		return append(buf, s...)
+	}
+	buf = append(buf, "\x1b["+color+"m"...)
+	buf = append(buf, s...)
+	return append(buf, "\x1b[0m"...)
+}
+
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return colorRed
+	case level >= slog.LevelWarn:
+		return colorYellow
+	case level >= slog.LevelInfo:
+		return colorGreen
+	default:
+		return colorBlue
+	}
+}
+
+// shortSource shortens a source file path to its directory and name, like
+// serve/serve.go:120.
+func shortSource(file string, line int) string {
+	return filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file)) + ":" + strconv.Itoa(line)
+}
+
+// needsQuoting reports whether s would be ambiguous unquoted in key=value
+// pairs.
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	return strings.ContainsFunc(s, func(r rune) bool {
+		return r == '"' || r == '=' || unicode.IsSpace(r) || !unicode.IsPrint(r)
+	})
+}
@@ -0,0 +1,39 @@
+package slog
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestPrettyHandler(t *testing.T) {
+	var buf bytes.Buffer
+	lg := slog.New(NewPrettyHandler(&buf, &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug}))
+
+	lg.With("dir", "/srv").WithGroup("http").With("addr", ":3000").Info("serving files", "path", "/a b", slog.Group("req", "n", 2))
+	lg.Debug("quiet")
+	lg.Error("failed", "err", errors.New("boom"), "empty", "", slog.Group("none"))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for i, want := range []string{
+		`^\d\d:\d\d:\d\d\.\d{3} INFO  serving files dir=/srv http\.addr=:3000 http\.path="/a b" http\.req\.n=2 slog/pretty_test\.go:\d+$`,
+		`^\S+ DEBUG quiet slog/pretty_test\.go:\d+$`,
+		`^\S+ ERROR failed err=boom empty="" slog/pretty_test\.go:\d+$`,
+	} {
+		if i >= len(lines) || !regexp.MustCompile(want).MatchString(lines[i]) {
+			t.Errorf("line %d doesn't match %s in:\n%s", i, want, &buf)
+		}
+	}
+
+	buf.Reset()
+	h := NewPrettyHandler(&buf, nil)
+	h.color = true
+	slog.New(h).Debug("hidden")
+	slog.New(h).Warn("careful", "n", 1)
+	if want := "\x1b[33mWARN \x1b[0m careful \x1b[36mn=\x1b[0m1\n"; !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("colored: %q, want suffix %q", &buf, want)
+	}
+}
@@ -0,0 +1,208 @@
+package slog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the time a file was rotated, as added to its name.
+const backupTimeFormat = "20060102T150405.000"
+
+// RotatingFile appends to the file at Path, and moves it aside for a new one
+// once it grows past MaxSize or gets older than MaxAge. The rotated files
+// are named like app.log.20060102T150405.000, with .gz added if compressed.
+type RotatingFile struct {
+	Path string
+	// MaxSize is in bytes, and MaxAge counts from when the file was opened.
+	// Zero means no limit.
+	MaxSize int64
+	MaxAge  time.Duration
+	// MaxBackups is how many rotated files are kept, all of them if zero.
+	MaxBackups int
+	// Compress gzips rotated files.
+	Compress bool
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	now    func() time.Time
+
+	// cleanups are the compressing and pruning after rotations.
+	cleanups sync.WaitGroup
+	cleanMu  sync.Mutex
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.clock()
+	if f.file == nil {
+		if err := f.open(now); err != nil {
+			return 0, err
+		}
+	}
+	tooBig := f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize
+	tooOld := f.MaxAge > 0 && now.Sub(f.opened) >= f.MaxAge
+	if tooBig || tooOld {
+		if err := f.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the file, after waiting for rotated files to be compressed.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cleanups.Wait()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) clock() time.Time {
+	if f.now != nil {
+		return f.now()
+	}
+	return time.Now()
+}
+
+func (f *RotatingFile) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size, f.opened = file, fi.Size(), now
+	return nil
+}
+
+func (f *RotatingFile) rotate(now time.Time) error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	backup := f.Path + "." + now.UTC().Format(backupTimeFormat)
+	if err := os.Rename(f.Path, backup); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := f.open(now); err != nil {
+		return err
+	}
+
+	f.cleanups.Add(1)
+	go func() {
+		defer f.cleanups.Done()
+		f.cleanMu.Lock()
+		defer f.cleanMu.Unlock()
+		if err := f.cleanup(); err != nil {
+			// Logging it could loop back here
+			fmt.Fprintf(os.Stderr, "can't clean up rotated log files: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// cleanup compresses the rotated files, if f.Compress, and removes the ones
+// past MaxBackups.
+func (f *RotatingFile) cleanup() error {
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+	if f.Compress {
+		for i, name := range backups {
+			if strings.HasSuffix(name, ".gz") {
+				continue
+			}
+			if err := gzipFile(name); err != nil {
+				return err
+			}
+			backups[i] = name + ".gz"
+		}
+	}
+	if f.MaxBackups > 0 && len(backups) > f.MaxBackups {
+		for _, name := range backups[:len(backups)-f.MaxBackups] {
+			if err := os.Remove(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backups returns the paths of the rotated files, oldest first.
+func (f *RotatingFile) backups() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(f.Path))
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Base(f.Path) + "."
+	var names []string
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, strings.TrimSuffix(stamp, ".gz")); err != nil {
+			continue
+		}
+		names = append(names, filepath.Join(filepath.Dir(f.Path), e.Name()))
+	}
+	// The timestamps sort in time order, so the names do too
+	slices.Sort(names)
+	return names, nil
+}
+
+// gzipFile replaces the file at path with path.gz.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := path + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
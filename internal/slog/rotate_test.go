@@ -0,0 +1,95 @@
+package slog
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	f := &RotatingFile{
+		Path:       filepath.Join(dir, "logs", "app.log"),
+		MaxSize:    10,
+		MaxAge:     time.Hour,
+		MaxBackups: 2,
+		Compress:   true,
+		now:        func() time.Time { return now },
+	}
+	write := func(s string) {
+		t.Helper()
+		if _, err := f.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Second)
+	}
+
+	write("first\n")
+	write("second\n") // past MaxSize
+	write("third\n")  // past MaxSize
+	now = now.Add(time.Hour)
+	write("4\n") // past MaxAge
+	write("5\n")
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		file, err := os.Open(filepath.Join(dir, "logs", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		var r io.Reader = file
+		if strings.HasSuffix(name, ".gz") {
+			if r, err = gzip.NewReader(file); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, _ := io.ReadAll(r)
+		return string(data)
+	}
+	if got := read("app.log"); got != "4\n5\n" {
+		t.Errorf("app.log = %q", got)
+	}
+	// The first rotated file is past MaxBackups
+	entries, _ := os.ReadDir(filepath.Join(dir, "logs"))
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{"app.log", "app.log.20261016T120002.000.gz", "app.log.20261016T130003.000.gz"}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Fatalf("files %q, want %q", names, want)
+	}
+	if got := read(want[1]); got != "second\n" {
+		t.Errorf("%s = %q", want[1], got)
+	}
+	if got := read(want[2]); got != "third\n" {
+		t.Errorf("%s = %q", want[2], got)
+	}
+}
+
+func TestMultiHandler(t *testing.T) {
+	var info, debug strings.Builder
+	h := multiHandler{
+		NewPrettyHandler(&info, nil),
+		NewPrettyHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	}
+	lg := slog.New(h).With("a", 1)
+	lg.Debug("d")
+	lg.Info("i")
+	if strings.Contains(info.String(), " d ") || !strings.Contains(info.String(), "INFO  i a=1") {
+		t.Errorf("info handler got %q", info.String())
+	}
+	if !strings.Contains(debug.String(), "DEBUG d a=1") || !strings.Contains(debug.String(), "INFO  i a=1") {
+		t.Errorf("debug handler got %q", debug.String())
+	}
+}
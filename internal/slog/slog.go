@@ -2,63 +2,180 @@
 package slog
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
+
+	"pkg.jsn.cam/jsn/internal/httpserver"
 )
 
 var (
-	slogLevel = flag.String("slog-level", "INFO", "log level")
+	slogLevel  = flag.String("slog-level", "INFO", "log level, and those of components that differ, like INFO,scanner=DEBUG,http=WARN")
+	slogFormat = flag.String("slog-format", "json", "log format: json, text, or pretty for reading at a terminal")
+
+	slogFile     = flag.String("slog-file", "", "file to write logs to as well as stderr, rotated as set by the other -slog-file flags")
+	slogFileSize = flag.Int("slog-file-max-size", 100, "rotate -slog-file once it's this many megabytes (0 disables)")
+	slogFileAge  = flag.Duration("slog-file-max-age", 0, "rotate -slog-file once it's been written to for this long, like 24h (0 disables)")
+	slogFileKeep = flag.Int("slog-file-max-backups", 10, "number of rotated -slog-file files to keep (0 keeps all)")
+	slogFileGzip = flag.Bool("slog-file-compress", true, "gzip rotated -slog-file files")
+	slogOTLP     = flag.String("slog-otlp", "", "OTLP/HTTP collector URL to export logs to as JSON, like http://localhost:4318/v1/logs (default from $OTEL_EXPORTER_OTLP_LOGS_ENDPOINT or $OTEL_EXPORTER_OTLP_ENDPOINT)")
+	slogSyslog   = flag.String("slog-syslog", "", "syslog server to send logs to as well: udp:host:port, tcp:host:port or unix:/dev/log")
+	slogLimit    = flag.Int("slog-limit", 0, "most records with the same message and level to log per -slog-limit-interval, counting the rest in a summary (0 disables)")
+	slogLimitPer = flag.Duration("slog-limit-interval", 10*time.Second, "interval for -slog-limit")
 
 	// The current slog handler.
 	Handler slog.Handler
 
 	leveler *slog.LevelVar
+
+	// closers flush and close the outputs other than stderr.
+	closers []io.Closer
 )
 
 func Init() {
-	var programLevel slog.Level
-	if err := (&programLevel).UnmarshalText([]byte(*slogLevel)); err != nil {
+	leveler = &slog.LevelVar{}
+	if err := setLevels(*slogLevel); err != nil {
 		fmt.Fprintf(os.Stderr, "invalid log level %s: %v, using info\n", *slogLevel, err)
-		programLevel = slog.LevelInfo
 	}
 
-	leveler = &slog.LevelVar{}
-	leveler.Set(programLevel)
+	switch *slogFormat {
+	case "json", "text", "pretty":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid log format %s, using json\n", *slogFormat)
+	}
 
-	h := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+	// The handlers let everything through, so that components can have
+	// lower levels than the program
+	opts := &slog.HandlerOptions{
 		AddSource: true,
-		Level:     leveler,
-	})
-	slog.SetDefault(slog.New(h))
-
-	Handler = h
+		Level:     levelAll,
+	}
+	hs := multiHandler{newHandler(os.Stderr, opts)}
+	if *slogFile != "" {
+		f := &RotatingFile{
+			Path:       *slogFile,
+			MaxSize:    int64(*slogFileSize) << 20,
+			MaxAge:     *slogFileAge,
+			MaxBackups: *slogFileKeep,
+			Compress:   *slogFileGzip,
+		}
+		closers = append(closers, f)
+		hs = append(hs, newHandler(f, opts))
+	}
+	if url := otlpEndpoint(*slogOTLP); url != "" {
+		if e, err := newOTLPExporter(url); err != nil {
+			fmt.Fprintf(os.Stderr, "can't export logs over OTLP: %v\n", err)
+		} else {
+			closers = append(closers, e)
+			hs = append(hs, e.handler())
+		}
+	}
+	if *slogSyslog != "" {
+		if w, err := dialSyslog(*slogSyslog); err != nil {
+			fmt.Fprintf(os.Stderr, "can't send logs to syslog: %v\n", err)
+		} else {
+			closers = append(closers, w)
+			hs = append(hs, newSyslogHandler(w))
+		}
+	}
+	var h slog.Handler = hs
+	if len(hs) == 1 {
+		h = hs[0]
+	}
+	if *slogLimit > 0 {
+		h = NewLimitHandler(h, *slogLimit, *slogLimitPer)
+	}
+	base = h
+	Handler = levelHandler{h, leveler}
+	slog.SetDefault(slog.New(Handler))
 
-	http.HandleFunc("/.jsn/debug/slog-level", func(w http.ResponseWriter, r *http.Request) {
-		var level, old slog.Level
-		old = leveler.Level()
+	httpserver.HandleDebug("slog-level", "GET shows the log levels, POST sets them like INFO,scanner=DEBUG", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		old := levels()
 
 		if r.Method == http.MethodPost {
-			data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 64))
+			data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1024))
 			defer r.Body.Close()
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
 
-			if err := (&level).UnmarshalText(data); err != nil {
+			if err := setLevels(string(data)); err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
 
-			leveler.Set(level)
-			slog.Info("changed level", "from", old, "to", level)
-			fmt.Fprintln(w, level)
+			slog.Info("changed level", "from", old, "to", levels())
+			fmt.Fprintln(w, levels())
 		} else {
 			fmt.Fprintln(w, old)
 		}
-	})
+	}))
+}
+
+// Close sends the log records waiting to be exported, and closes the log
+// outputs other than stderr. Records logged afterwards may be lost.
+func Close() error {
+	var errs []error
+	for _, c := range closers {
+		errs = append(errs, c.Close())
+	}
+	closers = nil
+	return errors.Join(errs...)
+}
+
+// newHandler returns a handler writing to w in the -slog-format format.
+func newHandler(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	switch *slogFormat {
+	case "text":
+		return slog.NewTextHandler(w, opts)
+	case "pretty":
+		return NewPrettyHandler(w, opts)
+	default:
+		return slog.NewJSONHandler(w, opts)
+	}
+}
+
+// multiHandler sends records to each of its handlers that's enabled for them.
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range m {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	m2 := make(multiHandler, len(m))
+	for i, h := range m {
+		m2[i] = h.WithAttrs(attrs)
+	}
+	return m2
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	m2 := make(multiHandler, len(m))
+	for i, h := range m {
+		m2[i] = h.WithGroup(name)
+	}
+	return m2
 }
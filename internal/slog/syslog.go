@@ -0,0 +1,140 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syslogFacility is the user-level messages facility.
+const syslogFacility = 1
+
+// syslogWriter sends RFC 5424 messages to a syslog server, redialing it
+// once if a send fails.
+type syslogWriter struct {
+	network, addr string
+	hostname, app string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// dialSyslog connects to the syslog server at addr, which is one of
+// "udp:host:port", "tcp:host:port", "unix:<path>" for a datagram socket like
+// /dev/log, or "host:port" for UDP.
+func dialSyslog(addr string) (*syslogWriter, error) {
+	network, rest, ok := strings.Cut(addr, ":")
+	switch {
+	case ok && (network == "udp" || network == "tcp"):
+		addr = rest
+	case ok && network == "unix":
+		network, addr = "unixgram", rest
+	default:
+		network = "udp"
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+	w := &syslogWriter{network: network, addr: addr, hostname: hostname, app: filepath.Base(os.Args[0])}
+	if err := w.dial(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *syslogWriter) dial() error {
+	conn, err := net.DialTimeout(w.network, w.addr, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	w.conn = conn
+	return nil
+}
+
+// write sends msg with the syslog severity for level.
+func (w *syslogWriter) write(level slog.Level, t time.Time, msg []byte) error {
+	var severity int
+	switch {
+	case level >= slog.LevelError:
+		severity = 3
+	case level >= slog.LevelWarn:
+		severity = 4
+	case level >= slog.LevelInfo:
+		severity = 6
+	default:
+		severity = 7
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %d - - ", syslogFacility*8+severity,
+		t.Format("2006-01-02T15:04:05.000000Z07:00"), w.hostname, w.app, os.Getpid())
+	buf.Write(bytes.TrimSuffix(msg, []byte("\n")))
+	packet := buf.Bytes()
+	if w.network == "tcp" {
+		// Octet counting framing, RFC 6587
+		packet = append(fmt.Appendf(nil, "%d ", len(packet)), packet...)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		if _, err := w.conn.Write(packet); err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	if err := w.dial(); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(packet)
+	return err
+}
+
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// syslogHandler sends records to syslog, as PrettyHandler formats them
+// without the time and level, which syslog has fields for.
+type syslogHandler struct {
+	h *PrettyHandler
+	w *syslogWriter
+}
+
+func newSyslogHandler(w *syslogWriter) syslogHandler {
+	h := NewPrettyHandler(nil, &slog.HandlerOptions{AddSource: true, Level: levelAll})
+	h.plain = true
+	return syslogHandler{h, w}
+}
+
+func (h syslogHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h syslogHandler) Handle(_ context.Context, r slog.Record) error {
+	return h.w.write(r.Level, r.Time, h.h.format(r))
+}
+
+func (h syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return syslogHandler{h.h.WithAttrs(attrs).(*PrettyHandler), h.w}
+}
+
+func (h syslogHandler) WithGroup(name string) slog.Handler {
+	return syslogHandler{h.h.WithGroup(name).(*PrettyHandler), h.w}
+}
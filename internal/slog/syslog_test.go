@@ -0,0 +1,77 @@
+package slog
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	w, err := dialSyslog(pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	slog.New(newSyslogHandler(w)).With("dir", "/srv").Error("failed", "err", "boom")
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `^<11>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}\S+ \S+ \S+ \d+ - - failed dir=/srv err=boom slog/syslog_test\.go:\d+$`
+	if !regexp.MustCompile(want).Match(buf[:n]) {
+		t.Errorf("got %q, want match for %s", buf[:n], want)
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	w, err := dialSyslog("tcp:" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	lg := slog.New(newSyslogHandler(w))
+	lg.Debug("one")
+	lg.Info("two")
+	r := bufio.NewReader(conn)
+	for _, want := range []string{"<15>1 ", "<14>1 "} {
+		// Each message is framed as its length, a space and the message
+		size, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil {
+			t.Fatalf("bad frame length %q", size)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(msg), want) {
+			t.Errorf("got %q, want prefix %q", msg, want)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+/*
+Package vanity implements custom import paths (Go vanity URLs) as an HTTP
+handler that can be installed at the vanity URL.
+
+For most repositories New is all that's needed:
+
+	http.Handle("/tool/", vanity.New("example.org/tool", "https://github.com/me/tool",
+		vanity.WithBranch("main"),
+	))
+
+It serves the go-import tag the go tool needs, a go-source tag so
+pkg.go.dev and editors can link to files and lines, and redirects browsers
+to the package documentation. Options change the VCS, branch, source link
+style and where browsers are sent. Handler builds the tags from scratch for
+anything New doesn't cover.
+*/
+package vanity
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type config struct {
+	sourceTag *string
+	redir     Redirector
+	importTag []string
+
+	// Set by New and its options
+	importPath     string
+	repoURL        string
+	cloneURL       string
+	vcs            string
+	ref            string
+	subdir         string
+	style          SourceStyle
+	redirectStatus int
+}
+
+// Configures the Handler. The only required option is WithImport, unless the
+// handler is made with New.
+type Option func(*config)
+
+// Instructs the go tool where to fetch the repo at vcsRoot and the importPath
+// that tree should be rooted at.
+func WithImport(importPath, vcs, vcsRoot string) Option {
+	importTag := "<meta name=\"go-import\" content=\"" + importPath + " " +
+		vcs + " " + vcsRoot + "\">"
+	return func(cfg *config) {
+		cfg.importTag = append(cfg.importTag, importTag)
+	}
+}
+
+// WithSubdirImport is like WithImport for a module that lives in subdir of
+// the repository rather than at its root. The subdirectory field is
+// understood by Go 1.25 and later.
+func WithSubdirImport(importPath, vcs, vcsRoot, subdir string) Option {
+	return WithImport(importPath, vcs, vcsRoot+" "+subdir)
+}
+
+// WithGoModProxy adds a go module proxy to the option chain.
+func WithGoModProxy(importPath, proxyServer string) Option {
+	return WithImport(importPath, "mod", proxyServer)
+}
+
+// Instructs gddo (godoc.org) how to direct browsers to browsable source code
+// for packages and their contents rooted at prefix.
+//
+// home specifies the home page of prefix, directory gives a format for how to
+// browse a directory, and file gives a format for how to view a file and go to
+// specific lines within it.
+//
+// More information can be found at https://github.com/golang/gddo/wiki/Source-Code-Links.
+func WithSource(prefix, home, directory, file string) Option {
+	sourceTag := "<meta name=\"go-source\" content=\"" + prefix + " " +
+		home + " " + directory + " " + file + "\">"
+	return func(cfg *config) {
+		if cfg.sourceTag != nil {
+			panic(fmt.Sprintf("vanity: existing source tag: %s", *cfg.sourceTag))
+		}
+		cfg.sourceTag = &sourceTag
+	}
+}
+
+// When a browser navigates to the vanity URL of pkg, this function rewrites
+// pkg to a browsable URL.
+type Redirector func(pkg string) (url string)
+
+// WithRedirector loads a redirector instance into the config.
+func WithRedirector(redir Redirector) Option {
+	return func(cfg *config) {
+		if cfg.redir != nil {
+			panic("vanity: existing Redirector")
+		}
+		cfg.redir = redir
+	}
+}
+
+// WithRedirectStatus sets the status code browsers are redirected with,
+// 307 Temporary Redirect by default. A status of 0 doesn't redirect at all
+// and serves browsers the same page as the go tool, which links to the
+// Redirector's URL.
+func WithRedirectStatus(status int) Option {
+	return func(cfg *config) {
+		cfg.redirectStatus = status
+	}
+}
+
+// A SourceStyle builds the go-source tag for a repository browsable at
+// repoURL, linking to ref. WithGitHubStyleSource, WithGogsStyleSource and
+// WithGitLabStyleSource are SourceStyles.
+type SourceStyle func(importPath, repoURL, ref string) Option
+
+// WithVCS sets the version control system New tells the go tool to use,
+// "git" by default.
+func WithVCS(vcs string) Option {
+	return func(cfg *config) {
+		cfg.vcs = vcs
+	}
+}
+
+// WithBranch sets the branch or other ref New's source links point at,
+// "master" by default.
+func WithBranch(ref string) Option {
+	return func(cfg *config) {
+		cfg.ref = ref
+	}
+}
+
+// WithSubdir tells New the module lives in subdir of the repository. Source
+// links are rooted at that directory too.
+func WithSubdir(subdir string) Option {
+	return func(cfg *config) {
+		cfg.subdir = strings.Trim(subdir, "/")
+	}
+}
+
+// WithCloneURL makes New tell the go tool to fetch from url, such as an
+// ssh:// URL, rather than the repository URL that browsers use.
+func WithCloneURL(url string) Option {
+	return func(cfg *config) {
+		cfg.cloneURL = url
+	}
+}
+
+// WithSourceStyle sets how New links to source files. Without it New picks
+// the style for github.com and gitlab.com repositories and leaves out the
+// go-source tag for other hosts.
+func WithSourceStyle(style SourceStyle) Option {
+	return func(cfg *config) {
+		cfg.style = style
+	}
+}
+
+// New creates a handler for the repository at repoURL, served at
+// importPath. It emits go-import and go-source tags from repoURL and the
+// options, then applies any WithImport and WithSource options as given.
+func New(importPath, repoURL string, opts ...Option) http.Handler {
+	return handlerFrom(compile(append([]Option{func(cfg *config) {
+		cfg.importPath = importPath
+		cfg.repoURL = strings.TrimSuffix(repoURL, "/")
+	}}, opts...)))
+}
+
+// MakeHandler creates a handler with custom options.
+func MakeHandler(opts ...Option) http.Handler {
+	return handlerFrom(compile(opts))
+}
+
+// repoTags adds the tags New derives from the repository settings.
+func (cfg *config) repoTags() {
+	vcsRoot := cmp.Or(cfg.cloneURL, cfg.repoURL)
+	vcs := cmp.Or(cfg.vcs, "git")
+	if cfg.subdir != "" {
+		WithSubdirImport(cfg.importPath, vcs, vcsRoot, cfg.subdir)(cfg)
+	} else {
+		WithImport(cfg.importPath, vcs, vcsRoot)(cfg)
+	}
+
+	if cfg.sourceTag != nil {
+		return
+	}
+	style := cfg.style
+	if style == nil {
+		u, err := url.Parse(cfg.repoURL)
+		if err != nil {
+			return
+		}
+		switch u.Hostname() {
+		case "github.com":
+			style = WithGitHubStyleSource
+		case "gitlab.com":
+			style = WithGitLabStyleSource
+		default:
+			return
+		}
+	}
+	ref := cmp.Or(cfg.ref, "master")
+	if cfg.subdir != "" {
+		ref += "/" + cfg.subdir
+	}
+	style(cfg.importPath, cfg.repoURL, ref)(cfg)
+}
+
+func compile(opts []Option) (*template.Template, *config) {
+	// Process options.
+	cfg := config{redirectStatus: http.StatusTemporaryRedirect}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.repoURL != "" {
+		cfg.repoTags()
+	}
+
+	// A WithImport is required.
+	if cfg.importTag == nil {
+		panic("vanity: WithImport is required")
+	}
+
+	tags := make([]string, len(cfg.importTag))
+	copy(tags, cfg.importTag)
+	if cfg.sourceTag != nil {
+		tags = append(tags, *cfg.sourceTag)
+	}
+	tagBlk := strings.Join(tags, "\n")
+
+	h := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=utf-8"/>
+%s
+<meta http-equiv="refresh" content="0; url={{ . }}">
+</head>
+<body>
+Please see <a href="{{ . }}">here</a> for documentation on this package.
+</body>
+</html>
+`, tagBlk)
+
+	// Use default GDDO Redirector.
+	if cfg.redir == nil {
+		cfg.redir = func(pkg string) string {
+			return "https://godoc.org/" + pkg
+		}
+	}
+
+	return template.Must(template.New("").Parse(h)), &cfg
+}
+
+func handlerFrom(tpl *template.Template, cfg *config) http.Handler {
+	redir, status := cfg.redir, cfg.redirectStatus
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only method supported is GET.
+		if r.Method != http.MethodGet {
+			status := http.StatusMethodNotAllowed
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		pkg := r.Host + r.URL.Path
+		redirURL := redir(pkg)
+
+		// Issue an HTTP redirect if this is definitely a browser.
+		if r.FormValue("go-get") != "1" && status != 0 {
+			http.Redirect(w, r, redirURL, status)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		if err := tpl.ExecuteTemplate(w, "", redirURL); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Handler returns an http.Handler that serves the vanity URL information for a single
+// repository. Each Option gives additional information to agents about the
+// repository or provides help to browsers that may have navigated to the vanity
+// URL. The WithImport Option is mandatory since the go tool requires it to
+// fetch the repository.
+func Handler(opts ...Option) http.Handler {
+	return handlerFrom(compile(opts))
+}
+
+// Helpers for common VCSs.
+
+// Redirects gddo to browsable source files for GitHub hosted repositories.
+func WithGitHubStyleSource(importPath, repoPath, ref string) Option {
+	directory := repoPath + "/tree/" + ref + "{/dir}"
+	file := repoPath + "/blob/" + ref + "{/dir}/{file}#L{line}"
+
+	return WithSource(importPath, repoPath, directory, file)
+}
+
+// Redirects gddo to browsable source files for Gogs hosted repositories.
+func WithGogsStyleSource(importPath, repoPath, ref string) Option {
+	directory := repoPath + "/src/" + ref + "{/dir}"
+	file := repoPath + "/src/" + ref + "{/dir}/{file}#L{line}"
+
+	return WithSource(importPath, repoPath, directory, file)
+}
+
+// Redirects gddo to browsable source files for GitLab hosted repositories.
+func WithGitLabStyleSource(importPath, repoPath, ref string) Option {
+	directory := repoPath + "/-/tree/" + ref + "{/dir}"
+	file := repoPath + "/-/blob/" + ref + "{/dir}/{file}#L{line}"
+
+	return WithSource(importPath, repoPath, directory, file)
+}
+
+// Creates a Handler that serves a GitHub repository at a specific importPath.
+func GitHubHandler(importPath, user, repo, gitScheme string) http.Handler {
+	ghImportPath := "github.com/" + user + "/" + repo
+	return Handler(
+		WithImport(importPath, "git", gitScheme+"://"+ghImportPath),
+		WithGitHubStyleSource(importPath, "https://"+ghImportPath, "master"),
+	)
+}
+
+// Creates a Handler that serves a repository hosted with Gogs at host at a
+// specific importPath.
+func GogsHandler(importPath, host, user, repo, gitScheme string) http.Handler {
+	gogsImportPath := host + "/" + user + "/" + repo
+	return Handler(
+		WithImport(importPath, "git", gitScheme+"://"+gogsImportPath),
+		WithGogsStyleSource(importPath, "https://"+gogsImportPath, "master"),
+	)
+}
+
+// Creates a Handler that serves a repository hosted with GitLab at host at a
+// specific importPath. GitLab projects can be nested in subgroups, so user
+// may be a group path like "group/subgroup" and repo may have a subgroup
+// prefix too; the go-import root is always the full project path.
+func GitLabHandler(importPath, host, user, repo, gitScheme string) http.Handler {
+	gitlabImportPath := strings.Trim(host, "/") + "/" + strings.Trim(user, "/") + "/" + strings.Trim(repo, "/")
+	return Handler(
+		WithImport(importPath, "git", gitScheme+"://"+gitlabImportPath),
+		WithGitLabStyleSource(importPath, "https://"+gitlabImportPath, "master"),
+	)
+}
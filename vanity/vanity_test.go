@@ -0,0 +1,191 @@
+package vanity
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, h http.Handler, url string) *http.Response {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	return rec.Result()
+}
+
+func TestHandlers(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		handler http.Handler
+		imports string
+		source  string
+	}{
+		{
+			name:    "github",
+			handler: GitHubHandler("pkg.jsn.cam/jsn", "JasonLovesDoggo", "jsn", "https"),
+			imports: `<meta name="go-import" content="pkg.jsn.cam/jsn git https://github.com/JasonLovesDoggo/jsn">`,
+			source:  `<meta name="go-source" content="pkg.jsn.cam/jsn https://github.com/JasonLovesDoggo/jsn https://github.com/JasonLovesDoggo/jsn/tree/master{/dir} https://github.com/JasonLovesDoggo/jsn/blob/master{/dir}/{file}#L{line}">`,
+		},
+		{
+			name:    "gogs",
+			handler: GogsHandler("example.org/tool", "git.example.org", "me", "tool", "https"),
+			imports: `<meta name="go-import" content="example.org/tool git https://git.example.org/me/tool">`,
+			source:  `<meta name="go-source" content="example.org/tool https://git.example.org/me/tool https://git.example.org/me/tool/src/master{/dir} https://git.example.org/me/tool/src/master{/dir}/{file}#L{line}">`,
+		},
+		{
+			name:    "gitlab",
+			handler: GitLabHandler("example.org/tool", "gitlab.com", "me", "tool", "https"),
+			imports: `<meta name="go-import" content="example.org/tool git https://gitlab.com/me/tool">`,
+			source:  `<meta name="go-source" content="example.org/tool https://gitlab.com/me/tool https://gitlab.com/me/tool/-/tree/master{/dir} https://gitlab.com/me/tool/-/blob/master{/dir}/{file}#L{line}">`,
+		},
+		{
+			name:    "gitlab subgroup",
+			handler: GitLabHandler("example.org/tool", "gitlab.com", "group/subgroup/", "/tool", "ssh"),
+			imports: `<meta name="go-import" content="example.org/tool git ssh://gitlab.com/group/subgroup/tool">`,
+			source:  `<meta name="go-source" content="example.org/tool https://gitlab.com/group/subgroup/tool https://gitlab.com/group/subgroup/tool/-/tree/master{/dir} https://gitlab.com/group/subgroup/tool/-/blob/master{/dir}/{file}#L{line}">`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, tt.handler, "https://example.org/tool/sub?go-get=1")
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("got %d", resp.StatusCode)
+			}
+			for _, want := range []string{tt.imports, tt.source} {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("missing %s in\n%s", want, body)
+				}
+			}
+		})
+	}
+}
+
+func TestSubdirImport(t *testing.T) {
+	h := Handler(WithSubdirImport("pkg.jsn.cam/fsdiff", "git", "https://github.com/JasonLovesDoggo/jsn", "cmd/fsdiff"))
+	body, _ := io.ReadAll(get(t, h, "https://pkg.jsn.cam/fsdiff?go-get=1").Body)
+	want := `<meta name="go-import" content="pkg.jsn.cam/fsdiff git https://github.com/JasonLovesDoggo/jsn cmd/fsdiff">`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("missing %s in\n%s", want, body)
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	h := Handler(
+		WithImport("pkg.jsn.cam/jsn", "git", "https://github.com/JasonLovesDoggo/jsn"),
+		WithRedirector(func(pkg string) string { return "https://pkg.go.dev/" + pkg }),
+	)
+
+	resp := get(t, h, "https://pkg.jsn.cam/jsn/vanity")
+	if resp.StatusCode != http.StatusTemporaryRedirect || resp.Header.Get("Location") != "https://pkg.go.dev/pkg.jsn.cam/jsn/vanity" {
+		t.Errorf("browser: got %d to %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "https://pkg.jsn.cam/jsn", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: got %d", rec.Code)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		repoURL string
+		opts    []Option
+		want    []string
+		notWant string
+	}{
+		{
+			name:    "github defaults",
+			repoURL: "https://github.com/me/tool/",
+			want: []string{
+				`<meta name="go-import" content="example.org/tool git https://github.com/me/tool">`,
+				`<meta name="go-source" content="example.org/tool https://github.com/me/tool https://github.com/me/tool/tree/master{/dir} https://github.com/me/tool/blob/master{/dir}/{file}#L{line}">`,
+			},
+		},
+		{
+			name:    "gitlab branch",
+			repoURL: "https://gitlab.com/group/sub/tool",
+			opts:    []Option{WithBranch("main")},
+			want: []string{
+				`<meta name="go-source" content="example.org/tool https://gitlab.com/group/sub/tool https://gitlab.com/group/sub/tool/-/tree/main{/dir} https://gitlab.com/group/sub/tool/-/blob/main{/dir}/{file}#L{line}">`,
+			},
+		},
+		{
+			name:    "unknown host has no source",
+			repoURL: "https://hg.example.org/tool",
+			opts:    []Option{WithVCS("hg")},
+			want:    []string{`<meta name="go-import" content="example.org/tool hg https://hg.example.org/tool">`},
+			notWant: "go-source",
+		},
+		{
+			name:    "source style",
+			repoURL: "https://git.example.org/me/tool",
+			opts:    []Option{WithSourceStyle(WithGogsStyleSource), WithBranch("main")},
+			want: []string{
+				`<meta name="go-source" content="example.org/tool https://git.example.org/me/tool https://git.example.org/me/tool/src/main{/dir} https://git.example.org/me/tool/src/main{/dir}/{file}#L{line}">`,
+			},
+		},
+		{
+			name:    "custom source",
+			repoURL: "https://github.com/me/tool",
+			opts:    []Option{WithSource("example.org/tool", "https://src.example.org", "{/dir}", "{/dir}/{file}")},
+			want:    []string{`<meta name="go-source" content="example.org/tool https://src.example.org {/dir} {/dir}/{file}">`},
+		},
+		{
+			name:    "subdir and clone url",
+			repoURL: "https://github.com/me/mono",
+			opts:    []Option{WithSubdir("/cmd/tool/"), WithCloneURL("ssh://git@github.com/me/mono")},
+			want: []string{
+				`<meta name="go-import" content="example.org/tool git ssh://git@github.com/me/mono cmd/tool">`,
+				`<meta name="go-source" content="example.org/tool https://github.com/me/mono https://github.com/me/mono/tree/master/cmd/tool{/dir} https://github.com/me/mono/blob/master/cmd/tool{/dir}/{file}#L{line}">`,
+			},
+		},
+		{
+			name:    "extra import",
+			repoURL: "https://github.com/me/tool",
+			opts:    []Option{WithGoModProxy("example.org/tool", "https://proxy.example.org")},
+			want: []string{
+				`<meta name="go-import" content="example.org/tool git https://github.com/me/tool">`,
+				`<meta name="go-import" content="example.org/tool mod https://proxy.example.org">`,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New("example.org/tool", tt.repoURL, tt.opts...)
+			body, _ := io.ReadAll(get(t, h, "https://example.org/tool?go-get=1").Body)
+			for _, want := range tt.want {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("missing %s in\n%s", want, body)
+				}
+			}
+			if tt.notWant != "" && strings.Contains(string(body), tt.notWant) {
+				t.Errorf("unexpected %s in\n%s", tt.notWant, body)
+			}
+		})
+	}
+}
+
+func TestNewRedirect(t *testing.T) {
+	docs := WithRedirector(func(pkg string) string { return "https://docs.example.org/" + pkg })
+	for _, tt := range []struct {
+		name     string
+		opts     []Option
+		status   int
+		location string
+	}{
+		{"default", nil, http.StatusTemporaryRedirect, "https://godoc.org/example.org/tool"},
+		{"redirector", []Option{docs}, http.StatusTemporaryRedirect, "https://docs.example.org/example.org/tool"},
+		{"permanent", []Option{docs, WithRedirectStatus(http.StatusMovedPermanently)}, http.StatusMovedPermanently, "https://docs.example.org/example.org/tool"},
+		{"no redirect", []Option{WithRedirectStatus(0)}, http.StatusOK, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, New("example.org/tool", "https://github.com/me/tool", tt.opts...), "https://example.org/tool")
+			if resp.StatusCode != tt.status || resp.Header.Get("Location") != tt.location {
+				t.Errorf("got %d to %q, want %d to %q", resp.StatusCode, resp.Header.Get("Location"), tt.status, tt.location)
+			}
+		})
+	}
+}
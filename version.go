@@ -1,5 +1,45 @@
 package jsn
 
+import "runtime/debug"
+
+// Version is the version of the running program. Unless it's set with
+// -ldflags "-X pkg.jsn.cam/jsn.Version=...", it comes from the build info:
+// the module version for go install, or the VCS revision for builds from a
+// checkout.
 var (
 	Version = "devel"
 )
+
+func init() {
+	if Version != "devel" {
+		return
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		Version = versionOf(bi)
+	}
+}
+
+// versionOf returns the version bi says the program is, or "devel".
+func versionOf(bi *debug.BuildInfo) string {
+	if v := bi.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	var rev string
+	var dirty bool
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	if rev == "" {
+		return "devel"
+	}
+	v := "devel-" + rev[:min(len(rev), 12)]
+	if dirty {
+		v += "-dirty"
+	}
+	return v
+}
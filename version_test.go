@@ -0,0 +1,29 @@
+package jsn
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestVersionOf(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		bi   debug.BuildInfo
+		want string
+	}{
+		{"go install", debug.BuildInfo{Main: debug.Module{Version: "v1.4.0"}}, "v1.4.0"},
+		{"no info", debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}, "devel"},
+		{"checkout", debug.BuildInfo{Main: debug.Module{Version: "(devel)"}, Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef0123"},
+			{Key: "vcs.modified", Value: "false"},
+		}}, "devel-0123456789ab"},
+		{"dirty checkout", debug.BuildInfo{Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef0123"},
+			{Key: "vcs.modified", Value: "true"},
+		}}, "devel-0123456789ab-dirty"},
+	} {
+		if got := versionOf(&tt.bi); got != tt.want {
+			t.Errorf("%s: versionOf() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}